@@ -0,0 +1,224 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package baseline records existing Failures so that only new Failures are
+// reported, allowing stricter checks to be adopted incrementally.
+package baseline
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/uber/prototool/internal/text"
+)
+
+const (
+	// Version is the version of the baseline file format.
+	Version = 1
+
+	// StaleFailureID is the LintID of Failures for Entries that are no
+	// longer reported.
+	StaleFailureID = "STALE_BASELINE"
+)
+
+// positionRegexp matches positions such as "foo.proto:12:3" and "line 12"
+// within messages, so that messages are stable when lines move.
+var positionRegexp = regexp.MustCompile(`(?i)(\bline\s+|:)\d+(:\d+)?`)
+
+// Baseline is a set of recorded Failures.
+type Baseline struct {
+	Version int      `json:"version"`
+	Entries []*Entry `json:"entries"`
+}
+
+// Entry is a recorded Failure.
+//
+// Entries do not contain lines or columns, so that they stay valid
+// when unrelated changes move the Failure.
+type Entry struct {
+	// Filename is slash-separated and relative to the directory of the
+	// baseline file, or empty if the Failure had no filename.
+	Filename string `json:"filename,omitempty"`
+	// ID is the LintID, or text.CompileFailureID if the Failure had no LintID.
+	ID string `json:"id"`
+	// Message is the normalized message.
+	Message string `json:"message"`
+	// Count is the number of Failures with the same filename, ID and
+	// normalized message.
+	Count int `json:"count"`
+}
+
+// New returns a new Baseline for the given Failures.
+//
+// The dirPath is the directory of the baseline file, and the workDirPath is
+// the directory that relative Failure filenames are relative to. Both must
+// be absolute.
+func New(dirPath string, workDirPath string, failures []*text.Failure) (*Baseline, error) {
+	keyToEntry := make(map[entryKey]*Entry)
+	for _, failure := range failures {
+		key, err := getEntryKey(dirPath, workDirPath, failure)
+		if err != nil {
+			return nil, err
+		}
+		entry, ok := keyToEntry[key]
+		if !ok {
+			entry = &Entry{
+				Filename: key.filename,
+				ID:       key.id,
+				Message:  key.message,
+			}
+			keyToEntry[key] = entry
+		}
+		entry.Count++
+	}
+	entries := make([]*Entry, 0, len(keyToEntry))
+	for _, entry := range keyToEntry {
+		entries = append(entries, entry)
+	}
+	sortEntries(entries)
+	return &Baseline{
+		Version: Version,
+		Entries: entries,
+	}, nil
+}
+
+// Read reads the Baseline from the given file path.
+func Read(filePath string) (*Baseline, error) {
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	baseline := &Baseline{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(baseline); err != nil {
+		return nil, fmt.Errorf("could not read baseline %s: %v", filePath, err)
+	}
+	if baseline.Version != Version {
+		return nil, fmt.Errorf("unsupported baseline version %d in %s, expected %d", baseline.Version, filePath, Version)
+	}
+	for _, entry := range baseline.Entries {
+		if entry.Count < 1 {
+			entry.Count = 1
+		}
+	}
+	return baseline, nil
+}
+
+// Write writes the Baseline to the given file path.
+func Write(filePath string, baseline *Baseline) error {
+	data, err := json.MarshalIndent(baseline, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filePath, append(data, '\n'), 0644)
+}
+
+// Filter returns the Failures that are not recorded in the Baseline, and the
+// Entries that did not match any Failure.
+//
+// Each Entry suppresses at most Count Failures. The returned stale Entries
+// have Count set to the number of Failures that were not seen.
+//
+// The dirPath is the directory of the baseline file, and the workDirPath is
+// the directory that relative Failure filenames are relative to. Both must
+// be absolute.
+func (b *Baseline) Filter(dirPath string, workDirPath string, failures []*text.Failure) ([]*text.Failure, []*Entry, error) {
+	keyToCount := make(map[entryKey]int)
+	for _, entry := range b.Entries {
+		keyToCount[entryKey{filename: entry.Filename, id: entry.ID, message: entry.Message}] += entry.Count
+	}
+	var newFailures []*text.Failure
+	for _, failure := range failures {
+		key, err := getEntryKey(dirPath, workDirPath, failure)
+		if err != nil {
+			return nil, nil, err
+		}
+		if keyToCount[key] > 0 {
+			keyToCount[key]--
+			continue
+		}
+		newFailures = append(newFailures, failure)
+	}
+	var staleEntries []*Entry
+	for key, count := range keyToCount {
+		if count > 0 {
+			staleEntries = append(staleEntries, &Entry{
+				Filename: key.filename,
+				ID:       key.id,
+				Message:  key.message,
+				Count:    count,
+			})
+		}
+	}
+	sortEntries(staleEntries)
+	return newFailures, staleEntries, nil
+}
+
+// NormalizeMessage removes positions and repeated whitespace from the message.
+func NormalizeMessage(message string) string {
+	return strings.Join(strings.Fields(positionRegexp.ReplaceAllString(message, "${1}N")), " ")
+}
+
+type entryKey struct {
+	filename string
+	id       string
+	message  string
+}
+
+func getEntryKey(dirPath string, workDirPath string, failure *text.Failure) (entryKey, error) {
+	filename := ""
+	if failure.Filename != "" {
+		// display paths are relative to the working directory of the
+		// runner, which is not necessarily the current directory
+		absFilename := failure.Filename
+		if !filepath.IsAbs(absFilename) {
+			absFilename = filepath.Join(workDirPath, absFilename)
+		}
+		relFilename, err := filepath.Rel(dirPath, absFilename)
+		if err != nil {
+			return entryKey{}, err
+		}
+		filename = filepath.ToSlash(relFilename)
+	}
+	return entryKey{
+		filename: filename,
+		id:       failure.ID(),
+		message:  NormalizeMessage(failure.Message),
+	}, nil
+}
+
+func sortEntries(entries []*Entry) {
+	sort.Slice(entries, func(i int, j int) bool {
+		if entries[i].Filename != entries[j].Filename {
+			return entries[i].Filename < entries[j].Filename
+		}
+		if entries[i].ID != entries[j].ID {
+			return entries[i].ID < entries[j].ID
+		}
+		return entries[i].Message < entries[j].Message
+	})
+}
@@ -0,0 +1,141 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package baseline
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/prototool/internal/text"
+)
+
+func TestNormalizeMessage(t *testing.T) {
+	assert.Equal(t, `"Foo" is already defined in file "a.proto".`, NormalizeMessage(`"Foo" is  already defined in file "a.proto".`))
+	assert.Equal(t, "Previously defined at a.proto:N.", NormalizeMessage("Previously defined at a.proto:12:3."))
+	assert.Equal(t, "Conflicts with line N", NormalizeMessage("Conflicts with line 7"))
+}
+
+func TestNewAndFilter(t *testing.T) {
+	dirPath := filepath.Join(string(filepath.Separator), "a", "b")
+	// relative filenames are relative to the working directory
+	workDirPath := filepath.Join(dirPath, "c")
+	baseline, err := New(
+		dirPath,
+		workDirPath,
+		[]*text.Failure{
+			newTestFailure("foo.proto", 1, 1, "", "Expected \";\" at a.proto:1:2."),
+			newTestFailure(filepath.Join(dirPath, "c", "foo.proto"), 2, 1, "BAR", "hello"),
+			newTestFailure(filepath.Join(dirPath, "c", "foo.proto"), 5, 1, "BAR", "hello"),
+			newTestFailure(filepath.Join(dirPath, "bar.proto"), 5, 1, "BAR", "hello"),
+			newTestFailure("", 0, 0, "", "Import \"x.proto\" was not found."),
+		},
+	)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		&Baseline{
+			Version: Version,
+			Entries: []*Entry{
+				{ID: "COMPILE", Message: "Import \"x.proto\" was not found.", Count: 1},
+				{Filename: "bar.proto", ID: "BAR", Message: "hello", Count: 1},
+				{Filename: "c/foo.proto", ID: "BAR", Message: "hello", Count: 2},
+				{Filename: "c/foo.proto", ID: "COMPILE", Message: "Expected \";\" at a.proto:N.", Count: 1},
+			},
+		},
+		baseline,
+	)
+
+	newFailure := newTestFailure(filepath.Join(dirPath, "c", "foo.proto"), 9, 1, "BAR", "hello")
+	otherFailure := newTestFailure(filepath.Join(dirPath, "c", "foo.proto"), 9, 1, "BAZ", "hello")
+	failures, staleEntries, err := baseline.Filter(
+		dirPath,
+		workDirPath,
+		[]*text.Failure{
+			newTestFailure(filepath.Join(dirPath, "c", "foo.proto"), 3, 1, "", "Expected \";\" at a.proto:2:2."),
+			newTestFailure("foo.proto", 3, 1, "BAR", "hello"),
+			newTestFailure(filepath.Join(dirPath, "c", "foo.proto"), 6, 1, "BAR", "hello"),
+			newFailure,
+			otherFailure,
+		},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, []*text.Failure{newFailure, otherFailure}, failures)
+	assert.Equal(
+		t,
+		[]*Entry{
+			{ID: "COMPILE", Message: "Import \"x.proto\" was not found.", Count: 1},
+			{Filename: "bar.proto", ID: "BAR", Message: "hello", Count: 1},
+		},
+		staleEntries,
+	)
+}
+
+func TestReadWrite(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tmpDir)) }()
+	filePath := filepath.Join(tmpDir, "prototool-baseline.json")
+	baseline, err := New(tmpDir, tmpDir, []*text.Failure{newTestFailure(filepath.Join(tmpDir, "foo.proto"), 1, 1, "BAR", "hello")})
+	require.NoError(t, err)
+	require.NoError(t, Write(filePath, baseline))
+	data, err := ioutil.ReadFile(filePath)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		`{
+  "version": 1,
+  "entries": [
+    {
+      "filename": "foo.proto",
+      "id": "BAR",
+      "message": "hello",
+      "count": 1
+    }
+  ]
+}
+`,
+		string(data),
+	)
+	readBaseline, err := Read(filePath)
+	require.NoError(t, err)
+	assert.Equal(t, baseline, readBaseline)
+
+	require.NoError(t, ioutil.WriteFile(filePath, []byte(`{"version":2,"entries":[]}`), 0644))
+	_, err = Read(filePath)
+	assert.Error(t, err)
+	require.NoError(t, ioutil.WriteFile(filePath, []byte(`{"version":1,"entries":[],"foo":1}`), 0644))
+	_, err = Read(filePath)
+	assert.Error(t, err)
+}
+
+func newTestFailure(filename string, line int, column int, id string, message string) *text.Failure {
+	return &text.Failure{
+		Filename: filename,
+		Line:     line,
+		Column:   column,
+		LintID:   id,
+		Message:  message,
+	}
+}
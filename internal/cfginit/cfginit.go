@@ -21,11 +21,15 @@
 // Package cfginit contains the template for prototool.yaml files, as well
 // as a function to generate a prototool.yaml file given a specific protoc
 // version, with or without commenting out the remainder of the options.
+// Settings can also be inferred from an existing tree of Protobuf files.
 package cfginit
 
 import (
+	"bufio"
 	"bytes"
 	"html/template"
+	"regexp"
+	"strings"
 )
 
 var (
@@ -38,9 +42,31 @@ lint:
 	documentTmpl = template.Must(template.New("documentTmpl").Parse(`# Paths to exclude when searching for Protobuf files.
 # These can either be file or directory names.
 # If there is a directory name, that directory and all sub-directories will be excluded.
+# Paths containing any of "*?[" are glob patterns relative to this file,
+# where "**" matches any number of directories.
 {{.V}}excludes:
 {{.V}}  - path/to/a
 {{.V}}  - path/to/b/file.proto
+{{.V}}  - "**/testdata/**"
+
+# Glob patterns of Protobuf files to include, relative to this file.
+# If set, only files that match, or whose parent directories match,
+# one of these patterns are used.
+{{.V}}includes:
+{{.V}}  - idl/**/*.proto
+
+# Exclude files and directories ignored by .gitignore files found
+# when searching for Protobuf files.
+{{.V}}honor_gitignore: true
+
+# The severities to report failures with, by failure ID.
+# Available severities: "error", "warning", "info".
+# By default all failures are errors. Warnings only fail if there are more
+# than --max-warnings of them, and info failures never fail.
+# Unused imports have the ID UNUSED_IMPORT. Other compile failures have the ID
+# COMPILE, and cannot be changed.
+{{.V}}severities:
+{{.V}}  UNUSED_IMPORT: warning
 
 # Protoc directives.
 protoc:
@@ -60,6 +86,11 @@ protoc:
   # Setting this will ignore unused imports.
   {{.V}}allow_unused_imports: true
 
+  # The maximum time for a single protoc invocation, including plugins.
+  # Invocations that take longer are killed and reported as failures.
+  # By default there is no timeout. Overridden by --protoc-timeout.
+  {{.V}}timeout: 5m
+
 # Create directives.
 {{.V}}create:
   # List of mappings from relative directory to base package.
@@ -227,6 +258,9 @@ lint:
 {{.V}}      include_source_info: true`))
 )
 
+// matches a key line such as "  key:", "  key: value" or "  - key: value"
+var keyLineRegexp = regexp.MustCompile(`^(\s*)(- )?([A-Za-z0-9_]+):(\s|$)`)
+
 type tmplData struct {
 	V             string
 	ProtocVersion string
@@ -255,3 +289,76 @@ func Generate(protocVersion string, uncomment bool, document bool) ([]byte, erro
 	}
 	return buffer.Bytes(), nil
 }
+
+// Descriptions returns the documentation for each configuration key, taken
+// from the comments that precede the key in the documented template.
+//
+// Keys are dot-separated paths such as "generate.plugins.name". List items
+// do not add an element to the path. If a key is documented more than once,
+// the first documentation is used.
+func Descriptions(protocVersion string) (map[string]string, error) {
+	data, err := Generate(protocVersion, true, true)
+	if err != nil {
+		return nil, err
+	}
+	type stackEntry struct {
+		indent int
+		key    string
+	}
+	var stack []stackEntry
+	var commentLines []string
+	descriptions := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "#") {
+			commentLines = append(commentLines, strings.TrimSpace(strings.TrimPrefix(trimmed, "#")))
+			continue
+		}
+		matches := keyLineRegexp.FindStringSubmatch(line)
+		if matches == nil {
+			continue
+		}
+		// the key of a list item is indented past the "- "
+		indent := len(matches[1]) + len(matches[2])
+		for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+			stack = stack[:len(stack)-1]
+		}
+		stack = append(stack, stackEntry{indent: indent, key: matches[3]})
+		keys := make([]string, 0, len(stack))
+		for _, entry := range stack {
+			keys = append(keys, entry.key)
+		}
+		path := strings.Join(keys, ".")
+		if _, ok := descriptions[path]; !ok && len(commentLines) > 0 {
+			descriptions[path] = joinCommentLines(commentLines)
+		}
+		commentLines = nil
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return descriptions, nil
+}
+
+// joinCommentLines joins comment lines into paragraphs, where an empty
+// comment line separates paragraphs.
+func joinCommentLines(commentLines []string) string {
+	var paragraphs []string
+	var current []string
+	for _, commentLine := range commentLines {
+		if commentLine == "" {
+			if len(current) > 0 {
+				paragraphs = append(paragraphs, strings.Join(current, " "))
+				current = nil
+			}
+			continue
+		}
+		current = append(current, commentLine)
+	}
+	if len(current) > 0 {
+		paragraphs = append(paragraphs, strings.Join(current, " "))
+	}
+	return strings.Join(paragraphs, "\n\n")
+}
@@ -0,0 +1,525 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cfginit
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/uber/prototool/internal/file"
+	"github.com/uber/prototool/internal/strs"
+	"github.com/uber/prototool/internal/wkt"
+	yaml "gopkg.in/yaml.v2"
+)
+
+var (
+	inferTmpl = template.Must(template.New("inferTmpl").Funcs(template.FuncMap{"yaml": yamlString}).Parse(`# Settings were inferred from {{.FileCount}} existing Protobuf files.
+# Review them before using this file.
+{{- if .Excludes}}
+
+# Paths to exclude when searching for Protobuf files.
+# Include paths found within this directory are excluded so that
+# their files are not compiled twice.
+excludes:
+{{- range .Excludes}}
+  - {{yaml .}}
+{{- end}}
+{{- end}}
+
+# Protoc directives.
+protoc:
+  version: {{.ProtocVersion}}
+{{- if .Includes}}
+  # Additional paths to include with -I to protoc, inferred from imports
+  # that are not relative to this directory.
+  includes:
+{{- range .Includes}}
+    - {{yaml .}}
+{{- end}}
+{{- end}}
+{{- if .ImportsWellKnownTypes}}
+  # The well-known types in google/protobuf are imported. These are always
+  # included, so they do not need to be added to includes.
+{{- end}}
+{{- if .UnresolvedImports}}
+  # The following imports could not be found, add includes for them:
+{{- range .UnresolvedImports}}
+  #   - {{.}}
+{{- end}}
+{{- end}}
+{{- if .Packages}}
+
+# Create directives.
+create:
+  # Mappings from relative directory to base package, inferred
+  # from the package statements of existing files.
+  packages:
+{{- range .Packages}}
+    - directory: {{yaml .Directory}}
+      name: {{yaml .Name}}
+{{- end}}
+{{- end}}
+
+# Lint directives.
+lint:
+  group: uber2
+{{- if .Plugins}}
+
+# Code generation directives, inferred from existing generated Go code.
+{{- if .GoImportPath}}
+generate:
+  go_options:
+    # The base import path. This is the go path of this file.
+    import_path: {{yaml .GoImportPath}}
+  plugins:
+{{- range .Plugins}}
+    - name: {{yaml .Name}}
+      type: {{yaml .Type}}
+{{- if .Flags}}
+      flags: {{yaml .Flags}}
+{{- end}}
+      output: {{yaml .Output}}
+{{- end}}
+{{- else}}
+# The go path of this file could not be inferred from a go.mod file.
+# Set generate.go_options.import_path and uncomment this section.
+#generate:
+#  go_options:
+#    import_path: TODO
+#  plugins:
+{{- range .Plugins}}
+#    - name: {{yaml .Name}}
+#      type: {{yaml .Type}}
+{{- if .Flags}}
+#      flags: {{yaml .Flags}}
+{{- end}}
+#      output: {{yaml .Output}}
+{{- end}}
+{{- end}}
+{{- end}}
+`))
+
+	sourceRegexp = regexp.MustCompile(`^// source: (\S+\.proto)$`)
+	moduleRegexp = regexp.MustCompile(`(?m)^module\s+"?([^"\s]+)"?`)
+)
+
+// Inference contains the settings inferred from an existing tree of
+// Protobuf files by Infer.
+type Inference struct {
+	// The number of .proto files settings were inferred from.
+	FileCount int
+	// Include paths relative to the config directory.
+	Includes []string
+	// Exclude paths relative to the config directory.
+	Excludes []string
+	// Imports that could not be found in the tree.
+	UnresolvedImports []string
+	// ImportsWellKnownTypes is set if any file imports a well-known type.
+	ImportsWellKnownTypes bool
+	// Mappings for create.packages.
+	Packages []InferredPackage
+	// The go path of the config directory, if a go.mod file was found.
+	GoImportPath string
+	// Plugins that generated existing Go code.
+	Plugins []InferredPlugin
+}
+
+// InferredPackage is an inferred create.packages mapping.
+type InferredPackage struct {
+	// The directory relative to the config directory.
+	Directory string
+	Name      string
+}
+
+// InferredPlugin is an inferred generate.plugins entry.
+type InferredPlugin struct {
+	Name  string
+	Type  string
+	Flags string
+	// The output path relative to the config directory.
+	Output string
+}
+
+// Infer infers the settings for a config file in dirPath from the given
+// .proto files and any generated Go code within dirPath.
+//
+// dirPath and the file paths must be absolute and cleaned.
+func Infer(dirPath string, filePaths []string) (*Inference, error) {
+	relFilePathToPackage := make(map[string]string, len(filePaths))
+	relFilePathToImports := make(map[string][]string, len(filePaths))
+	for _, filePath := range filePaths {
+		relFilePath, err := filepath.Rel(dirPath, filePath)
+		if err != nil {
+			return nil, err
+		}
+		data, err := ioutil.ReadFile(filePath)
+		if err != nil {
+			return nil, err
+		}
+		pkg, imports := file.ParsePackageAndImports(data)
+		relFilePath = filepath.ToSlash(relFilePath)
+		relFilePathToPackage[relFilePath] = pkg
+		relFilePathToImports[relFilePath] = imports
+	}
+	inference := &Inference{
+		FileCount: len(filePaths),
+	}
+	inferIncludes(inference, relFilePathToImports)
+	inferPackages(inference, relFilePathToPackage)
+	if err := inferPlugins(inference, dirPath); err != nil {
+		return nil, err
+	}
+	if len(inference.Plugins) > 0 {
+		goImportPath, err := getGoImportPath(dirPath)
+		if err != nil {
+			return nil, err
+		}
+		inference.GoImportPath = goImportPath
+	}
+	return inference, nil
+}
+
+// GenerateInferred generates the data for a config file with the inferred settings.
+func GenerateInferred(protocVersion string, inference *Inference) ([]byte, error) {
+	buffer := bytes.NewBuffer(nil)
+	if err := inferTmpl.Execute(
+		buffer,
+		struct {
+			*Inference
+			ProtocVersion string
+		}{
+			Inference:     inference,
+			ProtocVersion: protocVersion,
+		},
+	); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// inferIncludes finds the include paths needed for imports that are not
+// relative to the config directory.
+//
+// An import "google/api/http.proto" of a file within the tree at
+// "third_party/google/api/http.proto" results in the include "third_party".
+func inferIncludes(inference *Inference, relFilePathToImports map[string][]string) {
+	var includes []string
+	var unresolvedImports []string
+	for _, imports := range relFilePathToImports {
+		for _, importPath := range imports {
+			if _, ok := wkt.Filenames[importPath]; ok {
+				inference.ImportsWellKnownTypes = true
+				continue
+			}
+			if _, ok := relFilePathToImports[importPath]; ok {
+				continue
+			}
+			include := ""
+			for relFilePath := range relFilePathToImports {
+				if !strings.HasSuffix(relFilePath, "/"+importPath) {
+					continue
+				}
+				candidate := strings.TrimSuffix(relFilePath, "/"+importPath)
+				if include == "" || len(candidate) < len(include) {
+					include = candidate
+				}
+			}
+			if include == "" {
+				unresolvedImports = append(unresolvedImports, importPath)
+				continue
+			}
+			includes = append(includes, include)
+		}
+	}
+	inference.Includes = strs.SortUniq(includes)
+	inference.Excludes = inference.Includes
+	inference.UnresolvedImports = strs.SortUniq(unresolvedImports)
+}
+
+// inferPackages finds the create.packages mappings needed so that create
+// would produce the packages of existing files.
+//
+// Without a mapping, create uses the directory relative to the config
+// directory as the package, so only directories where this does not match
+// the existing package get a mapping. The mapping is placed as high up as
+// the directory and package suffixes allow, so that one mapping can cover
+// many directories.
+func inferPackages(inference *Inference, relFilePathToPackage map[string]string) {
+	dirToPackageCounts := make(map[string]map[string]int)
+	for relFilePath, pkg := range relFilePathToPackage {
+		if pkg == "" || isExcludedRelPath(relFilePath, inference.Excludes) {
+			continue
+		}
+		dir := filepath.ToSlash(filepath.Dir(relFilePath))
+		if dirToPackageCounts[dir] == nil {
+			dirToPackageCounts[dir] = make(map[string]int)
+		}
+		dirToPackageCounts[dir][pkg]++
+	}
+	dirs := make([]string, 0, len(dirToPackageCounts))
+	for dir := range dirToPackageCounts {
+		dirs = append(dirs, dir)
+	}
+	// parents sort before their children
+	sort.Strings(dirs)
+	mappings := make(map[string]string)
+	for _, dir := range dirs {
+		pkg := getMostCommon(dirToPackageCounts[dir])
+		if getCreatePackage(mappings, dir) == pkg {
+			continue
+		}
+		var dirSplit []string
+		if dir != "." {
+			dirSplit = strings.Split(dir, "/")
+		}
+		pkgSplit := strings.Split(pkg, ".")
+		// the number of trailing elements shared by the directory and package,
+		// keeping at least one package element for the base package
+		k := 0
+		for k < len(dirSplit) && k < len(pkgSplit)-1 && dirSplit[len(dirSplit)-1-k] == pkgSplit[len(pkgSplit)-1-k] {
+			k++
+		}
+		for ; k >= 0; k-- {
+			mappingDir := strings.Join(dirSplit[:len(dirSplit)-k], "/")
+			if mappingDir == "" {
+				mappingDir = "."
+			}
+			mappingName := strings.Join(pkgSplit[:len(pkgSplit)-k], ".")
+			if existing, ok := mappings[mappingDir]; ok && existing != mappingName {
+				continue
+			}
+			mappings[mappingDir] = mappingName
+			break
+		}
+	}
+	mappingDirs := make([]string, 0, len(mappings))
+	for mappingDir := range mappings {
+		mappingDirs = append(mappingDirs, mappingDir)
+	}
+	sort.Strings(mappingDirs)
+	for _, dir := range mappingDirs {
+		inference.Packages = append(inference.Packages, InferredPackage{Directory: dir, Name: mappings[dir]})
+	}
+}
+
+// getCreatePackage returns the package that create would use for a file in
+// the directory given the mappings, or an empty string if create would
+// use its default package.
+//
+// This mirrors the logic in the create package.
+func getCreatePackage(mappings map[string]string, dir string) string {
+	longestMappingDir := ""
+	for mappingDir := range mappings {
+		if mappingDir != "." && dir != mappingDir && !strings.HasPrefix(dir, mappingDir+"/") {
+			continue
+		}
+		if longestMappingDir == "" || longestMappingDir == "." || len(mappingDir) > len(longestMappingDir) {
+			longestMappingDir = mappingDir
+		}
+	}
+	basePkg := mappings[longestMappingDir]
+	rel := dir
+	if longestMappingDir != "" && longestMappingDir != "." {
+		rel = strings.TrimPrefix(strings.TrimPrefix(dir, longestMappingDir), "/")
+	}
+	if rel == "" || rel == "." {
+		return basePkg
+	}
+	relPkg := strings.Replace(rel, "/", ".", -1)
+	if basePkg == "" {
+		return relPkg
+	}
+	return basePkg + "." + relPkg
+}
+
+// inferPlugins finds generated Go code within dirPath and infers the
+// plugins and output paths that generated it.
+func inferPlugins(inference *Inference, dirPath string) error {
+	type pluginKey struct {
+		name   string
+		output string
+	}
+	pluginKeyToPlugin := make(map[pluginKey]InferredPlugin)
+	if err := filepath.Walk(
+		dirPath,
+		func(filePath string, fileInfo os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if fileInfo.IsDir() {
+				name := fileInfo.Name()
+				if filePath != dirPath && (strings.HasPrefix(name, ".") || name == "vendor" || name == "node_modules") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !strings.HasSuffix(filePath, ".go") {
+				return nil
+			}
+			plugin, source, err := getGoPlugin(filePath)
+			if err != nil || plugin.Name == "" {
+				return err
+			}
+			relFileDir, err := filepath.Rel(dirPath, filepath.Dir(filePath))
+			if err != nil {
+				return err
+			}
+			// the generated file is written to the output path joined with
+			// the directory of the source file
+			relFileDir = filepath.ToSlash(relFileDir)
+			sourceDir := filepath.ToSlash(filepath.Dir(source))
+			output := relFileDir
+			if sourceDir != "." {
+				if relFileDir != sourceDir && !strings.HasSuffix(relFileDir, "/"+sourceDir) {
+					// not generated relative to the source file, ignore
+					return nil
+				}
+				output = strings.TrimSuffix(strings.TrimSuffix(relFileDir, sourceDir), "/")
+			}
+			if output == "" {
+				output = "."
+			}
+			plugin.Output = output
+			key := pluginKey{name: plugin.Name, output: plugin.Output}
+			if existing, ok := pluginKeyToPlugin[key]; ok && existing.Flags != "" {
+				plugin.Flags = existing.Flags
+			}
+			pluginKeyToPlugin[key] = plugin
+			return nil
+		},
+	); err != nil {
+		return err
+	}
+	for _, plugin := range pluginKeyToPlugin {
+		inference.Plugins = append(inference.Plugins, plugin)
+	}
+	sort.Slice(inference.Plugins, func(i int, j int) bool {
+		if inference.Plugins[i].Name == inference.Plugins[j].Name {
+			return inference.Plugins[i].Output < inference.Plugins[j].Output
+		}
+		return inference.Plugins[i].Name < inference.Plugins[j].Name
+	})
+	return nil
+}
+
+// getGoPlugin returns the plugin that generated the Go file and the
+// .proto file it was generated from.
+//
+// An empty plugin is returned if the file was not generated from a .proto file.
+func getGoPlugin(filePath string) (InferredPlugin, string, error) {
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return InferredPlugin{}, "", err
+	}
+	source := ""
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "package ") {
+			break
+		}
+		if matches := sourceRegexp.FindStringSubmatch(line); matches != nil {
+			source = matches[1]
+			break
+		}
+	}
+	if source == "" {
+		return InferredPlugin{}, "", nil
+	}
+	base := filepath.Base(filePath)
+	switch {
+	case strings.HasSuffix(base, ".pb.gw.go"):
+		return InferredPlugin{Name: "grpc-gateway", Type: "go"}, source, nil
+	case strings.HasSuffix(base, "_grpc.pb.go"):
+		return InferredPlugin{Name: "go-grpc", Type: "go"}, source, nil
+	case strings.HasSuffix(base, ".pb.go"):
+		plugin := InferredPlugin{Name: "go", Type: "go"}
+		if bytes.Contains(data, []byte(`"github.com/gogo/protobuf/`)) {
+			plugin = InferredPlugin{Name: "gogo", Type: "gogo"}
+		}
+		if bytes.Contains(data, []byte("grpc.ClientConn")) {
+			plugin.Flags = "plugins=grpc"
+		}
+		return plugin, source, nil
+	default:
+		return InferredPlugin{}, "", nil
+	}
+}
+
+// getGoImportPath returns the go path of dirPath from the closest go.mod
+// file, or an empty string if there is none.
+func getGoImportPath(dirPath string) (string, error) {
+	for moduleDirPath := dirPath; ; moduleDirPath = filepath.Dir(moduleDirPath) {
+		data, err := ioutil.ReadFile(filepath.Join(moduleDirPath, "go.mod"))
+		if err == nil {
+			matches := moduleRegexp.FindSubmatch(data)
+			if matches == nil {
+				return "", nil
+			}
+			rel, err := filepath.Rel(moduleDirPath, dirPath)
+			if err != nil {
+				return "", err
+			}
+			return filepath.ToSlash(filepath.Join(string(matches[1]), rel)), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+		if filepath.Dir(moduleDirPath) == moduleDirPath {
+			return "", nil
+		}
+	}
+}
+
+func isExcludedRelPath(relFilePath string, excludes []string) bool {
+	for _, exclude := range excludes {
+		if exclude == "." || strings.HasPrefix(relFilePath, exclude+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func getMostCommon(counts map[string]int) string {
+	mostCommon := ""
+	for value, count := range counts {
+		if mostCommon == "" || count > counts[mostCommon] || (count == counts[mostCommon] && value < mostCommon) {
+			mostCommon = value
+		}
+	}
+	return mostCommon
+}
+
+// yamlString returns the value as a YAML scalar, quoting it if needed.
+func yamlString(value string) (string, error) {
+	data, err := yaml.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(string(data), "\n"), nil
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cfginit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/prototool/internal/settings"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestInfer(t *testing.T) {
+	inference := testInfer(t, "testdata/infer")
+	assert.Equal(
+		t,
+		&Inference{
+			FileCount:             4,
+			Includes:              []string{"third_party"},
+			Excludes:              []string{"third_party"},
+			UnresolvedImports:     []string{"missing/missing.proto"},
+			ImportsWellKnownTypes: true,
+			Packages: []InferredPackage{
+				{Directory: ".", Name: "acme"},
+				{Directory: "other/legacy", Name: "legacy"},
+			},
+			GoImportPath: "github.com/acme/idl",
+			Plugins: []InferredPlugin{
+				{Name: "go", Type: "go", Flags: "plugins=grpc", Output: "gen/go"},
+				{Name: "grpc-gateway", Type: "go", Output: "gen/go"},
+			},
+		},
+		inference,
+	)
+	data, err := GenerateInferred("3.11.0", inference)
+	require.NoError(t, err)
+	externalConfig := settings.ExternalConfig{}
+	require.NoError(t, yaml.UnmarshalStrict(data, &externalConfig))
+	assert.Equal(t, []string{"third_party"}, externalConfig.Protoc.Includes)
+	assert.Equal(t, "github.com/acme/idl", externalConfig.Generate.GoOptions.ImportPath)
+	assert.Len(t, externalConfig.Generate.Plugins, 2)
+	assert.Contains(t, string(data), "#   - missing/missing.proto")
+}
+
+func TestInferPackages(t *testing.T) {
+	testInferPackages(t, nil, map[string]string{"a/b/c.proto": "a.b"})
+	testInferPackages(t, []InferredPackage{{Directory: "idl/code.uber", Name: "uber"}}, map[string]string{
+		"idl/code.uber/a/b/c.proto": "uber.a.b",
+		"idl/code.uber/a/d.proto":   "uber.a",
+	})
+	testInferPackages(t, []InferredPackage{{Directory: ".", Name: "foo"}}, map[string]string{"a.proto": "foo"})
+}
+
+func testInfer(t *testing.T, dirPath string) *Inference {
+	absDirPath, err := filepath.Abs(dirPath)
+	require.NoError(t, err)
+	var filePaths []string
+	require.NoError(t, filepath.Walk(absDirPath, func(filePath string, fileInfo os.FileInfo, err error) error {
+		if err == nil && strings.HasSuffix(filePath, ".proto") {
+			filePaths = append(filePaths, filePath)
+		}
+		return err
+	}))
+	inference, err := Infer(absDirPath, filePaths)
+	require.NoError(t, err)
+	return inference
+}
+
+func testInferPackages(t *testing.T, expected []InferredPackage, relFilePathToPackage map[string]string) {
+	inference := &Inference{}
+	inferPackages(inference, relFilePathToPackage)
+	assert.Equal(t, expected, inference.Packages)
+}
@@ -0,0 +1,10 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: foo/v1/foo.proto
+
+package foov1
+
+import (
+	grpc "google.golang.org/grpc"
+)
+
+var _ grpc.ClientConnInterface
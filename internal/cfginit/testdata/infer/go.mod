@@ -0,0 +1,3 @@
+module github.com/acme/idl
+
+go 1.17
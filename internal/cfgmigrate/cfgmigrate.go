@@ -0,0 +1,366 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package cfgmigrate upgrades prototool.yaml and prototool.json files that
+// use deprecated settings, and converts them between YAML and JSON.
+//
+// When the input and output are both YAML, comments and blank lines are
+// preserved.
+package cfgmigrate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/uber/prototool/internal/settings"
+	yamlv2 "gopkg.in/yaml.v2"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// FormatYAML is the YAML format.
+	FormatYAML Format = iota + 1
+	// FormatJSON is the JSON format.
+	FormatJSON
+
+	// blankLineComment is substituted for blank lines while the YAML
+	// document is parsed so that they survive re-encoding as comments.
+	blankLineComment = "#prototool:blank"
+)
+
+var (
+	_formatToString = map[Format]string{
+		FormatYAML: "yaml",
+		FormatJSON: "json",
+	}
+	_stringToFormat = map[string]Format{
+		"yaml": FormatYAML,
+		"json": FormatJSON,
+	}
+
+	// blockScalarRegexp matches lines that start a literal or folded block scalar.
+	blockScalarRegexp = regexp.MustCompile(`(:|^\s*-)\s+[|>]([-+0-9]*)\s*(#.*)?$`)
+)
+
+// Format is a configuration file format.
+type Format int
+
+// String implements fmt.Stringer.
+func (f Format) String() string {
+	if s, ok := _formatToString[f]; ok {
+		return s
+	}
+	return fmt.Sprintf("%d", f)
+}
+
+// Ext returns the file extension for the format, including the leading dot.
+func (f Format) Ext() string {
+	return "." + f.String()
+}
+
+// ParseFormat parses the Format from the given string.
+//
+// The string is case-insensitive and may have a leading dot.
+func ParseFormat(s string) (Format, error) {
+	format, ok := _stringToFormat[strings.TrimPrefix(strings.ToLower(s), ".")]
+	if !ok {
+		return 0, fmt.Errorf("could not parse %s to a Format, must be yaml or json", s)
+	}
+	return format, nil
+}
+
+// FormatForFilePath returns the Format for the extension of the given file path.
+func FormatForFilePath(filePath string) (Format, error) {
+	format, ok := _stringToFormat[strings.TrimPrefix(filepath.Ext(filePath), ".")]
+	if !ok {
+		return 0, fmt.Errorf("unknown config file extension, must be .json or .yaml: %s", filePath)
+	}
+	return format, nil
+}
+
+// Result is the result of a migration.
+type Result struct {
+	// Data is the migrated configuration data.
+	Data []byte
+	// Changes are descriptions of the migrations that were applied, in order.
+	Changes []string
+}
+
+// Migrate replaces deprecated settings in the given configuration data,
+// and converts the data from the from Format to the to Format.
+//
+// If no migrations apply and the formats are the same, the data is returned unchanged.
+// The migrated data is checked to be a valid configuration.
+func Migrate(data []byte, from Format, to Format) (*Result, error) {
+	if _, ok := _formatToString[from]; !ok {
+		return nil, fmt.Errorf("unknown Format: %v", from)
+	}
+	if _, ok := _formatToString[to]; !ok {
+		return nil, fmt.Errorf("unknown Format: %v", to)
+	}
+	input := data
+	if from == FormatYAML {
+		input = markBlankLines(data)
+	}
+	node := &yaml.Node{}
+	if err := yaml.Unmarshal(input, node); err != nil {
+		return nil, err
+	}
+	root, err := getRootMapping(node)
+	if err != nil {
+		return nil, err
+	}
+	var changes []string
+	for _, m := range _migrations {
+		if change := m(root); change != "" {
+			changes = append(changes, change)
+		}
+	}
+	if len(changes) == 0 && from == to {
+		return &Result{Data: data}, nil
+	}
+	var output []byte
+	switch to {
+	case FormatYAML:
+		if from == FormatJSON {
+			clearStyle(root)
+		}
+		output, err = encodeYAML(root)
+	case FormatJSON:
+		output, err = encodeJSON(root)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := validate(output); err != nil {
+		return nil, fmt.Errorf("migrated configuration is not valid: %v", err)
+	}
+	return &Result{
+		Data:    output,
+		Changes: changes,
+	}, nil
+}
+
+// Diff returns a unified diff between the from and to data.
+//
+// An empty string is returned if the data is equal.
+func Diff(fromFilePath string, toFilePath string, from []byte, to []byte) (string, error) {
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(from)),
+		B:        difflib.SplitLines(string(to)),
+		FromFile: fromFilePath,
+		ToFile:   toFilePath,
+		Context:  3,
+	})
+}
+
+// getRootMapping returns the top-level mapping of the document, creating it if
+// the document is empty.
+func getRootMapping(node *yaml.Node) (*yaml.Node, error) {
+	if node.Kind == 0 {
+		return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}, nil
+	}
+	if node.Kind != yaml.DocumentNode || len(node.Content) != 1 {
+		return nil, fmt.Errorf("expected a single document")
+	}
+	root := node.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("expected a mapping at the top level of the configuration")
+	}
+	// Comments at the top of a file are attached to the document node.
+	if node.HeadComment != "" {
+		if root.HeadComment != "" {
+			root.HeadComment = node.HeadComment + "\n" + root.HeadComment
+		} else {
+			root.HeadComment = node.HeadComment
+		}
+	}
+	if node.FootComment != "" {
+		if root.FootComment != "" {
+			root.FootComment = root.FootComment + "\n" + node.FootComment
+		} else {
+			root.FootComment = node.FootComment
+		}
+	}
+	return root, nil
+}
+
+// markBlankLines replaces blank lines outside of block scalars with blankLineComment.
+//
+// Trailing blank lines are dropped.
+func markBlankLines(data []byte) []byte {
+	lines := strings.Split(strings.TrimRight(string(data), "\n\t "), "\n")
+	blockScalarIndent := -1
+	blockScalarKeep := false
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		if blockScalarIndent >= 0 {
+			if trimmed == "" || indent > blockScalarIndent {
+				continue
+			}
+			// Trailing blank lines are not part of the block scalar
+			// unless the keep chomping indicator is used.
+			for j := i - 1; j >= 0 && !blockScalarKeep && strings.TrimSpace(lines[j]) == ""; j-- {
+				lines[j] = blankLineComment
+			}
+			blockScalarIndent = -1
+		}
+		if trimmed == "" {
+			lines[i] = blankLineComment
+			continue
+		}
+		if match := blockScalarRegexp.FindStringSubmatch(line); match != nil {
+			blockScalarIndent = indent
+			blockScalarKeep = strings.Contains(match[2], "+")
+		}
+	}
+	return []byte(strings.Join(lines, "\n") + "\n")
+}
+
+func encodeYAML(root *yaml.Node) ([]byte, error) {
+	if len(root.Content) == 0 && root.HeadComment == "" && root.FootComment == "" {
+		return []byte{}, nil
+	}
+	buffer := bytes.NewBuffer(nil)
+	encoder := yaml.NewEncoder(buffer)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(root); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	lines := strings.Split(buffer.String(), "\n")
+	for i, line := range lines {
+		if strings.TrimSpace(line) == blankLineComment {
+			lines[i] = ""
+		}
+	}
+	return []byte(strings.TrimSpace(strings.Join(lines, "\n")) + "\n"), nil
+}
+
+func encodeJSON(root *yaml.Node) ([]byte, error) {
+	buffer := bytes.NewBuffer(nil)
+	if err := writeJSON(buffer, root); err != nil {
+		return nil, err
+	}
+	output := bytes.NewBuffer(nil)
+	if err := json.Indent(output, buffer.Bytes(), "", "  "); err != nil {
+		return nil, err
+	}
+	output.WriteString("\n")
+	return output.Bytes(), nil
+}
+
+// writeJSON writes the node as compact JSON, preserving the order of mapping keys.
+func writeJSON(buffer *bytes.Buffer, node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) != 1 {
+			return fmt.Errorf("expected a single document")
+		}
+		return writeJSON(buffer, node.Content[0])
+	case yaml.AliasNode:
+		return writeJSON(buffer, node.Alias)
+	case yaml.MappingNode:
+		buffer.WriteString("{")
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if i > 0 {
+				buffer.WriteString(",")
+			}
+			if err := writeJSONValue(buffer, node.Content[i].Value); err != nil {
+				return err
+			}
+			buffer.WriteString(":")
+			if err := writeJSON(buffer, node.Content[i+1]); err != nil {
+				return err
+			}
+		}
+		buffer.WriteString("}")
+		return nil
+	case yaml.SequenceNode:
+		buffer.WriteString("[")
+		for i, child := range node.Content {
+			if i > 0 {
+				buffer.WriteString(",")
+			}
+			if err := writeJSON(buffer, child); err != nil {
+				return err
+			}
+		}
+		buffer.WriteString("]")
+		return nil
+	case yaml.ScalarNode:
+		var value interface{}
+		switch node.ShortTag() {
+		case "!!null":
+		case "!!bool":
+			value = new(bool)
+		case "!!int":
+			value = new(int64)
+		case "!!float":
+			value = new(float64)
+		default:
+			return writeJSONValue(buffer, node.Value)
+		}
+		if value != nil {
+			if err := node.Decode(value); err != nil {
+				return err
+			}
+		}
+		return writeJSONValue(buffer, value)
+	default:
+		return fmt.Errorf("unknown YAML node kind at line %d: %v", node.Line, node.Kind)
+	}
+}
+
+func writeJSONValue(buffer *bytes.Buffer, value interface{}) error {
+	encoder := json.NewEncoder(buffer)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return err
+	}
+	// json.Encoder always appends a newline.
+	buffer.Truncate(buffer.Len() - 1)
+	return nil
+}
+
+// clearStyle resets the style of every node so that JSON input is
+// written as block-style YAML.
+func clearStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		clearStyle(child)
+	}
+}
+
+// validate checks that the data is a valid configuration.
+//
+// YAML is a superset of JSON, so this works for both formats.
+func validate(data []byte) error {
+	externalConfig := settings.ExternalConfig{}
+	return yamlv2.UnmarshalStrict(data, &externalConfig)
+}
@@ -0,0 +1,246 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cfgmigrate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrateNoDefault(t *testing.T) {
+	testMigrate(
+		t,
+		FormatYAML,
+		FormatYAML,
+		`# Paths to exclude.
+excludes:
+  - foo
+
+# Lint directives.
+lint:
+  # Linter rules.
+  rules:
+    # Do not use the default linters.
+    no_default: true
+    add:
+      - ENUM_NAMES_CAMEL_CASE # trailing
+
+  java_package_prefix: au.com
+`,
+		`# Paths to exclude.
+excludes:
+  - foo
+
+# Lint directives.
+lint:
+  group: empty
+  # Linter rules.
+  rules:
+    add:
+      - ENUM_NAMES_CAMEL_CASE # trailing
+
+  java_package_prefix: au.com
+`,
+		`replaced lint.rules.no_default with lint.group "empty"`,
+	)
+}
+
+func TestMigrateNoDefaultWithGroup(t *testing.T) {
+	testMigrate(
+		t,
+		FormatYAML,
+		FormatYAML,
+		`lint:
+  group: uber2
+  rules:
+    no_default: true
+`,
+		`lint:
+  group: uber2
+`,
+		`removed lint.rules.no_default, which is ignored when lint.group is set`,
+	)
+}
+
+func TestMigrateNoDefaultFalse(t *testing.T) {
+	testMigrate(
+		t,
+		FormatJSON,
+		FormatJSON,
+		`{"lint": {"rules": {"no_default": false, "remove": ["FILE_OPTIONS_REQUIRE_JAVA_PACKAGE"]}}}`,
+		`{
+  "lint": {
+    "rules": {
+      "remove": [
+        "FILE_OPTIONS_REQUIRE_JAVA_PACKAGE"
+      ]
+    }
+  }
+}
+`,
+		`removed lint.rules.no_default, which was set to false`,
+	)
+}
+
+func TestMigrateUnchanged(t *testing.T) {
+	data := "protoc:\n    version:   3.11.0\n\n\n"
+	result, err := Migrate([]byte(data), FormatYAML, FormatYAML)
+	require.NoError(t, err)
+	assert.Equal(t, data, string(result.Data))
+	assert.Empty(t, result.Changes)
+}
+
+func TestMigrateBlockScalar(t *testing.T) {
+	testMigrate(
+		t,
+		FormatYAML,
+		FormatYAML,
+		`lint:
+  rules:
+    no_default: true
+  file_header:
+    content: |
+      // Copyright
+
+      // Licensed
+
+    is_commented: true
+`,
+		`lint:
+  group: empty
+  file_header:
+    content: |
+      // Copyright
+
+      // Licensed
+
+    is_commented: true
+`,
+		`replaced lint.rules.no_default with lint.group "empty"`,
+	)
+}
+
+func TestMigrateYAMLToJSON(t *testing.T) {
+	testMigrate(
+		t,
+		FormatYAML,
+		FormatJSON,
+		`# Comments are dropped.
+protoc:
+  version: 3.11.0
+  allow_unused_imports: true
+excludes:
+  - foo
+generate:
+  go_options:
+    import_path: github.com/foo/bar
+    extra_modifiers:
+      google/api/annotations.proto: google.golang.org/genproto/googleapis/api/annotations
+  plugins:
+    - name: go
+      type: go
+      flags: plugins=grpc
+      output: ../gen/go
+`,
+		`{
+  "protoc": {
+    "version": "3.11.0",
+    "allow_unused_imports": true
+  },
+  "excludes": [
+    "foo"
+  ],
+  "generate": {
+    "go_options": {
+      "import_path": "github.com/foo/bar",
+      "extra_modifiers": {
+        "google/api/annotations.proto": "google.golang.org/genproto/googleapis/api/annotations"
+      }
+    },
+    "plugins": [
+      {
+        "name": "go",
+        "type": "go",
+        "flags": "plugins=grpc",
+        "output": "../gen/go"
+      }
+    ]
+  }
+}
+`,
+	)
+}
+
+func TestMigrateJSONToYAML(t *testing.T) {
+	testMigrate(
+		t,
+		FormatJSON,
+		FormatYAML,
+		`{"protoc": {"version": "3.11.0", "includes": ["a", "b"]}, "lint": {"rules": {"no_default": true}}, "break": {"include_beta": true}}`,
+		`protoc:
+  version: 3.11.0
+  includes:
+    - a
+    - b
+lint:
+  group: empty
+break:
+  include_beta: true
+`,
+		`replaced lint.rules.no_default with lint.group "empty"`,
+	)
+}
+
+func TestMigrateEmpty(t *testing.T) {
+	testMigrate(t, FormatYAML, FormatJSON, ``, "{}\n")
+	testMigrate(t, FormatJSON, FormatYAML, `{}`, ``)
+}
+
+func TestMigrateInvalid(t *testing.T) {
+	_, err := Migrate([]byte("lint:\n  rules:\n    no_default: true\n  unknown: true\n"), FormatYAML, FormatYAML)
+	assert.Error(t, err)
+	_, err = Migrate([]byte("- foo\n"), FormatYAML, FormatJSON)
+	assert.Error(t, err)
+}
+
+func TestParseFormat(t *testing.T) {
+	format, err := ParseFormat("JSON")
+	assert.NoError(t, err)
+	assert.Equal(t, FormatJSON, format)
+	format, err = ParseFormat(".yaml")
+	assert.NoError(t, err)
+	assert.Equal(t, FormatYAML, format)
+	_, err = ParseFormat("yml")
+	assert.Error(t, err)
+	format, err = FormatForFilePath("/foo/prototool.json")
+	assert.NoError(t, err)
+	assert.Equal(t, FormatJSON, format)
+	assert.Equal(t, ".json", format.Ext())
+}
+
+func testMigrate(t *testing.T, from Format, to Format, input string, expected string, expectedChanges ...string) {
+	result, err := Migrate([]byte(input), from, to)
+	require.NoError(t, err)
+	assert.Equal(t, expected, string(result.Data))
+	assert.Equal(t, expectedChanges, result.Changes)
+}
@@ -0,0 +1,107 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cfgmigrate
+
+import (
+	"gopkg.in/yaml.v3"
+)
+
+// migration migrates a deprecated setting within the top-level mapping of
+// a configuration.
+//
+// A description of the change is returned, or an empty string if the
+// migration did not apply.
+type migration func(root *yaml.Node) string
+
+// _migrations are all migrations, applied in order.
+var _migrations = []migration{
+	migrateLintRulesNoDefault,
+}
+
+// migrateLintRulesNoDefault replaces lint.rules.no_default with lint.group "empty".
+//
+// no_default is ignored if a group is set, in which case it is just removed.
+func migrateLintRulesNoDefault(root *yaml.Node) string {
+	lint := getMapping(root, "lint")
+	rules := getMapping(lint, "rules")
+	noDefaultNode := getValue(rules, "no_default")
+	if noDefaultNode == nil {
+		return ""
+	}
+	var noDefault bool
+	// Invalid values are left in place so that validation reports them.
+	if err := noDefaultNode.Decode(&noDefault); err != nil {
+		return ""
+	}
+	removeKey(rules, "no_default")
+	if len(rules.Content) == 0 {
+		removeKey(lint, "rules")
+	}
+	if group := getValue(lint, "group"); group != nil && group.Value != "" {
+		return "removed lint.rules.no_default, which is ignored when lint.group is set"
+	}
+	if !noDefault {
+		return "removed lint.rules.no_default, which was set to false"
+	}
+	removeKey(lint, "group")
+	// group is the first key of lint in the documented configuration.
+	lint.Content = append(
+		[]*yaml.Node{
+			{Kind: yaml.ScalarNode, Tag: "!!str", Value: "group"},
+			{Kind: yaml.ScalarNode, Tag: "!!str", Value: "empty"},
+		},
+		lint.Content...,
+	)
+	return `replaced lint.rules.no_default with lint.group "empty"`
+}
+
+// getValue returns the value for the key in the mapping, or nil if the mapping
+// is nil or the key is not present.
+func getValue(mapping *yaml.Node, key string) *yaml.Node {
+	if mapping == nil || mapping.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// getMapping returns the value for the key in the mapping if it is a mapping itself.
+func getMapping(mapping *yaml.Node, key string) *yaml.Node {
+	value := getValue(mapping, key)
+	if value == nil || value.Kind != yaml.MappingNode {
+		return nil
+	}
+	return value
+}
+
+// removeKey removes the key and its value, including their comments, from the mapping.
+func removeKey(mapping *yaml.Node, key string) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			mapping.Content = append(mapping.Content[:i], mapping.Content[i+2:]...)
+			return
+		}
+	}
+}
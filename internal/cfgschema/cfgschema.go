@@ -0,0 +1,229 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package cfgschema generates a JSON Schema for prototool.yaml and
+// prototool.json files from settings.ExternalConfig.
+//
+// Descriptions are taken from the documentation in the cfginit template,
+// so that the schema and the output of prototool config init --document
+// stay consistent.
+package cfgschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/uber/prototool/internal/cfginit"
+	"github.com/uber/prototool/internal/settings"
+	"github.com/uber/prototool/internal/text"
+	"github.com/uber/prototool/internal/vars"
+)
+
+// SchemaVersion is the JSON Schema draft the generated schema conforms to.
+const SchemaVersion = "http://json-schema.org/draft-07/schema#"
+
+var (
+	// ExcludedPaths are the paths of ExternalConfig fields that are
+	// intentionally left out of the schema.
+	ExcludedPaths = map[string]struct{}{
+		// devel-mode only
+		"lint.allow_suppression": {},
+	}
+
+	// descriptions for keys that are not documented in the cfginit template,
+	// or where the template only documents an example
+	pathToDescriptionOverride = map[string]string{
+		"create.packages.directory":            "The directory, relative to the configuration file, that the base package applies to.",
+		"create.packages.name":                 "The base package for Protobuf files created in the directory. Sub-directories are appended to this package.",
+		"lint.ignores.id":                      "The linter ID to ignore.",
+		"lint.ignores.files":                   "The files or directories to ignore the linter for.",
+		"lint.rules.no_default":                "Exclude the default set of linters. Deprecated: use the lint group \"empty\" instead.",
+		"lint.file_header.path":                "The relative path to a file containing the file header.",
+		"lint.file_header.content":             "The file header content.",
+		"generate.plugins.include_imports":     "Add the --include_imports flag to protoc. Only valid for the descriptor_set plugin.",
+		"generate.plugins.include_source_info": "Add the --include_source_info flag to protoc. Only valid for the descriptor_set plugin.",
+		"lint.file_header.is_commented": "Whether the file header already contains comments. " +
+			"If not set, \"// \" will be added before every line.",
+	}
+
+	pathToEnum = map[string][]string{
+		"lint.group":            settings.LintGroups,
+		"generate.plugins.type": settings.GenPluginTypeStrings(),
+		"severities":            text.SeverityStrings(),
+	}
+)
+
+// Schema is a JSON Schema.
+//
+// Only the keywords needed to describe an ExternalConfig are represented.
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties interface{}        `json:"additionalProperties,omitempty"`
+}
+
+// New returns the Schema for settings.ExternalConfig.
+func New() (*Schema, error) {
+	descriptions, err := cfginit.Descriptions(vars.DefaultProtocVersion)
+	if err != nil {
+		return nil, err
+	}
+	for path, description := range pathToDescriptionOverride {
+		descriptions[path] = description
+	}
+	schema, err := newSchema(reflect.TypeOf(settings.ExternalConfig{}), "", descriptions)
+	if err != nil {
+		return nil, err
+	}
+	schema.Schema = SchemaVersion
+	schema.Title = "Prototool configuration"
+	schema.Description = "Configuration for Prototool, read from prototool.yaml or prototool.json files."
+	return schema, nil
+}
+
+// Generate returns the JSON Schema for settings.ExternalConfig as
+// indented JSON.
+func Generate() ([]byte, error) {
+	schema, err := New()
+	if err != nil {
+		return nil, err
+	}
+	buffer := bytes.NewBuffer(nil)
+	encoder := json.NewEncoder(buffer)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(schema); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// FieldPaths returns the dot-separated paths of all fields in
+// settings.ExternalConfig, in the same form as used for descriptions.
+func FieldPaths() []string {
+	var paths []string
+	addFieldPaths(reflect.TypeOf(settings.ExternalConfig{}), "", &paths)
+	return paths
+}
+
+func newSchema(t reflect.Type, path string, descriptions map[string]string) (*Schema, error) {
+	schema := &Schema{
+		Description: descriptions[path],
+		Enum:        pathToEnum[path],
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		schema.Type = "boolean"
+	case reflect.String:
+		schema.Type = "string"
+	case reflect.Slice:
+		// list items do not add an element to the path
+		items, err := newSchema(t.Elem(), path, descriptions)
+		if err != nil {
+			return nil, err
+		}
+		// the description belongs to the list, not to each item
+		if t.Elem().Kind() == reflect.Struct {
+			items.Description = ""
+		} else {
+			items = &Schema{Type: items.Type}
+		}
+		schema.Type = "array"
+		schema.Items = items
+		schema.Enum = nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("unsupported map key type %v for %q", t.Key(), path)
+		}
+		additionalProperties, err := newSchema(t.Elem(), path, map[string]string{})
+		if err != nil {
+			return nil, err
+		}
+		schema.Type = "object"
+		schema.AdditionalProperties = additionalProperties
+		// the enum belongs to the values, not to the map
+		schema.Enum = nil
+	case reflect.Struct:
+		schema.Type = "object"
+		schema.Properties = make(map[string]*Schema)
+		schema.AdditionalProperties = false
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name := getFieldName(field)
+			if name == "" {
+				continue
+			}
+			fieldPath := joinPath(path, name)
+			if _, ok := ExcludedPaths[fieldPath]; ok {
+				continue
+			}
+			fieldSchema, err := newSchema(field.Type, fieldPath, descriptions)
+			if err != nil {
+				return nil, err
+			}
+			schema.Properties[name] = fieldSchema
+		}
+	default:
+		return nil, fmt.Errorf("unsupported type %v for %q", t, path)
+	}
+	return schema, nil
+}
+
+func addFieldPaths(t reflect.Type, path string, paths *[]string) {
+	switch t.Kind() {
+	case reflect.Slice:
+		addFieldPaths(t.Elem(), path, paths)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			name := getFieldName(t.Field(i))
+			if name == "" {
+				continue
+			}
+			fieldPath := joinPath(path, name)
+			*paths = append(*paths, fieldPath)
+			addFieldPaths(t.Field(i).Type, fieldPath, paths)
+		}
+	}
+}
+
+// getFieldName returns the JSON name of the field, or "" if the field
+// is not serialized.
+func getFieldName(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+func joinPath(path string, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
@@ -0,0 +1,79 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cfgschema
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/prototool/internal/settings"
+)
+
+// the published schema, regenerate with
+// prototool config schema > etc/config/schema/prototool.schema.json
+var publishedSchemaFilePath = filepath.Join("..", "..", "etc", "config", "schema", "prototool.schema.json")
+
+func TestSchemaCoversExternalConfig(t *testing.T) {
+	schema, err := New()
+	require.NoError(t, err)
+	for _, path := range FieldPaths() {
+		if _, ok := ExcludedPaths[path]; ok {
+			continue
+		}
+		fieldSchema := getSchemaForPath(schema, path)
+		if assert.NotNil(t, fieldSchema, "no schema for %s", path) {
+			assert.NotEmpty(t, fieldSchema.Description, "no description for %s", path)
+			assert.NotEmpty(t, fieldSchema.Type, "no type for %s", path)
+		}
+	}
+}
+
+func TestSchemaEnums(t *testing.T) {
+	schema, err := New()
+	require.NoError(t, err)
+	assert.Equal(t, settings.LintGroups, getSchemaForPath(schema, "lint.group").Enum)
+	assert.Equal(t, []string{"go", "gogo"}, getSchemaForPath(schema, "generate.plugins.type").Enum)
+}
+
+func TestSchemaPublished(t *testing.T) {
+	data, err := Generate()
+	require.NoError(t, err)
+	publishedData, err := ioutil.ReadFile(publishedSchemaFilePath)
+	require.NoError(t, err)
+	assert.Equal(t, string(publishedData), string(data), "%s is out of date", publishedSchemaFilePath)
+}
+
+func getSchemaForPath(schema *Schema, path string) *Schema {
+	for _, name := range strings.Split(path, ".") {
+		for schema.Items != nil {
+			schema = schema.Items
+		}
+		schema = schema.Properties[name]
+		if schema == nil {
+			return nil
+		}
+	}
+	return schema
+}
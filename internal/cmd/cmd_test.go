@@ -22,17 +22,24 @@ package cmd
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
+	osexec "os/exec"
 	"path/filepath"
 	"regexp"
+	"runtime"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/uber/prototool/internal/event"
 	"github.com/uber/prototool/internal/settings"
 	"github.com/uber/prototool/internal/vars"
 )
@@ -67,7 +74,7 @@ func TestCompile(t *testing.T) {
 		t,
 		false,
 		false,
-		`testdata/compile/extra_import/extra_import.proto:6:1:Import "dep.proto" was not used.`,
+		`testdata/compile/extra_import/extra_import.proto:6:1:UNUSED_IMPORT:Import "dep.proto" was not used.`,
 		"testdata/compile/extra_import/extra_import.proto",
 	)
 	assertDoCompileFiles(
@@ -118,11 +125,120 @@ func TestCompile(t *testing.T) {
 		t,
 		false,
 		true,
-		`{"filename":"testdata/compile/errors_on_import/dep_errors.proto","line":6,"column":1,"message":"Expected \";\"."}`,
+		`{"filename":"testdata/compile/errors_on_import/dep_errors.proto","line":6,"column":1,"message":"Expected \";\".","severity":"error"}`,
 		"testdata/compile/errors_on_import/dep_errors.proto",
 	)
 }
 
+func TestCompileSeverities(t *testing.T) {
+	t.Parallel()
+	assertDo(
+		t,
+		true,
+		true,
+		0,
+		`testdata/compile/unused_import_warning/unused_import_warning.proto:6:1:UNUSED_IMPORT:Import "dep.proto" was not used.`,
+		"compile", "testdata/compile/unused_import_warning",
+	)
+	assertDo(
+		t,
+		true,
+		true,
+		255,
+		`testdata/compile/unused_import_warning/unused_import_warning.proto:6:1:UNUSED_IMPORT:Import "dep.proto" was not used.`,
+		"compile", "testdata/compile/unused_import_warning", "--max-warnings", "0",
+	)
+	assertDo(
+		t,
+		true,
+		true,
+		0,
+		`{"filename":"testdata/compile/unused_import_warning/unused_import_warning.proto","line":6,"column":1,"lint_id":"UNUSED_IMPORT","message":"Import \"dep.proto\" was not used.","severity":"warning"}`,
+		"compile", "testdata/compile/unused_import_warning", "--json", "--max-warnings", "1",
+	)
+}
+
+func TestCompileBaseline(t *testing.T) {
+	t.Parallel()
+	assertExact(t, false, false, 1, "cannot use --baseline with --write-baseline", "compile", "testdata/foo", "--baseline", "a.json", "--write-baseline", "b.json")
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tmpDir)) }()
+	baselinePath := filepath.Join(tmpDir, "prototool-baseline.json")
+	assertDo(t, true, true, 0, "", "compile", "testdata/compile/errors_on_import", "--write-baseline", baselinePath)
+	assertDo(t, true, true, 0, "", "compile", "testdata/compile/errors_on_import", "--baseline", baselinePath)
+	// entries for files that were not checked are not stale
+	assertDo(
+		t,
+		true,
+		true,
+		255,
+		`testdata/compile/extra_import/extra_import.proto:6:1:UNUSED_IMPORT:Import "dep.proto" was not used.`,
+		"compile", "testdata/compile/extra_import", "--baseline", baselinePath,
+	)
+}
+
+func TestGenerateBaseline(t *testing.T) {
+	t.Parallel()
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tmpDir)) }()
+	baselinePath := filepath.Join(tmpDir, "prototool-baseline.json")
+	assertDo(t, true, true, 0, "", "compile", "testdata/compile/errors_on_import", "--write-baseline", baselinePath)
+	// there are no descriptors or generated files if there are compile
+	// errors, so generating must fail even if the errors are baselined
+	assertRegexp(
+		t,
+		true,
+		false,
+		255,
+		`^\d+ failures with error severity were suppressed by the baseline, but there is no output if there are errors, fix them first$`,
+		"generate", "testdata/compile/errors_on_import", "--baseline", baselinePath,
+	)
+	assertRegexp(
+		t,
+		true,
+		false,
+		255,
+		`^\d+ failures with error severity were suppressed by the baseline`,
+		"generate", "testdata/compile/errors_on_import", "--write-baseline", filepath.Join(tmpDir, "other.json"),
+	)
+}
+
+func TestCompileSummary(t *testing.T) {
+	t.Parallel()
+	assertExact(t, false, false, 1, "cannot use --summary with --output-format sarif", "compile", "testdata/foo", "--summary", "--output-format", "sarif")
+	stdout, exitCode := testDo(t, true, false, "compile", "testdata/compile/unused_import_warning", "--summary", "--json")
+	require.Equal(t, 0, exitCode, stdout)
+	lines := getCleanLines(stdout)
+	require.NotEmpty(t, lines)
+	var summary struct {
+		Summary struct {
+			Files       int `json:"files"`
+			Directories int `json:"directories"`
+			Invocations []struct {
+				Directory string `json:"directory"`
+				Plugin    string `json:"plugin"`
+				Files     int    `json:"files"`
+			} `json:"invocations"`
+		} `json:"summary"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(lines[len(lines)-1]), &summary))
+	assert.Equal(t, 2, summary.Summary.Files)
+	assert.Equal(t, 1, summary.Summary.Directories)
+	require.Len(t, summary.Summary.Invocations, 1)
+	assert.Equal(t, "testdata/compile/unused_import_warning", summary.Summary.Invocations[0].Directory)
+	assert.Empty(t, summary.Summary.Invocations[0].Plugin)
+	assert.Equal(t, 2, summary.Summary.Invocations[0].Files)
+}
+
+func TestCompileJobsAndProtocTimeoutErrors(t *testing.T) {
+	t.Parallel()
+	assertExact(t, false, false, 1, "--jobs must not be negative: -1", "compile", "testdata/foo", "--jobs", "-1")
+	assertExact(t, false, false, 1, "--protoc-timeout must be positive: 0s", "compile", "testdata/foo", "--protoc-timeout", "0s")
+	assertExact(t, false, false, 1, `time: invalid duration "foo"`, "compile", "testdata/foo", "--protoc-timeout", "foo")
+}
+
 func TestInit(t *testing.T) {
 	t.Parallel()
 	tmpDir, err := ioutil.TempDir("", "")
@@ -134,6 +250,33 @@ func TestInit(t *testing.T) {
 
 	assertDo(t, false, false, 0, "", "config", "init", tmpDir)
 	assertDo(t, false, false, 1, fmt.Sprintf("%s already exists", filepath.Join(tmpDir, settings.DefaultConfigFilename)), "config", "init", tmpDir)
+	assertDo(t, false, false, 1, "cannot use --infer with --document or --uncomment", "config", "init", tmpDir, "--infer", "--document")
+}
+
+func TestMigrate(t *testing.T) {
+	t.Parallel()
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	require.NotEmpty(t, tmpDir)
+	defer func() {
+		_ = os.RemoveAll(tmpDir)
+	}()
+	filePath := filepath.Join(tmpDir, settings.DefaultConfigFilename)
+	require.NoError(t, ioutil.WriteFile(filePath, []byte("lint:\n  rules:\n    no_default: true\n"), 0644))
+
+	_, exitCode := testDo(t, false, false, "config", "migrate", tmpDir, "--dry-run")
+	assert.Equal(t, 0, exitCode)
+	data, err := ioutil.ReadFile(filePath)
+	require.NoError(t, err)
+	assert.Equal(t, "lint:\n  rules:\n    no_default: true\n", string(data))
+
+	_, exitCode = testDo(t, false, false, "config", "migrate", tmpDir, "--to", "json")
+	assert.Equal(t, 0, exitCode)
+	_, err = os.Stat(filePath)
+	assert.True(t, os.IsNotExist(err))
+	data, err = ioutil.ReadFile(filepath.Join(tmpDir, "prototool.json"))
+	require.NoError(t, err)
+	assert.Equal(t, "{\n  \"lint\": {\n    \"group\": \"empty\"\n  }\n}\n", string(data))
 }
 
 func TestVersion(t *testing.T) {
@@ -151,6 +294,452 @@ func TestFiles(t *testing.T) {
 testdata/foo/success.proto`, "files", "testdata/foo")
 }
 
+func TestFilesEvents(t *testing.T) {
+	t.Parallel()
+	assertExact(t, false, false, 1, `unknown events format "json", the only format is "ndjson"`, "files", "testdata/foo", "--events", "json")
+	assertExact(t, false, false, 1, "cannot use --events-file without --events", "files", "testdata/foo", "--events-file", "events.ndjson")
+	exitCode := 0
+	expectedEvents := []*event.Event{
+		{
+			Type:          event.TypeConfigResolved,
+			ConfigDirPath: "testdata/foo",
+		},
+		{
+			Type:  event.TypeFilesDiscovered,
+			Files: []string{"testdata/foo/bar/dep.proto", "testdata/foo/success.proto"},
+		},
+		{
+			Type:     event.TypeCommandFinished,
+			Command:  "files",
+			ExitCode: &exitCode,
+		},
+	}
+	getEvents := func(data string) []*event.Event {
+		var events []*event.Event
+		for _, line := range getCleanLines(data) {
+			e := &event.Event{}
+			// skip log lines
+			if json.Unmarshal([]byte(line), e) != nil {
+				continue
+			}
+			assert.False(t, e.Time.IsZero())
+			e.Time = time.Time{}
+			e.Duration = 0
+			events = append(events, e)
+		}
+		return events
+	}
+
+	// events are written to stderr by default
+	stdout := bytes.NewBuffer(nil)
+	stderr := bytes.NewBuffer(nil)
+	require.Equal(t, 0, do(true, []string{"files", "testdata/foo", "--events", "ndjson", "--debug"}, os.Stdin, stdout, stderr), stderr.String())
+	assert.Equal(t, "testdata/foo/bar/dep.proto\ntestdata/foo/success.proto\n", stdout.String())
+	assert.Equal(t, expectedEvents, getEvents(stderr.String()))
+
+	// or to --events-file, without the logs
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tmpDir)) }()
+	eventsFilePath := filepath.Join(tmpDir, "events.ndjson")
+	stdout.Reset()
+	stderr.Reset()
+	require.Equal(t, 0, do(true, []string{"files", "testdata/foo", "--events", "ndjson", "--events-file", eventsFilePath, "--debug"}, os.Stdin, stdout, stderr), stderr.String())
+	assert.Equal(t, "testdata/foo/bar/dep.proto\ntestdata/foo/success.proto\n", stdout.String())
+	assert.NotEmpty(t, stderr.String())
+	assert.NotContains(t, stderr.String(), `"type":`)
+	data, err := ioutil.ReadFile(eventsFilePath)
+	require.NoError(t, err)
+	for _, line := range getCleanLines(string(data)) {
+		assert.True(t, json.Valid([]byte(line)), line)
+	}
+	assert.Equal(t, expectedEvents, getEvents(string(data)))
+}
+
+func TestFilesOverlay(t *testing.T) {
+	t.Parallel()
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, os.RemoveAll(tmpDir))
+	}()
+	bufferFilePath := filepath.Join(tmpDir, "buffer.proto")
+	require.NoError(t, ioutil.WriteFile(bufferFilePath, []byte(`syntax = "proto3";`), 0644))
+	overlayFilePath := filepath.Join(tmpDir, "overlay.json")
+	require.NoError(t, ioutil.WriteFile(overlayFilePath, []byte(`{"Replace": {"testdata/foo/new.proto": "`+bufferFilePath+`"}}`), 0644))
+	assertExact(t, false, false, 0, "testdata/foo/bar/dep.proto\ntestdata/foo/new.proto\ntestdata/foo/success.proto", "files", "testdata/foo", "--overlay", overlayFilePath)
+	// files that only exist in the overlay can be given as arguments
+	assertExact(t, false, false, 0, "testdata/foo/new.proto", "files", "testdata/foo/new.proto", "--overlay", overlayFilePath)
+	assertExact(t, false, false, 1, "stat testdata/foo/new.proto: no such file or directory", "files", "testdata/foo/new.proto")
+}
+
+func TestFilesMultiple(t *testing.T) {
+	t.Parallel()
+	// files are grouped by configuration file, and only the given files are used
+	assertExact(
+		t, false, false, 0,
+		"testdata/foo/bar/dep.proto\ntestdata/foo/success.proto\ntestdata/grpc/grpc.proto\ntestdata/lint/samedir/foo1.proto",
+		"files", "testdata/lint/samedir/foo1.proto", "testdata/foo/success.proto", "testdata/grpc/grpc.proto", "testdata/foo/bar/dep.proto", "testdata/foo/success.proto",
+	)
+	stdout, exitCode := testDoStdin(
+		t,
+		strings.NewReader("testdata/foo/success.proto\n\ntestdata/lint/samedir/foo1.proto\n"),
+		false, false,
+		"files", "testdata/grpc", "--files-from", "-",
+	)
+	assert.Equal(t, 0, exitCode)
+	assert.Equal(t, "testdata/foo/success.proto\ntestdata/grpc/grpc.proto\ntestdata/lint/samedir/foo1.proto", stdout)
+	// nothing is done if there are no files
+	stdout, exitCode = testDoStdin(t, strings.NewReader(""), false, false, "files", "--files-from", "-")
+	assert.Equal(t, 0, exitCode)
+	assert.Equal(t, "", stdout)
+}
+
+func TestFilesSince(t *testing.T) {
+	t.Parallel()
+	if _, err := osexec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(tmpDir)
+	}()
+	tmpDir, err = filepath.EvalSymlinks(tmpDir)
+	require.NoError(t, err)
+	for relFilePath, data := range map[string]string{
+		"prototool.yaml": "",
+		"a/a.proto":      `import "b/b.proto";`,
+		"b/b.proto":      "",
+		"c/c.proto":      "",
+	} {
+		filePath := filepath.Join(tmpDir, relFilePath)
+		require.NoError(t, os.MkdirAll(filepath.Dir(filePath), 0755))
+		require.NoError(t, ioutil.WriteFile(filePath, []byte(data), 0644))
+	}
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "."},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "-c", "commit.gpgsign=false", "commit", "-q", "-m", "first"},
+	} {
+		cmd := osexec.Command("git", args...)
+		cmd.Dir = tmpDir
+		output, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(output))
+	}
+
+	workDirPath, err := os.Getwd()
+	require.NoError(t, err)
+	relTmpDir, err := filepath.Rel(workDirPath, tmpDir)
+	require.NoError(t, err)
+
+	assertExact(t, false, false, 0, ``, "files", tmpDir, "--since", "HEAD")
+	require.NoError(t, ioutil.WriteFile(filepath.Join(tmpDir, "b", "b.proto"), []byte(`syntax = "proto3";`), 0644))
+	assertExact(
+		t,
+		false,
+		false,
+		0,
+		filepath.Join(relTmpDir, "a", "a.proto")+"\n"+filepath.Join(relTmpDir, "b", "b.proto"),
+		"files", tmpDir, "--since", "HEAD",
+	)
+}
+
+func TestHooks(t *testing.T) {
+	t.Parallel()
+	if _, err := osexec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(tmpDir)
+	}()
+	tmpDir, err = filepath.EvalSymlinks(tmpDir)
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Join(tmpDir, "a"), 0755))
+	cmd := osexec.Command("git", "init", "-q")
+	cmd.Dir = tmpDir
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(output))
+	hookFilePath := filepath.Join(tmpDir, ".git", "hooks", "pre-commit")
+
+	assertExact(t, false, false, 0, ``, "hooks", "install", filepath.Join(tmpDir, "a"))
+	data, err := ioutil.ReadFile(hookFilePath)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "exec prototool hooks pre-commit 'a'\n")
+	fileInfo, err := os.Stat(hookFilePath)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0755), fileInfo.Mode().Perm())
+	assertDo(t, false, false, 1, fmt.Sprintf("%s already exists, use --force to overwrite", hookFilePath), "hooks", "install", tmpDir)
+	assertExact(t, false, false, 0, ``, "hooks", "install", tmpDir, "--force")
+	data, err = ioutil.ReadFile(hookFilePath)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "exec prototool hooks pre-commit\n")
+
+	// nothing is staged
+	assertExact(t, false, false, 0, ``, "hooks", "pre-commit", tmpDir)
+}
+
+func TestOutputFormatErrors(t *testing.T) {
+	t.Parallel()
+	assertExact(t, false, false, 1, "could not parse xml to an OutputFormat", "compile", "testdata/foo", "--output-format", "xml")
+	assertExact(t, false, false, 1, "cannot use --json with --output-format sarif", "compile", "testdata/foo", "--json", "--output-format", "sarif")
+}
+
+func TestDocsErrors(t *testing.T) {
+	t.Parallel()
+	assertExact(t, false, false, 1, "must set --output", "docs", "testdata/foo")
+	assertExact(t, false, false, 1, "could not parse pdf to a Format", "docs", "testdata/foo", "-o", "out", "--format", "pdf")
+}
+
+func TestOpenAPIErrors(t *testing.T) {
+	t.Parallel()
+	assertExact(t, false, false, 1, "must set --output", "openapi", "testdata/foo")
+}
+
+func TestJSONSchemaErrors(t *testing.T) {
+	t.Parallel()
+	assertExact(t, false, false, 1, "must set --message", "jsonschema", "testdata/foo")
+}
+
+func TestConvertErrors(t *testing.T) {
+	t.Parallel()
+	assertExact(t, false, false, 1, "must set --type", "convert", "testdata/foo")
+	assertExact(t, false, false, 1, "cannot use --type with --decode-raw", "convert", "testdata/foo", "--type", "foo.Bar", "--decode-raw")
+	assertExact(t, false, false, 1, "could not parse yaml to a Format", "convert", "testdata/foo", "--type", "foo.Bar", "--to", "yaml")
+}
+
+func TestConvertDecodeRaw(t *testing.T) {
+	t.Parallel()
+	stdout, exitCode := testDoStdin(t, strings.NewReader("\x08\x96\x01\x12\x02hi"), false, false, "convert", "--decode-raw")
+	assert.Equal(t, 0, exitCode)
+	assert.Equal(t, "1 varint: 150\n2 bytes: \"hi\"", stdout)
+}
+
+func TestSampleErrors(t *testing.T) {
+	t.Parallel()
+	assertExact(t, false, false, 1, "must set --type", "sample", "testdata/foo")
+	assertExact(t, false, false, 1, "--count must be positive", "sample", "testdata/foo", "--type", "foo.Bar", "--count", "0")
+	assertExact(t, false, false, 1, "could not parse yaml to a Format", "sample", "testdata/foo", "--type", "foo.Bar", "--format", "yaml")
+}
+
+func TestConvertAndSampleFailures(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a shell script as protoc")
+	}
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tmpDir)) }()
+	// a protoc that outputs a FileDescriptorSet and prints a warning
+	fileDescriptorSetData, err := proto.Marshal(&descriptor.FileDescriptorSet{
+		File: []*descriptor.FileDescriptorProto{
+			{
+				Name:    proto.String("foo/a.proto"),
+				Package: proto.String("foo"),
+				Syntax:  proto.String("proto3"),
+				MessageType: []*descriptor.DescriptorProto{
+					{
+						Name: proto.String("Foo"),
+						Field: []*descriptor.FieldDescriptorProto{
+							{
+								Name:     proto.String("name"),
+								Number:   proto.Int32(1),
+								Label:    descriptor.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+								Type:     descriptor.FieldDescriptorProto_TYPE_STRING.Enum(),
+								JsonName: proto.String("name"),
+							},
+						},
+					},
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+	fileDescriptorSetPath := filepath.Join(tmpDir, "foo.fds")
+	require.NoError(t, ioutil.WriteFile(fileDescriptorSetPath, fileDescriptorSetData, 0644))
+	protocBinPath := filepath.Join(tmpDir, "bin", "protoc")
+	protocWKTPath := filepath.Join(tmpDir, "include")
+	require.NoError(t, os.MkdirAll(filepath.Dir(protocBinPath), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(protocWKTPath, "google", "protobuf"), 0755))
+	require.NoError(t, ioutil.WriteFile(protocBinPath, []byte(fmt.Sprintf("#!/bin/sh\nprev=\nfor arg; do [ \"${prev}\" = -o ] && cp %q \"${arg}\"; prev=\"${arg}\"; done\necho 'foo/a.proto:3:1: warning: Import dep.proto is unused.' >&2\nexit 0\n", fileDescriptorSetPath)), 0755))
+	workDirPath := filepath.Join(tmpDir, "work")
+	require.NoError(t, os.MkdirAll(filepath.Join(workDirPath, "foo"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(workDirPath, "foo", "a.proto"), []byte("syntax = \"proto3\";\n\npackage foo;\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(workDirPath, "prototool.yaml"), []byte("severities:\n  UNUSED_IMPORT: warning\n"), 0644))
+
+	// the failures are printed to stderr so that stdout is only the payload
+	for _, args := range [][]string{
+		{"convert", "--type", "foo.Foo", "--from", "json", "--to", "json"},
+		{"sample", "--type", "foo.Foo", "--format", "json"},
+	} {
+		stdout := bytes.NewBuffer(nil)
+		stderr := bytes.NewBuffer(nil)
+		args = append(
+			args,
+			filepath.Join(workDirPath, "foo"),
+			"--protoc-bin-path", protocBinPath,
+			"--protoc-wkt-path", protocWKTPath,
+		)
+		require.Equal(t, 0, do(true, args, strings.NewReader(`{"name":"hello"}`), stdout, stderr), stderr.String())
+		assert.True(t, json.Valid(stdout.Bytes()), stdout.String())
+		assert.Contains(t, stderr.String(), `Import "dep.proto" was not used.`)
+	}
+}
+
+func TestErrorFormatTemplate(t *testing.T) {
+	t.Parallel()
+	assertExact(
+		t,
+		true,
+		false,
+		255,
+		`testdata/compile/errors_on_import/dep_errors.proto(6,1): COMPILE Expected ";".`,
+		"compile",
+		"testdata/compile/errors_on_import/dep_errors.proto",
+		"--error-format", "template:{{.Filename}}({{.Line}},{{.Column}}): {{id .}} {{.Message}}",
+	)
+	assertExact(t, false, false, 1, `template: error-format:1: unclosed action`, "compile", "testdata/foo", "--error-format", "template:{{.Filename")
+}
+
+// TestWatch is not parallel as it stops the watch with SIGINT, which
+// would stop any other running command.
+func TestCompileFileFailures(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a shell script as protoc")
+	}
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tmpDir)) }()
+	// a protoc that fails for a sibling file and for an unknown file
+	protocBinPath := filepath.Join(tmpDir, "bin", "protoc")
+	protocWKTPath := filepath.Join(tmpDir, "include")
+	require.NoError(t, os.MkdirAll(filepath.Dir(protocBinPath), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(protocWKTPath, "google", "protobuf"), 0755))
+	require.NoError(t, ioutil.WriteFile(protocBinPath, []byte("#!/bin/sh\necho 'b.proto:1:1: Some error.' >&2\necho 'c.proto: File not found.' >&2\nexit 1\n"), 0755))
+	workDirPath := filepath.Join(tmpDir, "work")
+	require.NoError(t, os.MkdirAll(filepath.Join(workDirPath, "foo"), 0755))
+	for _, name := range []string{"a", "b"} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(workDirPath, "foo", name+".proto"), []byte("syntax = \"proto3\";\n\npackage foo;\n"), 0644))
+	}
+	require.NoError(t, ioutil.WriteFile(filepath.Join(workDirPath, "prototool.yaml"), nil, 0644))
+
+	stdout, exitCode := testDo(
+		t,
+		false,
+		false,
+		"compile", filepath.Join(workDirPath, "foo", "a.proto"),
+		"--protoc-bin-path", protocBinPath,
+		"--protoc-wkt-path", protocWKTPath,
+	)
+	assert.Equal(t, 255, exitCode, stdout)
+	// the failure without a filename is printed, the failure for b.proto
+	// is not, but is summarized as it causes the non-zero exit code
+	assert.Contains(t, stdout, `<input>:1:1:Import "c.proto" was not found.`)
+	assert.NotContains(t, stdout, "Some error.")
+	assert.Contains(t, stdout, "errors in other files of the compiled directories")
+	assert.Contains(t, stdout, "b.proto")
+}
+
+func TestWatch(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a shell script as protoc and SIGINT")
+	}
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, os.RemoveAll(tmpDir)) }()
+	// a protoc that always succeeds
+	protocBinPath := filepath.Join(tmpDir, "bin", "protoc")
+	protocWKTPath := filepath.Join(tmpDir, "include")
+	require.NoError(t, os.MkdirAll(filepath.Dir(protocBinPath), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(protocWKTPath, "google", "protobuf"), 0755))
+	require.NoError(t, ioutil.WriteFile(protocBinPath, []byte("#!/bin/sh\nexit 0\n"), 0755))
+	workDirPath := filepath.Join(tmpDir, "work")
+	for _, pkg := range []string{"a", "b"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(workDirPath, pkg), 0755))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(workDirPath, pkg, pkg+".proto"), []byte("syntax = \"proto3\";\n\npackage "+pkg+";\n"), 0644))
+	}
+	require.NoError(t, ioutil.WriteFile(filepath.Join(workDirPath, "prototool.yaml"), nil, 0644))
+	eventsFilePath := filepath.Join(tmpDir, "events.ndjson")
+
+	exitCodeC := make(chan int, 1)
+	go func() {
+		exitCodeC <- do(
+			true,
+			[]string{
+				"watch", workDirPath,
+				"--protoc-bin-path", protocBinPath,
+				"--protoc-wkt-path", protocWKTPath,
+				"--events", "ndjson",
+				"--events-file", eventsFilePath,
+			},
+			os.Stdin,
+			ioutil.Discard,
+			ioutil.Discard,
+		)
+	}()
+	// the number of protoc invocations for each directory
+	getDirToCount := func() map[string]int {
+		data, err := ioutil.ReadFile(eventsFilePath)
+		if err != nil {
+			return nil
+		}
+		dirToCount := make(map[string]int)
+		for _, line := range getCleanLines(string(data)) {
+			e := &event.Event{}
+			// the last line may be partially written
+			if json.Unmarshal([]byte(line), e) == nil && e.Type == event.TypeProtocFinished {
+				dirToCount[filepath.Base(e.Directory)]++
+			}
+		}
+		return dirToCount
+	}
+	waitForDirToCount := func(expected map[string]int) {
+		deadline := time.Now().Add(10 * time.Second)
+		for !assert.ObjectsAreEqual(expected, getDirToCount()) && time.Now().Before(deadline) {
+			time.Sleep(50 * time.Millisecond)
+		}
+		require.Equal(t, expected, getDirToCount())
+	}
+
+	waitForDirToCount(map[string]int{"a": 1, "b": 1})
+	aFile, err := os.OpenFile(filepath.Join(workDirPath, "a", "a.proto"), os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, err = aFile.WriteString("\nmessage Foo {}\n")
+	require.NoError(t, err)
+	require.NoError(t, aFile.Close())
+	// only the directory of the changed file is compiled, once
+	waitForDirToCount(map[string]int{"a": 2, "b": 1})
+	time.Sleep(2 * time.Second)
+	assert.Equal(t, map[string]int{"a": 2, "b": 1}, getDirToCount())
+
+	process, err := os.FindProcess(os.Getpid())
+	require.NoError(t, err)
+	require.NoError(t, process.Signal(os.Interrupt))
+	select {
+	case exitCode := <-exitCodeC:
+		assert.Equal(t, 0, exitCode)
+	case <-time.After(10 * time.Second):
+		t.Fatal("watch did not stop after SIGINT")
+	}
+}
+
+func TestLSP(t *testing.T) {
+	t.Parallel()
+	stdin := bytes.NewBuffer(nil)
+	for _, message := range []string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"shutdown"}`,
+		`{"jsonrpc":"2.0","method":"exit"}`,
+	} {
+		_, _ = fmt.Fprintf(stdin, "Content-Length: %d\r\n\r\n%s", len(message), message)
+	}
+	stdout, exitCode := testDoStdin(t, stdin, false, false, "lsp")
+	assert.Equal(t, 0, exitCode)
+	assert.Contains(t, stdout, `"documentSymbolProvider":true`)
+	assert.True(t, strings.HasSuffix(stdout, `{"jsonrpc":"2.0","id":2,"result":null}`), stdout)
+}
+
 func TestGenerateDescriptorSetSameDirAsConfigFile(t *testing.T) {
 	t.Parallel()
 	// https://github.com/uber/prototool/issues/389
@@ -21,25 +21,66 @@
 package cmd
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/spf13/pflag"
+	"github.com/uber/prototool/internal/text"
 )
 
 type flags struct {
+	apiVersion    string
+	baseline      string
 	cachePath     string
 	configData    string
+	count         int
 	debug         bool
+	decodeRaw     bool
+	delimited     bool
 	disableFormat bool
 	disableLint   bool
 	document      bool
 	dryRun        bool
 	errorFormat   string
+	events        string
+	eventsFile    string
+	filesFrom     string
 	fix           bool
+	force         bool
+	from          string
+	fuzzCorpus    string
+	format        string
+	gen           bool
+	infer         bool
+	jobs          int
 	json          bool
+	maxWarnings   int
+	merge         bool
+	message       string
+	output        string
+	outputFormat  string
+	overlay       string
 	protocBinPath string
 	protocWKTPath string
+	protocTimeout string
 	protocURL     string
+	seed          int64
+	since         string
+	summary       bool
+	template      string
+	typeName      string
+	to            string
 	uncomment     bool
 	walkTimeout   string
+	writeBaseline string
+}
+
+func (f *flags) bindBaseline(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.baseline, "baseline", "", "The path to a baseline file written by --write-baseline. Failures recorded in the baseline file are not reported, and recorded failures that no longer occur are reported with the info severity and the ID STALE_BASELINE.")
+}
+
+func (f *flags) bindAPIVersion(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.apiVersion, "api-version", "", `The version to set as info.version of the documents. The default is "version not set".`)
 }
 
 func (f *flags) bindCachePath(flagSet *pflag.FlagSet) {
@@ -54,6 +95,26 @@ func (f *flags) bindDebug(flagSet *pflag.FlagSet) {
 	flagSet.BoolVar(&f.debug, "debug", false, "Run in debug mode, which will print out debug logging.")
 }
 
+func (f *flags) bindConvertFrom(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.from, "from", "binary", "The format of the input, one of binary, json, or text.")
+}
+
+func (f *flags) bindConvertTo(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.to, "to", "json", "The format of the output, one of binary, json, or text.")
+}
+
+func (f *flags) bindCount(flagSet *pflag.FlagSet) {
+	flagSet.IntVar(&f.count, "count", 1, "The number of messages to generate.")
+}
+
+func (f *flags) bindDecodeRaw(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(&f.decodeRaw, "decode-raw", false, "Print the field numbers, wire types, and values of binary input without a type instead of converting it. Cannot be used with --type.")
+}
+
+func (f *flags) bindDelimited(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(&f.delimited, "delimited", false, "Read and write streams of messages. Binary streams are length-delimited, with each message preceded by its size as a varint. JSON streams are a sequence of objects, and are written one object per line. Text streams are written with an empty line between messages, and cannot be read.")
+}
+
 func (f *flags) bindDisableFormat(flagSet *pflag.FlagSet) {
 	flagSet.BoolVar(&f.disableFormat, "disable-format", false, "Do not run formatting.")
 }
@@ -62,26 +123,94 @@ func (f *flags) bindDisableLint(flagSet *pflag.FlagSet) {
 	flagSet.BoolVar(&f.disableLint, "disable-lint", false, "Do not run linting.")
 }
 
+func (f *flags) bindDocsFormat(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.format, "format", "markdown", "The format of the pages, either markdown or html.")
+}
+
+func (f *flags) bindDocsTemplate(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.template, "template", "", `The path to a Go template file that is parsed after the default templates, and can redefine the "index" and "package" templates or any template they use with {{define}}. Templates are text/template templates for markdown and html/template templates for html.`)
+}
+
 func (f *flags) bindDryRun(flagSet *pflag.FlagSet) {
 	flagSet.BoolVar(&f.dryRun, "dry-run", false, "Print the protoc commands that would have been run without actually running them.")
 }
 
+func (f *flags) bindMigrateDryRun(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(&f.dryRun, "dry-run", false, "Print the diff without rewriting the config file.")
+}
+
 func (f *flags) bindDocument(flagSet *pflag.FlagSet) {
 	flagSet.BoolVar(&f.document, "document", false, "Document all available options. Automatically set if --uncomment is set.")
 }
 
 func (f *flags) bindErrorFormat(flagSet *pflag.FlagSet) {
-	flagSet.StringVar(&f.errorFormat, "error-format", "filename:line:column:message", `The colon-separated fields to print out on error. Valid values are "filename:line:column:id:message".`)
+	flagSet.StringVar(&f.errorFormat, "error-format", "filename:line:column:message", `The colon-separated fields to print out on error. Valid values are "filename:line:column:id:message". Alternatively, a Go text/template prefixed with "template:" evaluated against each failure, for example "template:{{.Filename}}({{.Line}},{{.Column}}): {{.Message}}".`)
+}
+
+func (f *flags) bindEvents(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.events, "events", "", `Emit lifecycle events to stderr, or to --events-file if set, while the command runs. The only format is "ndjson", which writes one JSON object per line.`)
+}
+
+func (f *flags) bindEventsFile(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.eventsFile, "events-file", "", "The file to write events to instead of stderr if --events is set, such as a named pipe or /dev/fd/3. The file is created or truncated.")
+}
+
+func (f *flags) bindFilesFrom(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.filesFrom, "files-from", "", `Also use the files listed in the given file, one per line, or read the list from stdin if "-". Files are grouped by their configuration file, only the directories of the files are compiled, and only the failures for the files are printed. If the list is empty and there are no other arguments, nothing is done.`)
 }
 
 func (f *flags) bindFix(flagSet *pflag.FlagSet) {
 	flagSet.BoolVarP(&f.fix, "fix", "f", false, "Fix the file according to the Style Guide.")
 }
 
+func (f *flags) bindForce(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(&f.force, "force", false, "Overwrite the file if it already exists.")
+}
+
+func (f *flags) bindFuzzCorpus(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.fuzzCorpus, "fuzz-corpus", "", "Write each message in the binary format to a Go native fuzzing corpus file in the given directory, such as testdata/fuzz/FuzzBar, instead of printing the messages.")
+}
+
+func (f *flags) bindGen(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(&f.gen, "gen", false, "Generate stubs in addition to compiling.")
+}
+
+func (f *flags) bindInfer(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(&f.infer, "infer", false, "Infer settings from the existing Protobuf files and generated Go code in the directory. Cannot be used with --document or --uncomment.")
+}
+
+func (f *flags) bindJobs(flagSet *pflag.FlagSet) {
+	flagSet.IntVar(&f.jobs, "jobs", 0, "The maximum number of protoc invocations to run in parallel. The default is the number of CPUs.")
+}
+
 func (f *flags) bindJSON(flagSet *pflag.FlagSet) {
 	flagSet.BoolVar(&f.json, "json", false, "Output as JSON.")
 }
 
+func (f *flags) bindMaxWarnings(flagSet *pflag.FlagSet) {
+	flagSet.IntVar(&f.maxWarnings, "max-warnings", -1, "The number of warnings to allow before exiting with a non-zero exit code. A negative value allows any number of warnings. Errors always result in a non-zero exit code.")
+}
+
+func (f *flags) bindMerge(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(&f.merge, "merge", false, "Generate a single openapi.json document for the services in all packages instead of one document per package.")
+}
+
+func (f *flags) bindMessage(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.message, "message", "", "The fully-qualified name of the message to generate a JSON Schema for, for example foo.v1.Bar. Required.")
+}
+
+func (f *flags) bindOutput(flagSet *pflag.FlagSet) {
+	flagSet.StringVarP(&f.output, "output", "o", "", "The directory to write the generated files to. Required.")
+}
+
+func (f *flags) bindOutputFormat(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.outputFormat, "output-format", "", fmt.Sprintf("The format to print failures in, one of %s. The default is text, formatted with --error-format. The json format is the same as --json.", strings.Join(text.OutputFormatStrings(), ", ")))
+}
+
+func (f *flags) bindOverlay(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.overlay, "overlay", "", `The path to a JSON file that replaces the contents of files, in the same format as for go build -overlay, for example {"Replace": {"foo/v1/foo.proto": "/tmp/buffer.proto"}}. Replaced files are discovered and compiled with the contents of the replacement files, and failures refer to the replaced files. The replaced files do not need to exist, however their directory must.`)
+}
+
 func (f *flags) bindProtocURL(flagSet *pflag.FlagSet) {
 	flagSet.StringVar(&f.protocURL, "protoc-url", "", "The url to use to download the protoc zip file, otherwise uses GitHub Releases. Setting this option will ignore the config protoc.version setting.")
 }
@@ -90,10 +219,38 @@ func (f *flags) bindProtocBinPath(flagSet *pflag.FlagSet) {
 	flagSet.StringVar(&f.protocBinPath, "protoc-bin-path", "", "The path to the protoc binary. Setting this option will ignore the config protoc.version setting.\nThis flag must be used with protoc-wkt-path and must not be used with the protoc-url flag.\nThis setting can also be controlled using the $PROTOTOOL_PROTOC_BIN_PATH environment variable, however this flag takes precedence.")
 }
 
+func (f *flags) bindProtocTimeout(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.protocTimeout, "protoc-timeout", "", "The maximum time for a single protoc invocation, for example 5m. Invocations that take longer are killed and reported as failures. Setting this option will ignore the config protoc.timeout setting.")
+}
+
 func (f *flags) bindProtocWKTPath(flagSet *pflag.FlagSet) {
 	flagSet.StringVar(&f.protocWKTPath, "protoc-wkt-path", "", "The path to the well-known types. Setting this option will ignore the config protoc.version setting.\nThis flag must be used with protoc-bin-path and must not be used with the protoc-url flag.\nThis setting can also be controlled using the $PROTOTOOL_PROTOC_WKT_PATH environment variable, however this flag takes precedence.")
 }
 
+func (f *flags) bindSampleFormat(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.format, "format", "json", "The format of the messages, one of json, binary, or text. Multiple JSON messages are printed one per line, and multiple binary messages are length-delimited.")
+}
+
+func (f *flags) bindSeed(flagSet *pflag.FlagSet) {
+	flagSet.Int64Var(&f.seed, "seed", 0, "The seed for the random values. The same seed always generates the same messages.")
+}
+
+func (f *flags) bindSince(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.since, "since", "", "Only use the files changed in the local git repository since the merge base of the given git ref and HEAD, plus the files that transitively import them. Uncommitted and untracked files are included.")
+}
+
+func (f *flags) bindSummary(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(&f.summary, "summary", false, "Print a summary at the end of the compile with the number of files and directories, the protoc invocations per plugin, the wall and CPU time of each invocation, the slowest directories, and the cache hit rate. With --json, the summary is printed as a JSON object.")
+}
+
+func (f *flags) bindTo(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.to, "to", "", `The format to convert the config file to, either "yaml" or "json". The file is renamed to match. The default is to keep the current format.`)
+}
+
+func (f *flags) bindType(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.typeName, "type", "", "The fully-qualified name of the message type, for example foo.v1.Bar.")
+}
+
 func (f *flags) bindUncomment(flagSet *pflag.FlagSet) {
 	flagSet.BoolVar(&f.uncomment, "uncomment", false, "Uncomment the example config settings. Automatically sets --document.")
 }
@@ -101,3 +258,7 @@ func (f *flags) bindUncomment(flagSet *pflag.FlagSet) {
 func (f *flags) bindWalkTimeout(flagSet *pflag.FlagSet) {
 	flagSet.StringVar(&f.walkTimeout, "walk-timeout", "3s", "The maximum time to allow for walking the directory structure looking for proto files.")
 }
+
+func (f *flags) bindWriteBaseline(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(&f.writeBaseline, "write-baseline", "", "Write all failures to the given baseline file for use with --baseline instead of reporting them. Failures are recorded by file, ID and message, without lines and columns.")
+}
@@ -21,16 +21,22 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 	"time"
 
 	wordwrap "github.com/mitchellh/go-wordwrap"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
+	"github.com/uber/prototool/internal/event"
 	"github.com/uber/prototool/internal/exec"
+	"github.com/uber/prototool/internal/file"
+	"github.com/uber/prototool/internal/text"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
@@ -46,17 +52,27 @@ var (
 			return runner.All(args, flags.disableFormat, flags.disableLint, flags.fix)
 		},
 		BindFlags: func(flagSet *pflag.FlagSet, flags *flags) {
+			flags.bindBaseline(flagSet)
 			flags.bindCachePath(flagSet)
 			flags.bindConfigData(flagSet)
 			flags.bindDisableFormat(flagSet)
 			flags.bindDisableLint(flagSet)
 			flags.bindErrorFormat(flagSet)
+			flags.bindEvents(flagSet)
+			flags.bindEventsFile(flagSet)
 			flags.bindJSON(flagSet)
+			flags.bindMaxWarnings(flagSet)
+			flags.bindOutputFormat(flagSet)
+			flags.bindOverlay(flagSet)
 			flags.bindFix(flagSet)
+			flags.bindJobs(flagSet)
 			flags.bindProtocURL(flagSet)
 			flags.bindProtocBinPath(flagSet)
 			flags.bindProtocWKTPath(flagSet)
+			flags.bindProtocTimeout(flagSet)
+			flags.bindSummary(flagSet)
 			flags.bindWalkTimeout(flagSet)
+			flags.bindWriteBaseline(flagSet)
 		},
 	}
 
@@ -105,55 +121,331 @@ Artifacts are downloaded to the following directories based on flags and environ
 	}
 
 	compileCmdTemplate = &cmdTemplate{
-		Use:   "compile [dirOrFile]",
+		Use:   "compile [dirOrFile...]",
 		Short: "Compile with protoc to check for failures.",
 		Long:  `Stubs will not be generated. To generate stubs, use the "gen" command. Calling "compile" has the effect of calling protoc with "-o /dev/null".`,
-		Args:  cobra.MaximumNArgs(1),
+		Args:  cobra.ArbitraryArgs,
 		Run: func(runner exec.Runner, args []string, flags *flags) error {
 			return runner.Compile(args, flags.dryRun)
 		},
 		BindFlags: func(flagSet *pflag.FlagSet, flags *flags) {
+			flags.bindBaseline(flagSet)
 			flags.bindCachePath(flagSet)
 			flags.bindConfigData(flagSet)
 			flags.bindDryRun(flagSet)
 			flags.bindErrorFormat(flagSet)
+			flags.bindEvents(flagSet)
+			flags.bindEventsFile(flagSet)
+			flags.bindFilesFrom(flagSet)
+			flags.bindJSON(flagSet)
+			flags.bindMaxWarnings(flagSet)
+			flags.bindOutputFormat(flagSet)
+			flags.bindOverlay(flagSet)
+			flags.bindJobs(flagSet)
+			flags.bindProtocURL(flagSet)
+			flags.bindProtocBinPath(flagSet)
+			flags.bindProtocWKTPath(flagSet)
+			flags.bindProtocTimeout(flagSet)
+			flags.bindSince(flagSet)
+			flags.bindSummary(flagSet)
+			flags.bindWalkTimeout(flagSet)
+			flags.bindWriteBaseline(flagSet)
+		},
+	}
+
+	convertCmdTemplate = &cmdTemplate{
+		Use:   "convert [dirOrFile]",
+		Short: "Convert a message between the binary, JSON, and text formats.",
+		Long: `Reads a message of the type given with --type from stdin in the --from format, and writes it
+to stdout in the --to format. The type is resolved from the compiled Protobuf files in the
+current or given directory, including imports. Pass --delimited to convert a stream of
+messages, such as length-delimited binary messages.
+
+Pass --decode-raw to print the field numbers, wire types, and values of binary input of an
+unknown type instead. Nothing is compiled with --decode-raw.
+
+  prototool convert --type foo.v1.Bar --from binary --to json < bar.bin
+  prototool convert --decode-raw < bar.bin`,
+		Args: cobra.MaximumNArgs(1),
+		Run: func(runner exec.Runner, args []string, flags *flags) error {
+			return runner.Convert(args, flags.typeName, flags.from, flags.to, flags.delimited, flags.decodeRaw)
+		},
+		BindFlags: func(flagSet *pflag.FlagSet, flags *flags) {
+			flags.bindCachePath(flagSet)
+			flags.bindConfigData(flagSet)
+			flags.bindConvertFrom(flagSet)
+			flags.bindConvertTo(flagSet)
+			flags.bindDecodeRaw(flagSet)
+			flags.bindDelimited(flagSet)
+			flags.bindErrorFormat(flagSet)
+			flags.bindJSON(flagSet)
+			flags.bindOutputFormat(flagSet)
+			flags.bindOverlay(flagSet)
+			flags.bindJobs(flagSet)
+			flags.bindProtocURL(flagSet)
+			flags.bindProtocBinPath(flagSet)
+			flags.bindProtocWKTPath(flagSet)
+			flags.bindProtocTimeout(flagSet)
+			flags.bindType(flagSet)
+			flags.bindWalkTimeout(flagSet)
+		},
+	}
+
+	docsCmdTemplate = &cmdTemplate{
+		Use:   "docs [dirOrFile]",
+		Short: "Generate API reference documentation from the comments in Protobuf files.",
+		Long: `Compiles with --include_source_info and writes one page per package to the --output
+directory, along with an index page. Leading and trailing comments are attached to every
+service, method, message, field, enum, and enum value, deprecated elements are marked, and
+streaming methods are annotated. Message and enum types link to their definitions, including
+types in imported packages.
+
+Pass --template to override the templates the pages are generated from.`,
+		Args: cobra.MaximumNArgs(1),
+		Run: func(runner exec.Runner, args []string, flags *flags) error {
+			return runner.Docs(args, flags.format, flags.output, flags.template)
+		},
+		BindFlags: func(flagSet *pflag.FlagSet, flags *flags) {
+			flags.bindCachePath(flagSet)
+			flags.bindConfigData(flagSet)
+			flags.bindDocsFormat(flagSet)
+			flags.bindDocsTemplate(flagSet)
+			flags.bindErrorFormat(flagSet)
 			flags.bindJSON(flagSet)
+			flags.bindOutput(flagSet)
+			flags.bindOutputFormat(flagSet)
+			flags.bindOverlay(flagSet)
+			flags.bindJobs(flagSet)
 			flags.bindProtocURL(flagSet)
 			flags.bindProtocBinPath(flagSet)
 			flags.bindProtocWKTPath(flagSet)
+			flags.bindProtocTimeout(flagSet)
 			flags.bindWalkTimeout(flagSet)
 		},
 	}
 
 	filesCmdTemplate = &cmdTemplate{
-		Use:   "files [dirOrFile]",
+		Use:   "files [dirOrFile...]",
 		Short: "Print all files that match the input arguments.",
-		Args:  cobra.MaximumNArgs(1),
+		Args:  cobra.ArbitraryArgs,
 		Run: func(runner exec.Runner, args []string, flags *flags) error {
 			return runner.Files(args)
 		},
 		BindFlags: func(flagSet *pflag.FlagSet, flags *flags) {
 			flags.bindConfigData(flagSet)
+			flags.bindEvents(flagSet)
+			flags.bindEventsFile(flagSet)
+			flags.bindFilesFrom(flagSet)
+			flags.bindOverlay(flagSet)
+			flags.bindSince(flagSet)
 			flags.bindWalkTimeout(flagSet)
 		},
 	}
 
 	generateCmdTemplate = &cmdTemplate{
-		Use:   "generate [dirOrFile]",
+		Use:   "generate [dirOrFile...]",
 		Short: "Generate with protoc.",
-		Args:  cobra.MaximumNArgs(1),
+		Args:  cobra.ArbitraryArgs,
 		Run: func(runner exec.Runner, args []string, flags *flags) error {
 			return runner.Gen(args, flags.dryRun)
 		},
 		BindFlags: func(flagSet *pflag.FlagSet, flags *flags) {
+			flags.bindBaseline(flagSet)
 			flags.bindCachePath(flagSet)
 			flags.bindConfigData(flagSet)
 			flags.bindDryRun(flagSet)
 			flags.bindErrorFormat(flagSet)
+			flags.bindEvents(flagSet)
+			flags.bindEventsFile(flagSet)
+			flags.bindFilesFrom(flagSet)
+			flags.bindJSON(flagSet)
+			flags.bindMaxWarnings(flagSet)
+			flags.bindOutputFormat(flagSet)
+			flags.bindOverlay(flagSet)
+			flags.bindJobs(flagSet)
+			flags.bindProtocURL(flagSet)
+			flags.bindProtocBinPath(flagSet)
+			flags.bindProtocWKTPath(flagSet)
+			flags.bindProtocTimeout(flagSet)
+			flags.bindSince(flagSet)
+			flags.bindSummary(flagSet)
+			flags.bindWalkTimeout(flagSet)
+			flags.bindWriteBaseline(flagSet)
+		},
+	}
+
+	hooksInstallCmdTemplate = &cmdTemplate{
+		Use:   "install [dirPath]",
+		Short: "Install a git pre-commit hook that compiles the staged Protobuf files.",
+		Long: `The hook is written to the hooks directory of the git repository that contains the current
+or given directory, respecting core.hooksPath, and runs "prototool hooks pre-commit" for that
+directory. prototool must be on the PATH when committing.
+
+An existing pre-commit hook is not overwritten unless --force is set. To use the pre-commit
+framework instead, reference the hooks in .pre-commit-hooks.yaml at the root of the prototool
+repository.`,
+		Args: cobra.MaximumNArgs(1),
+		Run: func(runner exec.Runner, args []string, flags *flags) error {
+			return runner.HooksInstall(args, flags.force)
+		},
+		BindFlags: func(flagSet *pflag.FlagSet, flags *flags) {
+			flags.bindForce(flagSet)
+		},
+	}
+
+	hooksPreCommitCmdTemplate = &cmdTemplate{
+		Use:   "pre-commit [dirPath]",
+		Short: "Compile the staged Protobuf files in the current or given directory.",
+		Long: `The staged contents of all Protobuf files are used rather than the working copy, so partially
+staged files are checked as they will be committed. Only the directories of the staged files
+are compiled, and only the failures for the staged files are printed. If no Protobuf files are
+staged, nothing is done.
+
+This is run by the hook written by "prototool hooks install".`,
+		Args: cobra.MaximumNArgs(1),
+		Run: func(runner exec.Runner, args []string, flags *flags) error {
+			return runner.HooksPreCommit(args)
+		},
+		BindFlags: func(flagSet *pflag.FlagSet, flags *flags) {
+			flags.bindBaseline(flagSet)
+			flags.bindCachePath(flagSet)
+			flags.bindConfigData(flagSet)
+			flags.bindErrorFormat(flagSet)
+			flags.bindEvents(flagSet)
+			flags.bindEventsFile(flagSet)
+			flags.bindJSON(flagSet)
+			flags.bindMaxWarnings(flagSet)
+			flags.bindOutputFormat(flagSet)
+			flags.bindJobs(flagSet)
+			flags.bindProtocURL(flagSet)
+			flags.bindProtocBinPath(flagSet)
+			flags.bindProtocWKTPath(flagSet)
+			flags.bindProtocTimeout(flagSet)
+			flags.bindWalkTimeout(flagSet)
+		},
+	}
+
+	jsonschemaCmdTemplate = &cmdTemplate{
+		Use:   "jsonschema [dirOrFile]",
+		Short: "Print a JSON Schema for the JSON mapping of a message.",
+		Long: `Compiles with --include_source_info and prints a draft 2020-12 JSON Schema for the message
+given with --message, following the JSON mapping: fields use their JSON names, 64-bit integers
+are strings, enums are their value names, the fields of a oneof are mutually exclusive, and
+maps are objects. The Well-Known Types are mapped to their JSON equivalents.
+
+All referenced messages and enums are defined in $defs. Comments become descriptions.`,
+		Args: cobra.MaximumNArgs(1),
+		Run: func(runner exec.Runner, args []string, flags *flags) error {
+			return runner.JSONSchema(args, flags.message)
+		},
+		BindFlags: func(flagSet *pflag.FlagSet, flags *flags) {
+			flags.bindCachePath(flagSet)
+			flags.bindConfigData(flagSet)
+			flags.bindErrorFormat(flagSet)
+			flags.bindJSON(flagSet)
+			flags.bindMessage(flagSet)
+			flags.bindOutputFormat(flagSet)
+			flags.bindOverlay(flagSet)
+			flags.bindJobs(flagSet)
+			flags.bindProtocURL(flagSet)
+			flags.bindProtocBinPath(flagSet)
+			flags.bindProtocWKTPath(flagSet)
+			flags.bindProtocTimeout(flagSet)
+			flags.bindWalkTimeout(flagSet)
+		},
+	}
+
+	lspCmdTemplate = &cmdTemplate{
+		Use:   "lsp",
+		Short: "Run a language server over stdio.",
+		Long: `Speaks the Language Server Protocol over stdin and stdout, for use by editors.
+
+Files are compiled when opened and saved, and compile failures are published as diagnostics. Go-to-definition, find-references, hover, and document symbols are supported for the last successfully compiled version of each file.`,
+		Args: cobra.NoArgs,
+		Run: func(runner exec.Runner, args []string, flags *flags) error {
+			return runner.LSP()
+		},
+		BindFlags: func(flagSet *pflag.FlagSet, flags *flags) {
+			flags.bindCachePath(flagSet)
+			flags.bindConfigData(flagSet)
+			flags.bindJobs(flagSet)
+			flags.bindProtocURL(flagSet)
+			flags.bindProtocBinPath(flagSet)
+			flags.bindProtocWKTPath(flagSet)
+			flags.bindProtocTimeout(flagSet)
+			flags.bindWalkTimeout(flagSet)
+		},
+	}
+
+	openapiCmdTemplate = &cmdTemplate{
+		Use:   "openapi [dirOrFile]",
+		Short: "Generate OpenAPI v3 documents for the services in Protobuf files.",
+		Long: `Compiles with --include_source_info and writes one OpenAPI v3 document per package with
+services to the --output directory, or a single openapi.json document with --merge.
+
+Messages and enums are mapped to component schemas following the JSON mapping, and the
+Well-Known Types are mapped to their JSON equivalents. Methods use their google.api.http
+annotations if present, otherwise they are mapped to POST /package.Service/Method with the
+request message as the body. Comments become descriptions.`,
+		Args: cobra.MaximumNArgs(1),
+		Run: func(runner exec.Runner, args []string, flags *flags) error {
+			return runner.OpenAPI(args, flags.output, flags.merge, flags.apiVersion)
+		},
+		BindFlags: func(flagSet *pflag.FlagSet, flags *flags) {
+			flags.bindAPIVersion(flagSet)
+			flags.bindCachePath(flagSet)
+			flags.bindConfigData(flagSet)
+			flags.bindErrorFormat(flagSet)
 			flags.bindJSON(flagSet)
+			flags.bindMerge(flagSet)
+			flags.bindOutput(flagSet)
+			flags.bindOutputFormat(flagSet)
+			flags.bindOverlay(flagSet)
+			flags.bindJobs(flagSet)
 			flags.bindProtocURL(flagSet)
 			flags.bindProtocBinPath(flagSet)
 			flags.bindProtocWKTPath(flagSet)
+			flags.bindProtocTimeout(flagSet)
+			flags.bindWalkTimeout(flagSet)
+		},
+	}
+
+	sampleCmdTemplate = &cmdTemplate{
+		Use:   "sample [dirOrFile]",
+		Short: "Generate random messages of a type for test fixtures and fuzz corpora.",
+		Long: `Generates --count random messages of the type given with --type, resolved from the compiled
+Protobuf files in the current or given directory, including imports.
+
+Enums only use their declared values, one field of each oneof is set, repeated and map fields
+have up to three elements, and google.protobuf.Timestamp and google.protobuf.Duration have
+realistic values. Nested messages are populated up to a depth of three. The same --seed always
+generates the same messages.
+
+Pass --fuzz-corpus to write each message in the binary format to a Go native fuzzing corpus file.
+
+  prototool sample --type foo.v1.Bar --count 10 --seed 42
+  prototool sample --type foo.v1.Bar --count 100 --fuzz-corpus testdata/fuzz/FuzzBar`,
+		Args: cobra.MaximumNArgs(1),
+		Run: func(runner exec.Runner, args []string, flags *flags) error {
+			return runner.Sample(args, flags.typeName, flags.count, flags.seed, flags.format, flags.fuzzCorpus)
+		},
+		BindFlags: func(flagSet *pflag.FlagSet, flags *flags) {
+			flags.bindCachePath(flagSet)
+			flags.bindConfigData(flagSet)
+			flags.bindCount(flagSet)
+			flags.bindErrorFormat(flagSet)
+			flags.bindFuzzCorpus(flagSet)
+			flags.bindJSON(flagSet)
+			flags.bindOutputFormat(flagSet)
+			flags.bindOverlay(flagSet)
+			flags.bindJobs(flagSet)
+			flags.bindProtocURL(flagSet)
+			flags.bindProtocBinPath(flagSet)
+			flags.bindProtocWKTPath(flagSet)
+			flags.bindProtocTimeout(flagSet)
+			flags.bindSampleFormat(flagSet)
+			flags.bindSeed(flagSet)
+			flags.bindType(flagSet)
 			flags.bindWalkTimeout(flagSet)
 		},
 	}
@@ -161,17 +453,49 @@ Artifacts are downloaded to the following directories based on flags and environ
 	configInitCmdTemplate = &cmdTemplate{
 		Use:   "init [dirPath]",
 		Short: "Generate an initial config file in the current or given directory.",
-		Long:  `The currently recommended options will be set.`,
-		Args:  cobra.MaximumNArgs(1),
+		Long: `The currently recommended options will be set.
+
+With --infer, the existing Protobuf files in the directory are used to infer protoc.includes,
+create.packages, and generate.plugins for existing generated Go code.`,
+		Args: cobra.MaximumNArgs(1),
 		Run: func(runner exec.Runner, args []string, flags *flags) error {
-			return runner.Init(args, flags.uncomment, flags.document)
+			return runner.Init(args, flags.uncomment, flags.document, flags.infer)
 		},
 		BindFlags: func(flagSet *pflag.FlagSet, flags *flags) {
 			flags.bindDocument(flagSet)
+			flags.bindInfer(flagSet)
 			flags.bindUncomment(flagSet)
 		},
 	}
 
+	configMigrateCmdTemplate = &cmdTemplate{
+		Use:   "migrate [dirPath]",
+		Short: "Upgrade deprecated settings in the config file for the current or given directory.",
+		Long: `The config file is found the same way as for all other commands.
+
+Deprecated settings are replaced with their successors, and the file can be converted
+between YAML and JSON with --to. Comments are preserved if the file stays YAML.
+A diff is printed before the file is rewritten.`,
+		Args: cobra.MaximumNArgs(1),
+		Run: func(runner exec.Runner, args []string, flags *flags) error {
+			return runner.Migrate(args, flags.to, flags.dryRun)
+		},
+		BindFlags: func(flagSet *pflag.FlagSet, flags *flags) {
+			flags.bindMigrateDryRun(flagSet)
+			flags.bindTo(flagSet)
+		},
+	}
+
+	configSchemaCmdTemplate = &cmdTemplate{
+		Use:   "schema",
+		Short: "Print the JSON Schema for configuration files.",
+		Long:  `The schema can be used by editors to validate and autocomplete prototool.yaml and prototool.json files.`,
+		Args:  cobra.NoArgs,
+		Run: func(runner exec.Runner, args []string, flags *flags) error {
+			return runner.Schema()
+		},
+	}
+
 	versionCmdTemplate = &cmdTemplate{
 		Use:   "version",
 		Short: "Print the version.",
@@ -183,6 +507,35 @@ Artifacts are downloaded to the following directories based on flags and environ
 			return runner.Version()
 		},
 	}
+
+	watchCmdTemplate = &cmdTemplate{
+		Use:   "watch [dirOrFile]",
+		Short: "Watch for changes and recompile.",
+		Long: `Compiles, and then polls the files and config file for changes. On a change, the changed files and all files that import them are recompiled, and any failures are printed. A change to the config file recompiles everything. Pass --gen to also generate stubs.
+
+Polling is used instead of filesystem notifications so that this works in containers. Stop with Ctrl-C.`,
+		Args: cobra.MaximumNArgs(1),
+		Run: func(runner exec.Runner, args []string, flags *flags) error {
+			return runner.Watch(args, flags.gen)
+		},
+		BindFlags: func(flagSet *pflag.FlagSet, flags *flags) {
+			flags.bindBaseline(flagSet)
+			flags.bindCachePath(flagSet)
+			flags.bindConfigData(flagSet)
+			flags.bindErrorFormat(flagSet)
+			flags.bindEvents(flagSet)
+			flags.bindEventsFile(flagSet)
+			flags.bindGen(flagSet)
+			flags.bindJSON(flagSet)
+			flags.bindOutputFormat(flagSet)
+			flags.bindJobs(flagSet)
+			flags.bindProtocURL(flagSet)
+			flags.bindProtocBinPath(flagSet)
+			flags.bindProtocWKTPath(flagSet)
+			flags.bindProtocTimeout(flagSet)
+			flags.bindWalkTimeout(flagSet)
+		},
+	}
 )
 
 // cmdTemplate contains the static parts of a cobra.Command such as
@@ -230,8 +583,9 @@ func (c *cmdTemplate) Build(develMode bool, exitCodeAddr *int, stdin io.Reader,
 		command.Long = wordwrap.WrapString(fmt.Sprintf("%s\n\n%s", strings.TrimSpace(c.Short), strings.TrimSpace(c.Long)), wordWrapLength)
 	}
 	command.Args = c.Args
-	command.Run = func(_ *cobra.Command, args []string) {
-		checkCmd(develMode, exitCodeAddr, stdin, stdout, stderr, args, flags, c.Run)
+	command.Run = func(command *cobra.Command, args []string) {
+		commandName := strings.TrimPrefix(command.CommandPath(), command.Root().Name()+" ")
+		checkCmd(commandName, develMode, exitCodeAddr, stdin, stdout, stderr, args, flags, c.Run)
 	}
 	if c.BindFlags != nil {
 		c.BindFlags(command.PersistentFlags(), flags)
@@ -239,8 +593,27 @@ func (c *cmdTemplate) Build(develMode bool, exitCodeAddr *int, stdin io.Reader,
 	return command
 }
 
-func checkCmd(develMode bool, exitCodeAddr *int, stdin io.Reader, stdout io.Writer, stderr io.Writer, args []string, flags *flags, f func(exec.Runner, []string, *flags) error) {
-	runner, err := getRunner(develMode, stdin, stdout, stderr, flags)
+func checkCmd(commandName string, develMode bool, exitCodeAddr *int, stdin io.Reader, stdout io.Writer, stderr io.Writer, args []string, flags *flags, f func(exec.Runner, []string, *flags) error) {
+	// cancelled on SIGINT or SIGTERM, which kills any running protoc invocations
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	emitter, closeEmitter, err := getEmitter(flags, stderr)
+	if err != nil {
+		*exitCodeAddr = printAndGetErrorExitCode(err, stdout)
+		return
+	}
+	defer closeEmitter()
+	start := time.Now()
+	defer func() {
+		exitCode := *exitCodeAddr
+		emitter.Emit(&event.Event{
+			Type:     event.TypeCommandFinished,
+			Command:  commandName,
+			ExitCode: &exitCode,
+			Duration: time.Since(start).Seconds(),
+		})
+	}()
+	runner, err := getRunner(ctx, emitter, develMode, stdin, stdout, stderr, flags)
 	if err != nil {
 		*exitCodeAddr = printAndGetErrorExitCode(err, stdout)
 		return
@@ -250,13 +623,57 @@ func checkCmd(develMode bool, exitCodeAddr *int, stdin io.Reader, stdout io.Writ
 	}
 }
 
-func getRunner(develMode bool, stdin io.Reader, stdout io.Writer, stderr io.Writer, flags *flags) (exec.Runner, error) {
+// getEmitter returns the Emitter for the flags, and a function to close
+// the events file.
+//
+// Events are written to a separate file, so that they are not mixed with
+// the output on stdout or the logs on stderr.
+func getEmitter(flags *flags, stderr io.Writer) (event.Emitter, func(), error) {
+	switch flags.events {
+	case "":
+		if flags.eventsFile != "" {
+			return nil, nil, fmt.Errorf("cannot use --events-file without --events")
+		}
+		return event.NewNopEmitter(), func() {}, nil
+	case "ndjson":
+		if flags.eventsFile == "" {
+			return event.NewNDJSONEmitter(stderr), func() {}, nil
+		}
+		eventsFile, err := os.Create(flags.eventsFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		return event.NewNDJSONEmitter(eventsFile), func() { _ = eventsFile.Close() }, nil
+	default:
+		return nil, nil, fmt.Errorf(`unknown events format %q, the only format is "ndjson"`, flags.events)
+	}
+}
+
+func getRunner(ctx context.Context, emitter event.Emitter, develMode bool, stdin io.Reader, stdout io.Writer, stderr io.Writer, flags *flags) (exec.Runner, error) {
 	logger, err := getLogger(stderr, flags.debug)
 	if err != nil {
 		return nil, err
 	}
 	runnerOptions := []exec.RunnerOption{
+		exec.RunnerWithContext(ctx),
+		exec.RunnerWithEmitter(emitter),
 		exec.RunnerWithLogger(logger),
+		exec.RunnerWithErrorOutput(stderr),
+	}
+	if flags.baseline != "" && flags.writeBaseline != "" {
+		return nil, fmt.Errorf("cannot use --baseline with --write-baseline")
+	}
+	if flags.baseline != "" {
+		runnerOptions = append(
+			runnerOptions,
+			exec.RunnerWithBaseline(flags.baseline),
+		)
+	}
+	if flags.writeBaseline != "" {
+		runnerOptions = append(
+			runnerOptions,
+			exec.RunnerWithWriteBaseline(flags.writeBaseline),
+		)
 	}
 	if flags.cachePath != "" {
 		runnerOptions = append(
@@ -281,6 +698,22 @@ func getRunner(develMode bool, stdin io.Reader, stdout io.Writer, stderr io.Writ
 			exec.RunnerWithJSON(),
 		)
 	}
+	if flags.outputFormat != "" {
+		outputFormat, err := text.ParseOutputFormat(flags.outputFormat)
+		if err != nil {
+			return nil, err
+		}
+		if flags.json && outputFormat != text.OutputFormatJSON {
+			return nil, fmt.Errorf("cannot use --json with --output-format %s", outputFormat)
+		}
+		if flags.summary && outputFormat != text.OutputFormatText && outputFormat != text.OutputFormatJSON {
+			return nil, fmt.Errorf("cannot use --summary with --output-format %s", outputFormat)
+		}
+		runnerOptions = append(
+			runnerOptions,
+			exec.RunnerWithOutputFormat(outputFormat),
+		)
+	}
 	if flags.protocBinPath != "" {
 		runnerOptions = append(
 			runnerOptions,
@@ -304,17 +737,79 @@ func getRunner(develMode bool, stdin io.Reader, stdout io.Writer, stderr io.Writ
 		)
 	}
 	if flags.errorFormat != "" {
+		if text.IsTemplate(flags.errorFormat) {
+			// parse now so that an invalid template fails before anything runs
+			if _, err := text.ParseTemplate(flags.errorFormat); err != nil {
+				return nil, err
+			}
+		}
 		runnerOptions = append(
 			runnerOptions,
 			exec.RunnerWithErrorFormat(flags.errorFormat),
 		)
 	}
+	if flags.filesFrom != "" {
+		runnerOptions = append(
+			runnerOptions,
+			exec.RunnerWithFilesFrom(flags.filesFrom),
+		)
+	}
+	if flags.jobs < 0 {
+		return nil, fmt.Errorf("--jobs must not be negative: %d", flags.jobs)
+	}
+	if flags.jobs > 0 {
+		runnerOptions = append(
+			runnerOptions,
+			exec.RunnerWithJobs(flags.jobs),
+		)
+	}
+	if flags.maxWarnings >= 0 {
+		runnerOptions = append(
+			runnerOptions,
+			exec.RunnerWithMaxWarnings(flags.maxWarnings),
+		)
+	}
+	if flags.overlay != "" {
+		overlay, err := file.ReadOverlay(flags.overlay)
+		if err != nil {
+			return nil, err
+		}
+		runnerOptions = append(
+			runnerOptions,
+			exec.RunnerWithOverlay(overlay),
+		)
+	}
+	if flags.protocTimeout != "" {
+		parsedProtocTimeout, err := time.ParseDuration(flags.protocTimeout)
+		if err != nil {
+			return nil, err
+		}
+		if parsedProtocTimeout <= 0 {
+			return nil, fmt.Errorf("--protoc-timeout must be positive: %s", flags.protocTimeout)
+		}
+		runnerOptions = append(
+			runnerOptions,
+			exec.RunnerWithProtocTimeout(parsedProtocTimeout),
+		)
+	}
 	if flags.protocURL != "" {
 		runnerOptions = append(
 			runnerOptions,
 			exec.RunnerWithProtocURL(flags.protocURL),
 		)
 	}
+	if flags.since != "" {
+		runnerOptions = append(
+			runnerOptions,
+			exec.RunnerWithSince(flags.since),
+		)
+	}
+	if flags.summary {
+		runnerOptions = append(
+			runnerOptions,
+			exec.RunnerWithSummary(),
+		)
+	}
 	if flags.walkTimeout != "" {
 		parsedWalkTimeout, err := time.ParseDuration(flags.walkTimeout)
 		if err != nil {
@@ -0,0 +1,374 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package convert converts Protobuf payloads between the binary, JSON and
+// text formats using the types in FileDescriptorSets.
+//
+// Payloads without a known type can be printed with DecodeRaw.
+package convert
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/uber/prototool/internal/protoc"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const (
+	// FormatBinary says to use the Protobuf binary wire format.
+	FormatBinary Format = iota
+	// FormatJSON says to use the Protobuf JSON format.
+	FormatJSON
+	// FormatText says to use the Protobuf text format.
+	FormatText
+)
+
+var (
+	_formatToString = map[Format]string{
+		FormatBinary: "binary",
+		FormatJSON:   "json",
+		FormatText:   "text",
+	}
+	_stringToFormat = map[string]Format{
+		"binary": FormatBinary,
+		"json":   FormatJSON,
+		"text":   FormatText,
+	}
+)
+
+// Format is a payload format.
+type Format int
+
+// String implements fmt.Stringer.
+func (f Format) String() string {
+	if s, ok := _formatToString[f]; ok {
+		return s
+	}
+	return strconv.Itoa(int(f))
+}
+
+// ParseFormat parses the Format from the given string.
+//
+// Input is case-insensitive.
+func ParseFormat(s string) (Format, error) {
+	format, ok := _stringToFormat[strings.ToLower(s)]
+	if !ok {
+		return 0, fmt.Errorf("could not parse %s to a Format", s)
+	}
+	return format, nil
+}
+
+// Converter converts payloads of a single message type.
+type Converter interface {
+	// Convert reads a message in the input Format from the reader, and
+	// writes it in the output Format to the writer.
+	//
+	// If ConverterWithDelimited is used, all messages are read from the
+	// reader until EOF, and each is written to the writer.
+	Convert(reader io.Reader, writer io.Writer) error
+}
+
+// ConverterOption is an option for a new Converter.
+type ConverterOption func(*converter)
+
+// ConverterWithDelimited returns a ConverterOption that reads and writes
+// streams of messages instead of a single message.
+//
+// Binary streams are length-delimited, where each message is preceded by
+// its size as a varint, as written by Java's writeDelimitedTo. JSON
+// streams are a sequence of JSON objects, and are written with one object
+// per line. Text streams are written with an empty line between messages,
+// and cannot be read.
+func ConverterWithDelimited() ConverterOption {
+	return func(converter *converter) {
+		converter.delimited = true
+	}
+}
+
+// NewConverter returns a new Converter for the message with the given
+// fully-qualified name, without the leading '.'.
+//
+// The FileDescriptorSets must include all imports.
+func NewConverter(
+	fileDescriptorSets []*descriptor.FileDescriptorSet,
+	typeName string,
+	from Format,
+	to Format,
+	options ...ConverterOption,
+) (Converter, error) {
+	return newConverter(fileDescriptorSets, typeName, from, to, options...)
+}
+
+type converter struct {
+	messageType protoreflect.MessageType
+	types       *protoregistry.Types
+	from        Format
+	to          Format
+	delimited   bool
+}
+
+func newConverter(
+	fileDescriptorSets []*descriptor.FileDescriptorSet,
+	typeName string,
+	from Format,
+	to Format,
+	options ...ConverterOption,
+) (*converter, error) {
+	for _, format := range []Format{from, to} {
+		if _, ok := _formatToString[format]; !ok {
+			return nil, fmt.Errorf("unknown Format: %v", format)
+		}
+	}
+	converter := &converter{
+		from: from,
+		to:   to,
+	}
+	for _, option := range options {
+		option(converter)
+	}
+	if converter.delimited && from == FormatText {
+		return nil, errors.New("text input cannot be delimited")
+	}
+	types, err := newTypes(fileDescriptorSets)
+	if err != nil {
+		return nil, err
+	}
+	messageType, err := types.FindMessageByName(protoreflect.FullName(typeName))
+	if err != nil {
+		if err == protoregistry.NotFound {
+			return nil, fmt.Errorf("unknown message %s", typeName)
+		}
+		return nil, err
+	}
+	converter.messageType = messageType
+	converter.types = types
+	return converter, nil
+}
+
+func (c *converter) Convert(reader io.Reader, writer io.Writer) error {
+	if !c.delimited {
+		data, err := ioutil.ReadAll(reader)
+		if err != nil {
+			return err
+		}
+		message, err := c.unmarshal(data)
+		if err != nil {
+			return err
+		}
+		data, err = c.marshal(message)
+		if err != nil {
+			return err
+		}
+		_, err = writer.Write(data)
+		return err
+	}
+	next := c.newDelimitedReader(reader)
+	for i := 0; ; i++ {
+		data, err := next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("message %d: %v", i, err)
+		}
+		message, err := c.unmarshal(data)
+		if err != nil {
+			return fmt.Errorf("message %d: %v", i, err)
+		}
+		data, err = c.marshal(message)
+		if err != nil {
+			return fmt.Errorf("message %d: %v", i, err)
+		}
+		if c.to == FormatText && i > 0 {
+			data = append([]byte("\n"), data...)
+		}
+		if _, err := writer.Write(data); err != nil {
+			return err
+		}
+	}
+}
+
+func (c *converter) unmarshal(data []byte) (proto.Message, error) {
+	message := c.messageType.New().Interface()
+	var err error
+	switch c.from {
+	case FormatBinary:
+		err = proto.UnmarshalOptions{Resolver: c.types}.Unmarshal(data, message)
+	case FormatJSON:
+		err = protojson.UnmarshalOptions{Resolver: c.types}.Unmarshal(data, message)
+	case FormatText:
+		err = prototext.UnmarshalOptions{Resolver: c.types}.Unmarshal(data, message)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not parse %s %s: %v", c.from, c.messageType.Descriptor().FullName(), err)
+	}
+	return message, nil
+}
+
+func (c *converter) marshal(message proto.Message) ([]byte, error) {
+	return marshal(message, c.to, c.delimited, c.types)
+}
+
+// newDelimitedReader returns a function that returns the next message in
+// the input Format, or io.EOF if there are no more messages.
+func (c *converter) newDelimitedReader(reader io.Reader) func() ([]byte, error) {
+	if c.from == FormatJSON {
+		decoder := json.NewDecoder(reader)
+		return func() ([]byte, error) {
+			var data json.RawMessage
+			if err := decoder.Decode(&data); err != nil {
+				return nil, err
+			}
+			return data, nil
+		}
+	}
+	return newLengthDelimitedReader(reader)
+}
+
+// newLengthDelimitedReader returns a function that returns the next
+// varint length-prefixed message, or io.EOF if there are no more messages.
+func newLengthDelimitedReader(reader io.Reader) func() ([]byte, error) {
+	bufReader := bufio.NewReader(reader)
+	return func() ([]byte, error) {
+		size, err := binary.ReadUvarint(bufReader)
+		if err != nil {
+			if err == io.EOF {
+				return nil, io.EOF
+			}
+			return nil, fmt.Errorf("could not read message size: %v", err)
+		}
+		data := make([]byte, size)
+		if _, err := io.ReadFull(bufReader, data); err != nil {
+			return nil, fmt.Errorf("could not read message of size %d: %v", size, err)
+		}
+		return data, nil
+	}
+}
+
+// Marshal returns the message in the Format.
+//
+// If delimited is true, binary messages are preceded by their size as a
+// varint, and JSON messages are on a single line, so that the results for
+// multiple messages can be concatenated into a stream that a Converter
+// created with ConverterWithDelimited can read. Types within
+// google.protobuf.Any messages are resolved from the linked Go types.
+func Marshal(message proto.Message, format Format, delimited bool) ([]byte, error) {
+	if _, ok := _formatToString[format]; !ok {
+		return nil, fmt.Errorf("unknown Format: %v", format)
+	}
+	return marshal(message, format, delimited, protoregistry.GlobalTypes)
+}
+
+func marshal(message proto.Message, format Format, delimited bool, types *protoregistry.Types) ([]byte, error) {
+	switch format {
+	case FormatBinary:
+		data, err := proto.MarshalOptions{Deterministic: true}.Marshal(message)
+		if err != nil {
+			return nil, err
+		}
+		if delimited {
+			data = append(protowire.AppendVarint(nil, uint64(len(data))), data...)
+		}
+		return data, nil
+	case FormatJSON:
+		data, err := protojson.MarshalOptions{Resolver: types}.Marshal(message)
+		if err != nil {
+			return nil, err
+		}
+		// protojson output is deliberately unstable, so normalize it
+		buffer := bytes.NewBuffer(nil)
+		if delimited {
+			err = json.Compact(buffer, data)
+		} else {
+			err = json.Indent(buffer, data, "", "  ")
+		}
+		if err != nil {
+			return nil, err
+		}
+		buffer.WriteByte('\n')
+		return buffer.Bytes(), nil
+	default:
+		return prototext.MarshalOptions{Multiline: true, Resolver: types}.Marshal(message)
+	}
+}
+
+// newTypes returns the types of all messages, enums and extensions in the
+// FileDescriptorSets.
+func newTypes(fileDescriptorSets []*descriptor.FileDescriptorSet) (*protoregistry.Types, error) {
+	files, err := protodesc.NewFiles(protoc.MergeFileDescriptorSets(fileDescriptorSets...))
+	if err != nil {
+		return nil, err
+	}
+	types := &protoregistry.Types{}
+	var rangeErr error
+	files.RangeFiles(func(fileDescriptor protoreflect.FileDescriptor) bool {
+		rangeErr = registerTypes(types, fileDescriptor.Messages(), fileDescriptor.Enums(), fileDescriptor.Extensions())
+		return rangeErr == nil
+	})
+	if rangeErr != nil {
+		return nil, rangeErr
+	}
+	return types, nil
+}
+
+func registerTypes(
+	types *protoregistry.Types,
+	messageDescriptors protoreflect.MessageDescriptors,
+	enumDescriptors protoreflect.EnumDescriptors,
+	extensionDescriptors protoreflect.ExtensionDescriptors,
+) error {
+	for i := 0; i < enumDescriptors.Len(); i++ {
+		if err := types.RegisterEnum(dynamicpb.NewEnumType(enumDescriptors.Get(i))); err != nil {
+			return err
+		}
+	}
+	for i := 0; i < extensionDescriptors.Len(); i++ {
+		if err := types.RegisterExtension(dynamicpb.NewExtensionType(extensionDescriptors.Get(i))); err != nil {
+			return err
+		}
+	}
+	for i := 0; i < messageDescriptors.Len(); i++ {
+		messageDescriptor := messageDescriptors.Get(i)
+		if err := types.RegisterMessage(dynamicpb.NewMessageType(messageDescriptor)); err != nil {
+			return err
+		}
+		if err := registerTypes(types, messageDescriptor.Messages(), messageDescriptor.Enums(), messageDescriptor.Extensions()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package convert
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const testFooJSON = `{
+  "id": "12",
+  "name": "hello",
+  "hello": "HELLO_WORLD",
+  "bar": {
+    "ok": true
+  },
+  "createTime": "2020-01-02T03:04:05Z"
+}
+`
+
+func TestConvert(t *testing.T) {
+	data := testConvert(t, FormatJSON, FormatBinary, testFooJSON)
+	assert.Equal(t, testConvert(t, FormatText, FormatBinary, testConvert(t, FormatJSON, FormatText, testFooJSON)), data)
+	assert.Equal(t, testFooJSON, testConvert(t, FormatBinary, FormatJSON, data))
+
+	_, err := testNewConverter(t, FormatJSON, FormatBinary).(*converter).unmarshal([]byte(`{"unknown": 1}`))
+	assert.Error(t, err)
+}
+
+func TestConvertDelimited(t *testing.T) {
+	data := testConvert(t, FormatJSON, FormatBinary, `{"id": "1"} {"name": "two"}`, ConverterWithDelimited())
+	assert.Equal(t, "\x02\x08\x01\x05\x12\x03two", data)
+	assert.Equal(t, "{\"id\":\"1\"}\n{\"name\":\"two\"}\n", testConvert(t, FormatBinary, FormatJSON, data, ConverterWithDelimited()))
+
+	err := testNewConverter(t, FormatBinary, FormatJSON, ConverterWithDelimited()).Convert(strings.NewReader("\x05\x08"), bytes.NewBuffer(nil))
+	assert.EqualError(t, err, "message 0: could not read message of size 5: unexpected EOF")
+	_, err = NewConverter(testFileDescriptorSets(t), "foo.v1.Foo", FormatText, FormatJSON, ConverterWithDelimited())
+	assert.EqualError(t, err, "text input cannot be delimited")
+}
+
+func TestNewConverterErrors(t *testing.T) {
+	_, err := NewConverter(testFileDescriptorSets(t), "foo.v1.Baz", FormatBinary, FormatJSON)
+	assert.EqualError(t, err, "unknown message foo.v1.Baz")
+	_, err = NewConverter(testFileDescriptorSets(t), "foo.v1.Foo", FormatBinary, Format(10))
+	assert.EqualError(t, err, "unknown Format: 10")
+}
+
+func TestParseFormat(t *testing.T) {
+	format, err := ParseFormat("JSON")
+	require.NoError(t, err)
+	assert.Equal(t, FormatJSON, format)
+	assert.Equal(t, "binary", FormatBinary.String())
+	_, err = ParseFormat("yaml")
+	assert.Error(t, err)
+}
+
+func TestDecodeRaw(t *testing.T) {
+	data := testConvert(t, FormatJSON, FormatBinary, testFooJSON)
+	buffer := bytes.NewBuffer(nil)
+	require.NoError(t, DecodeRaw(strings.NewReader(data), buffer, false))
+	assert.Equal(
+		t,
+		`1 varint: 12
+2 bytes: "hello"
+3 varint: 1
+4 bytes {
+  1 varint: 1
+}
+5 bytes {
+  1 varint: 1577934245
+}
+`,
+		buffer.String(),
+	)
+
+	buffer.Reset()
+	require.NoError(t, DecodeRaw(strings.NewReader("\x02\x08\x01\x05\x12\x03\xff\x00\x01"), buffer, true))
+	assert.Equal(t, "1 varint: 1\n\n2 bytes: \"\\xff\\x00\\x01\"\n", buffer.String())
+
+	assert.Error(t, DecodeRaw(strings.NewReader("\x08"), bytes.NewBuffer(nil), false))
+}
+
+func testConvert(t *testing.T, from Format, to Format, input string, options ...ConverterOption) string {
+	buffer := bytes.NewBuffer(nil)
+	require.NoError(t, testNewConverter(t, from, to, options...).Convert(strings.NewReader(input), buffer))
+	return buffer.String()
+}
+
+func testNewConverter(t *testing.T, from Format, to Format, options ...ConverterOption) Converter {
+	converter, err := NewConverter(testFileDescriptorSets(t), "foo.v1.Foo", from, to, options...)
+	require.NoError(t, err)
+	return converter
+}
+
+func testFileDescriptorSets(t *testing.T) []*descriptor.FileDescriptorSet {
+	fileDescriptorSet := &descriptor.FileDescriptorSet{}
+	require.NoError(t, jsonpb.UnmarshalString(testFileDescriptorSetJSON, fileDescriptorSet))
+	return []*descriptor.FileDescriptorSet{
+		{
+			File: []*descriptor.FileDescriptorProto{
+				protodesc.ToFileDescriptorProto(timestamppb.File_google_protobuf_timestamp_proto),
+			},
+		},
+		fileDescriptorSet,
+	}
+}
+
+const testFileDescriptorSetJSON = `
+{
+  "file": [
+    {
+      "name": "foo/v1/foo.proto",
+      "package": "foo.v1",
+      "dependency": ["google/protobuf/timestamp.proto"],
+      "syntax": "proto3",
+      "messageType": [
+        {
+          "name": "Foo",
+          "field": [
+            {"name": "id", "jsonName": "id", "number": 1, "label": "LABEL_OPTIONAL", "type": "TYPE_INT64"},
+            {"name": "name", "jsonName": "name", "number": 2, "label": "LABEL_OPTIONAL", "type": "TYPE_STRING"},
+            {"name": "hello", "jsonName": "hello", "number": 3, "label": "LABEL_OPTIONAL", "type": "TYPE_ENUM", "typeName": ".foo.v1.Hello"},
+            {"name": "bar", "jsonName": "bar", "number": 4, "label": "LABEL_OPTIONAL", "type": "TYPE_MESSAGE", "typeName": ".foo.v1.Foo.Bar"},
+            {"name": "create_time", "jsonName": "createTime", "number": 5, "label": "LABEL_OPTIONAL", "type": "TYPE_MESSAGE", "typeName": ".google.protobuf.Timestamp"}
+          ],
+          "nestedType": [
+            {
+              "name": "Bar",
+              "field": [
+                {"name": "ok", "jsonName": "ok", "number": 1, "label": "LABEL_OPTIONAL", "type": "TYPE_BOOL"}
+              ]
+            }
+          ]
+        }
+      ],
+      "enumType": [
+        {
+          "name": "Hello",
+          "value": [{"name": "HELLO_INVALID", "number": 0}, {"name": "HELLO_WORLD", "number": 1}]
+        }
+      ]
+    }
+  ]
+}
+`
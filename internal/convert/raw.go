@@ -0,0 +1,151 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package convert
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// DecodeRaw reads a message in the binary format from the reader without
+// knowing its type, and writes its fields to the writer.
+//
+// Each field is printed on its own line with its number, wire type, and
+// value. Length-delimited values are printed as nested messages if they
+// are not printable strings and can be parsed as messages, otherwise
+// they are printed as quoted strings. If delimited is true, all
+// length-delimited messages are read from the reader until EOF, and an
+// empty line is written between them.
+func DecodeRaw(reader io.Reader, writer io.Writer, delimited bool) error {
+	if !delimited {
+		data, err := ioutil.ReadAll(reader)
+		if err != nil {
+			return err
+		}
+		return decodeRaw(writer, data)
+	}
+	next := newLengthDelimitedReader(reader)
+	for i := 0; ; i++ {
+		data, err := next()
+		if err == io.EOF {
+			return nil
+		}
+		if err == nil && i > 0 {
+			_, err = io.WriteString(writer, "\n")
+		}
+		if err == nil {
+			err = decodeRaw(writer, data)
+		}
+		if err != nil {
+			return fmt.Errorf("message %d: %v", i, err)
+		}
+	}
+}
+
+func decodeRaw(writer io.Writer, data []byte) error {
+	buffer := bytes.NewBuffer(nil)
+	if err := writeRawFields(buffer, data, ""); err != nil {
+		return err
+	}
+	_, err := writer.Write(buffer.Bytes())
+	return err
+}
+
+// writeRawFields writes the fields in data with the given indent, or
+// returns an error if data is not a valid message.
+func writeRawFields(buffer *bytes.Buffer, data []byte, indent string) error {
+	for len(data) > 0 {
+		number, wireType, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return fmt.Errorf("invalid field tag: %v", protowire.ParseError(n))
+		}
+		data = data[n:]
+		if wireType == protowire.EndGroupType {
+			return fmt.Errorf("unexpected end group for field %d", number)
+		}
+		if wireType == protowire.StartGroupType {
+			groupData, n := protowire.ConsumeGroup(number, data)
+			if n < 0 {
+				return fmt.Errorf("invalid group for field %d: %v", number, protowire.ParseError(n))
+			}
+			data = data[n:]
+			fmt.Fprintf(buffer, "%s%d group {\n", indent, number)
+			if err := writeRawFields(buffer, groupData, indent+"  "); err != nil {
+				return err
+			}
+			fmt.Fprintf(buffer, "%s}\n", indent)
+			continue
+		}
+		n = protowire.ConsumeFieldValue(number, wireType, data)
+		if n < 0 {
+			return fmt.Errorf("invalid value for field %d: %v", number, protowire.ParseError(n))
+		}
+		value := data[:n]
+		data = data[n:]
+		switch wireType {
+		case protowire.VarintType:
+			v, _ := protowire.ConsumeVarint(value)
+			fmt.Fprintf(buffer, "%s%d varint: %d\n", indent, number, v)
+		case protowire.Fixed32Type:
+			v, _ := protowire.ConsumeFixed32(value)
+			fmt.Fprintf(buffer, "%s%d fixed32: %d\n", indent, number, v)
+		case protowire.Fixed64Type:
+			v, _ := protowire.ConsumeFixed64(value)
+			fmt.Fprintf(buffer, "%s%d fixed64: %d\n", indent, number, v)
+		case protowire.BytesType:
+			v, _ := protowire.ConsumeBytes(value)
+			if len(v) > 0 && !isPrintable(v) {
+				// write the nested message to a separate buffer so that
+				// nothing is written if it turns out not to be a message
+				nested := bytes.NewBuffer(nil)
+				if err := writeRawFields(nested, v, indent+"  "); err == nil {
+					fmt.Fprintf(buffer, "%s%d bytes {\n", indent, number)
+					_, _ = nested.WriteTo(buffer)
+					fmt.Fprintf(buffer, "%s}\n", indent)
+					continue
+				}
+			}
+			fmt.Fprintf(buffer, "%s%d bytes: %s\n", indent, number, strconv.Quote(string(v)))
+		default:
+			return fmt.Errorf("unknown wire type %d for field %d", wireType, number)
+		}
+	}
+	return nil
+}
+
+// isPrintable returns true if data is valid UTF-8 that only contains
+// printable characters and whitespace.
+func isPrintable(data []byte) bool {
+	if !utf8.Valid(data) {
+		return false
+	}
+	return strings.IndexFunc(string(data), func(r rune) bool {
+		return !unicode.IsPrint(r) && !unicode.IsSpace(r)
+	}) < 0
+}
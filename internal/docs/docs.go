@@ -0,0 +1,443 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package docs generates API reference documentation from PackageSets.
+package docs
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	reflectv1 "github.com/uber/prototool/internal/reflect/gen/uber/proto/reflect/v1"
+)
+
+const (
+	// FormatMarkdown says to generate Markdown pages.
+	FormatMarkdown Format = iota
+	// FormatHTML says to generate HTML pages.
+	FormatHTML
+)
+
+const (
+	// IndexTemplateName is the name of the template for the index page.
+	IndexTemplateName = "index"
+	// PackageTemplateName is the name of the template for package pages.
+	PackageTemplateName = "package"
+)
+
+var (
+	_formatToString = map[Format]string{
+		FormatMarkdown: "markdown",
+		FormatHTML:     "html",
+	}
+	_stringToFormat = map[string]Format{
+		"markdown": FormatMarkdown,
+		"html":     FormatHTML,
+	}
+	_formatToExtension = map[Format]string{
+		FormatMarkdown: ".md",
+		FormatHTML:     ".html",
+	}
+)
+
+// Format is a documentation format.
+type Format int
+
+// String implements fmt.Stringer.
+func (f Format) String() string {
+	if s, ok := _formatToString[f]; ok {
+		return s
+	}
+	return strconv.Itoa(int(f))
+}
+
+// ParseFormat parses the Format from the given string.
+//
+// Input is case-insensitive.
+func ParseFormat(s string) (Format, error) {
+	format, ok := _stringToFormat[strings.ToLower(s)]
+	if !ok {
+		return 0, fmt.Errorf("could not parse %s to a Format", s)
+	}
+	return format, nil
+}
+
+// Generator generates documentation.
+type Generator interface {
+	// Generate returns the pages for the given PackageSet, keyed by file name.
+	//
+	// There is one page per package named after the package, such as
+	// foo.v1.md, and an index page named index.md that links to all
+	// package pages. If packageNames is not empty, only pages for the
+	// given packages are generated, and types of other packages are
+	// not linked to.
+	Generate(packageSet *reflectv1.PackageSet, packageNames ...string) (map[string][]byte, error)
+}
+
+// GeneratorOption is an option for a new Generator.
+type GeneratorOption func(*generator)
+
+// GeneratorWithTemplate returns a GeneratorOption that parses the given
+// template after the default templates for the Format.
+//
+// The template can redefine the "index" and "package" templates, or any of
+// the templates that they use, with {{define}}. Templates are text/template
+// templates for FormatMarkdown and html/template templates for FormatHTML.
+// The index template is executed with an Index, and the package template
+// is executed with a Package.
+func GeneratorWithTemplate(templateText string) GeneratorOption {
+	return func(generator *generator) {
+		generator.templateText = templateText
+	}
+}
+
+// NewGenerator returns a new Generator for the given Format.
+func NewGenerator(format Format, options ...GeneratorOption) (Generator, error) {
+	return newGenerator(format, options...)
+}
+
+// Index is the data for the index template.
+type Index struct {
+	// The packages that pages are generated for, sorted by name.
+	Packages []*Package
+}
+
+// Package is the data for the package template.
+type Package struct {
+	*reflectv1.Package
+	// The file name of the page of the package.
+	FileName string
+	// The dependencies of the package, with links if they get a page.
+	Dependencies []*TypeRef
+	// All messages, including nested messages, sorted by full name.
+	//
+	// This does not include map entry messages.
+	Messages []*Message
+	// All enums, including nested enums, sorted by full name.
+	Enums []*Enum
+	// The services, sorted by name.
+	Services []*Service
+}
+
+// Message is a message within a Package.
+type Message struct {
+	*reflectv1.Message
+	// The fully-qualified name, without the leading '.'.
+	FullName string
+	// The anchor of the message on the page.
+	Anchor string
+	// The fields, sorted by number.
+	Fields []*Field
+}
+
+// Field is a message field.
+type Field struct {
+	*reflectv1.MessageField
+	// The label to print before the type, either "repeated" or "".
+	Label string
+	// The type of the field.
+	Type *TypeRef
+	// The name of the oneof the field is in, if any.
+	OneofName string
+}
+
+// Enum is an enum within a Package.
+type Enum struct {
+	*reflectv1.Enum
+	// The fully-qualified name, without the leading '.'.
+	FullName string
+	// The anchor of the enum on the page.
+	Anchor string
+}
+
+// Service is a service within a Package.
+type Service struct {
+	*reflectv1.Service
+	// The fully-qualified name, without the leading '.'.
+	FullName string
+	// The anchor of the service on the page.
+	Anchor string
+	// The methods, sorted by name.
+	Methods []*Method
+}
+
+// Method is a service method.
+type Method struct {
+	*reflectv1.ServiceMethod
+	// The request type.
+	Request *TypeRef
+	// The response type.
+	Response *TypeRef
+}
+
+// TypeRef is a reference to a type or package.
+type TypeRef struct {
+	// The name of the type, which is fully-qualified for messages and enums.
+	Name string
+	// The link to the type, relative to the page the reference is on.
+	//
+	// This is empty for scalar types and types that do not get a page.
+	Href string
+}
+
+type executor interface {
+	ExecuteTemplate(writer io.Writer, name string, data interface{}) error
+}
+
+type generator struct {
+	format       Format
+	templateText string
+	executor     executor
+}
+
+func newGenerator(format Format, options ...GeneratorOption) (*generator, error) {
+	if _, ok := _formatToString[format]; !ok {
+		return nil, fmt.Errorf("unknown Format: %v", format)
+	}
+	generator := &generator{
+		format: format,
+	}
+	for _, option := range options {
+		option(generator)
+	}
+	executor, err := generator.newExecutor()
+	if err != nil {
+		return nil, err
+	}
+	generator.executor = executor
+	return generator, nil
+}
+
+func (g *generator) newExecutor() (executor, error) {
+	switch g.format {
+	case FormatHTML:
+		tmpl, err := htmltemplate.New("docs").Funcs(htmltemplate.FuncMap(templateFuncs)).Parse(htmlTemplateText)
+		if err != nil {
+			return nil, err
+		}
+		if g.templateText != "" {
+			if tmpl, err = tmpl.Parse(g.templateText); err != nil {
+				return nil, err
+			}
+		}
+		return tmpl, nil
+	default:
+		tmpl, err := template.New("docs").Funcs(templateFuncs).Parse(markdownTemplateText)
+		if err != nil {
+			return nil, err
+		}
+		if g.templateText != "" {
+			if tmpl, err = tmpl.Parse(g.templateText); err != nil {
+				return nil, err
+			}
+		}
+		return tmpl, nil
+	}
+}
+
+func (g *generator) Generate(packageSet *reflectv1.PackageSet, packageNames ...string) (map[string][]byte, error) {
+	index := &Index{
+		Packages: newPackages(packageSet, g.format, packageNames),
+	}
+	fileNameToData := make(map[string][]byte, len(index.Packages)+1)
+	for _, pkg := range index.Packages {
+		data, err := g.execute(PackageTemplateName, pkg)
+		if err != nil {
+			return nil, err
+		}
+		fileNameToData[pkg.FileName] = data
+	}
+	data, err := g.execute(IndexTemplateName, index)
+	if err != nil {
+		return nil, err
+	}
+	fileNameToData["index"+_formatToExtension[g.format]] = data
+	return fileNameToData, nil
+}
+
+func (g *generator) execute(name string, data interface{}) ([]byte, error) {
+	buffer := bytes.NewBuffer(nil)
+	if err := g.executor.ExecuteTemplate(buffer, name, data); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// pages resolves the references between the packages that get pages.
+type pages struct {
+	packages []*Package
+	// fully-qualified type name to the href of the type from any page
+	typeNameToHref map[string]string
+	// package name to the file name of its page
+	packageNameToFileName map[string]string
+}
+
+// newPackages returns the packages of the PackageSet that get pages, which
+// are all packages if packageNames is empty.
+func newPackages(packageSet *reflectv1.PackageSet, format Format, packageNames []string) []*Package {
+	p := &pages{
+		typeNameToHref:        make(map[string]string),
+		packageNameToFileName: make(map[string]string),
+	}
+	for _, pkg := range packageSet.GetPackages() {
+		if len(packageNames) > 0 && !containsString(packageNames, pkg.GetName()) {
+			continue
+		}
+		p.packages = append(p.packages, &Package{
+			Package:  pkg,
+			FileName: pkg.GetName() + _formatToExtension[format],
+		})
+		p.packageNameToFileName[pkg.GetName()] = pkg.GetName() + _formatToExtension[format]
+	}
+	for _, pkg := range p.packages {
+		p.addEnums(pkg, pkg.Name, pkg.GetEnums())
+		p.addMessages(pkg, pkg.Name, pkg.GetMessages())
+		for _, service := range pkg.GetServices() {
+			fullName := pkg.Name + "." + service.GetName()
+			pkg.Services = append(pkg.Services, &Service{
+				Service:  service,
+				FullName: fullName,
+				Anchor:   fullName,
+			})
+		}
+	}
+	// fields and methods can only be resolved once all types are known
+	for _, pkg := range p.packages {
+		for _, dependencyName := range pkg.GetDependencyNames() {
+			pkg.Dependencies = append(pkg.Dependencies, &TypeRef{
+				Name: dependencyName,
+				Href: p.packageNameToFileName[dependencyName],
+			})
+		}
+		for _, message := range pkg.Messages {
+			p.populateFields(pkg, message)
+		}
+		for _, service := range pkg.Services {
+			for _, serviceMethod := range service.GetServiceMethods() {
+				service.Methods = append(service.Methods, &Method{
+					ServiceMethod: serviceMethod,
+					Request:       p.getTypeRef(pkg, serviceMethod.GetRequestTypeName()),
+					Response:      p.getTypeRef(pkg, serviceMethod.GetResponseTypeName()),
+				})
+			}
+		}
+		sort.Slice(pkg.Messages, func(i int, j int) bool { return pkg.Messages[i].FullName < pkg.Messages[j].FullName })
+		sort.Slice(pkg.Enums, func(i int, j int) bool { return pkg.Enums[i].FullName < pkg.Enums[j].FullName })
+	}
+	return p.packages
+}
+
+func (p *pages) addEnums(pkg *Package, prefix string, enums []*reflectv1.Enum) {
+	for _, enum := range enums {
+		fullName := prefix + "." + enum.GetName()
+		pkg.Enums = append(pkg.Enums, &Enum{
+			Enum:     enum,
+			FullName: fullName,
+			Anchor:   fullName,
+		})
+		p.typeNameToHref[fullName] = pkg.FileName + "#" + fullName
+	}
+}
+
+func (p *pages) addMessages(pkg *Package, prefix string, messages []*reflectv1.Message) {
+	for _, message := range messages {
+		fullName := prefix + "." + message.GetName()
+		p.addEnums(pkg, fullName, message.GetNestedEnums())
+		p.addMessages(pkg, fullName, message.GetNestedMessages())
+		// map entries are documented by the map field
+		if message.GetMapEntry() {
+			continue
+		}
+		pkg.Messages = append(pkg.Messages, &Message{
+			Message:  message,
+			FullName: fullName,
+			Anchor:   fullName,
+		})
+		p.typeNameToHref[fullName] = pkg.FileName + "#" + fullName
+	}
+}
+
+func (p *pages) populateFields(pkg *Package, message *Message) {
+	numberToOneofName := make(map[int32]string)
+	for _, messageOneof := range message.GetMessageOneofs() {
+		for _, fieldNumber := range messageOneof.GetFieldNumbers() {
+			numberToOneofName[fieldNumber] = messageOneof.GetName()
+		}
+	}
+	nameToNestedMessage := make(map[string]*reflectv1.Message)
+	for _, nestedMessage := range message.GetNestedMessages() {
+		nameToNestedMessage[message.FullName+"."+nestedMessage.GetName()] = nestedMessage
+	}
+	for _, messageField := range message.GetMessageFields() {
+		field := &Field{
+			MessageField: messageField,
+			OneofName:    numberToOneofName[messageField.GetNumber()],
+		}
+		if mapEntry, ok := nameToNestedMessage[messageField.GetTypeName()]; ok && mapEntry.GetMapEntry() && messageField.GetLabel() == reflectv1.MessageField_LABEL_REPEATED {
+			keyType := p.getFieldTypeRef(pkg, mapEntry.GetMessageFields()[0])
+			valueType := p.getFieldTypeRef(pkg, mapEntry.GetMessageFields()[1])
+			field.Type = &TypeRef{
+				Name: fmt.Sprintf("map<%s, %s>", keyType.Name, valueType.Name),
+				Href: valueType.Href,
+			}
+		} else {
+			field.Type = p.getFieldTypeRef(pkg, messageField)
+			if messageField.GetLabel() == reflectv1.MessageField_LABEL_REPEATED {
+				field.Label = "repeated"
+			}
+		}
+		message.Fields = append(message.Fields, field)
+	}
+	sort.Slice(message.Fields, func(i int, j int) bool { return message.Fields[i].GetNumber() < message.Fields[j].GetNumber() })
+}
+
+func (p *pages) getFieldTypeRef(pkg *Package, messageField *reflectv1.MessageField) *TypeRef {
+	if typeName := messageField.GetTypeName(); typeName != "" {
+		return p.getTypeRef(pkg, typeName)
+	}
+	return &TypeRef{
+		Name: strings.ToLower(strings.TrimPrefix(messageField.GetType().String(), "TYPE_")),
+	}
+}
+
+func (p *pages) getTypeRef(pkg *Package, typeName string) *TypeRef {
+	href := p.typeNameToHref[typeName]
+	// links within the same page only need the anchor
+	href = strings.TrimPrefix(href, pkg.FileName)
+	return &TypeRef{
+		Name: typeName,
+		Href: href,
+	}
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
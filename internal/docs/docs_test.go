@@ -0,0 +1,227 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package docs
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	reflectv1 "github.com/uber/prototool/internal/reflect/gen/uber/proto/reflect/v1"
+)
+
+func TestGenerateMarkdown(t *testing.T) {
+	generator, err := NewGenerator(FormatMarkdown)
+	require.NoError(t, err)
+	fileNameToData, err := generator.Generate(testPackageSet(t))
+	require.NoError(t, err)
+	require.Len(t, fileNameToData, 3)
+	assert.Equal(t, "# API Reference\n\n- [bar.v1](bar.v1.md)\n- [foo.v1](foo.v1.md)\n", string(fileNameToData["index.md"]))
+	assert.Equal(t, testFooMarkdown, string(fileNameToData["foo.v1.md"]))
+}
+
+func TestGenerateHTML(t *testing.T) {
+	generator, err := NewGenerator(FormatHTML)
+	require.NoError(t, err)
+	// bar.v1 does not get a page, so it is not linked to
+	fileNameToData, err := generator.Generate(testPackageSet(t), "foo.v1")
+	require.NoError(t, err)
+	require.Len(t, fileNameToData, 2)
+	assert.Contains(t, string(fileNameToData["index.html"]), `<li><a href="foo.v1.html">foo.v1</a></li>`)
+	data := string(fileNameToData["foo.v1.html"])
+	assert.NotContains(t, data, "bar.v1.html")
+	for _, expected := range []string{
+		`<p>Dependencies: bar.v1, google.protobuf</p>`,
+		`<tr><td>Watch</td><td>stream <a href="#foo.v1.Foo">foo.v1.Foo</a></td><td>stream <a href="#foo.v1.Foo">foo.v1.Foo</a></td><td class="comments"><span class="deprecated">Deprecated.</span> </td></tr>`,
+		`<h3 id="foo.v1.Foo">foo.v1.Foo</h3>`,
+		`It has &lt;two&gt; | lines.</p>`,
+		`<td>map&lt;string, bar.v1.Bar&gt;</td>`,
+	} {
+		assert.Contains(t, data, expected)
+	}
+}
+
+func TestGenerateWithTemplate(t *testing.T) {
+	generator, err := NewGenerator(
+		FormatMarkdown,
+		GeneratorWithTemplate(`{{define "package"}}{{.Name}}:{{range .Messages}} {{.FullName}}{{end}}{{end}}`),
+	)
+	require.NoError(t, err)
+	fileNameToData, err := generator.Generate(testPackageSet(t))
+	require.NoError(t, err)
+	assert.Equal(t, "bar.v1: bar.v1.Bar", string(fileNameToData["bar.v1.md"]))
+	assert.Equal(t, "foo.v1: foo.v1.Foo foo.v1.Foo.Baz foo.v1.Foo.PairEntry", string(fileNameToData["foo.v1.md"]))
+	assert.True(t, strings.HasPrefix(string(fileNameToData["index.md"]), "# API Reference"))
+
+	_, err = NewGenerator(FormatHTML, GeneratorWithTemplate(`{{define "package"}}{{.Name}`))
+	assert.Error(t, err)
+}
+
+func TestParseFormat(t *testing.T) {
+	format, err := ParseFormat("HTML")
+	require.NoError(t, err)
+	assert.Equal(t, FormatHTML, format)
+	assert.Equal(t, "markdown", FormatMarkdown.String())
+	_, err = ParseFormat("pdf")
+	assert.Error(t, err)
+	_, err = NewGenerator(Format(10))
+	assert.Error(t, err)
+}
+
+func testPackageSet(t *testing.T) *reflectv1.PackageSet {
+	packageSet := &reflectv1.PackageSet{}
+	require.NoError(t, jsonpb.UnmarshalString(testPackageSetJSON, packageSet))
+	return packageSet
+}
+
+const testPackageSetJSON = `
+{
+  "packages": [
+    {
+      "name": "bar.v1",
+      "messages": [{"name": "Bar"}]
+    },
+    {
+      "name": "foo.v1",
+      "dependencyNames": ["bar.v1", "google.protobuf"],
+      "enums": [
+        {
+          "name": "Hello",
+          "leadingComments": "Hello is a greeting.",
+          "enumValues": [
+            {"name": "HELLO_INVALID", "leadingComments": "Invalid."},
+            {"name": "HELLO_WORLD", "number": 1, "deprecated": true}
+          ]
+        }
+      ],
+      "messages": [
+        {
+          "name": "Foo",
+          "leadingComments": "Foo is a foo.\n\nIt has <two> | lines.",
+          "deprecated": true,
+          "messageFields": [
+            {"name": "pairs", "number": 7, "label": "LABEL_REPEATED", "type": "TYPE_MESSAGE", "typeName": "foo.v1.Foo.PairEntry"},
+            {"name": "one", "number": 1, "label": "LABEL_OPTIONAL", "type": "TYPE_INT64", "trailingComments": "one is one.", "deprecated": true},
+            {"name": "bar", "number": 2, "label": "LABEL_REPEATED", "type": "TYPE_MESSAGE", "typeName": "bar.v1.Bar"},
+            {"name": "hello", "number": 3, "label": "LABEL_OPTIONAL", "type": "TYPE_ENUM", "typeName": "foo.v1.Hello"},
+            {"name": "labels", "number": 4, "label": "LABEL_REPEATED", "type": "TYPE_MESSAGE", "typeName": "foo.v1.Foo.LabelsEntry"},
+            {"name": "time", "number": 5, "label": "LABEL_OPTIONAL", "type": "TYPE_MESSAGE", "typeName": "google.protobuf.Timestamp"},
+            {"name": "baz", "number": 6, "label": "LABEL_OPTIONAL", "type": "TYPE_MESSAGE", "typeName": "foo.v1.Foo.Baz"}
+          ],
+          "messageOneofs": [{"name": "value", "fieldNumbers": [3, 6]}],
+          "nestedMessages": [
+            {"name": "Baz", "leadingComments": "Baz is nested."},
+            {
+              "name": "LabelsEntry",
+              "mapEntry": true,
+              "messageFields": [
+                {"name": "key", "number": 1, "label": "LABEL_OPTIONAL", "type": "TYPE_STRING"},
+                {"name": "value", "number": 2, "label": "LABEL_OPTIONAL", "type": "TYPE_MESSAGE", "typeName": "bar.v1.Bar"}
+              ]
+            },
+            {
+              "name": "PairEntry",
+              "leadingComments": "PairEntry looks like a map entry, but is not one.",
+              "messageFields": [
+                {"name": "key", "number": 1, "label": "LABEL_OPTIONAL", "type": "TYPE_STRING"},
+                {"name": "value", "number": 2, "label": "LABEL_OPTIONAL", "type": "TYPE_STRING"}
+              ]
+            }
+          ]
+        }
+      ],
+      "services": [
+        {
+          "name": "FooAPI",
+          "leadingComments": "FooAPI is an API.",
+          "serviceMethods": [
+            {"name": "Get", "requestTypeName": "foo.v1.Foo", "responseTypeName": "bar.v1.Bar", "leadingComments": "Get gets.", "trailingComments": "Really."},
+            {"name": "Watch", "requestTypeName": "foo.v1.Foo", "responseTypeName": "foo.v1.Foo", "clientStreaming": true, "serverStreaming": true, "deprecated": true}
+          ]
+        }
+      ]
+    }
+  ]
+}
+`
+
+const testFooMarkdown = `# foo.v1
+
+Dependencies: [bar.v1](bar.v1.md), google.protobuf
+
+## Services
+
+### <a name="foo.v1.FooAPI"></a>foo.v1.FooAPI
+
+FooAPI is an API.
+
+| Method | Request | Response | Description |
+| ------ | ------- | -------- | ----------- |
+| Get | [foo.v1.Foo](#foo.v1.Foo) | [bar.v1.Bar](bar.v1.md#bar.v1.Bar) | Get gets.<br><br>Really. |
+| Watch | stream [foo.v1.Foo](#foo.v1.Foo) | stream [foo.v1.Foo](#foo.v1.Foo) | **Deprecated.**  |
+
+## Messages
+
+### <a name="foo.v1.Foo"></a>foo.v1.Foo
+
+**Deprecated.**
+
+Foo is a foo.
+
+It has <two> | lines.
+
+| Field | Number | Type | Description |
+| ----- | ------ | ---- | ----------- |
+| one | 1 | int64 | **Deprecated.** one is one. |
+| bar | 2 | repeated [bar.v1.Bar](bar.v1.md#bar.v1.Bar) |  |
+| hello | 3 | [foo.v1.Hello](#foo.v1.Hello) | Oneof value.  |
+| labels | 4 | [map&lt;string, bar.v1.Bar&gt;](bar.v1.md#bar.v1.Bar) |  |
+| time | 5 | google.protobuf.Timestamp |  |
+| baz | 6 | [foo.v1.Foo.Baz](#foo.v1.Foo.Baz) | Oneof value.  |
+| pairs | 7 | repeated [foo.v1.Foo.PairEntry](#foo.v1.Foo.PairEntry) |  |
+
+### <a name="foo.v1.Foo.Baz"></a>foo.v1.Foo.Baz
+
+Baz is nested.
+
+
+### <a name="foo.v1.Foo.PairEntry"></a>foo.v1.Foo.PairEntry
+
+PairEntry looks like a map entry, but is not one.
+
+| Field | Number | Type | Description |
+| ----- | ------ | ---- | ----------- |
+| key | 1 | string |  |
+| value | 2 | string |  |
+
+## Enums
+
+### <a name="foo.v1.Hello"></a>foo.v1.Hello
+
+Hello is a greeting.
+
+| Name | Number | Description |
+| ---- | ------ | ----------- |
+| HELLO_INVALID | 0 | Invalid. |
+| HELLO_WORLD | 1 | **Deprecated.**  |
+`
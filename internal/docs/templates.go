@@ -0,0 +1,196 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package docs
+
+import (
+	"strings"
+)
+
+var templateFuncs = map[string]interface{}{
+	// cell escapes text for a Markdown table cell
+	"cell": func(s string) string {
+		return strings.NewReplacer(
+			"|", `\|`,
+			"<", "&lt;",
+			">", "&gt;",
+			"\n", "<br>",
+		).Replace(s)
+	},
+	// description joins leading and trailing comments into one description
+	"description": func(leadingComments string, trailingComments string) string {
+		if leadingComments == "" || trailingComments == "" {
+			return leadingComments + trailingComments
+		}
+		return leadingComments + "\n\n" + trailingComments
+	},
+}
+
+const markdownTemplateText = `{{define "index" -}}
+# API Reference
+
+{{range .Packages -}}
+- [{{.Name}}]({{.FileName}})
+{{end -}}
+{{end}}
+
+{{- define "typeRef"}}{{if .Href}}[{{cell .Name}}]({{.Href}}){{else}}{{cell .Name}}{{end}}{{end}}
+
+{{- define "header"}}{{if .Deprecated}}**Deprecated.**
+
+{{end}}{{with description .LeadingComments .TrailingComments}}{{.}}
+
+{{end}}{{end}}
+
+{{- define "package" -}}
+# {{.Name}}
+{{with .Dependencies}}
+Dependencies: {{range $i, $dependency := .}}{{if $i}}, {{end}}{{template "typeRef" $dependency}}{{end}}
+{{end}}
+{{- if .Services}}
+## Services
+{{range .Services}}
+### <a name="{{.Anchor}}"></a>{{.FullName}}
+
+{{template "header" .}}| Method | Request | Response | Description |
+| ------ | ------- | -------- | ----------- |
+{{range .Methods -}}
+| {{.Name}} | {{if .ClientStreaming}}stream {{end}}{{template "typeRef" .Request}} | {{if .ServerStreaming}}stream {{end}}{{template "typeRef" .Response}} | {{if .Deprecated}}**Deprecated.** {{end}}{{cell (description .LeadingComments .TrailingComments)}} |
+{{end}}{{end}}{{end}}
+{{- if .Messages}}
+## Messages
+{{range .Messages}}
+### <a name="{{.Anchor}}"></a>{{.FullName}}
+
+{{template "header" .}}{{if .Fields -}}
+| Field | Number | Type | Description |
+| ----- | ------ | ---- | ----------- |
+{{range .Fields -}}
+| {{.Name}} | {{.Number}} | {{with .Label}}{{.}} {{end}}{{template "typeRef" .Type}} | {{if .Deprecated}}**Deprecated.** {{end}}{{with .OneofName}}Oneof {{.}}. {{end}}{{cell (description .LeadingComments .TrailingComments)}} |
+{{end}}{{end}}{{end}}{{end}}
+{{- if .Enums}}
+## Enums
+{{range .Enums}}
+### <a name="{{.Anchor}}"></a>{{.FullName}}
+
+{{template "header" .}}| Name | Number | Description |
+| ---- | ------ | ----------- |
+{{range .EnumValues -}}
+| {{.Name}} | {{.Number}} | {{if .Deprecated}}**Deprecated.** {{end}}{{cell (description .LeadingComments .TrailingComments)}} |
+{{end}}{{end}}{{end}}
+{{- end}}`
+
+const htmlTemplateText = `{{define "index" -}}
+<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>API Reference</title>
+{{template "style"}}
+</head>
+<body>
+<h1>API Reference</h1>
+<ul>
+{{range .Packages -}}
+<li><a href="{{.FileName}}">{{.Name}}</a></li>
+{{end -}}
+</ul>
+</body>
+</html>
+{{end}}
+
+{{- define "style" -}}
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+.comments { white-space: pre-line; }
+.deprecated { color: #a00; font-weight: bold; }
+</style>
+{{- end}}
+
+{{- define "typeRef"}}{{if .Href}}<a href="{{.Href}}">{{.Name}}</a>{{else}}{{.Name}}{{end}}{{end}}
+
+{{- define "deprecated"}}{{if .Deprecated}}<span class="deprecated">Deprecated.</span> {{end}}{{end}}
+
+{{- define "header"}}{{if .Deprecated}}
+<p class="deprecated">Deprecated.</p>
+{{- end}}{{with description .LeadingComments .TrailingComments}}
+<p class="comments">{{.}}</p>
+{{- end}}{{end}}
+
+{{- define "package" -}}
+<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Name}}</title>
+{{template "style"}}
+</head>
+<body>
+<p><a href="index.html">API Reference</a></p>
+<h1>{{.Name}}</h1>
+{{- with .Dependencies}}
+<p>Dependencies: {{range $i, $dependency := .}}{{if $i}}, {{end}}{{template "typeRef" $dependency}}{{end}}</p>
+{{- end}}
+{{- if .Services}}
+<h2>Services</h2>
+{{- range .Services}}
+<h3 id="{{.Anchor}}">{{.FullName}}</h3>
+{{- template "header" .}}
+<table>
+<tr><th>Method</th><th>Request</th><th>Response</th><th>Description</th></tr>
+{{- range .Methods}}
+<tr><td>{{.Name}}</td><td>{{if .ClientStreaming}}stream {{end}}{{template "typeRef" .Request}}</td><td>{{if .ServerStreaming}}stream {{end}}{{template "typeRef" .Response}}</td><td class="comments">{{template "deprecated" .}}{{description .LeadingComments .TrailingComments}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- end}}
+{{- if .Messages}}
+<h2>Messages</h2>
+{{- range .Messages}}
+<h3 id="{{.Anchor}}">{{.FullName}}</h3>
+{{- template "header" .}}
+{{- if .Fields}}
+<table>
+<tr><th>Field</th><th>Number</th><th>Type</th><th>Description</th></tr>
+{{- range .Fields}}
+<tr><td>{{.Name}}</td><td>{{.Number}}</td><td>{{with .Label}}{{.}} {{end}}{{template "typeRef" .Type}}</td><td class="comments">{{template "deprecated" .}}{{with .OneofName}}Oneof {{.}}. {{end}}{{description .LeadingComments .TrailingComments}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- end}}
+{{- end}}
+{{- if .Enums}}
+<h2>Enums</h2>
+{{- range .Enums}}
+<h3 id="{{.Anchor}}">{{.FullName}}</h3>
+{{- template "header" .}}
+<table>
+<tr><th>Name</th><th>Number</th><th>Description</th></tr>
+{{- range .EnumValues}}
+<tr><td>{{.Name}}</td><td>{{.Number}}</td><td class="comments">{{template "deprecated" .}}{{description .LeadingComments .TrailingComments}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- end}}
+</body>
+</html>
+{{end}}`
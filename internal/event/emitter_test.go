@@ -0,0 +1,68 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package event
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uber/prototool/internal/text"
+)
+
+func TestNDJSONEmitter(t *testing.T) {
+	buffer := bytes.NewBuffer(nil)
+	emitter := NewNDJSONEmitter(buffer)
+	emitter.Emit(&Event{
+		Type: TypeFailureFound,
+		Time: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		Failure: &text.Failure{
+			Filename: "foo.proto",
+			Line:     1,
+			Column:   2,
+			Message:  "bar",
+		},
+	})
+	assert.Equal(
+		t,
+		`{"type":"failure_found","time":"2020-01-02T03:04:05Z","failure":{"filename":"foo.proto","line":1,"column":2,"message":"bar","severity":"error"}}`+"\n",
+		buffer.String(),
+	)
+
+	// each event is written as a whole line when emitting concurrently
+	buffer.Reset()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			emitter.Emit(&Event{Type: TypeProtocStarted, Args: []string{"protoc", "foo.proto"}})
+		}()
+	}
+	wg.Wait()
+	lines := bytes.Split(bytes.TrimSpace(buffer.Bytes()), []byte("\n"))
+	assert.Len(t, lines, 10)
+	for _, line := range lines {
+		assert.Contains(t, string(line), `"args":["protoc","foo.proto"]`)
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package event contains the lifecycle events emitted while a command
+// runs, for progress reporting by IDEs and build tools.
+package event
+
+import (
+	"io"
+	"time"
+
+	"github.com/uber/prototool/internal/text"
+)
+
+const (
+	// TypeConfigResolved is emitted when the configuration for the given
+	// directory or file is resolved.
+	TypeConfigResolved Type = "config_resolved"
+	// TypeFilesDiscovered is emitted with the files that a command
+	// operates on.
+	TypeFilesDiscovered Type = "files_discovered"
+	// TypeProtocStarted is emitted when a protoc invocation starts.
+	TypeProtocStarted Type = "protoc_started"
+	// TypeProtocFinished is emitted when a protoc invocation finishes,
+	// including when it fails or times out.
+	TypeProtocFinished Type = "protoc_finished"
+	// TypePluginOutputWritten is emitted when a protoc invocation with a
+	// plugin finishes without failures.
+	TypePluginOutputWritten Type = "plugin_output_written"
+	// TypeFailureFound is emitted for each failure that is reported.
+	TypeFailureFound Type = "failure_found"
+	// TypeCommandFinished is emitted when a command finishes.
+	TypeCommandFinished Type = "command_finished"
+)
+
+// Type is the type of an Event.
+type Type string
+
+// Event is a lifecycle event.
+//
+// Only the fields relevant to the Type are set. Paths are relative to the
+// working directory, or absolute if outside of it.
+type Event struct {
+	Type Type      `json:"type"`
+	Time time.Time `json:"time"`
+	// The directory of the configuration file, or the working directory
+	// if there is no configuration file.
+	ConfigDirPath string `json:"config_dir_path,omitempty"`
+	// The protoc version from the configuration.
+	ProtocVersion string `json:"protoc_version,omitempty"`
+	// The discovered files.
+	Files []string `json:"files,omitempty"`
+	// The directory of the files compiled by protoc.
+	Directory string `json:"directory,omitempty"`
+	// The plugin, if not just compiling.
+	Plugin string `json:"plugin,omitempty"`
+	// The arguments to protoc.
+	Args []string `json:"args,omitempty"`
+	// The path that plugin output was written to.
+	OutputPath string `json:"output_path,omitempty"`
+	// The failure found.
+	Failure *text.Failure `json:"failure,omitempty"`
+	// The command that finished.
+	Command string `json:"command,omitempty"`
+	// The exit code of the command.
+	ExitCode *int `json:"exit_code,omitempty"`
+	// The elapsed time of the protoc invocation or the command, in seconds.
+	Duration float64 `json:"duration,omitempty"`
+}
+
+// Emitter emits Events.
+type Emitter interface {
+	// Emit emits the Event, setting the Time if not set.
+	//
+	// This is thread-safe.
+	Emit(event *Event)
+}
+
+// NewNDJSONEmitter returns a new Emitter that writes each Event to the
+// writer as a single line of JSON.
+//
+// Write errors are ignored, as events are only informational.
+func NewNDJSONEmitter(writer io.Writer) Emitter {
+	return newNDJSONEmitter(writer)
+}
+
+// NewNopEmitter returns a new Emitter that does nothing.
+func NewNopEmitter() Emitter {
+	return nopEmitter{}
+}
@@ -24,9 +24,12 @@
 package exec
 
 import (
+	"context"
 	"io"
 	"time"
 
+	"github.com/uber/prototool/internal/event"
+	"github.com/uber/prototool/internal/text"
 	"go.uber.org/zap"
 )
 
@@ -46,14 +49,25 @@ func (e *ExitError) Error() string {
 // The args given are the args from the command line.
 // Each additional parameter generally refers to a command-specific flag.
 type Runner interface {
-	Init(args []string, uncomment bool, document bool) error
+	Init(args []string, uncomment bool, document bool, infer bool) error
+	Schema() error
+	Migrate(args []string, to string, dryRun bool) error
 	Create(args []string, pkg string) error
 	Version() error
 	CacheUpdate(args []string) error
 	CacheDelete() error
+	HooksInstall(args []string, force bool) error
+	HooksPreCommit(args []string) error
 	Files(args []string) error
 	Compile(args []string, dryRun bool) error
 	Gen(args []string, dryRun bool) error
+	Docs(args []string, format string, outputDirPath string, templatePath string) error
+	OpenAPI(args []string, outputDirPath string, merge bool, version string) error
+	JSONSchema(args []string, messageName string) error
+	Convert(args []string, typeName string, from string, to string, delimited bool, decodeRaw bool) error
+	Sample(args []string, typeName string, count int, seed int64, format string, fuzzCorpusDirPath string) error
+	Watch(args []string, doGen bool) error
+	LSP() error
 	All(args []string, disableFormat, disableLint, fix bool) error
 }
 
@@ -97,14 +111,105 @@ func RunnerWithJSON() RunnerOption {
 	}
 }
 
+// RunnerWithOutputFormat returns a RunnerOption that will print failures
+// in the given OutputFormat.
+//
+// The default is to use text.OutputFormatText. RunnerWithJSON takes
+// precedence for backwards compatibility.
+func RunnerWithOutputFormat(outputFormat text.OutputFormat) RunnerOption {
+	return func(runner *runner) {
+		runner.outputFormat = outputFormat
+	}
+}
+
 // RunnerWithErrorFormat returns a RunnerOption that uses the given colon-separated
-// error format. The default is filename:line:column:message.
+// error format, or a template as parsed by text.ParseTemplate if prefixed with
+// text.TemplatePrefix. The default is filename:line:column:message.
 func RunnerWithErrorFormat(errorFormat string) RunnerOption {
 	return func(runner *runner) {
 		runner.errorFormat = errorFormat
 	}
 }
 
+// RunnerWithMaxWarnings returns a RunnerOption that fails if there are
+// more than the given number of Failures with text.SeverityWarning.
+//
+// The default is to allow any number of warnings.
+func RunnerWithMaxWarnings(maxWarnings int) RunnerOption {
+	return func(runner *runner) {
+		runner.maxWarnings = maxWarnings
+	}
+}
+
+// RunnerWithBaseline returns a RunnerOption that does not report Failures
+// recorded in the given baseline file, and reports baseline entries that no
+// longer occur with text.SeverityInfo.
+func RunnerWithBaseline(baselinePath string) RunnerOption {
+	return func(runner *runner) {
+		runner.baselinePath = baselinePath
+	}
+}
+
+// RunnerWithWriteBaseline returns a RunnerOption that records all Failures
+// to the given baseline file instead of reporting them.
+func RunnerWithWriteBaseline(writeBaselinePath string) RunnerOption {
+	return func(runner *runner) {
+		runner.writeBaselinePath = writeBaselinePath
+	}
+}
+
+// RunnerWithContext returns a RunnerOption that uses the given context.
+//
+// If the context is cancelled, running protoc invocations are killed,
+// and watch and lsp stop.
+//
+// The default is to use context.Background().
+func RunnerWithContext(ctx context.Context) RunnerOption {
+	return func(runner *runner) {
+		runner.ctx = ctx
+	}
+}
+
+// RunnerWithEmitter returns a RunnerOption that emits lifecycle events
+// to the given Emitter.
+//
+// The default is to use event.NewNopEmitter().
+func RunnerWithEmitter(emitter event.Emitter) RunnerOption {
+	return func(runner *runner) {
+		runner.emitter = emitter
+	}
+}
+
+// RunnerWithJobs returns a RunnerOption that runs at most the given
+// number of protoc invocations in parallel.
+//
+// The default is to use the number of CPUs.
+func RunnerWithJobs(jobs int) RunnerOption {
+	return func(runner *runner) {
+		runner.jobs = jobs
+	}
+}
+
+// RunnerWithProtocTimeout returns a RunnerOption that kills protoc
+// invocations that take longer than the given timeout, and reports
+// them as failures.
+//
+// The default is to use the protoc timeout from the config,
+// or no timeout if that is not set.
+func RunnerWithProtocTimeout(protocTimeout time.Duration) RunnerOption {
+	return func(runner *runner) {
+		runner.protocTimeout = protocTimeout
+	}
+}
+
+// RunnerWithSummary returns a RunnerOption that prints statistics about
+// the protoc invocations at the end of a compile.
+func RunnerWithSummary() RunnerOption {
+	return func(runner *runner) {
+		runner.summary = true
+	}
+}
+
 // RunnerWithProtocBinPath returns a RunnerOption that uses the given protoc binary path.
 func RunnerWithProtocBinPath(protocBinPath string) RunnerOption {
 	return func(runner *runner) {
@@ -133,6 +238,49 @@ func RunnerWithWalkTimeout(walkTimeout time.Duration) RunnerOption {
 	}
 }
 
+// RunnerWithOverlay returns a RunnerOption that uses the given overlay,
+// a map from absolute, cleaned file paths to file contents, instead of
+// reading the files from disk when discovering and compiling files.
+//
+// Failures refer to the real file paths.
+func RunnerWithOverlay(overlay map[string][]byte) RunnerOption {
+	return func(runner *runner) {
+		runner.overlay = overlay
+	}
+}
+
+// RunnerWithFilesFrom returns a RunnerOption that also operates on the
+// files read from the given file, one per line, or from the input if the
+// path is "-".
+//
+// This applies to Files, Compile, and Gen. If there are no files and no
+// args, nothing is done.
+func RunnerWithFilesFrom(filesFrom string) RunnerOption {
+	return func(runner *runner) {
+		runner.filesFrom = filesFrom
+	}
+}
+
+// RunnerWithSince returns a RunnerOption that limits the files operated on
+// to those changed in the local git repository since the given git ref, and
+// the files that transitively import them.
+func RunnerWithSince(since string) RunnerOption {
+	return func(runner *runner) {
+		runner.since = since
+	}
+}
+
+// RunnerWithErrorOutput returns a RunnerOption that prints the failures
+// and the summary to the given writer instead of the output for the
+// commands whose output is a payload, such as Convert and Sample.
+//
+// The default is to use the output.
+func RunnerWithErrorOutput(errorOutput io.Writer) RunnerOption {
+	return func(runner *runner) {
+		runner.errorOutput = errorOutput
+	}
+}
+
 // NewRunner returns a new Runner.
 //
 // workDirPath should generally be the current directory.
@@ -22,6 +22,8 @@ package exec
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -35,13 +37,27 @@ import (
 	"text/tabwriter"
 	"time"
 
+	"github.com/uber/prototool/internal/baseline"
 	"github.com/uber/prototool/internal/cfginit"
+	"github.com/uber/prototool/internal/cfgmigrate"
+	"github.com/uber/prototool/internal/cfgschema"
+	"github.com/uber/prototool/internal/convert"
 	"github.com/uber/prototool/internal/create"
+	"github.com/uber/prototool/internal/docs"
+	"github.com/uber/prototool/internal/event"
 	"github.com/uber/prototool/internal/file"
+	"github.com/uber/prototool/internal/git"
+	"github.com/uber/prototool/internal/jsonschema"
+	"github.com/uber/prototool/internal/lsp"
+	"github.com/uber/prototool/internal/openapi"
 	"github.com/uber/prototool/internal/protoc"
+	"github.com/uber/prototool/internal/reflect"
+	"github.com/uber/prototool/internal/sample"
 	"github.com/uber/prototool/internal/settings"
+	"github.com/uber/prototool/internal/strs"
 	"github.com/uber/prototool/internal/text"
 	"github.com/uber/prototool/internal/vars"
+	"github.com/uber/prototool/internal/watch"
 	"go.uber.org/zap"
 )
 
@@ -51,17 +67,31 @@ type runner struct {
 	workDirPath string
 	input       io.Reader
 	output      io.Writer
+	errorOutput io.Writer
+	ctx         context.Context
 
-	logger        *zap.Logger
-	develMode     bool
-	cachePath     string
-	configData    string
-	protocBinPath string
-	protocWKTPath string
-	protocURL     string
-	errorFormat   string
-	json          bool
-	walkTimeout   time.Duration
+	logger            *zap.Logger
+	emitter           event.Emitter
+	develMode         bool
+	cachePath         string
+	configData        string
+	protocBinPath     string
+	protocWKTPath     string
+	protocURL         string
+	protocTimeout     time.Duration
+	jobs              int
+	errorFormat       string
+	json              bool
+	outputFormat      text.OutputFormat
+	maxWarnings       int
+	baselinePath      string
+	writeBaselinePath string
+	summary           bool
+	walkTimeout       time.Duration
+	since             string
+	overlay           map[string][]byte
+	hiddenFilePaths   []string
+	filesFrom         string
 }
 
 func newRunner(workDirPath string, input io.Reader, output io.Writer, options ...RunnerOption) *runner {
@@ -69,28 +99,50 @@ func newRunner(workDirPath string, input io.Reader, output io.Writer, options ..
 		workDirPath: workDirPath,
 		input:       input,
 		output:      output,
+		ctx:         context.Background(),
+		emitter:     event.NewNopEmitter(),
+		maxWarnings: -1,
 	}
 	for _, option := range options {
 		option(runner)
 	}
+	if runner.errorOutput == nil {
+		runner.errorOutput = output
+	}
+	runner.protoSetProvider = runner.newProtoSetProvider()
+	return runner
+}
+
+func (r *runner) newProtoSetProvider() file.ProtoSetProvider {
 	protoSetProviderOptions := []file.ProtoSetProviderOption{
-		file.ProtoSetProviderWithLogger(runner.logger),
-		file.ProtoSetProviderWithWalkTimeout(runner.walkTimeout),
+		file.ProtoSetProviderWithLogger(r.logger),
+		file.ProtoSetProviderWithWalkTimeout(r.walkTimeout),
 	}
-	if runner.configData != "" {
+	if r.configData != "" {
 		protoSetProviderOptions = append(
 			protoSetProviderOptions,
-			file.ProtoSetProviderWithConfigData(runner.configData),
+			file.ProtoSetProviderWithConfigData(r.configData),
 		)
 	}
-	if runner.develMode {
+	if r.develMode {
 		protoSetProviderOptions = append(
 			protoSetProviderOptions,
 			file.ProtoSetProviderWithDevelMode(),
 		)
 	}
-	runner.protoSetProvider = file.NewProtoSetProvider(protoSetProviderOptions...)
-	return runner
+	if len(r.overlay) > 0 {
+		protoSetProviderOptions = append(
+			protoSetProviderOptions,
+			file.ProtoSetProviderWithOverlay(r.overlay),
+		)
+	}
+	if len(r.hiddenFilePaths) > 0 {
+		protoSetProviderOptions = append(
+			protoSetProviderOptions,
+			file.ProtoSetProviderWithHiddenFilePaths(r.hiddenFilePaths),
+		)
+	}
+	return file.NewProtoSetProvider(protoSetProviderOptions...)
 }
 
 func (r *runner) Version() error {
@@ -130,10 +182,13 @@ func (r *runner) Version() error {
 	return tabWriter.Flush()
 }
 
-func (r *runner) Init(args []string, uncomment bool, document bool) error {
+func (r *runner) Init(args []string, uncomment bool, document bool, infer bool) error {
 	if len(args) > 1 {
 		return errors.New("must provide one arg dirPath")
 	}
+	if infer && (uncomment || document) {
+		return errors.New("cannot use --infer with --document or --uncomment")
+	}
 	// TODO(pedge): cleanup
 	dirPath := r.workDirPath
 	if len(args) == 1 {
@@ -146,13 +201,131 @@ func (r *runner) Init(args []string, uncomment bool, document bool) error {
 	if _, err := os.Stat(filePath); err == nil {
 		return fmt.Errorf("%s already exists", filePath)
 	}
-	data, err := cfginit.Generate(vars.DefaultProtocVersion, uncomment, document)
+	var data []byte
+	var err error
+	if infer {
+		data, err = r.inferConfigData(dirPath)
+	} else {
+		data, err = cfginit.Generate(vars.DefaultProtocVersion, uncomment, document)
+	}
 	if err != nil {
 		return err
 	}
 	return ioutil.WriteFile(filePath, data, 0644)
 }
 
+func (r *runner) inferConfigData(dirPath string) ([]byte, error) {
+	absDirPath, err := file.AbsClean(dirPath)
+	if err != nil {
+		return nil, err
+	}
+	// files without a config file are associated with the working directory,
+	// so walk as if prototool was invoked in the directory of the new config file
+	protoSet, err := r.protoSetProvider.GetForDir(absDirPath, absDirPath)
+	if err != nil {
+		return nil, err
+	}
+	var filePaths []string
+	for subDirPath, protoFiles := range protoSet.DirPathToFiles {
+		// a config file in a parent directory may cover more than the directory
+		if subDirPath != absDirPath && !strings.HasPrefix(subDirPath, absDirPath+string(filepath.Separator)) {
+			continue
+		}
+		for _, protoFile := range protoFiles {
+			filePaths = append(filePaths, protoFile.Path)
+		}
+	}
+	sort.Strings(filePaths)
+	inference, err := cfginit.Infer(absDirPath, filePaths)
+	if err != nil {
+		return nil, err
+	}
+	return cfginit.GenerateInferred(vars.DefaultProtocVersion, inference)
+}
+
+func (r *runner) Schema() error {
+	data, err := cfgschema.Generate()
+	if err != nil {
+		return err
+	}
+	_, err = r.output.Write(data)
+	return err
+}
+
+func (r *runner) Migrate(args []string, to string, dryRun bool) error {
+	if len(args) > 1 {
+		return errors.New("must provide one arg dirPath")
+	}
+	dirPath := r.workDirPath
+	if len(args) == 1 {
+		dirPath = args[0]
+	}
+	absDirPath, err := file.AbsClean(dirPath)
+	if err != nil {
+		return err
+	}
+	filePath, err := settings.NewConfigProvider().GetFilePathForDir(absDirPath)
+	if err != nil {
+		return err
+	}
+	if filePath == "" {
+		return fmt.Errorf("no config file found for %s", dirPath)
+	}
+	fromFormat, err := cfgmigrate.FormatForFilePath(filePath)
+	if err != nil {
+		return err
+	}
+	toFormat := fromFormat
+	if to != "" {
+		toFormat, err = cfgmigrate.ParseFormat(to)
+		if err != nil {
+			return err
+		}
+	}
+	newFilePath := strings.TrimSuffix(filePath, filepath.Ext(filePath)) + toFormat.Ext()
+	if newFilePath != filePath {
+		if _, err := os.Stat(newFilePath); err == nil {
+			return fmt.Errorf("%s already exists", newFilePath)
+		}
+	}
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
+	result, err := cfgmigrate.Migrate(data, fromFormat, toFormat)
+	if err != nil {
+		return fmt.Errorf("%s: %v", filePath, err)
+	}
+	for _, change := range result.Changes {
+		r.logger.Info(change, zap.String("file", filePath))
+	}
+	if newFilePath == filePath && bytes.Equal(data, result.Data) {
+		r.logger.Info("config file is up to date", zap.String("file", filePath))
+		return nil
+	}
+	diff, err := cfgmigrate.Diff(r.getDisplayFilePath(filePath), r.getDisplayFilePath(newFilePath), data, result.Data)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(r.output, diff); err != nil {
+		return err
+	}
+	if dryRun {
+		return nil
+	}
+	fileInfo, err := os.Stat(filePath)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(newFilePath, result.Data, fileInfo.Mode().Perm()); err != nil {
+		return err
+	}
+	if newFilePath != filePath {
+		return os.Remove(filePath)
+	}
+	return nil
+}
+
 func (r *runner) Create(args []string, pkg string) error {
 	return r.newCreateHandler(pkg).Create(args...)
 }
@@ -183,87 +356,680 @@ func (r *runner) CacheDelete() error {
 	return d.Delete()
 }
 
+// preCommitHookTemplate is the git pre-commit hook written by HooksInstall,
+// where %s is replaced by the quoted arguments to prototool hooks pre-commit.
+const preCommitHookTemplate = `#!/bin/sh
+# Installed by prototool hooks install.
+#
+# Compiles the staged Protobuf files using their staged contents.
+exec prototool hooks pre-commit%s
+`
+
+func (r *runner) HooksInstall(args []string, force bool) error {
+	if len(args) > 1 {
+		return errors.New("must provide one arg dirPath")
+	}
+	dirPath := r.workDirPath
+	if len(args) == 1 {
+		dirPath = args[0]
+	}
+	absDirPath, err := file.AbsClean(dirPath)
+	if err != nil {
+		return err
+	}
+	rootDirPath, err := git.RootDirPath(absDirPath)
+	if err != nil {
+		return err
+	}
+	hooksDirPath, err := git.HooksDirPath(absDirPath)
+	if err != nil {
+		return err
+	}
+	filePath := filepath.Join(hooksDirPath, "pre-commit")
+	if _, err := os.Stat(filePath); err == nil && !force {
+		return fmt.Errorf("%s already exists, use --force to overwrite", filePath)
+	}
+	// git runs hooks from the repository root
+	relDirPath, err := filepath.Rel(rootDirPath, absDirPath)
+	if err != nil {
+		return err
+	}
+	hookArgs := ""
+	if relDirPath != "." {
+		hookArgs = " '" + strings.Replace(filepath.ToSlash(relDirPath), "'", `'\''`, -1) + "'"
+	}
+	if err := os.MkdirAll(hooksDirPath, 0755); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filePath, []byte(fmt.Sprintf(preCommitHookTemplate, hookArgs)), 0755); err != nil {
+		return err
+	}
+	// WriteFile does not change the permissions of an existing file
+	return os.Chmod(filePath, 0755)
+}
+
+func (r *runner) HooksPreCommit(args []string) error {
+	if len(args) > 1 {
+		return errors.New("must provide one arg dirPath")
+	}
+	dirPath := r.workDirPath
+	if len(args) == 1 {
+		dirPath = args[0]
+	}
+	absDirPath, err := file.AbsClean(dirPath)
+	if err != nil {
+		return err
+	}
+	filePaths, err := git.StagedFiles(absDirPath, "*.proto")
+	if err != nil {
+		return err
+	}
+	if len(filePaths) == 0 {
+		return nil
+	}
+	// imports can be outside of dirPath, so read the staged
+	// contents of every file in the repository
+	rootDirPath, err := git.RootDirPath(absDirPath)
+	if err != nil {
+		return err
+	}
+	stagedOverlay, err := git.StagedContents(rootDirPath, "*.proto")
+	if err != nil {
+		return err
+	}
+	// untracked files will not be committed, so they cannot be imported
+	untrackedFilePaths, err := git.UntrackedFiles(rootDirPath, "*.proto")
+	if err != nil {
+		return err
+	}
+	if len(stagedOverlay) > 0 || len(untrackedFilePaths) > 0 {
+		overlay := make(map[string][]byte, len(r.overlay)+len(stagedOverlay))
+		for filePath, data := range r.overlay {
+			overlay[filePath] = data
+		}
+		for filePath, data := range stagedOverlay {
+			overlay[filePath] = data
+		}
+		r.overlay = overlay
+		r.hiddenFilePaths = untrackedFilePaths
+		r.protoSetProvider = r.newProtoSetProvider()
+	}
+	return r.Compile(filePaths, false)
+}
+
 func (r *runner) Files(args []string) error {
+	metas, err := r.getMetas(args)
+	if err != nil {
+		return err
+	}
+	allFiles, err := getCheckedFilenames(metas...)
+	if err != nil {
+		return err
+	}
+	for _, file := range allFiles {
+		if err := r.println(file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *runner) Compile(args []string, dryRun bool) error {
+	metas, err := r.getMetas(args)
+	if err != nil {
+		return err
+	}
+	r.printAffectedFiles(metas...)
+	_, err = r.compile(false, false, dryRun, metas...)
+	return err
+}
+
+func (r *runner) Gen(args []string, dryRun bool) error {
+	metas, err := r.getMetas(args)
+	if err != nil {
+		return err
+	}
+	r.printAffectedFiles(metas...)
+	_, err = r.compile(true, false, dryRun, metas...)
+	return err
+}
+
+func (r *runner) Docs(args []string, format string, outputDirPath string, templatePath string) error {
+	if outputDirPath == "" {
+		return errors.New("must set --output")
+	}
+	docsFormat, err := docs.ParseFormat(format)
+	if err != nil {
+		return err
+	}
+	var generatorOptions []docs.GeneratorOption
+	if templatePath != "" {
+		data, err := ioutil.ReadFile(templatePath)
+		if err != nil {
+			return err
+		}
+		generatorOptions = append(generatorOptions, docs.GeneratorWithTemplate(string(data)))
+	}
+	generator, err := docs.NewGenerator(docsFormat, generatorOptions...)
+	if err != nil {
+		return err
+	}
+	meta, err := r.getMeta(args)
+	if err != nil {
+		return err
+	}
+	r.printAffectedFiles(meta)
+	fileDescriptorSets, err := r.getFileDescriptorSets(meta, true)
+	if err != nil {
+		return err
+	}
+	packageSet, err := reflect.NewPackageSet(fileDescriptorSets.Unwrap()...)
+	if err != nil {
+		return err
+	}
+	// imports are only used to link to, they do not get pages
+	fileNameToData, err := generator.Generate(packageSet, getTargetPackageNames(meta, fileDescriptorSets)...)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(outputDirPath, 0755); err != nil {
+		return err
+	}
+	for fileName, data := range fileNameToData {
+		if err := ioutil.WriteFile(filepath.Join(outputDirPath, fileName), data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *runner) OpenAPI(args []string, outputDirPath string, merge bool, version string) error {
+	if outputDirPath == "" {
+		return errors.New("must set --output")
+	}
+	var generatorOptions []openapi.GeneratorOption
+	if merge {
+		generatorOptions = append(generatorOptions, openapi.GeneratorWithMerge())
+	}
+	if version != "" {
+		generatorOptions = append(generatorOptions, openapi.GeneratorWithVersion(version))
+	}
 	meta, err := r.getMeta(args)
 	if err != nil {
 		return err
 	}
-	var allFiles []string
-	for dirPath, files := range meta.ProtoSet.DirPathToFiles {
-		// skip those files not under the directory
-		if !strings.HasPrefix(dirPath, meta.ProtoSet.DirPath) {
-			continue
-		}
-		for _, file := range files {
-			allFiles = append(allFiles, file.DisplayPath)
+	r.printAffectedFiles(meta)
+	fileDescriptorSets, err := r.getFileDescriptorSets(meta, true)
+	if err != nil {
+		return err
+	}
+	// imports are only used for their types, their services are not included
+	fileNameToData, err := openapi.NewGenerator(generatorOptions...).Generate(
+		fileDescriptorSets.Unwrap(),
+		getTargetPackageNames(meta, fileDescriptorSets)...,
+	)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(outputDirPath, 0755); err != nil {
+		return err
+	}
+	for fileName, data := range fileNameToData {
+		if err := ioutil.WriteFile(filepath.Join(outputDirPath, fileName), data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *runner) JSONSchema(args []string, messageName string) error {
+	if messageName == "" {
+		return errors.New("must set --message")
+	}
+	meta, err := r.getMeta(args)
+	if err != nil {
+		return err
+	}
+	r.printAffectedFiles(meta)
+	fileDescriptorSets, err := r.getFileDescriptorSets(meta, true)
+	if err != nil {
+		return err
+	}
+	packageSet, err := reflect.NewPackageSet(fileDescriptorSets.Unwrap()...)
+	if err != nil {
+		return err
+	}
+	data, err := jsonschema.Generate(packageSet, strings.TrimPrefix(messageName, "."))
+	if err != nil {
+		return err
+	}
+	_, err = r.output.Write(data)
+	return err
+}
+
+func (r *runner) Convert(args []string, typeName string, from string, to string, delimited bool, decodeRaw bool) error {
+	if decodeRaw {
+		if typeName != "" {
+			return errors.New("cannot use --type with --decode-raw")
+		}
+		return convert.DecodeRaw(r.input, r.output, delimited)
+	}
+	if typeName == "" {
+		return errors.New("must set --type")
+	}
+	fromFormat, err := convert.ParseFormat(from)
+	if err != nil {
+		return err
+	}
+	toFormat, err := convert.ParseFormat(to)
+	if err != nil {
+		return err
+	}
+	var converterOptions []convert.ConverterOption
+	if delimited {
+		converterOptions = append(converterOptions, convert.ConverterWithDelimited())
+	}
+	meta, err := r.getMeta(args)
+	if err != nil {
+		return err
+	}
+	r.printAffectedFiles(meta)
+	fileDescriptorSets, err := r.getFileDescriptorSets(meta, false)
+	if err != nil {
+		return err
+	}
+	converter, err := convert.NewConverter(
+		fileDescriptorSets.Unwrap(),
+		strings.TrimPrefix(typeName, "."),
+		fromFormat,
+		toFormat,
+		converterOptions...,
+	)
+	if err != nil {
+		return err
+	}
+	return converter.Convert(r.input, r.output)
+}
+
+func (r *runner) Sample(args []string, typeName string, count int, seed int64, format string, fuzzCorpusDirPath string) error {
+	if typeName == "" {
+		return errors.New("must set --type")
+	}
+	if count < 1 {
+		return errors.New("--count must be positive")
+	}
+	convertFormat, err := convert.ParseFormat(format)
+	if err != nil {
+		return err
+	}
+	meta, err := r.getMeta(args)
+	if err != nil {
+		return err
+	}
+	r.printAffectedFiles(meta)
+	fileDescriptorSets, err := r.getFileDescriptorSets(meta, false)
+	if err != nil {
+		return err
+	}
+	generator, err := sample.NewGenerator(
+		fileDescriptorSets.Unwrap(),
+		strings.TrimPrefix(typeName, "."),
+		sample.GeneratorWithSeed(seed),
+	)
+	if err != nil {
+		return err
+	}
+	if fuzzCorpusDirPath != "" {
+		if err := os.MkdirAll(fuzzCorpusDirPath, 0755); err != nil {
+			return err
+		}
+		for i := 0; i < count; i++ {
+			data, err := convert.Marshal(generator.Generate(), convert.FormatBinary, false)
+			if err != nil {
+				return err
+			}
+			corpusFile := sample.NewCorpusFile(data)
+			if err := ioutil.WriteFile(filepath.Join(fuzzCorpusDirPath, sample.CorpusFileName(corpusFile)), corpusFile, 0644); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for i := 0; i < count; i++ {
+		data, err := convert.Marshal(generator.Generate(), convertFormat, count > 1)
+		if err != nil {
+			return err
+		}
+		if convertFormat == convert.FormatText && i > 0 {
+			data = append([]byte("\n"), data...)
+		}
+		if _, err := r.output.Write(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *runner) Watch(args []string, doGen bool) error {
+	meta, err := r.getMeta(args)
+	if err != nil {
+		return err
+	}
+	compiler, err := r.newCompiler(doGen, false, false, false, false)
+	if err != nil {
+		return err
+	}
+	r.printAffectedFiles(meta)
+	if err := r.doWatchCompile(compiler, doGen, meta); err != nil {
+		return err
+	}
+	return watch.NewPoller(watch.PollerWithLogger(r.logger)).Poll(
+		r.ctx,
+		func() ([]string, error) {
+			return getWatchPaths(meta.ProtoSet), nil
+		},
+		func(changedPaths []string) error {
+			newMeta, err := r.getMeta(args)
+			if err != nil {
+				// the config file may be mid-edit, so keep watching
+				r.logger.Warn("could not read files", zap.Error(err))
+				return nil
+			}
+			targetDirPaths, err := getWatchTargetDirPaths(meta.ProtoSet, newMeta.ProtoSet, changedPaths)
+			if err != nil {
+				return err
+			}
+			meta = newMeta
+			if targetDirPaths != nil && len(targetDirPaths) == 0 {
+				return nil
+			}
+			meta.ProtoSet.TargetDirPaths = targetDirPaths
+			r.printAffectedFiles(meta)
+			return r.doWatchCompile(compiler, doGen, meta)
+		},
+	)
+}
+
+// doWatchCompile compiles and prints any failures, but does not return
+// an error for failures so that watching can continue.
+func (r *runner) doWatchCompile(compiler protoc.Compiler, doGen bool, watchMeta *meta) error {
+	metas := []*meta{watchMeta}
+	compileResult, err := compiler.Compile(r.ctx, watchMeta.ProtoSet)
+	if err != nil {
+		// being stopped while compiling is not an error when watching
+		if r.ctx.Err() != nil {
+			return nil
+		}
+		return err
+	}
+	failures, err := r.applyBaseline(metas, compileResult.Failures)
+	if err != nil {
+		return err
+	}
+	if err := r.printFailures(r.output, "", metas, r.filterFailures(watchMeta, failures)...); err != nil {
+		return err
+	}
+	if text.CountSeverities(failures...)[text.SeverityError] > 0 {
+		return nil
+	}
+	// the compiler does not generate anything if there are errors
+	if compileErrorCount := text.CountSeverities(compileResult.Failures...)[text.SeverityError]; doGen && compileErrorCount > 0 {
+		r.logger.Error("failures with error severity were suppressed by the baseline, but nothing is generated if there are errors", zap.Int("errors", compileErrorCount))
+		return nil
+	}
+	r.logger.Info("compiled successfully")
+	return nil
+}
+
+func (r *runner) LSP() error {
+	compiler, err := r.newCompiler(false, false, true, true, true)
+	if err != nil {
+		return err
+	}
+	return lsp.NewServer(
+		r.workDirPath,
+		r.protoSetProvider,
+		compiler,
+		lsp.ServerWithLogger(r.logger),
+	).Serve(r.ctx, r.input, r.output)
+}
+
+func (r *runner) compile(doGen bool, doFileDescriptorSet bool, dryRun bool, metas ...*meta) (protoc.FileDescriptorSets, error) {
+	// nothing to do, for example if no files were read with filesFrom
+	if len(metas) == 0 {
+		return nil, nil
+	}
+	if dryRun {
+		doFileDescriptorSet = false
+	}
+	compiler, err := r.newCompiler(doGen, doFileDescriptorSet, false, false, false)
+	if err != nil {
+		return nil, err
+	}
+	if dryRun {
+		return nil, r.doProtocCommands(compiler, metas...)
+	}
+	return r.doCompile(compiler, doGen || doFileDescriptorSet, r.output, metas...)
+}
+
+// getFileDescriptorSets compiles the meta and returns the FileDescriptorSets
+// with all imports included.
+//
+// The failures are printed to the error output, as the output of the
+// commands that use the FileDescriptorSets is a payload.
+func (r *runner) getFileDescriptorSets(meta *meta, includeSourceInfo bool) (protoc.FileDescriptorSets, error) {
+	compiler, err := r.newCompiler(false, false, true, true, includeSourceInfo)
+	if err != nil {
+		return nil, err
+	}
+	return r.doCompile(compiler, true, r.errorOutput, meta)
+}
+
+// doCompile compiles the metas and prints the failures and the summary
+// to the given output.
+//
+// If requireOutput is true, the FileDescriptorSets or generated files are
+// needed, so it is an error if there were errors that were suppressed by
+// the baseline, as the compiler does not produce output if there are errors.
+func (r *runner) doCompile(compiler protoc.Compiler, requireOutput bool, output io.Writer, metas ...*meta) (protoc.FileDescriptorSets, error) {
+	start := time.Now()
+	var failures []*text.Failure
+	var fileDescriptorSets protoc.FileDescriptorSets
+	stats := &protoc.CompileStats{}
+	failureToMeta := make(map[*text.Failure]*meta)
+	for _, meta := range metas {
+		compileResult, err := compiler.Compile(r.ctx, meta.ProtoSet)
+		if err != nil {
+			return nil, err
+		}
+		for _, failure := range compileResult.Failures {
+			failureToMeta[failure] = meta
+		}
+		failures = append(failures, compileResult.Failures...)
+		fileDescriptorSets = append(fileDescriptorSets, compileResult.FileDescriptorSets...)
+		mergeCompileStats(stats, compileResult.Stats)
+	}
+	wallTime := time.Since(start)
+	compileErrorCount := text.CountSeverities(failures...)[text.SeverityError]
+	failures, err := r.applyBaseline(metas, failures)
+	if err != nil {
+		return nil, err
+	}
+	// only the failures for the files of each meta are printed, however
+	// all failures are considered for the exit code
+	printFailures := make([]*text.Failure, 0, len(failures))
+	var otherErrorFailures []*text.Failure
+	for _, failure := range failures {
+		// failures for stale baseline entries are not from any meta
+		if meta, ok := failureToMeta[failure]; ok && !r.isMetaFailure(meta, failure) {
+			if failure.Severity == text.SeverityError {
+				otherErrorFailures = append(otherErrorFailures, failure)
+			}
+			continue
+		}
+		printFailures = append(printFailures, failure)
+	}
+	if err := r.printFailures(output, "", metas, printFailures...); err != nil {
+		return nil, err
+	}
+	if len(otherErrorFailures) > 0 {
+		// otherwise the command fails without saying why
+		r.logger.Error(
+			"errors in other files of the compiled directories",
+			zap.Int("errors", len(otherErrorFailures)),
+			zap.Strings("files", getFailureFilenames(otherErrorFailures)),
+		)
+	}
+	if r.summary {
+		if err := r.printSummary(output, r.newSummary(stats, wallTime)); err != nil {
+			return nil, err
 		}
 	}
-	sort.Strings(allFiles)
-	for _, file := range allFiles {
-		if err := r.println(file); err != nil {
-			return err
-		}
+	if err := r.getFailuresExitError(failures); err != nil {
+		return nil, err
 	}
-	return nil
-}
-
-func (r *runner) Compile(args []string, dryRun bool) error {
-	meta, err := r.getMeta(args)
-	if err != nil {
-		return err
+	if requireOutput && compileErrorCount > 0 {
+		return nil, newExitErrorf(
+			255,
+			"%d failures with error severity were suppressed by the baseline, but there is no output if there are errors, fix them first",
+			compileErrorCount,
+		)
 	}
-	r.printAffectedFiles(meta)
-	_, err = r.compile(false, false, dryRun, meta)
-	return err
+	return fileDescriptorSets, nil
 }
 
-func (r *runner) Gen(args []string, dryRun bool) error {
-	meta, err := r.getMeta(args)
-	if err != nil {
-		return err
+// mergeCompileStats adds the statistics of other to stats.
+func mergeCompileStats(stats *protoc.CompileStats, other *protoc.CompileStats) {
+	if other == nil {
+		return
 	}
-	r.printAffectedFiles(meta)
-	_, err = r.compile(true, false, dryRun, meta)
-	return err
+	stats.Invocations = append(stats.Invocations, other.Invocations...)
+	stats.CacheHits += other.CacheHits
+	stats.CacheMisses += other.CacheMisses
+	sort.SliceStable(stats.Invocations, func(i int, j int) bool {
+		if stats.Invocations[i].DirPath != stats.Invocations[j].DirPath {
+			return stats.Invocations[i].DirPath < stats.Invocations[j].DirPath
+		}
+		return stats.Invocations[i].PluginName < stats.Invocations[j].PluginName
+	})
 }
 
-func (r *runner) compile(doGen bool, doFileDescriptorSet bool, dryRun bool, meta *meta) (protoc.FileDescriptorSets, error) {
-	if dryRun {
-		doFileDescriptorSet = false
+// applyBaseline records all Failures to the baseline file and returns no
+// Failures if writeBaselinePath is set, or returns the Failures that are not
+// in the baseline file plus Failures for stale baseline entries if
+// baselinePath is set.
+func (r *runner) applyBaseline(metas []*meta, failures []*text.Failure) ([]*text.Failure, error) {
+	if r.writeBaselinePath != "" {
+		absWriteBaselinePath, err := file.AbsClean(r.writeBaselinePath)
+		if err != nil {
+			return nil, err
+		}
+		newBaseline, err := baseline.New(filepath.Dir(absWriteBaselinePath), r.workDirPath, failures)
+		if err != nil {
+			return nil, err
+		}
+		if err := baseline.Write(absWriteBaselinePath, newBaseline); err != nil {
+			return nil, err
+		}
+		r.logger.Info("wrote baseline", zap.String("path", r.writeBaselinePath), zap.Int("failures", len(failures)))
+		return nil, nil
 	}
-	compiler, err := r.newCompiler(doGen, doFileDescriptorSet, false, false, false)
+	if r.baselinePath == "" {
+		return failures, nil
+	}
+	absBaselinePath, err := file.AbsClean(r.baselinePath)
 	if err != nil {
 		return nil, err
 	}
-	if dryRun {
-		return nil, r.doProtocCommands(compiler, meta)
-	}
-	return r.doCompile(compiler, meta)
-}
-
-func (r *runner) doCompile(compiler protoc.Compiler, meta *meta) (protoc.FileDescriptorSets, error) {
-	compileResult, err := compiler.Compile(meta.ProtoSet)
+	baselineDirPath := filepath.Dir(absBaselinePath)
+	existingBaseline, err := baseline.Read(absBaselinePath)
 	if err != nil {
 		return nil, err
 	}
-	if err := r.printFailures("", meta, compileResult.Failures...); err != nil {
+	newFailures, staleEntries, err := existingBaseline.Filter(baselineDirPath, r.workDirPath, failures)
+	if err != nil {
 		return nil, err
 	}
-	if len(compileResult.Failures) > 0 {
-		return nil, newExitErrorf(255, "")
+	if len(staleEntries) == 0 {
+		return newFailures, nil
+	}
+	// the meta of each checked file, for the severities of its config
+	checkedFilePathToMeta := make(map[string]*meta)
+	// entries for files that were not checked, or that are not associated
+	// with a file, can only be known to be stale if everything was checked
+	checkedAll := true
+	for _, meta := range metas {
+		checkedProtoFiles, err := getCheckedProtoFiles(meta)
+		if err != nil {
+			return nil, err
+		}
+		for _, protoFile := range checkedProtoFiles {
+			checkedFilePathToMeta[protoFile.Path] = meta
+		}
+		if meta.FilePaths != nil || meta.ProtoSet.TargetDirPaths != nil {
+			checkedAll = false
+		}
+	}
+	for _, entry := range staleEntries {
+		filename := ""
+		config := metas[0].ProtoSet.Config
+		if entry.Filename != "" {
+			filePath := filepath.Join(baselineDirPath, filepath.FromSlash(entry.Filename))
+			if meta, ok := checkedFilePathToMeta[filePath]; ok {
+				config = meta.ProtoSet.Config
+			} else if _, err := os.Stat(filePath); !checkedAll || !os.IsNotExist(err) {
+				// the entries of deleted files are stale if everything was checked
+				continue
+			}
+			filename = r.getDisplayFilePath(filePath)
+		} else if !checkedAll {
+			continue
+		}
+		message := fmt.Sprintf("Baselined %s failure no longer occurs, remove it from the baseline: %s", entry.ID, entry.Message)
+		if entry.Count > 1 {
+			message = fmt.Sprintf("%d baselined %s failures no longer occur, remove them from the baseline: %s", entry.Count, entry.ID, entry.Message)
+		}
+		staleFailure := &text.Failure{
+			Filename: filename,
+			LintID:   baseline.StaleFailureID,
+			Message:  message,
+			Severity: text.SeverityInfo,
+		}
+		text.SetSeverities(config.IDToSeverity, staleFailure)
+		newFailures = append(newFailures, staleFailure)
 	}
-	return compileResult.FileDescriptorSets, nil
+	return newFailures, nil
 }
 
-func (r *runner) doProtocCommands(compiler protoc.Compiler, meta *meta) error {
-	commands, err := compiler.ProtocCommands(meta.ProtoSet)
-	if err != nil {
-		return err
+// getFailuresExitError returns an ExitError if there are any Failures with
+// text.SeverityError, or more Failures with text.SeverityWarning than allowed.
+func (r *runner) getFailuresExitError(failures []*text.Failure) error {
+	severityToCount := text.CountSeverities(failures...)
+	if severityToCount[text.SeverityError] > 0 {
+		return newExitErrorf(255, "")
+	}
+	if r.maxWarnings >= 0 && severityToCount[text.SeverityWarning] > r.maxWarnings {
+		r.logger.Error(
+			"too many warnings",
+			zap.Int("warnings", severityToCount[text.SeverityWarning]),
+			zap.Int("max_warnings", r.maxWarnings),
+		)
+		return newExitErrorf(255, "")
 	}
-	for _, command := range commands {
-		if err := r.println(command); err != nil {
+	return nil
+}
+
+func (r *runner) doProtocCommands(compiler protoc.Compiler, metas ...*meta) error {
+	for _, meta := range metas {
+		commands, err := compiler.ProtocCommands(meta.ProtoSet)
+		if err != nil {
 			return err
 		}
+		for _, command := range commands {
+			if err := r.println(command); err != nil {
+				return err
+			}
+		}
 	}
 	return nil
 }
@@ -330,6 +1096,7 @@ func (r *runner) newCompiler(
 	}
 	compilerOptions := []protoc.CompilerOption{
 		protoc.CompilerWithLogger(r.logger),
+		protoc.CompilerWithEmitter(r.emitter),
 	}
 	if r.cachePath != "" {
 		compilerOptions = append(
@@ -355,6 +1122,30 @@ func (r *runner) newCompiler(
 			protoc.CompilerWithProtocURL(r.protocURL),
 		)
 	}
+	if r.jobs > 0 {
+		compilerOptions = append(
+			compilerOptions,
+			protoc.CompilerWithJobs(r.jobs),
+		)
+	}
+	if r.protocTimeout > 0 {
+		compilerOptions = append(
+			compilerOptions,
+			protoc.CompilerWithTimeout(r.protocTimeout),
+		)
+	}
+	if len(r.overlay) > 0 {
+		compilerOptions = append(
+			compilerOptions,
+			protoc.CompilerWithOverlay(r.overlay),
+		)
+	}
+	if len(r.hiddenFilePaths) > 0 {
+		compilerOptions = append(
+			compilerOptions,
+			protoc.CompilerWithHiddenFilePaths(r.hiddenFilePaths),
+		)
+	}
 	if doGen {
 		compilerOptions = append(
 			compilerOptions,
@@ -395,42 +1186,328 @@ func (r *runner) newCreateHandler(pkg string) create.Handler {
 
 type meta struct {
 	ProtoSet *file.ProtoSet
-	// this will be empty if not in dir mode
-	// if in dir mode, this will be the single filename that we want to return errors for
-	SingleFilename string
+	// the absolute paths of the files to return failures for
+	// nil if operating on all files in the target directories of the ProtoSet
+	FilePaths map[string]struct{}
 }
 
+// getMeta returns the meta for the given args, which must resolve to
+// exactly one meta.
 func (r *runner) getMeta(args []string) (*meta, error) {
-	// TODO: does not fit in with workDirPath paradigm
-	fileOrDir := "."
-	if len(args) == 1 {
-		fileOrDir = args[0]
+	metas, err := r.resolveMetas(args)
+	if err != nil {
+		return nil, err
 	}
-	fileInfo, err := os.Stat(fileOrDir)
+	if len(metas) != 1 {
+		return nil, fmt.Errorf("expected exactly one directory or configuration for %v but got %d", args, len(metas))
+	}
+	if err := r.emitMetaEvents(metas[0]); err != nil {
+		return nil, err
+	}
+	return metas[0], nil
+}
+
+// getMetas returns the metas for the given args and the files read from
+// filesFrom if set.
+//
+// If filesFrom is set and there are no files, no metas are returned.
+func (r *runner) getMetas(args []string) ([]*meta, error) {
+	if r.filesFrom != "" {
+		filesFromArgs, err := r.readFilesFrom()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, filesFromArgs...)
+		if len(args) == 0 {
+			return nil, nil
+		}
+	}
+	metas, err := r.resolveMetas(args)
 	if err != nil {
 		return nil, err
 	}
-	if fileInfo.Mode().IsDir() {
-		protoSet, err := r.protoSetProvider.GetForDir(r.workDirPath, fileOrDir)
+	for _, meta := range metas {
+		if err := r.emitMetaEvents(meta); err != nil {
+			return nil, err
+		}
+	}
+	return metas, nil
+}
+
+// resolveMetas returns one meta for every directory in args, and one meta
+// for the files in args per configuration. The metas for files only
+// operate on the directories of the files, and only return failures
+// for the files.
+func (r *runner) resolveMetas(args []string) ([]*meta, error) {
+	// TODO: does not fit in with workDirPath paradigm
+	if len(args) == 0 {
+		args = []string{"."}
+	}
+	var metas []*meta
+	configDirPathToFileMeta := make(map[string]*meta)
+	// files are commonly in the same directories
+	dirPathToProtoSet := make(map[string]*file.ProtoSet)
+	for _, fileOrDir := range args {
+		isDir, err := r.isDir(fileOrDir)
+		if err != nil {
+			return nil, err
+		}
+		if isDir {
+			protoSet, err := r.getProtoSet(fileOrDir)
+			if err != nil {
+				return nil, err
+			}
+			metas = append(metas, &meta{
+				ProtoSet: protoSet,
+			})
+			continue
+		}
+		filePath, err := file.AbsClean(fileOrDir)
 		if err != nil {
 			return nil, err
 		}
-		return &meta{
-			ProtoSet: protoSet,
-		}, nil
+		dirPath := filepath.Dir(filePath)
+		protoSet, ok := dirPathToProtoSet[dirPath]
+		if !ok {
+			protoSet, err = r.getProtoSet(dirPath)
+			if err != nil {
+				return nil, err
+			}
+			dirPathToProtoSet[dirPath] = protoSet
+		}
+		fileMeta, ok := configDirPathToFileMeta[protoSet.Config.DirPath]
+		if ok {
+			mergeProtoSet(fileMeta.ProtoSet, protoSet)
+		} else {
+			fileMeta = &meta{
+				ProtoSet:  newFileProtoSet(protoSet),
+				FilePaths: make(map[string]struct{}),
+			}
+			configDirPathToFileMeta[protoSet.Config.DirPath] = fileMeta
+			metas = append(metas, fileMeta)
+		}
+		fileMeta.FilePaths[filePath] = struct{}{}
+		// files that are not discovered, for example if they are excluded,
+		// or are not affected by changes if since is set, are not operated on
+		if protoSet.IsTargetDirPath(dirPath) && containsProtoFile(protoSet, filePath) {
+			fileMeta.ProtoSet.TargetDirPaths[dirPath] = struct{}{}
+		}
+	}
+	return metas, nil
+}
+
+// isDir returns true if the path is a directory, and false if the path is
+// a regular file or a file that only exists in the overlay.
+func (r *runner) isDir(fileOrDir string) (bool, error) {
+	fileInfo, err := os.Stat(fileOrDir)
+	if err != nil {
+		// files that only exist in the overlay can still be operated on
+		if os.IsNotExist(err) && r.isOverlayFile(fileOrDir) {
+			return false, nil
+		}
+		return false, err
+	}
+	if fileInfo.Mode().IsDir() {
+		return true, nil
 	}
 	// TODO: allow symlinks?
 	if fileInfo.Mode().IsRegular() {
-		protoSet, err := r.protoSetProvider.GetForDir(r.workDirPath, filepath.Dir(fileOrDir))
-		if err != nil {
-			return nil, err
+		return false, nil
+	}
+	return false, fmt.Errorf("%s is not a directory or a regular file", fileOrDir)
+}
+
+// readFilesFrom reads the files in filesFrom, one per line, or from the
+// input if filesFrom is "-". Empty lines are ignored.
+func (r *runner) readFilesFrom() ([]string, error) {
+	var data []byte
+	var err error
+	if r.filesFrom == "-" {
+		data, err = ioutil.ReadAll(r.input)
+	} else {
+		data, err = ioutil.ReadFile(r.filesFrom)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			files = append(files, line)
+		}
+	}
+	return files, nil
+}
+
+// newFileProtoSet returns a copy of the ProtoSet that operates on no
+// directories, to which the directories of files can be added.
+func newFileProtoSet(protoSet *file.ProtoSet) *file.ProtoSet {
+	fileProtoSet := *protoSet
+	fileProtoSet.DirPathToFiles = make(map[string][]*file.ProtoFile, len(protoSet.DirPathToFiles))
+	for dirPath, protoFiles := range protoSet.DirPathToFiles {
+		fileProtoSet.DirPathToFiles[dirPath] = protoFiles
+	}
+	fileProtoSet.TargetDirPaths = make(map[string]struct{})
+	return &fileProtoSet
+}
+
+// mergeProtoSet adds the files of the other ProtoSet with the same config
+// to the ProtoSet returned by newFileProtoSet.
+//
+// The files only differ if there is no config file, as the files are then
+// discovered from the given directory instead of the config directory.
+func mergeProtoSet(protoSet *file.ProtoSet, other *file.ProtoSet) {
+	for dirPath, protoFiles := range other.DirPathToFiles {
+		if _, ok := protoSet.DirPathToFiles[dirPath]; !ok {
+			protoSet.DirPathToFiles[dirPath] = protoFiles
+		}
+	}
+	// the directory must contain all target directories
+	for !isWithinDirPath(protoSet.DirPath, other.DirPath) {
+		protoSet.DirPath = filepath.Dir(protoSet.DirPath)
+	}
+}
+
+func containsProtoFile(protoSet *file.ProtoSet, filePath string) bool {
+	for _, protoFile := range protoSet.DirPathToFiles[filepath.Dir(filePath)] {
+		if protoFile.Path == filePath {
+			return true
+		}
+	}
+	return false
+}
+
+// isWithinDirPath returns true if the path is the directory path,
+// or resides within it. Both paths must be absolute and cleaned.
+func isWithinDirPath(dirPath string, path string) bool {
+	relPath, err := filepath.Rel(dirPath, path)
+	return err == nil && relPath != ".." && !strings.HasPrefix(relPath, ".."+string(os.PathSeparator))
+}
+
+func (r *runner) isOverlayFile(path string) bool {
+	absPath, err := file.AbsClean(path)
+	if err != nil {
+		return false
+	}
+	_, ok := r.overlay[absPath]
+	return ok
+}
+
+func (r *runner) emitMetaEvents(meta *meta) error {
+	r.emitter.Emit(&event.Event{
+		Type:          event.TypeConfigResolved,
+		ConfigDirPath: r.getDisplayFilePath(meta.ProtoSet.Config.DirPath),
+		ProtocVersion: meta.ProtoSet.Config.Compile.ProtobufVersion,
+	})
+	filenames, err := getCheckedFilenames(meta)
+	if err != nil {
+		return err
+	}
+	r.emitter.Emit(&event.Event{
+		Type:  event.TypeFilesDiscovered,
+		Files: filenames,
+	})
+	return nil
+}
+
+// getProtoSet gets the ProtoSet for the given directory, limiting the target
+// directories to those with files affected by changes if since is set.
+func (r *runner) getProtoSet(dirPath string) (*file.ProtoSet, error) {
+	protoSet, err := r.protoSetProvider.GetForDir(r.workDirPath, dirPath)
+	if err != nil {
+		return nil, err
+	}
+	if r.since == "" {
+		return protoSet, nil
+	}
+	changedFilePaths, err := git.ChangedFiles(protoSet.DirPath, r.since)
+	if err != nil {
+		return nil, err
+	}
+	affectedFilePaths, err := file.GetAffectedFilePaths(protoSet, changedFilePaths)
+	if err != nil {
+		return nil, err
+	}
+	protoSet.TargetDirPaths = make(map[string]struct{})
+	for _, affectedFilePath := range affectedFilePaths {
+		protoSet.TargetDirPaths[filepath.Dir(affectedFilePath)] = struct{}{}
+	}
+	r.logger.Debug("files affected by changes", zap.String("since", r.since), zap.Int("count", len(affectedFilePaths)))
+	return protoSet, nil
+}
+
+// getWatchPaths returns the paths to watch for the ProtoSet, which are all
+// .proto files, their directories and parent directories up to the config
+// directory, and the possible config files in the config directory.
+func getWatchPaths(protoSet *file.ProtoSet) []string {
+	seen := make(map[string]struct{})
+	var paths []string
+	addPath := func(path string) {
+		if _, ok := seen[path]; !ok {
+			seen[path] = struct{}{}
+			paths = append(paths, path)
+		}
+	}
+	for _, configFilename := range settings.ConfigFilenames {
+		addPath(filepath.Join(protoSet.Config.DirPath, configFilename))
+	}
+	addPath(protoSet.Config.DirPath)
+	for dirPath, protoFiles := range protoSet.DirPathToFiles {
+		for curDirPath := dirPath; strings.HasPrefix(curDirPath, protoSet.Config.DirPath) && curDirPath != protoSet.Config.DirPath; curDirPath = filepath.Dir(curDirPath) {
+			addPath(curDirPath)
+		}
+		for _, protoFile := range protoFiles {
+			addPath(protoFile.Path)
+		}
+	}
+	return paths
+}
+
+// getWatchTargetDirPaths returns the directory paths to compile after the
+// given paths changed, given the ProtoSets from before and after the change.
+//
+// Returns nil if everything should be compiled, which is the case if a
+// config file changed.
+func getWatchTargetDirPaths(oldProtoSet *file.ProtoSet, newProtoSet *file.ProtoSet, changedPaths []string) (map[string]struct{}, error) {
+	if oldProtoSet.Config.DirPath != newProtoSet.Config.DirPath {
+		return nil, nil
+	}
+	var changedFilePaths []string
+	for _, changedPath := range changedPaths {
+		if filepath.Dir(changedPath) == oldProtoSet.Config.DirPath {
+			for _, configFilename := range settings.ConfigFilenames {
+				if filepath.Base(changedPath) == configFilename {
+					return nil, nil
+				}
+			}
+		}
+		if filepath.Ext(changedPath) == ".proto" {
+			changedFilePaths = append(changedFilePaths, changedPath)
+		}
+	}
+	// directories changed if files were added, so find the new files
+	oldFilePaths := make(map[string]struct{})
+	for _, protoFiles := range oldProtoSet.DirPathToFiles {
+		for _, protoFile := range protoFiles {
+			oldFilePaths[protoFile.Path] = struct{}{}
 		}
-		return &meta{
-			ProtoSet:       protoSet,
-			SingleFilename: fileOrDir,
-		}, nil
 	}
-	return nil, fmt.Errorf("%s is not a directory or a regular file", fileOrDir)
+	for _, protoFiles := range newProtoSet.DirPathToFiles {
+		for _, protoFile := range protoFiles {
+			if _, ok := oldFilePaths[protoFile.Path]; !ok {
+				changedFilePaths = append(changedFilePaths, protoFile.Path)
+			}
+		}
+	}
+	affectedFilePaths, err := file.GetAffectedFilePaths(newProtoSet, changedFilePaths)
+	if err != nil {
+		return nil, err
+	}
+	targetDirPaths := make(map[string]struct{})
+	for _, affectedFilePath := range affectedFilePaths {
+		targetDirPaths[filepath.Dir(affectedFilePath)] = struct{}{}
+	}
+	return targetDirPaths, nil
 }
 
 // TODO: we filter failures in dir mode in printFailures but above we count any failure
@@ -440,71 +1517,191 @@ func (r *runner) getMeta(args []string) (*meta, error) {
 // meta is optional
 // if set, it will update the Failures to have this filename
 // will be sorted
-func (r *runner) printFailures(filename string, meta *meta, failures ...*text.Failure) error {
-	return r.printFailuresForErrorFormat(r.errorFormat, filename, meta, failures...)
+func (r *runner) printFailures(output io.Writer, filename string, metas []*meta, failures ...*text.Failure) error {
+	return r.printFailuresForErrorFormat(output, r.errorFormat, filename, metas, failures...)
 }
 
-func (r *runner) printFailuresForErrorFormat(errorFormat string, filename string, meta *meta, failures ...*text.Failure) error {
+func (r *runner) printFailuresForErrorFormat(output io.Writer, errorFormat string, filename string, metas []*meta, failures ...*text.Failure) error {
 	for _, failure := range failures {
 		if filename != "" {
 			failure.Filename = filename
 		}
 	}
-	failureFields, err := text.ParseColonSeparatedFailureFields(errorFormat)
+	outputFormat := r.outputFormat
+	if r.json {
+		outputFormat = text.OutputFormatJSON
+	}
+	var failurePrinterOptions []text.FailurePrinterOption
+	if text.IsTemplate(errorFormat) {
+		tmpl, err := text.ParseTemplate(errorFormat)
+		if err != nil {
+			return err
+		}
+		failurePrinterOptions = append(failurePrinterOptions, text.FailurePrinterWithTemplate(tmpl))
+	} else {
+		failureFields, err := text.ParseColonSeparatedFailureFields(errorFormat)
+		if err != nil {
+			return err
+		}
+		failurePrinterOptions = append(failurePrinterOptions, text.FailurePrinterWithFailureFields(failureFields...))
+	}
+	if len(metas) > 0 {
+		checkedFilenames, err := getCheckedFilenames(metas...)
+		if err != nil {
+			return err
+		}
+		failurePrinterOptions = append(
+			failurePrinterOptions,
+			text.FailurePrinterWithFilenames(checkedFilenames...),
+		)
+	}
+	failurePrinter, err := text.NewFailurePrinter(outputFormat, failurePrinterOptions...)
 	if err != nil {
 		return err
 	}
 	text.SortFailures(failures)
-	bufWriter := bufio.NewWriter(r.output)
 	for _, failure := range failures {
-		shouldPrint := false
-		if meta != nil {
-			if meta.SingleFilename == "" || meta.SingleFilename == failure.Filename {
-				shouldPrint = true
-			} else if meta.SingleFilename != "" {
-				// TODO: the compiler may not return the rel path due to logic in bestFilePath
-				absSingleFilename, err := file.AbsClean(meta.SingleFilename)
-				if err != nil {
-					return err
-				}
-				absFailureFilename, err := file.AbsClean(failure.Filename)
-				if err != nil {
-					return err
-				}
-				if absSingleFilename == absFailureFilename {
-					shouldPrint = true
-				}
+		r.emitter.Emit(&event.Event{
+			Type:    event.TypeFailureFound,
+			Failure: failure,
+		})
+	}
+	bufWriter := bufio.NewWriter(output)
+	if err := failurePrinter.PrintFailures(bufWriter, failures...); err != nil {
+		return err
+	}
+	return bufWriter.Flush()
+}
+
+func (r *runner) printAffectedFiles(metas ...*meta) {
+	for _, meta := range metas {
+		for dirPath, files := range meta.ProtoSet.DirPathToFiles {
+			// skip those files not under the directory or not targeted
+			if !meta.ProtoSet.IsTargetDirPath(dirPath) {
+				continue
+			}
+			for _, file := range files {
+				r.logger.Debug("using file", zap.String("file", file.DisplayPath))
 			}
-		} else {
-			shouldPrint = true
 		}
-		if shouldPrint {
-			if r.json {
-				data, err := json.Marshal(failure)
-				if err != nil {
-					return err
+	}
+}
+
+// getTargetPackageNames returns the sorted packages of the files that were
+// compiled for the meta, excluding the files that were only imported.
+func getTargetPackageNames(meta *meta, fileDescriptorSets protoc.FileDescriptorSets) []string {
+	var packageNames []string
+	for _, fileDescriptorSet := range fileDescriptorSets {
+		for _, fileDescriptorProto := range fileDescriptorSet.GetFile() {
+			for _, protoFile := range fileDescriptorSet.ProtoFiles {
+				if meta.FilePaths != nil {
+					if _, ok := meta.FilePaths[protoFile.Path]; !ok {
+						continue
+					}
 				}
-				if _, err := fmt.Fprintln(bufWriter, string(data)); err != nil {
-					return err
+				// names are relative to the include path the file was found in
+				path := filepath.ToSlash(protoFile.Path)
+				if name := fileDescriptorProto.GetName(); path == name || strings.HasSuffix(path, "/"+name) {
+					packageNames = append(packageNames, fileDescriptorProto.GetPackage())
+					break
 				}
-			} else if err := failure.Fprintln(bufWriter, failureFields...); err != nil {
-				return err
 			}
 		}
 	}
-	return bufWriter.Flush()
+	return strs.SortUniq(packageNames)
+}
+
+// getCheckedFilenames returns the display paths of the files that were
+// operated on for the metas, for output formats that report every file.
+func getCheckedFilenames(metas ...*meta) ([]string, error) {
+	seen := make(map[string]struct{})
+	var filenames []string
+	for _, meta := range metas {
+		protoFiles, err := getCheckedProtoFiles(meta)
+		if err != nil {
+			return nil, err
+		}
+		for _, protoFile := range protoFiles {
+			if _, ok := seen[protoFile.DisplayPath]; !ok {
+				seen[protoFile.DisplayPath] = struct{}{}
+				filenames = append(filenames, protoFile.DisplayPath)
+			}
+		}
+	}
+	sort.Strings(filenames)
+	return filenames, nil
 }
 
-func (r *runner) printAffectedFiles(meta *meta) {
-	for dirPath, files := range meta.ProtoSet.DirPathToFiles {
-		// skip those files not under the directory
-		if !strings.HasPrefix(dirPath, meta.ProtoSet.DirPath) {
+// getCheckedProtoFiles returns the files in the target directories, or only
+// the files in FilePaths if set.
+func getCheckedProtoFiles(meta *meta) ([]*file.ProtoFile, error) {
+	var protoFiles []*file.ProtoFile
+	for dirPath, dirProtoFiles := range meta.ProtoSet.DirPathToFiles {
+		if !meta.ProtoSet.IsTargetDirPath(dirPath) {
 			continue
 		}
-		for _, file := range files {
-			r.logger.Debug("using file", zap.String("file", file.DisplayPath))
+		for _, protoFile := range dirProtoFiles {
+			if meta.FilePaths == nil {
+				protoFiles = append(protoFiles, protoFile)
+			} else if _, ok := meta.FilePaths[protoFile.Path]; ok {
+				protoFiles = append(protoFiles, protoFile)
+			}
+		}
+	}
+	return protoFiles, nil
+}
+
+// filterFailures returns the failures for the files of the meta.
+func (r *runner) filterFailures(meta *meta, failures []*text.Failure) []*text.Failure {
+	if meta.FilePaths == nil {
+		return failures
+	}
+	filteredFailures := make([]*text.Failure, 0, len(failures))
+	for _, failure := range failures {
+		if r.isMetaFailure(meta, failure) {
+			filteredFailures = append(filteredFailures, failure)
 		}
 	}
+	return filteredFailures
+}
+
+// isMetaFailure returns true if the failure is for a file of the meta, or
+// is not for any file, such as a plugin failure.
+func (r *runner) isMetaFailure(meta *meta, failure *text.Failure) bool {
+	if meta.FilePaths == nil || failure.Filename == "" {
+		return true
+	}
+	// the compiler may not return the relative path due to logic in bestFilePath
+	filePath := failure.Filename
+	if !filepath.IsAbs(filePath) {
+		filePath = filepath.Join(r.workDirPath, filePath)
+	}
+	_, ok := meta.FilePaths[filepath.Clean(filePath)]
+	return ok
+}
+
+// getFailureFilenames returns the sorted unique filenames of the failures.
+func getFailureFilenames(failures []*text.Failure) []string {
+	filenameMap := make(map[string]struct{})
+	for _, failure := range failures {
+		filenameMap[failure.Filename] = struct{}{}
+	}
+	filenames := make([]string, 0, len(filenameMap))
+	for filename := range filenameMap {
+		filenames = append(filenames, filename)
+	}
+	sort.Strings(filenames)
+	return filenames
+}
+
+// getDisplayFilePath returns the file path relative to the working directory
+// if the file is within the working directory.
+func (r *runner) getDisplayFilePath(filePath string) string {
+	displayPath, err := filepath.Rel(r.workDirPath, filePath)
+	if err != nil || strings.HasPrefix(displayPath, "..") {
+		return filePath
+	}
+	return filepath.Clean(displayPath)
 }
 
 func (r *runner) println(s string) error {
@@ -0,0 +1,203 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package exec
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/uber/prototool/internal/protoc"
+	"github.com/uber/prototool/internal/text"
+)
+
+// the number of directories to include in summary.SlowestDirectories
+const numSlowestDirectories = 5
+
+// summary is printed at the end of a compile with --summary.
+//
+// Times are in seconds. An empty plugin means that no plugin was used.
+type summary struct {
+	Files              int                  `json:"files"`
+	Directories        int                  `json:"directories"`
+	WallTime           float64              `json:"wall_time"`
+	CPUTime            float64              `json:"cpu_time"`
+	Plugins            []*summaryPlugin     `json:"plugins"`
+	Invocations        []*summaryInvocation `json:"invocations"`
+	SlowestDirectories []*summaryDirectory  `json:"slowest_directories"`
+	CacheHits          int                  `json:"cache_hits"`
+	CacheMisses        int                  `json:"cache_misses"`
+	// not set if there were no cache lookups
+	CacheHitRate *float64 `json:"cache_hit_rate,omitempty"`
+}
+
+type summaryPlugin struct {
+	Plugin      string  `json:"plugin,omitempty"`
+	Invocations int     `json:"invocations"`
+	WallTime    float64 `json:"wall_time"`
+	CPUTime     float64 `json:"cpu_time"`
+}
+
+type summaryInvocation struct {
+	Directory string  `json:"directory"`
+	Plugin    string  `json:"plugin,omitempty"`
+	Files     int     `json:"files"`
+	WallTime  float64 `json:"wall_time"`
+	CPUTime   float64 `json:"cpu_time"`
+}
+
+type summaryDirectory struct {
+	Directory string  `json:"directory"`
+	WallTime  float64 `json:"wall_time"`
+	CPUTime   float64 `json:"cpu_time"`
+}
+
+// newSummary returns a new summary for the CompileStats, where wallTime is
+// the elapsed time of the entire compile.
+func (r *runner) newSummary(stats *protoc.CompileStats, wallTime time.Duration) *summary {
+	s := &summary{
+		WallTime:           wallTime.Seconds(),
+		Plugins:            []*summaryPlugin{},
+		Invocations:        []*summaryInvocation{},
+		SlowestDirectories: []*summaryDirectory{},
+		CacheHits:          stats.CacheHits,
+		CacheMisses:        stats.CacheMisses,
+	}
+	if cacheLookups := stats.CacheHits + stats.CacheMisses; cacheLookups > 0 {
+		cacheHitRate := float64(stats.CacheHits) / float64(cacheLookups)
+		s.CacheHitRate = &cacheHitRate
+	}
+	pluginToSummaryPlugin := make(map[string]*summaryPlugin)
+	directoryToSummaryDirectory := make(map[string]*summaryDirectory)
+	for _, invocationStats := range stats.Invocations {
+		directory := r.getDisplayFilePath(invocationStats.DirPath)
+		s.Invocations = append(s.Invocations, &summaryInvocation{
+			Directory: directory,
+			Plugin:    invocationStats.PluginName,
+			Files:     invocationStats.NumFiles,
+			WallTime:  invocationStats.WallTime.Seconds(),
+			CPUTime:   invocationStats.CPUTime.Seconds(),
+		})
+		s.CPUTime += invocationStats.CPUTime.Seconds()
+		plugin, ok := pluginToSummaryPlugin[invocationStats.PluginName]
+		if !ok {
+			plugin = &summaryPlugin{Plugin: invocationStats.PluginName}
+			pluginToSummaryPlugin[invocationStats.PluginName] = plugin
+			s.Plugins = append(s.Plugins, plugin)
+		}
+		plugin.Invocations++
+		plugin.WallTime += invocationStats.WallTime.Seconds()
+		plugin.CPUTime += invocationStats.CPUTime.Seconds()
+		directorySummary, ok := directoryToSummaryDirectory[directory]
+		if !ok {
+			// every invocation for a directory compiles the same files
+			s.Files += invocationStats.NumFiles
+			directorySummary = &summaryDirectory{Directory: directory}
+			directoryToSummaryDirectory[directory] = directorySummary
+			s.SlowestDirectories = append(s.SlowestDirectories, directorySummary)
+		}
+		directorySummary.WallTime += invocationStats.WallTime.Seconds()
+		directorySummary.CPUTime += invocationStats.CPUTime.Seconds()
+	}
+	s.Directories = len(directoryToSummaryDirectory)
+	sort.Slice(s.Plugins, func(i int, j int) bool {
+		return s.Plugins[i].Plugin < s.Plugins[j].Plugin
+	})
+	sort.SliceStable(s.SlowestDirectories, func(i int, j int) bool {
+		return s.SlowestDirectories[i].WallTime > s.SlowestDirectories[j].WallTime
+	})
+	if len(s.SlowestDirectories) > numSlowestDirectories {
+		s.SlowestDirectories = s.SlowestDirectories[:numSlowestDirectories]
+	}
+	return s
+}
+
+// printSummary prints the summary to the given output as a single JSON
+// object if the output format is JSON, or as tables otherwise.
+func (r *runner) printSummary(output io.Writer, s *summary) error {
+	if r.json || r.outputFormat == text.OutputFormatJSON {
+		data, err := json.Marshal(struct {
+			Summary *summary `json:"summary"`
+		}{
+			Summary: s,
+		})
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(output, "%s\n", data)
+		return err
+	}
+	tabWriter := newTabWriter(output)
+	cacheHitRate := "n/a"
+	if s.CacheHitRate != nil {
+		cacheHitRate = fmt.Sprintf("%.0f%%", *s.CacheHitRate*100)
+	}
+	for _, line := range [][2]string{
+		{"Files", fmt.Sprint(s.Files)},
+		{"Directories", fmt.Sprint(s.Directories)},
+		{"Protoc invocations", fmt.Sprint(len(s.Invocations))},
+		{"Wall time", formatSeconds(s.WallTime)},
+		{"CPU time", formatSeconds(s.CPUTime)},
+		{"Cache hit rate", fmt.Sprintf("%s (%d hits, %d misses)", cacheHitRate, s.CacheHits, s.CacheMisses)},
+	} {
+		if _, err := fmt.Fprintf(tabWriter, "%s:\t%s\n", line[0], line[1]); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprint(tabWriter, "\nPLUGIN\tINVOCATIONS\tWALL\tCPU\n"); err != nil {
+		return err
+	}
+	for _, summaryPlugin := range s.Plugins {
+		if _, err := fmt.Fprintf(tabWriter, "%s\t%d\t%s\t%s\n", getSummaryPluginName(summaryPlugin.Plugin), summaryPlugin.Invocations, formatSeconds(summaryPlugin.WallTime), formatSeconds(summaryPlugin.CPUTime)); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprint(tabWriter, "\nDIRECTORY\tPLUGIN\tFILES\tWALL\tCPU\n"); err != nil {
+		return err
+	}
+	for _, summaryInvocation := range s.Invocations {
+		if _, err := fmt.Fprintf(tabWriter, "%s\t%s\t%d\t%s\t%s\n", summaryInvocation.Directory, getSummaryPluginName(summaryInvocation.Plugin), summaryInvocation.Files, formatSeconds(summaryInvocation.WallTime), formatSeconds(summaryInvocation.CPUTime)); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprint(tabWriter, "\nSLOWEST DIRECTORY\tWALL\tCPU\n"); err != nil {
+		return err
+	}
+	for _, summaryDirectory := range s.SlowestDirectories {
+		if _, err := fmt.Fprintf(tabWriter, "%s\t%s\t%s\n", summaryDirectory.Directory, formatSeconds(summaryDirectory.WallTime), formatSeconds(summaryDirectory.CPUTime)); err != nil {
+			return err
+		}
+	}
+	return tabWriter.Flush()
+}
+
+func getSummaryPluginName(plugin string) string {
+	if plugin == "" {
+		return "(none)"
+	}
+	return plugin
+}
+
+func formatSeconds(seconds float64) string {
+	return time.Duration(seconds * float64(time.Second)).Round(time.Millisecond).String()
+}
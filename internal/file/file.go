@@ -23,6 +23,7 @@ package file
 import (
 	"fmt"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/uber/prototool/internal/settings"
@@ -52,12 +53,31 @@ type ProtoSet struct {
 	// error and always return a non-empty string. Note the string could be ".".
 	// The ProtoFiles will always be in the directory specified by the key.
 	DirPathToFiles map[string][]*ProtoFile
+	// The directory paths within DirPathToFiles to operate on, for example
+	// when only compiling the files affected by a change.
+	// If nil, all directory paths within DirPath are operated on.
+	// All paths must be absolute.
+	// Must be cleaned.
+	TargetDirPaths map[string]struct{}
 	// The associated Config.
 	// Must be valid.
 	// The DirPath on the config may differ from the DirPath on the ProtoSet.
 	Config settings.Config
 }
 
+// IsTargetDirPath returns true if the files in the given directory path
+// within DirPathToFiles should be operated on.
+func (p *ProtoSet) IsTargetDirPath(dirPath string) bool {
+	if !strings.HasPrefix(dirPath, p.DirPath) {
+		return false
+	}
+	if p.TargetDirPaths == nil {
+		return true
+	}
+	_, ok := p.TargetDirPaths[dirPath]
+	return ok
+}
+
 // ProtoFile represents a .proto file.
 type ProtoFile struct {
 	// The path to the .proto file.
@@ -125,6 +145,34 @@ func ProtoSetProviderWithWalkTimeout(walkTimeout time.Duration) ProtoSetProvider
 	}
 }
 
+// ProtoSetProviderWithOverlay returns a ProtoSetProviderOption that uses the
+// given overlay, a map from absolute, cleaned file paths to file contents.
+//
+// Files in the overlay that do not exist on disk are returned as if they
+// did, as long as their directory exists and they would not be excluded.
+// The same overlay should be given to the protoc.Compiler so that the
+// contents are used for compilation.
+func ProtoSetProviderWithOverlay(overlay map[string][]byte) ProtoSetProviderOption {
+	return func(protoSetProvider *protoSetProvider) {
+		protoSetProvider.overlay = overlay
+	}
+}
+
+// ProtoSetProviderWithHiddenFilePaths returns a ProtoSetProviderOption that
+// does not return the given files, absolute and cleaned, even if they exist
+// on disk.
+//
+// The same files should be given to the protoc.Compiler so that they cannot
+// be imported either.
+func ProtoSetProviderWithHiddenFilePaths(filePaths []string) ProtoSetProviderOption {
+	return func(protoSetProvider *protoSetProvider) {
+		protoSetProvider.hiddenFilePaths = make(map[string]struct{}, len(filePaths))
+		for _, filePath := range filePaths {
+			protoSetProvider.hiddenFilePaths[filePath] = struct{}{}
+		}
+	}
+}
+
 // NewProtoSetProvider returns a new ProtoSetProvider.
 func NewProtoSetProvider(options ...ProtoSetProviderOption) ProtoSetProvider {
 	return newProtoSetProvider(options...)
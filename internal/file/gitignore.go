@@ -0,0 +1,139 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package file
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/uber/prototool/internal/glob"
+)
+
+const gitignoreFilename = ".gitignore"
+
+// gitignore is a parsed .gitignore file.
+//
+// See https://git-scm.com/docs/gitignore for the format. Patterns are
+// matched with glob.Match.
+type gitignore struct {
+	// The directory of the .gitignore file.
+	// Must be absolute.
+	// Must be cleaned.
+	dirPath string
+	rules   []gitignoreRule
+}
+
+type gitignoreRule struct {
+	// Relative to the directory of the .gitignore file.
+	pattern string
+	negate  bool
+	dirOnly bool
+}
+
+// readGitignore reads the .gitignore file in the given directory.
+//
+// Returns nil if there is no .gitignore file.
+func readGitignore(dirPath string) (*gitignore, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dirPath, gitignoreFilename))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return newGitignore(dirPath, data), nil
+}
+
+func newGitignore(dirPath string, data []byte) *gitignore {
+	gitignore := &gitignore{
+		dirPath: dirPath,
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule := gitignoreRule{}
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\#`) || strings.HasPrefix(line, `\!`) {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+		// A pattern with a slash at the beginning or middle is relative to
+		// the directory of the .gitignore file, otherwise it matches at any level.
+		if strings.Contains(line, "/") {
+			line = strings.TrimPrefix(line, "/")
+		} else {
+			line = "**/" + line
+		}
+		rule.pattern = line
+		gitignore.rules = append(gitignore.rules, rule)
+	}
+	return gitignore
+}
+
+// match returns whether the absolute path is ignored, and whether any rule
+// matched at all so that deeper .gitignore files can override shallower ones.
+func (g *gitignore) match(absPath string, isDir bool) (ignored bool, matched bool) {
+	relPath, ok := getSlashRelPath(g.dirPath, absPath)
+	if !ok || relPath == "." {
+		return false, false
+	}
+	for _, rule := range g.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		if glob.Match(rule.pattern, relPath) {
+			ignored = !rule.negate
+			matched = true
+		}
+	}
+	return ignored, matched
+}
+
+// getSlashRelPath returns the slash-separated path of absPath relative to
+// absDirPath, and false if absPath is not within absDirPath.
+func getSlashRelPath(absDirPath string, absPath string) (string, bool) {
+	if absPath == absDirPath {
+		return ".", true
+	}
+	if !strings.HasPrefix(absPath, absDirPath) {
+		return "", false
+	}
+	relPath, err := filepath.Rel(absDirPath, absPath)
+	if err != nil || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.ToSlash(relPath), true
+}
@@ -0,0 +1,58 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package file
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGitignore(t *testing.T) {
+	gitignore := newGitignore("/repo", []byte(`# comment
+*.tmp.proto
+!keep.tmp.proto
+gen/
+/root.proto
+a/b.proto
+\#hash.proto
+
+`))
+	testGitignore(t, gitignore, true, true, "/repo/foo.tmp.proto", false)
+	testGitignore(t, gitignore, true, true, "/repo/a/b/foo.tmp.proto", false)
+	testGitignore(t, gitignore, false, true, "/repo/keep.tmp.proto", false)
+	testGitignore(t, gitignore, true, true, "/repo/x/gen", true)
+	testGitignore(t, gitignore, false, false, "/repo/x/gen", false)
+	testGitignore(t, gitignore, true, true, "/repo/root.proto", false)
+	testGitignore(t, gitignore, false, false, "/repo/x/root.proto", false)
+	testGitignore(t, gitignore, true, true, "/repo/a/b.proto", false)
+	testGitignore(t, gitignore, false, false, "/repo/x/a/b.proto", false)
+	testGitignore(t, gitignore, true, true, "/repo/#hash.proto", false)
+	testGitignore(t, gitignore, false, false, "/other/foo.tmp.proto", false)
+	testGitignore(t, gitignore, false, false, "/repository/foo.tmp.proto", false)
+	testGitignore(t, gitignore, false, false, "/repo", true)
+}
+
+func testGitignore(t *testing.T, gitignore *gitignore, expectedIgnored bool, expectedMatched bool, absPath string, isDir bool) {
+	ignored, matched := gitignore.match(absPath, isDir)
+	assert.Equal(t, expectedIgnored, ignored, absPath)
+	assert.Equal(t, expectedMatched, matched, absPath)
+}
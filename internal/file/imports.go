@@ -0,0 +1,153 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package file
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+)
+
+var (
+	packageRegexp = regexp.MustCompile(`(?m)^\s*package\s+([\w.]+)\s*;`)
+	importRegexp  = regexp.MustCompile(`(?m)^\s*import\s+(?:public\s+|weak\s+)?"([^"]+)"\s*;`)
+)
+
+// ParsePackageAndImports returns the package and imports of a .proto file.
+//
+// This does not fully parse the file, and is only meant for cases where
+// the file may not compile, or compiling would be too expensive.
+func ParsePackageAndImports(data []byte) (string, []string) {
+	data = stripComments(data)
+	pkg := ""
+	if matches := packageRegexp.FindSubmatch(data); matches != nil {
+		pkg = string(matches[1])
+	}
+	var imports []string
+	for _, matches := range importRegexp.FindAllSubmatch(data, -1) {
+		imports = append(imports, string(matches[1]))
+	}
+	return pkg, imports
+}
+
+// GetAffectedFilePaths returns the paths of the files in the ProtoSet that
+// are within changedFilePaths, or that transitively import any file within
+// changedFilePaths.
+//
+// Imports are resolved against the config directory and the compile include
+// paths. Files in changedFilePaths that no longer exist still affect the
+// files that import them.
+//
+// All paths must be absolute and cleaned. The returned paths are sorted.
+func GetAffectedFilePaths(protoSet *ProtoSet, changedFilePaths []string) ([]string, error) {
+	changed := make(map[string]struct{}, len(changedFilePaths))
+	for _, changedFilePath := range changedFilePaths {
+		changed[changedFilePath] = struct{}{}
+	}
+	includeDirPaths := append([]string{protoSet.Config.DirPath}, protoSet.Config.Compile.IncludePaths...)
+	// import file path to the file paths that import it
+	importedBy := make(map[string][]string)
+	affected := make(map[string]struct{})
+	var queue []string
+	for _, protoFiles := range protoSet.DirPathToFiles {
+		for _, protoFile := range protoFiles {
+			data, err := ioutil.ReadFile(protoFile.Path)
+			if err != nil {
+				return nil, err
+			}
+			_, imports := ParsePackageAndImports(data)
+			for _, imp := range imports {
+				for _, includeDirPath := range includeDirPaths {
+					importFilePath := filepath.Join(includeDirPath, filepath.FromSlash(imp))
+					importedBy[importFilePath] = append(importedBy[importFilePath], protoFile.Path)
+				}
+			}
+			if _, ok := changed[protoFile.Path]; ok {
+				affected[protoFile.Path] = struct{}{}
+				queue = append(queue, protoFile.Path)
+			}
+		}
+	}
+	// deleted files are not in the ProtoSet but can still be imported
+	for _, changedFilePath := range changedFilePaths {
+		if _, ok := affected[changedFilePath]; !ok {
+			queue = append(queue, changedFilePath)
+		}
+	}
+	for len(queue) > 0 {
+		filePath := queue[0]
+		queue = queue[1:]
+		for _, importerFilePath := range importedBy[filePath] {
+			if _, ok := affected[importerFilePath]; ok {
+				continue
+			}
+			affected[importerFilePath] = struct{}{}
+			queue = append(queue, importerFilePath)
+		}
+	}
+	affectedFilePaths := make([]string, 0, len(affected))
+	for filePath := range affected {
+		affectedFilePaths = append(affectedFilePaths, filePath)
+	}
+	sort.Strings(affectedFilePaths)
+	return affectedFilePaths, nil
+}
+
+// stripComments replaces comments in .proto file data with spaces,
+// leaving strings untouched.
+func stripComments(data []byte) []byte {
+	output := make([]byte, len(data))
+	copy(output, data)
+	var quote byte
+	for i := 0; i < len(output); i++ {
+		c := output[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote || c == '\n' {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '/' && i+1 < len(output) && output[i+1] == '/':
+			for ; i < len(output) && output[i] != '\n'; i++ {
+				output[i] = ' '
+			}
+		case c == '/' && i+1 < len(output) && output[i+1] == '*':
+			output[i] = ' '
+			output[i+1] = ' '
+			for i += 2; i < len(output); i++ {
+				if output[i] == '*' && i+1 < len(output) && output[i+1] == '/' {
+					output[i] = ' '
+					output[i+1] = ' '
+					i++
+					break
+				}
+				if output[i] != '\n' {
+					output[i] = ' '
+				}
+			}
+		}
+	}
+	return output
+}
@@ -0,0 +1,94 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePackageAndImports(t *testing.T) {
+	pkg, imports := ParsePackageAndImports([]byte(`syntax = "proto3";
+// package bar;
+package foo.v1; // trailing
+import "a.proto";
+/*
+import "b.proto";
+*/
+import weak "c.proto";
+option go_package = "github.com/foo//bar";
+`))
+	assert.Equal(t, "foo.v1", pkg)
+	assert.Equal(t, []string{"a.proto", "c.proto"}, imports)
+}
+
+func TestGetAffectedFilePaths(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	for filePath, data := range map[string]string{
+		"prototool.yaml":                    "protoc:\n  includes:\n    - third_party\n",
+		"a/a.proto":                         `import "b/b.proto";`,
+		"b/b.proto":                         `import "c/c.proto";`,
+		"c/c.proto":                         `import "google/api/http.proto";`,
+		"d/d.proto":                         `import "c/deleted.proto";`,
+		"e/e.proto":                         "",
+		"third_party/google/api/http.proto": "",
+	} {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, filepath.Dir(filePath)), 0755))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, filePath), []byte(data), 0644))
+	}
+	protoSet, err := newTestProtoSetProvider(t).GetForDir(dir, dir)
+	require.NoError(t, err)
+
+	testGetAffectedFilePaths(t, dir, protoSet, []string{"c/c.proto", "a/a.proto", "b/b.proto"}, "c/c.proto")
+	testGetAffectedFilePaths(t, dir, protoSet, []string{"a/a.proto"}, "a/a.proto", "other.txt")
+	testGetAffectedFilePaths(t, dir, protoSet, []string{"d/d.proto"}, "c/deleted.proto")
+	testGetAffectedFilePaths(
+		t,
+		dir,
+		protoSet,
+		[]string{"c/c.proto", "a/a.proto", "b/b.proto", "third_party/google/api/http.proto"},
+		"third_party/google/api/http.proto",
+	)
+	testGetAffectedFilePaths(t, dir, protoSet, []string{})
+}
+
+func testGetAffectedFilePaths(t *testing.T, dir string, protoSet *ProtoSet, expectedRelFilePaths []string, changedRelFilePaths ...string) {
+	var changedFilePaths []string
+	for _, changedRelFilePath := range changedRelFilePaths {
+		changedFilePaths = append(changedFilePaths, filepath.Join(dir, changedRelFilePath))
+	}
+	affectedFilePaths, err := GetAffectedFilePaths(protoSet, changedFilePaths)
+	require.NoError(t, err)
+	expectedFilePaths := make([]string, 0, len(expectedRelFilePaths))
+	for _, expectedRelFilePath := range expectedRelFilePaths {
+		expectedFilePaths = append(expectedFilePaths, filepath.Join(dir, expectedRelFilePath))
+	}
+	assert.ElementsMatch(t, expectedFilePaths, affectedFilePaths)
+}
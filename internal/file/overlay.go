@@ -0,0 +1,77 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package file
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+)
+
+type overlayJSON struct {
+	Replace map[string]string
+}
+
+// ReadOverlay reads the overlay file at the given path.
+//
+// The file has the same format as for go build -overlay, that is a JSON
+// object with a single "Replace" key that maps file paths to the paths of
+// the files with the contents to use instead:
+//
+//	{"Replace": {"foo/v1/foo.proto": "/tmp/buffer1234.proto"}}
+//
+// Relative paths are relative to the current directory. Deleting files
+// by mapping them to an empty path is not supported.
+//
+// Returns a map from absolute, cleaned file paths to file contents that
+// can be given to ProtoSetProviderWithOverlay.
+func ReadOverlay(filePath string) (map[string][]byte, error) {
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	var overlayJSON overlayJSON
+	if err := json.Unmarshal(data, &overlayJSON); err != nil {
+		return nil, fmt.Errorf("could not parse overlay file %s: %v", filePath, err)
+	}
+	overlay := make(map[string][]byte, len(overlayJSON.Replace))
+	for path, replacementPath := range overlayJSON.Replace {
+		if replacementPath == "" {
+			return nil, fmt.Errorf("overlay file %s: cannot delete %s, only replacing files is supported", filePath, path)
+		}
+		absPath, err := AbsClean(path)
+		if err != nil {
+			return nil, err
+		}
+		if absPath == "" {
+			return nil, fmt.Errorf("overlay file %s: empty path", filePath)
+		}
+		if _, ok := overlay[absPath]; ok {
+			return nil, fmt.Errorf("overlay file %s: duplicate path %s", filePath, path)
+		}
+		replacementData, err := ioutil.ReadFile(replacementPath)
+		if err != nil {
+			return nil, fmt.Errorf("overlay file %s: %v", filePath, err)
+		}
+		overlay[absPath] = replacementData
+	}
+	return overlay, nil
+}
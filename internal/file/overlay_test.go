@@ -0,0 +1,56 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadOverlay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	bufferFilePath := filepath.Join(dir, "buffer.proto")
+	require.NoError(t, ioutil.WriteFile(bufferFilePath, []byte("syntax = \"proto3\";\n"), 0644))
+	overlayFilePath := filepath.Join(dir, "overlay.json")
+	require.NoError(t, ioutil.WriteFile(overlayFilePath, []byte(`{"Replace": {"`+dir+`/foo/../foo.proto": "`+bufferFilePath+`"}}`), 0644))
+	overlay, err := ReadOverlay(overlayFilePath)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{filepath.Join(dir, "foo.proto"): []byte("syntax = \"proto3\";\n")}, overlay)
+
+	require.NoError(t, ioutil.WriteFile(overlayFilePath, []byte(`{"Replace": {"foo.proto": ""}}`), 0644))
+	_, err = ReadOverlay(overlayFilePath)
+	assert.Error(t, err)
+	require.NoError(t, ioutil.WriteFile(overlayFilePath, []byte(`{"Replace": {"foo.proto": "`+filepath.Join(dir, "missing.proto")+`"}}`), 0644))
+	_, err = ReadOverlay(overlayFilePath)
+	assert.Error(t, err)
+	require.NoError(t, ioutil.WriteFile(overlayFilePath, []byte(`{"Replace": `), 0644))
+	_, err = ReadOverlay(overlayFilePath)
+	assert.Error(t, err)
+}
@@ -23,21 +23,25 @@ package file
 import (
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
 	"sort"
 	"strings"
 	"time"
 
+	"github.com/uber/prototool/internal/glob"
 	"github.com/uber/prototool/internal/settings"
 	"go.uber.org/zap"
 )
 
 type protoSetProvider struct {
-	logger         *zap.Logger
-	develMode      bool
-	configData     string
-	walkTimeout    time.Duration
-	configProvider settings.ConfigProvider
+	logger          *zap.Logger
+	develMode       bool
+	configData      string
+	walkTimeout     time.Duration
+	overlay         map[string][]byte
+	hiddenFilePaths map[string]struct{}
+	configProvider  settings.ConfigProvider
 }
 
 func newProtoSetProvider(options ...ProtoSetProviderOption) *protoSetProvider {
@@ -215,14 +219,20 @@ func (c *protoSetProvider) walkAndGetAllProtoFiles(absWorkDirPath string, absDir
 	var numWalkedFiles int
 	var timedOut bool
 	var excludes []string
-	var err error
-	// if we have a configData, we compute the exclude prefixes once
+	var discoveries []*settings.DiscoveryConfig
+	var gitignores []*gitignore
+	// the directories that were walked and not skipped, used to decide
+	// if a file that only exists in the overlay should be discovered
+	walkedDirPaths := make(map[string]struct{})
+	// if we have a configData, we compute the discovery config once
 	// from this dirPath and data, and do not do it again in the below walk function
 	if c.configData != "" {
-		excludes, err = c.configProvider.GetExcludePrefixesForData(absWorkDirPath, c.configData)
+		discovery, err := c.configProvider.GetDiscoveryForData(absWorkDirPath, c.configData)
 		if err != nil {
 			return nil, err
 		}
+		excludes = discovery.ExcludePrefixes
+		discoveries = append(discoveries, discovery)
 	}
 
 	c.logger.Debug("walking the directory structure", zap.Duration("walkTimeout", c.walkTimeout))
@@ -246,15 +256,31 @@ func (c *protoSetProvider) walkAndGetAllProtoFiles(absWorkDirPath string, absDir
 					// Add the excluded files with respect to the current file path.
 					// Do not add if we have configData.
 					if c.configData == "" {
-						iExcludes, err := c.configProvider.GetExcludePrefixesForDir(filePath)
+						discovery, err := c.configProvider.GetDiscoveryForDir(filePath)
 						if err != nil {
 							return err
 						}
-						excludes = append(excludes, iExcludes...)
+						if discovery != nil {
+							excludes = append(excludes, discovery.ExcludePrefixes...)
+							discoveries = append(discoveries, discovery)
+						}
 					}
 					if IsExcluded(filePath, absDirPath, excludes...) {
 						return filepath.SkipDir
 					}
+					if filePath != absDirPath && isExcludedByDiscovery(filePath, true, discoveries, gitignores) {
+						return filepath.SkipDir
+					}
+					if honorsGitignore(filePath, discoveries) {
+						gitignore, err := readGitignore(filePath)
+						if err != nil {
+							return err
+						}
+						if gitignore != nil {
+							gitignores = append(gitignores, gitignore)
+						}
+					}
+					walkedDirPaths[filePath] = struct{}{}
 					return nil
 				}
 				if filepath.Ext(filePath) != ".proto" {
@@ -263,17 +289,15 @@ func (c *protoSetProvider) walkAndGetAllProtoFiles(absWorkDirPath string, absDir
 				if IsExcluded(filePath, absDirPath, excludes...) {
 					return nil
 				}
+				if isExcludedByDiscovery(filePath, false, discoveries, gitignores) || !isIncluded(filePath, discoveries) {
+					return nil
+				}
+				if _, ok := c.hiddenFilePaths[filePath]; ok {
+					return nil
+				}
 
 				// Visit this file.
-				displayPath, err := filepath.Rel(absWorkDirPath, filePath)
-				if err != nil {
-					displayPath = filePath
-				}
-				displayPath = filepath.Clean(displayPath)
-				protoFiles = append(protoFiles, &ProtoFile{
-					Path:        filePath,
-					DisplayPath: displayPath,
-				})
+				protoFiles = append(protoFiles, newProtoFile(absWorkDirPath, filePath))
 				return nil
 			},
 		)
@@ -282,14 +306,14 @@ func (c *protoSetProvider) walkAndGetAllProtoFiles(absWorkDirPath string, absDir
 		if walkErr := <-walkErrC; walkErr != nil {
 			return nil, walkErr
 		}
-		return protoFiles, nil
+		return c.addOverlayProtoFiles(absWorkDirPath, protoFiles, walkedDirPaths, excludes, discoveries, gitignores), nil
 	}
 	select {
 	case walkErr := <-walkErrC:
 		if walkErr != nil {
 			return nil, walkErr
 		}
-		return protoFiles, nil
+		return c.addOverlayProtoFiles(absWorkDirPath, protoFiles, walkedDirPaths, excludes, discoveries, gitignores), nil
 	case <-time.After(c.walkTimeout):
 		timedOut = true
 		if walkErr := <-walkErrC; walkErr != nil {
@@ -299,6 +323,144 @@ func (c *protoSetProvider) walkAndGetAllProtoFiles(absWorkDirPath string, absDir
 	}
 }
 
+// addOverlayProtoFiles adds the .proto files that only exist in the overlay
+// to the walked protoFiles, if their directory was walked and they would
+// not have been excluded had they existed on disk.
+func (c *protoSetProvider) addOverlayProtoFiles(
+	absWorkDirPath string,
+	protoFiles []*ProtoFile,
+	walkedDirPaths map[string]struct{},
+	excludes []string,
+	discoveries []*settings.DiscoveryConfig,
+	gitignores []*gitignore,
+) []*ProtoFile {
+	if len(c.overlay) == 0 {
+		return protoFiles
+	}
+	filePaths := make(map[string]struct{}, len(protoFiles))
+	for _, protoFile := range protoFiles {
+		filePaths[protoFile.Path] = struct{}{}
+	}
+	added := false
+	for filePath := range c.overlay {
+		if _, ok := filePaths[filePath]; ok {
+			continue
+		}
+		if _, ok := c.hiddenFilePaths[filePath]; ok {
+			continue
+		}
+		if filepath.Ext(filePath) != ".proto" {
+			continue
+		}
+		dirPath := filepath.Dir(filePath)
+		if _, ok := walkedDirPaths[dirPath]; !ok {
+			continue
+		}
+		// the directory was not excluded as it was walked, so only check the file
+		if IsExcluded(filePath, dirPath, excludes...) {
+			continue
+		}
+		if isExcludedByDiscovery(filePath, false, discoveries, gitignores) || !isIncluded(filePath, discoveries) {
+			continue
+		}
+		protoFiles = append(protoFiles, newProtoFile(absWorkDirPath, filePath))
+		added = true
+	}
+	if added {
+		// keep the order of the walk
+		sort.Slice(protoFiles, func(i int, j int) bool {
+			return protoFiles[i].Path < protoFiles[j].Path
+		})
+	}
+	return protoFiles
+}
+
+func newProtoFile(absWorkDirPath string, filePath string) *ProtoFile {
+	displayPath, err := filepath.Rel(absWorkDirPath, filePath)
+	if err != nil {
+		displayPath = filePath
+	}
+	return &ProtoFile{
+		Path:        filePath,
+		DisplayPath: filepath.Clean(displayPath),
+	}
+}
+
+// isExcludedByDiscovery returns true if the absolute path matches an exclude
+// glob of a config file containing it, or is ignored by a .gitignore file
+// containing it if the closest config file honors .gitignore files.
+//
+// Deeper .gitignore files take precedence over shallower ones.
+func isExcludedByDiscovery(absPath string, isDir bool, discoveries []*settings.DiscoveryConfig, gitignores []*gitignore) bool {
+	for _, discovery := range discoveries {
+		relPath, ok := getSlashRelPath(discovery.DirPath, absPath)
+		if !ok || relPath == "." {
+			continue
+		}
+		for _, excludeGlob := range discovery.ExcludeGlobs {
+			if glob.Match(excludeGlob, relPath) {
+				return true
+			}
+		}
+	}
+	if !honorsGitignore(absPath, discoveries) {
+		return false
+	}
+	// gitignores are in walk order, so parents come before their children
+	ignored := false
+	for _, gitignore := range gitignores {
+		if gitignoreIgnored, matched := gitignore.match(absPath, isDir); matched {
+			ignored = gitignoreIgnored
+		}
+	}
+	return ignored
+}
+
+// isIncluded returns true if the absolute file path, or one of its parent
+// directories, matches an include glob of every config file containing
+// it that has include globs.
+func isIncluded(absFilePath string, discoveries []*settings.DiscoveryConfig) bool {
+	for _, discovery := range discoveries {
+		if len(discovery.IncludeGlobs) == 0 {
+			continue
+		}
+		relPath, ok := getSlashRelPath(discovery.DirPath, absFilePath)
+		if !ok {
+			continue
+		}
+		if !matchesIncludeGlob(relPath, discovery.IncludeGlobs) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchesIncludeGlob(relFilePath string, includeGlobs []string) bool {
+	for curPath := relFilePath; curPath != "."; curPath = path.Dir(curPath) {
+		for _, includeGlob := range includeGlobs {
+			if glob.Match(includeGlob, curPath) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// honorsGitignore returns true if the closest config file containing the
+// absolute path honors .gitignore files.
+func honorsGitignore(absPath string, discoveries []*settings.DiscoveryConfig) bool {
+	var closest *settings.DiscoveryConfig
+	for _, discovery := range discoveries {
+		if _, ok := getSlashRelPath(discovery.DirPath, absPath); !ok {
+			continue
+		}
+		if closest == nil || len(discovery.DirPath) > len(closest.DirPath) {
+			closest = discovery
+		}
+	}
+	return closest != nil && closest.HonorGitignore
+}
+
 func validateProtoSet(protoSet *ProtoSet) error {
 	for dirPath, protoFiles := range protoSet.DirPathToFiles {
 		for _, protoFile := range protoFiles {
@@ -24,6 +24,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -442,6 +443,134 @@ func TestProtoSetProviderGetForDirConfigData(t *testing.T) {
 	)
 }
 
+func TestProtoSetProviderGetForDirDiscovery(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	for filePath, data := range map[string]string{
+		"prototool.yaml": `excludes:
+  - "**/testdata/**"
+includes:
+  - idl
+  - other/*.proto
+honor_gitignore: true
+`,
+		".gitignore":              "gen/\n*.tmp.proto\n!keep.tmp.proto\n",
+		"idl/a.proto":             "",
+		"idl/testdata/b.proto":    "",
+		"idl/gen/c.proto":         "",
+		"idl/x.tmp.proto":         "",
+		"idl/keep.tmp.proto":      "",
+		"idl/sub/.gitignore":      "d.proto\n",
+		"idl/sub/d.proto":         "",
+		"idl/sub/e.proto":         "",
+		"other/f.proto":           "",
+		"other/deep/g.proto":      "",
+		"node_modules/h.proto":    "",
+		"node_modules/.gitignore": "",
+	} {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, filepath.Dir(filePath)), 0755))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, filePath), []byte(data), 0644))
+	}
+	protoSetProvider := newTestProtoSetProvider(t)
+	protoSet, err := protoSetProvider.GetForDir(dir, dir)
+	require.NoError(t, err)
+	var displayPaths []string
+	for _, protoFiles := range protoSet.DirPathToFiles {
+		for _, protoFile := range protoFiles {
+			displayPaths = append(displayPaths, protoFile.DisplayPath)
+		}
+	}
+	sort.Strings(displayPaths)
+	assert.Equal(
+		t,
+		[]string{
+			"idl/a.proto",
+			"idl/keep.tmp.proto",
+			"idl/sub/e.proto",
+			"other/f.proto",
+		},
+		displayPaths,
+	)
+	assert.Equal(t, []string{"**/testdata/**"}, protoSet.Config.ExcludeGlobs)
+	assert.Equal(t, []string{"idl", "other/*.proto"}, protoSet.Config.IncludeGlobs)
+	assert.True(t, protoSet.Config.HonorGitignore)
+}
+
+func TestProtoSetProviderGetForDirOverlay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	for filePath, data := range map[string]string{
+		"prototool.yaml":       "excludes:\n  - \"**/testdata/**\"\n",
+		"idl/a.proto":          "",
+		"idl/testdata/b.proto": "",
+	} {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, filepath.Dir(filePath)), 0755))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, filePath), []byte(data), 0644))
+	}
+	protoSetProvider := newProtoSetProvider(
+		ProtoSetProviderWithLogger(newTestLogger(t)),
+		ProtoSetProviderWithOverlay(
+			map[string][]byte{
+				filepath.Join(dir, "idl", "a.proto"):             []byte("syntax = \"proto3\";"),
+				filepath.Join(dir, "idl", "0.proto"):             []byte("syntax = \"proto3\";"),
+				filepath.Join(dir, "idl", "c.txt"):               []byte(""),
+				filepath.Join(dir, "idl", "testdata", "d.proto"): []byte(""),
+				filepath.Join(dir, "missing", "e.proto"):         []byte(""),
+			},
+		),
+	)
+	protoSet, err := protoSetProvider.GetForDir(dir, dir)
+	require.NoError(t, err)
+	var displayPaths []string
+	for _, protoFiles := range protoSet.DirPathToFiles {
+		for _, protoFile := range protoFiles {
+			displayPaths = append(displayPaths, protoFile.DisplayPath)
+		}
+	}
+	assert.Equal(t, []string{"idl/0.proto", "idl/a.proto"}, displayPaths)
+}
+
+func TestProtoSetProviderGetForDirHiddenFilePaths(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	for _, filePath := range []string{"idl/a.proto", "idl/b.proto"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, filepath.Dir(filePath)), 0755))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, filePath), nil, 0644))
+	}
+	protoSetProvider := newProtoSetProvider(
+		ProtoSetProviderWithLogger(newTestLogger(t)),
+		ProtoSetProviderWithOverlay(
+			map[string][]byte{
+				filepath.Join(dir, "idl", "c.proto"): []byte(""),
+			},
+		),
+		ProtoSetProviderWithHiddenFilePaths(
+			[]string{
+				filepath.Join(dir, "idl", "b.proto"),
+				filepath.Join(dir, "idl", "c.proto"),
+			},
+		),
+	)
+	protoSet, err := protoSetProvider.GetForDir(dir, dir)
+	require.NoError(t, err)
+	var displayPaths []string
+	for _, protoFiles := range protoSet.DirPathToFiles {
+		for _, protoFile := range protoFiles {
+			displayPaths = append(displayPaths, protoFile.DisplayPath)
+		}
+	}
+	assert.Equal(t, []string{"idl/a.proto"}, displayPaths)
+}
+
 func newTestProtoSetProvider(t *testing.T) *protoSetProvider {
 	return newProtoSetProvider(ProtoSetProviderWithLogger(newTestLogger(t)))
 }
@@ -0,0 +1,243 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package git contains functionality to query the local git repository.
+//
+// This shells out to the git binary, which must be on the PATH.
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// ChangedFiles returns the absolute paths of the files that have changed
+// in the working tree since the merge base of ref and HEAD.
+//
+// This includes committed, staged, unstaged, and untracked files, as well
+// as deleted files, for the git repository that contains dirPath.
+// Files ignored by git are not included.
+//
+// dirPath must be absolute, and the returned paths are joined to the
+// repository root as seen from dirPath, so symlinks in dirPath are preserved.
+func ChangedFiles(dirPath string, ref string) ([]string, error) {
+	if !filepath.IsAbs(dirPath) {
+		return nil, fmt.Errorf("%s is not an absolute path", dirPath)
+	}
+	rootDirPath, err := getRootDirPath(dirPath)
+	if err != nil {
+		return nil, err
+	}
+	mergeBase, err := run(rootDirPath, "merge-base", ref, "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	diffOutput, err := run(rootDirPath, "diff", "--name-only", "--no-renames", "-z", strings.TrimSpace(mergeBase), "--")
+	if err != nil {
+		return nil, err
+	}
+	untrackedOutput, err := run(rootDirPath, "ls-files", "--others", "--exclude-standard", "-z")
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]struct{})
+	var filePaths []string
+	for _, relFilePath := range append(splitNul(diffOutput), splitNul(untrackedOutput)...) {
+		filePath := filepath.Join(rootDirPath, filepath.FromSlash(relFilePath))
+		if _, ok := seen[filePath]; ok {
+			continue
+		}
+		seen[filePath] = struct{}{}
+		filePaths = append(filePaths, filePath)
+	}
+	return filePaths, nil
+}
+
+// StagedFiles returns the absolute paths of the files under dirPath that
+// have staged changes in the index of the git repository that contains dirPath.
+//
+// Deleted files are not included. If pathspecs are given, only the files
+// matching them are returned, where the pathspecs are relative to dirPath.
+//
+// dirPath must be absolute, and the returned paths are joined to the
+// repository root as seen from dirPath, so symlinks in dirPath are preserved.
+func StagedFiles(dirPath string, pathspecs ...string) ([]string, error) {
+	rootDirPath, err := getAbsRootDirPath(dirPath)
+	if err != nil {
+		return nil, err
+	}
+	output, err := run(dirPath, append([]string{"diff", "--cached", "--name-only", "--no-renames", "--diff-filter=d", "-z", "--"}, getPathspecs(pathspecs)...)...)
+	if err != nil {
+		return nil, err
+	}
+	var filePaths []string
+	for _, relFilePath := range splitNul(output) {
+		filePaths = append(filePaths, filepath.Join(rootDirPath, filepath.FromSlash(relFilePath)))
+	}
+	return filePaths, nil
+}
+
+// StagedContents returns the staged contents of the files under dirPath whose
+// working copy differs from the index of the git repository that contains dirPath.
+//
+// The result is keyed by absolute path, and can be used as an overlay so that
+// the files are read as they will be committed rather than from the working
+// copy. Files that are staged but deleted in the working copy are included.
+// If pathspecs are given, only the files matching them are returned, where
+// the pathspecs are relative to dirPath.
+//
+// dirPath must be absolute, and the returned paths are joined to the
+// repository root as seen from dirPath, so symlinks in dirPath are preserved.
+func StagedContents(dirPath string, pathspecs ...string) (map[string][]byte, error) {
+	rootDirPath, err := getAbsRootDirPath(dirPath)
+	if err != nil {
+		return nil, err
+	}
+	output, err := run(dirPath, append([]string{"diff", "--name-only", "--no-renames", "-z", "--"}, getPathspecs(pathspecs)...)...)
+	if err != nil {
+		return nil, err
+	}
+	filePathToData := make(map[string][]byte)
+	for _, relFilePath := range splitNul(output) {
+		data, err := run(rootDirPath, "cat-file", "blob", ":"+relFilePath)
+		if err != nil {
+			return nil, err
+		}
+		filePathToData[filepath.Join(rootDirPath, filepath.FromSlash(relFilePath))] = []byte(data)
+	}
+	return filePathToData, nil
+}
+
+// UntrackedFiles returns the absolute paths of the files under dirPath that
+// are not tracked by the git repository that contains dirPath.
+//
+// Files ignored by git are not included. If pathspecs are given, only the
+// files matching them are returned, where the pathspecs are relative to dirPath.
+//
+// Untracked files will not be committed, so they should not be visible when
+// checking the staged contents.
+//
+// dirPath must be absolute, and the returned paths are joined to the
+// repository root as seen from dirPath, so symlinks in dirPath are preserved.
+func UntrackedFiles(dirPath string, pathspecs ...string) ([]string, error) {
+	rootDirPath, err := getAbsRootDirPath(dirPath)
+	if err != nil {
+		return nil, err
+	}
+	// --full-name so the paths are relative to the root like the diff output
+	output, err := run(dirPath, append([]string{"ls-files", "--others", "--exclude-standard", "--full-name", "-z", "--"}, getPathspecs(pathspecs)...)...)
+	if err != nil {
+		return nil, err
+	}
+	var filePaths []string
+	for _, relFilePath := range splitNul(output) {
+		filePaths = append(filePaths, filepath.Join(rootDirPath, filepath.FromSlash(relFilePath)))
+	}
+	return filePaths, nil
+}
+
+// HooksDirPath returns the absolute path of the hooks directory of the git
+// repository that contains dirPath, which must be absolute.
+//
+// This respects core.hooksPath and worktrees.
+func HooksDirPath(dirPath string) (string, error) {
+	if !filepath.IsAbs(dirPath) {
+		return "", fmt.Errorf("%s is not an absolute path", dirPath)
+	}
+	output, err := run(dirPath, "rev-parse", "--git-path", "hooks")
+	if err != nil {
+		return "", err
+	}
+	hooksDirPath := strings.TrimSpace(output)
+	if !filepath.IsAbs(hooksDirPath) {
+		hooksDirPath = filepath.Join(dirPath, hooksDirPath)
+	}
+	return filepath.Clean(hooksDirPath), nil
+}
+
+// RootDirPath returns the root of the git repository that contains dirPath,
+// which must be absolute.
+//
+// Symlinks in dirPath are preserved.
+func RootDirPath(dirPath string) (string, error) {
+	return getAbsRootDirPath(dirPath)
+}
+
+func getAbsRootDirPath(dirPath string) (string, error) {
+	if !filepath.IsAbs(dirPath) {
+		return "", fmt.Errorf("%s is not an absolute path", dirPath)
+	}
+	return getRootDirPath(dirPath)
+}
+
+// getRootDirPath returns the root of the git repository that contains dirPath.
+func getRootDirPath(dirPath string) (string, error) {
+	// --show-toplevel resolves symlinks, so instead strip the path of
+	// dirPath relative to the root from dirPath
+	prefix, err := run(dirPath, "rev-parse", "--show-prefix")
+	if err != nil {
+		return "", err
+	}
+	rootDirPath := filepath.Clean(dirPath)
+	prefix = strings.TrimSuffix(strings.TrimSpace(prefix), "/")
+	if prefix == "" {
+		return rootDirPath, nil
+	}
+	for range strings.Split(prefix, "/") {
+		rootDirPath = filepath.Dir(rootDirPath)
+	}
+	return rootDirPath, nil
+}
+
+func run(dirPath string, args ...string) (string, error) {
+	stdout := bytes.NewBuffer(nil)
+	stderr := bytes.NewBuffer(nil)
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dirPath
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		if output := strings.TrimSpace(stderr.String()); output != "" {
+			return "", fmt.Errorf("git %s: %s", strings.Join(args, " "), output)
+		}
+		return "", fmt.Errorf("git %s: %v", strings.Join(args, " "), err)
+	}
+	return stdout.String(), nil
+}
+
+func getPathspecs(pathspecs []string) []string {
+	if len(pathspecs) == 0 {
+		return []string{"."}
+	}
+	return pathspecs
+}
+
+func splitNul(output string) []string {
+	var values []string
+	for _, value := range strings.Split(output, "\x00") {
+		if value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
@@ -0,0 +1,272 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package git
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangedFiles(t *testing.T) {
+	dirPath := newTestRepo(t)
+	defer func() {
+		_ = os.RemoveAll(dirPath)
+	}()
+	writeTestFiles(t, dirPath, map[string]string{
+		".gitignore":  "*.tmp\n",
+		"a/a.proto":   "",
+		"a/b/b.proto": "",
+		"c.proto":     "",
+		"d.proto":     "",
+	})
+	runTestGit(t, dirPath, "add", ".")
+	runTestGit(t, dirPath, "commit", "-m", "first")
+	writeTestFiles(t, dirPath, map[string]string{
+		"a/a.proto": "syntax = \"proto3\";\n",
+	})
+	runTestGit(t, dirPath, "commit", "-a", "-m", "second")
+	writeTestFiles(t, dirPath, map[string]string{
+		"a/b/b.proto": "syntax = \"proto3\";\n",
+		"e.proto":     "",
+		"f.tmp":       "",
+	})
+	require.NoError(t, os.Remove(filepath.Join(dirPath, "c.proto")))
+
+	filePaths, err := ChangedFiles(dirPath, "HEAD~1")
+	require.NoError(t, err)
+	sort.Strings(filePaths)
+	assert.Equal(
+		t,
+		[]string{
+			filepath.Join(dirPath, "a", "a.proto"),
+			filepath.Join(dirPath, "a", "b", "b.proto"),
+			filepath.Join(dirPath, "c.proto"),
+			filepath.Join(dirPath, "e.proto"),
+		},
+		filePaths,
+	)
+
+	filePaths, err = ChangedFiles(filepath.Join(dirPath, "a", "b"), "HEAD")
+	require.NoError(t, err)
+	sort.Strings(filePaths)
+	assert.Equal(
+		t,
+		[]string{
+			filepath.Join(dirPath, "a", "b", "b.proto"),
+			filepath.Join(dirPath, "c.proto"),
+			filepath.Join(dirPath, "e.proto"),
+		},
+		filePaths,
+	)
+}
+
+func TestChangedFilesErrors(t *testing.T) {
+	dirPath := newTestRepo(t)
+	defer func() {
+		_ = os.RemoveAll(dirPath)
+	}()
+	writeTestFiles(t, dirPath, map[string]string{"a.proto": ""})
+	runTestGit(t, dirPath, "add", ".")
+	runTestGit(t, dirPath, "commit", "-m", "first")
+
+	_, err := ChangedFiles(dirPath, "does-not-exist")
+	assert.Error(t, err)
+	_, err = ChangedFiles("relative", "HEAD")
+	assert.Error(t, err)
+}
+
+func TestStagedFiles(t *testing.T) {
+	dirPath := newTestRepo(t)
+	defer func() {
+		_ = os.RemoveAll(dirPath)
+	}()
+	writeTestFiles(t, dirPath, map[string]string{
+		"a/a.proto":   "",
+		"a/b/b.proto": "",
+		"c.proto":     "",
+		"d.proto":     "",
+	})
+	runTestGit(t, dirPath, "add", ".")
+	runTestGit(t, dirPath, "commit", "-m", "first")
+	writeTestFiles(t, dirPath, map[string]string{
+		"a/a.proto":   "staged\n",
+		"a/b/b.proto": "staged\n",
+		"e.proto":     "staged\n",
+		"e.txt":       "staged\n",
+		"f.proto":     "untracked\n",
+	})
+	runTestGit(t, dirPath, "add", "a/a.proto", "a/b/b.proto", "e.proto", "e.txt")
+	runTestGit(t, dirPath, "rm", "-q", "c.proto")
+	// partially staged and unstaged changes
+	writeTestFiles(t, dirPath, map[string]string{
+		"a/a.proto": "unstaged\n",
+		"d.proto":   "unstaged\n",
+	})
+	require.NoError(t, os.Remove(filepath.Join(dirPath, "e.proto")))
+
+	filePaths, err := StagedFiles(dirPath, "*.proto")
+	require.NoError(t, err)
+	sort.Strings(filePaths)
+	assert.Equal(
+		t,
+		[]string{
+			filepath.Join(dirPath, "a", "a.proto"),
+			filepath.Join(dirPath, "a", "b", "b.proto"),
+			filepath.Join(dirPath, "e.proto"),
+		},
+		filePaths,
+	)
+	filePaths, err = StagedFiles(filepath.Join(dirPath, "a", "b"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(dirPath, "a", "b", "b.proto")}, filePaths)
+
+	filePathToData, err := StagedContents(dirPath, "*.proto")
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		map[string][]byte{
+			filepath.Join(dirPath, "a", "a.proto"): []byte("staged\n"),
+			filepath.Join(dirPath, "d.proto"):      []byte(""),
+			filepath.Join(dirPath, "e.proto"):      []byte("staged\n"),
+		},
+		filePathToData,
+	)
+	filePathToData, err = StagedContents(filepath.Join(dirPath, "a"))
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		map[string][]byte{
+			filepath.Join(dirPath, "a", "a.proto"): []byte("staged\n"),
+		},
+		filePathToData,
+	)
+
+	_, err = StagedFiles("relative")
+	assert.Error(t, err)
+	_, err = StagedContents("relative")
+	assert.Error(t, err)
+}
+
+func TestUntrackedFiles(t *testing.T) {
+	dirPath := newTestRepo(t)
+	defer func() {
+		_ = os.RemoveAll(dirPath)
+	}()
+	writeTestFiles(t, dirPath, map[string]string{
+		".gitignore": "*.tmp.proto\n",
+		"a/a.proto":  "",
+	})
+	runTestGit(t, dirPath, "add", ".")
+	runTestGit(t, dirPath, "commit", "-m", "first")
+	writeTestFiles(t, dirPath, map[string]string{
+		"a/a.proto":     "unstaged\n",
+		"a/b/b.proto":   "untracked\n",
+		"a/c.txt":       "untracked\n",
+		"a/d.tmp.proto": "ignored\n",
+		"e.proto":       "staged\n",
+		"f.proto":       "untracked\n",
+	})
+	runTestGit(t, dirPath, "add", "e.proto")
+
+	filePaths, err := UntrackedFiles(dirPath, "*.proto")
+	require.NoError(t, err)
+	sort.Strings(filePaths)
+	assert.Equal(
+		t,
+		[]string{
+			filepath.Join(dirPath, "a", "b", "b.proto"),
+			filepath.Join(dirPath, "f.proto"),
+		},
+		filePaths,
+	)
+	filePaths, err = UntrackedFiles(filepath.Join(dirPath, "a"))
+	require.NoError(t, err)
+	sort.Strings(filePaths)
+	assert.Equal(
+		t,
+		[]string{
+			filepath.Join(dirPath, "a", "b", "b.proto"),
+			filepath.Join(dirPath, "a", "c.txt"),
+		},
+		filePaths,
+	)
+
+	_, err = UntrackedFiles("relative")
+	assert.Error(t, err)
+}
+
+func TestHooksDirPath(t *testing.T) {
+	dirPath := newTestRepo(t)
+	defer func() {
+		_ = os.RemoveAll(dirPath)
+	}()
+	writeTestFiles(t, dirPath, map[string]string{"a/a.proto": ""})
+
+	hooksDirPath, err := HooksDirPath(filepath.Join(dirPath, "a"))
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dirPath, ".git", "hooks"), hooksDirPath)
+	rootDirPath, err := RootDirPath(filepath.Join(dirPath, "a"))
+	require.NoError(t, err)
+	assert.Equal(t, dirPath, rootDirPath)
+
+	runTestGit(t, dirPath, "config", "core.hooksPath", "githooks")
+	hooksDirPath, err = HooksDirPath(dirPath)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dirPath, "githooks"), hooksDirPath)
+}
+
+func newTestRepo(t *testing.T) string {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	// resolve symlinks such as /tmp on macOS so paths can be compared
+	dirPath, err = filepath.EvalSymlinks(dirPath)
+	require.NoError(t, err)
+	runTestGit(t, dirPath, "init", "-q")
+	return dirPath
+}
+
+func writeTestFiles(t *testing.T, dirPath string, relFilePathToData map[string]string) {
+	for relFilePath, data := range relFilePathToData {
+		filePath := filepath.Join(dirPath, filepath.FromSlash(relFilePath))
+		require.NoError(t, os.MkdirAll(filepath.Dir(filePath), 0755))
+		require.NoError(t, ioutil.WriteFile(filePath, []byte(data), 0644))
+	}
+}
+
+func runTestGit(t *testing.T, dirPath string, args ...string) {
+	cmd := exec.Command(
+		"git",
+		append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com", "-c", "commit.gpgsign=false"}, args...)...,
+	)
+	cmd.Dir = dirPath
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(output))
+}
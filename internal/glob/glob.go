@@ -0,0 +1,72 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package glob contains the glob matching used for file discovery.
+//
+// This is a leaf package so that both the settings and file packages can use it.
+package glob
+
+import (
+	"path"
+	"strings"
+)
+
+// IsGlob returns true if the pattern contains any of the glob metacharacters "*?[".
+func IsGlob(pattern string) bool {
+	return strings.ContainsAny(pattern, "*?[")
+}
+
+// Match returns true if the slash-separated relative path matches the pattern.
+//
+// Patterns are matched per path element using path.Match, with the addition
+// that an element of "**" matches zero or more path elements. For example,
+// "**/testdata/**" matches "testdata", "a/testdata", and "a/testdata/b/c.proto".
+// Malformed patterns never match.
+func Match(pattern string, relPath string) bool {
+	return matchElements(strings.Split(pattern, "/"), strings.Split(relPath, "/"))
+}
+
+func matchElements(patternElements []string, pathElements []string) bool {
+	for len(patternElements) > 0 {
+		patternElement := patternElements[0]
+		if patternElement == "**" {
+			// collapse consecutive "**" elements
+			for len(patternElements) > 0 && patternElements[0] == "**" {
+				patternElements = patternElements[1:]
+			}
+			for i := 0; i <= len(pathElements); i++ {
+				if matchElements(patternElements, pathElements[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(pathElements) == 0 {
+			return false
+		}
+		matched, err := path.Match(patternElement, pathElements[0])
+		if err != nil || !matched {
+			return false
+		}
+		patternElements = patternElements[1:]
+		pathElements = pathElements[1:]
+	}
+	return len(pathElements) == 0
+}
@@ -0,0 +1,59 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package glob
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatch(t *testing.T) {
+	testMatch(t, true, "foo.proto", "foo.proto")
+	testMatch(t, false, "foo.proto", "a/foo.proto")
+	testMatch(t, true, "*.proto", "foo.proto")
+	testMatch(t, false, "*.proto", "a/foo.proto")
+	testMatch(t, true, "a/*/c.proto", "a/b/c.proto")
+	testMatch(t, false, "a/*/c.proto", "a/b/b/c.proto")
+	testMatch(t, true, "**/testdata/**", "testdata")
+	testMatch(t, true, "**/testdata/**", "a/testdata")
+	testMatch(t, true, "**/testdata/**", "a/b/testdata/c/d.proto")
+	testMatch(t, false, "**/testdata/**", "a/testdatas/c.proto")
+	testMatch(t, true, "**/*.proto", "a/b/c.proto")
+	testMatch(t, true, "**/*.proto", "c.proto")
+	testMatch(t, true, "a/**/c.proto", "a/c.proto")
+	testMatch(t, true, "a/**/**/c.proto", "a/b/d/c.proto")
+	testMatch(t, true, "a/?.proto", "a/b.proto")
+	testMatch(t, true, "a/[bc].proto", "a/c.proto")
+	testMatch(t, false, "a/[bc].proto", "a/d.proto")
+	testMatch(t, false, "a/[b.proto", "a/[b.proto")
+}
+
+func TestIsGlob(t *testing.T) {
+	assert.True(t, IsGlob("**/testdata"))
+	assert.True(t, IsGlob("a/?.proto"))
+	assert.True(t, IsGlob("a/[bc].proto"))
+	assert.False(t, IsGlob("a/b/c.proto"))
+}
+
+func testMatch(t *testing.T, expected bool, pattern string, relPath string) {
+	assert.Equal(t, expected, Match(pattern, relPath), "%s %s", pattern, relPath)
+}
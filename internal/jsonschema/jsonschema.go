@@ -0,0 +1,398 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package jsonschema generates JSON Schemas for messages in a PackageSet.
+//
+// The schemas validate the JSON mapping of the messages as produced by
+// protojson: fields use their JSON names, 64-bit integers are strings,
+// enums are their value names, the fields of each oneof are mutually
+// exclusive, and maps are objects. The Google Well-Known Types are
+// mapped to their JSON equivalents.
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+
+	reflectv1 "github.com/uber/prototool/internal/reflect/gen/uber/proto/reflect/v1"
+)
+
+// SchemaVersion is the JSON Schema draft generated schemas conform to.
+const SchemaVersion = "https://json-schema.org/draft/2020-12/schema"
+
+// Generate returns the JSON Schema for the message with the given
+// fully-qualified name, without the leading '.'.
+//
+// Every message and enum the message references, including itself, is
+// defined in $defs by its fully-qualified name, and the root schema
+// references the message, so recursive messages are supported. The
+// PackageSet must include all dependencies of the message.
+func Generate(packageSet *reflectv1.PackageSet, messageName string) ([]byte, error) {
+	builder := newSchemaBuilder(packageSet)
+	if _, ok := builder.messages[messageName]; !ok {
+		return nil, fmt.Errorf("unknown message %s", messageName)
+	}
+	rootSchema, err := builder.getMessageSchema(messageName)
+	if err != nil {
+		return nil, err
+	}
+	rootSchema.Schema = SchemaVersion
+	rootSchema.Title = messageName
+	if len(builder.defs) > 0 {
+		rootSchema.Defs = builder.defs
+	}
+	buffer := bytes.NewBuffer(nil)
+	encoder := json.NewEncoder(buffer)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(rootSchema); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+type schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	Ref                  string             `json:"$ref,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Deprecated           bool               `json:"deprecated,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	ContentEncoding      string             `json:"contentEncoding,omitempty"`
+	Minimum              *int64             `json:"minimum,omitempty"`
+	Maximum              *int64             `json:"maximum,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Items                *schema            `json:"items,omitempty"`
+	Properties           map[string]*schema `json:"properties,omitempty"`
+	PropertyNames        *schema            `json:"propertyNames,omitempty"`
+	AdditionalProperties interface{}        `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	OneOf                []*schema          `json:"oneOf,omitempty"`
+	AnyOf                []*schema          `json:"anyOf,omitempty"`
+	AllOf                []*schema          `json:"allOf,omitempty"`
+	Not                  *schema            `json:"not,omitempty"`
+	Defs                 map[string]*schema `json:"$defs,omitempty"`
+}
+
+// schemaBuilder collects the definitions of all messages and enums
+// referenced from the root message.
+type schemaBuilder struct {
+	messages map[string]*reflectv1.Message
+	enums    map[string]*reflectv1.Enum
+	defs     map[string]*schema
+}
+
+func newSchemaBuilder(packageSet *reflectv1.PackageSet) *schemaBuilder {
+	builder := &schemaBuilder{
+		messages: make(map[string]*reflectv1.Message),
+		enums:    make(map[string]*reflectv1.Enum),
+		defs:     make(map[string]*schema),
+	}
+	for _, pkg := range packageSet.GetPackages() {
+		builder.addMessages(pkg.GetName(), pkg.GetMessages())
+		builder.addEnums(pkg.GetName(), pkg.GetEnums())
+	}
+	return builder
+}
+
+func (b *schemaBuilder) addMessages(prefix string, messages []*reflectv1.Message) {
+	for _, message := range messages {
+		fullName := prefix + "." + message.GetName()
+		b.messages[fullName] = message
+		b.addMessages(fullName, message.GetNestedMessages())
+		b.addEnums(fullName, message.GetNestedEnums())
+	}
+}
+
+func (b *schemaBuilder) addEnums(prefix string, enums []*reflectv1.Enum) {
+	for _, enum := range enums {
+		b.enums[prefix+"."+enum.GetName()] = enum
+	}
+}
+
+// getMessageSchema returns the schema of the message, which is a
+// reference to a definition unless the message is a Well-Known Type.
+func (b *schemaBuilder) getMessageSchema(fullName string) (*schema, error) {
+	if wktSchema := getWKTSchema(fullName); wktSchema != nil {
+		return wktSchema, nil
+	}
+	message, ok := b.messages[fullName]
+	if !ok {
+		return nil, fmt.Errorf("unknown message %s", fullName)
+	}
+	if _, ok := b.defs[fullName]; !ok {
+		// add before populating so recursive messages terminate
+		messageSchema := &schema{
+			Type:                 "object",
+			Description:          getComments(message.GetLeadingComments(), message.GetTrailingComments()),
+			Deprecated:           message.GetDeprecated(),
+			Properties:           make(map[string]*schema),
+			AdditionalProperties: false,
+		}
+		b.defs[fullName] = messageSchema
+		if err := b.populateMessageSchema(messageSchema, message); err != nil {
+			return nil, err
+		}
+	}
+	return newRefSchema(fullName), nil
+}
+
+func (b *schemaBuilder) populateMessageSchema(messageSchema *schema, message *reflectv1.Message) error {
+	numberToJSONName := make(map[int32]string, len(message.GetMessageFields()))
+	for _, field := range message.GetMessageFields() {
+		fieldSchema, err := b.getFieldSchema(field)
+		if err != nil {
+			return err
+		}
+		if description := getComments(field.GetLeadingComments(), field.GetTrailingComments()); description != "" || field.GetDeprecated() {
+			// wrap so that shared schemas such as references are not modified
+			fieldSchema = &schema{
+				AllOf:       []*schema{fieldSchema},
+				Description: description,
+				Deprecated:  field.GetDeprecated(),
+			}
+		}
+		messageSchema.Properties[field.GetJsonName()] = fieldSchema
+		numberToJSONName[field.GetNumber()] = field.GetJsonName()
+		if field.GetLabel() == reflectv1.MessageField_LABEL_REQUIRED {
+			messageSchema.Required = append(messageSchema.Required, field.GetJsonName())
+		}
+	}
+	var oneofSchemas []*schema
+	for _, messageOneof := range message.GetMessageOneofs() {
+		// exactly one of the fields, or none of them
+		oneofSchema := &schema{}
+		none := &schema{}
+		for _, fieldNumber := range messageOneof.GetFieldNumbers() {
+			required := &schema{Required: []string{numberToJSONName[fieldNumber]}}
+			oneofSchema.OneOf = append(oneofSchema.OneOf, required)
+			none.AnyOf = append(none.AnyOf, required)
+		}
+		oneofSchema.OneOf = append(oneofSchema.OneOf, &schema{Not: none})
+		oneofSchemas = append(oneofSchemas, oneofSchema)
+	}
+	switch len(oneofSchemas) {
+	case 0:
+	case 1:
+		messageSchema.OneOf = oneofSchemas[0].OneOf
+	default:
+		messageSchema.AllOf = oneofSchemas
+	}
+	return nil
+}
+
+func (b *schemaBuilder) getFieldSchema(field *reflectv1.MessageField) (*schema, error) {
+	if field.GetLabel() != reflectv1.MessageField_LABEL_REPEATED {
+		return b.getSingularFieldSchema(field)
+	}
+	if field.GetType() == reflectv1.MessageField_TYPE_MESSAGE {
+		if entry, ok := b.messages[field.GetTypeName()]; ok && entry.GetMapEntry() {
+			return b.getMapSchema(entry)
+		}
+	}
+	itemsSchema, err := b.getSingularFieldSchema(field)
+	if err != nil {
+		return nil, err
+	}
+	return &schema{
+		Type:  "array",
+		Items: itemsSchema,
+	}, nil
+}
+
+func (b *schemaBuilder) getMapSchema(entry *reflectv1.Message) (*schema, error) {
+	var keyField, valueField *reflectv1.MessageField
+	for _, field := range entry.GetMessageFields() {
+		switch field.GetName() {
+		case "key":
+			keyField = field
+		case "value":
+			valueField = field
+		}
+	}
+	if keyField == nil || valueField == nil {
+		return nil, fmt.Errorf("map entry %s must have a key and value field", entry.GetName())
+	}
+	valueSchema, err := b.getSingularFieldSchema(valueField)
+	if err != nil {
+		return nil, err
+	}
+	return &schema{
+		Type:                 "object",
+		PropertyNames:        getMapKeySchema(keyField.GetType()),
+		AdditionalProperties: valueSchema,
+	}, nil
+}
+
+func (b *schemaBuilder) getSingularFieldSchema(field *reflectv1.MessageField) (*schema, error) {
+	switch field.GetType() {
+	case reflectv1.MessageField_TYPE_MESSAGE, reflectv1.MessageField_TYPE_GROUP:
+		return b.getMessageSchema(field.GetTypeName())
+	case reflectv1.MessageField_TYPE_ENUM:
+		return b.getEnumSchema(field.GetTypeName())
+	default:
+		return getScalarSchema(field.GetType()), nil
+	}
+}
+
+func (b *schemaBuilder) getEnumSchema(fullName string) (*schema, error) {
+	// google.protobuf.NullValue is always null in JSON
+	if fullName == "google.protobuf.NullValue" {
+		return &schema{Type: "null"}, nil
+	}
+	enum, ok := b.enums[fullName]
+	if !ok {
+		return nil, fmt.Errorf("unknown enum %s", fullName)
+	}
+	if _, ok := b.defs[fullName]; !ok {
+		enumSchema := &schema{
+			Type:        "string",
+			Description: getComments(enum.GetLeadingComments(), enum.GetTrailingComments()),
+			Deprecated:  enum.GetDeprecated(),
+		}
+		for _, enumValue := range enum.GetEnumValues() {
+			enumSchema.Enum = append(enumSchema.Enum, enumValue.GetName())
+		}
+		b.defs[fullName] = enumSchema
+	}
+	return newRefSchema(fullName), nil
+}
+
+// getScalarSchema returns the schema of the scalar type in the protojson mapping.
+func getScalarSchema(fieldType reflectv1.MessageField_Type) *schema {
+	switch fieldType {
+	case reflectv1.MessageField_TYPE_DOUBLE, reflectv1.MessageField_TYPE_FLOAT:
+		return &schema{
+			AnyOf: []*schema{
+				{Type: "number"},
+				{Type: "string", Enum: []string{"NaN", "Infinity", "-Infinity"}},
+			},
+		}
+	case reflectv1.MessageField_TYPE_INT32,
+		reflectv1.MessageField_TYPE_SINT32,
+		reflectv1.MessageField_TYPE_SFIXED32:
+		return newIntegerSchema(math.MinInt32, math.MaxInt32)
+	case reflectv1.MessageField_TYPE_UINT32,
+		reflectv1.MessageField_TYPE_FIXED32:
+		return newIntegerSchema(0, math.MaxUint32)
+	// 64-bit integers are strings in JSON
+	case reflectv1.MessageField_TYPE_INT64,
+		reflectv1.MessageField_TYPE_SINT64,
+		reflectv1.MessageField_TYPE_SFIXED64:
+		return &schema{Type: "string", Format: "int64", Pattern: "^-?[0-9]+$"}
+	case reflectv1.MessageField_TYPE_UINT64,
+		reflectv1.MessageField_TYPE_FIXED64:
+		return &schema{Type: "string", Format: "uint64", Pattern: "^[0-9]+$"}
+	case reflectv1.MessageField_TYPE_BOOL:
+		return &schema{Type: "boolean"}
+	case reflectv1.MessageField_TYPE_BYTES:
+		return &schema{Type: "string", ContentEncoding: "base64"}
+	default:
+		return &schema{Type: "string"}
+	}
+}
+
+// getMapKeySchema returns the schema of the keys of a map, which are
+// always strings in JSON.
+func getMapKeySchema(keyType reflectv1.MessageField_Type) *schema {
+	switch keyType {
+	case reflectv1.MessageField_TYPE_BOOL:
+		return &schema{Enum: []string{"true", "false"}}
+	case reflectv1.MessageField_TYPE_STRING:
+		return nil
+	case reflectv1.MessageField_TYPE_UINT32,
+		reflectv1.MessageField_TYPE_FIXED32,
+		reflectv1.MessageField_TYPE_UINT64,
+		reflectv1.MessageField_TYPE_FIXED64:
+		return &schema{Pattern: "^[0-9]+$"}
+	default:
+		return &schema{Pattern: "^-?[0-9]+$"}
+	}
+}
+
+// getWKTSchema returns the schema of the JSON equivalent of the message
+// if it is a Well-Known Type with a special JSON mapping, or nil otherwise.
+func getWKTSchema(fullName string) *schema {
+	switch fullName {
+	case "google.protobuf.Any":
+		return &schema{
+			Type: "object",
+			Properties: map[string]*schema{
+				"@type": {Type: "string"},
+			},
+			Required: []string{"@type"},
+		}
+	case "google.protobuf.Duration":
+		return &schema{Type: "string", Pattern: `^-?[0-9]+(\.[0-9]{1,9})?s$`}
+	case "google.protobuf.Empty":
+		return &schema{Type: "object", AdditionalProperties: false}
+	case "google.protobuf.FieldMask":
+		return &schema{Type: "string"}
+	case "google.protobuf.ListValue":
+		return &schema{Type: "array"}
+	case "google.protobuf.Struct":
+		return &schema{Type: "object"}
+	case "google.protobuf.Timestamp":
+		return &schema{Type: "string", Format: "date-time"}
+	case "google.protobuf.Value":
+		return &schema{}
+	case "google.protobuf.BoolValue":
+		return getScalarSchema(reflectv1.MessageField_TYPE_BOOL)
+	case "google.protobuf.BytesValue":
+		return getScalarSchema(reflectv1.MessageField_TYPE_BYTES)
+	case "google.protobuf.DoubleValue":
+		return getScalarSchema(reflectv1.MessageField_TYPE_DOUBLE)
+	case "google.protobuf.FloatValue":
+		return getScalarSchema(reflectv1.MessageField_TYPE_FLOAT)
+	case "google.protobuf.Int32Value":
+		return getScalarSchema(reflectv1.MessageField_TYPE_INT32)
+	case "google.protobuf.Int64Value":
+		return getScalarSchema(reflectv1.MessageField_TYPE_INT64)
+	case "google.protobuf.StringValue":
+		return getScalarSchema(reflectv1.MessageField_TYPE_STRING)
+	case "google.protobuf.UInt32Value":
+		return getScalarSchema(reflectv1.MessageField_TYPE_UINT32)
+	case "google.protobuf.UInt64Value":
+		return getScalarSchema(reflectv1.MessageField_TYPE_UINT64)
+	default:
+		return nil
+	}
+}
+
+// getComments returns the leading comments, or the trailing comments if
+// there are no leading comments.
+func getComments(leadingComments string, trailingComments string) string {
+	if leadingComments != "" {
+		return leadingComments
+	}
+	return trailingComments
+}
+
+func newIntegerSchema(minimum int64, maximum int64) *schema {
+	return &schema{Type: "integer", Minimum: &minimum, Maximum: &maximum}
+}
+
+func newRefSchema(fullName string) *schema {
+	return &schema{Ref: "#/$defs/" + fullName}
+}
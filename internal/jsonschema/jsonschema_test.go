@@ -0,0 +1,222 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package jsonschema
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	reflectv1 "github.com/uber/prototool/internal/reflect/gen/uber/proto/reflect/v1"
+)
+
+func TestGenerate(t *testing.T) {
+	data, err := Generate(testPackageSet(t), "foo.v1.Foo")
+	require.NoError(t, err)
+	assert.Equal(t, testFooSchema, string(data))
+}
+
+func TestGenerateErrors(t *testing.T) {
+	_, err := Generate(testPackageSet(t), "foo.v1.Bar")
+	assert.EqualError(t, err, "unknown message foo.v1.Bar")
+
+	packageSet := testPackageSet(t)
+	// drop bar.v1, which foo.v1.Foo references
+	packageSet.Packages = packageSet.Packages[1:]
+	_, err = Generate(packageSet, "foo.v1.Foo")
+	assert.EqualError(t, err, "unknown message bar.v1.Bar")
+}
+
+func testPackageSet(t *testing.T) *reflectv1.PackageSet {
+	packageSet := &reflectv1.PackageSet{}
+	require.NoError(t, jsonpb.UnmarshalString(testPackageSetJSON, packageSet))
+	return packageSet
+}
+
+const testPackageSetJSON = `
+{
+  "packages": [
+    {
+      "name": "bar.v1",
+      "messages": [
+        {
+          "name": "Bar",
+          "messageFields": [
+            {"name": "id", "jsonName": "id", "number": 1, "label": "LABEL_REQUIRED", "type": "TYPE_UINT64"}
+          ]
+        }
+      ]
+    },
+    {
+      "name": "foo.v1",
+      "dependencyNames": ["bar.v1", "google.protobuf"],
+      "enums": [
+        {
+          "name": "Hello",
+          "enumValues": [{"name": "HELLO_INVALID"}, {"name": "HELLO_WORLD", "number": 1}]
+        }
+      ],
+      "messages": [
+        {
+          "name": "Foo",
+          "leadingComments": "Foo is a foo.",
+          "messageFields": [
+            {"name": "big_count", "jsonName": "bigCount", "number": 1, "label": "LABEL_OPTIONAL", "type": "TYPE_INT64", "trailingComments": "big.", "deprecated": true},
+            {"name": "count", "jsonName": "count", "number": 2, "label": "LABEL_OPTIONAL", "type": "TYPE_INT32"},
+            {"name": "hello", "jsonName": "hello", "number": 3, "label": "LABEL_OPTIONAL", "type": "TYPE_ENUM", "typeName": "foo.v1.Hello"},
+            {"name": "bar", "jsonName": "bar", "number": 4, "label": "LABEL_OPTIONAL", "type": "TYPE_MESSAGE", "typeName": "bar.v1.Bar"},
+            {"name": "labels", "jsonName": "labels", "number": 5, "label": "LABEL_REPEATED", "type": "TYPE_MESSAGE", "typeName": "foo.v1.Foo.LabelsEntry"},
+            {"name": "children", "jsonName": "children", "number": 6, "label": "LABEL_REPEATED", "type": "TYPE_MESSAGE", "typeName": "foo.v1.Foo"},
+            {"name": "create_time", "jsonName": "createTime", "number": 7, "label": "LABEL_OPTIONAL", "type": "TYPE_MESSAGE", "typeName": "google.protobuf.Timestamp"},
+            {"name": "data", "jsonName": "data", "number": 8, "label": "LABEL_OPTIONAL", "type": "TYPE_BYTES"}
+          ],
+          "messageOneofs": [{"name": "value", "fieldNumbers": [3, 4]}],
+          "nestedMessages": [
+            {
+              "name": "LabelsEntry",
+              "mapEntry": true,
+              "messageFields": [
+                {"name": "key", "jsonName": "key", "number": 1, "label": "LABEL_OPTIONAL", "type": "TYPE_INT32"},
+                {"name": "value", "jsonName": "value", "number": 2, "label": "LABEL_OPTIONAL", "type": "TYPE_STRING"}
+              ]
+            }
+          ]
+        }
+      ]
+    },
+    {
+      "name": "google.protobuf",
+      "messages": [{"name": "Timestamp"}]
+    }
+  ]
+}
+`
+
+const testFooSchema = `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "$ref": "#/$defs/foo.v1.Foo",
+  "title": "foo.v1.Foo",
+  "$defs": {
+    "bar.v1.Bar": {
+      "type": "object",
+      "properties": {
+        "id": {
+          "type": "string",
+          "format": "uint64",
+          "pattern": "^[0-9]+$"
+        }
+      },
+      "additionalProperties": false,
+      "required": [
+        "id"
+      ]
+    },
+    "foo.v1.Foo": {
+      "description": "Foo is a foo.",
+      "type": "object",
+      "properties": {
+        "bar": {
+          "$ref": "#/$defs/bar.v1.Bar"
+        },
+        "bigCount": {
+          "description": "big.",
+          "deprecated": true,
+          "allOf": [
+            {
+              "type": "string",
+              "format": "int64",
+              "pattern": "^-?[0-9]+$"
+            }
+          ]
+        },
+        "children": {
+          "type": "array",
+          "items": {
+            "$ref": "#/$defs/foo.v1.Foo"
+          }
+        },
+        "count": {
+          "type": "integer",
+          "minimum": -2147483648,
+          "maximum": 2147483647
+        },
+        "createTime": {
+          "type": "string",
+          "format": "date-time"
+        },
+        "data": {
+          "type": "string",
+          "contentEncoding": "base64"
+        },
+        "hello": {
+          "$ref": "#/$defs/foo.v1.Hello"
+        },
+        "labels": {
+          "type": "object",
+          "propertyNames": {
+            "pattern": "^-?[0-9]+$"
+          },
+          "additionalProperties": {
+            "type": "string"
+          }
+        }
+      },
+      "additionalProperties": false,
+      "oneOf": [
+        {
+          "required": [
+            "hello"
+          ]
+        },
+        {
+          "required": [
+            "bar"
+          ]
+        },
+        {
+          "not": {
+            "anyOf": [
+              {
+                "required": [
+                  "hello"
+                ]
+              },
+              {
+                "required": [
+                  "bar"
+                ]
+              }
+            ]
+          }
+        }
+      ]
+    },
+    "foo.v1.Hello": {
+      "type": "string",
+      "enum": [
+        "HELLO_INVALID",
+        "HELLO_WORLD"
+      ]
+    }
+  }
+}
+`
@@ -0,0 +1,293 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package lsp
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+// Field numbers within descriptor.proto used to build source info paths.
+const (
+	fileMessageTypeTag    = 4
+	fileEnumTypeTag       = 5
+	fileServiceTag        = 6
+	fileExtensionTag      = 7
+	messageFieldTag       = 2
+	messageNestedTypeTag  = 3
+	messageEnumTypeTag    = 4
+	messageExtensionTag   = 6
+	fieldNameTag          = 1
+	fieldExtendeeTag      = 2
+	fieldTypeNameTag      = 6
+	enumValueTag          = 2
+	serviceMethodTag      = 2
+	methodInputTypeTag    = 2
+	methodOutputTypeTag   = 3
+	nameTag               = 1
+	sourceCodeInfoPathSep = ","
+)
+
+// definition is a definition of a named element in a file.
+type definition struct {
+	// The fully-qualified name without a leading dot.
+	//
+	// Enum values are scoped within their enum, unlike in Protobuf, so
+	// that they are unique.
+	FullName string
+	// The keyword used to declare this, for example "message".
+	Keyword string
+	// The LSP SymbolKind.
+	Kind int
+	// The range of the name.
+	NameRange textRange
+	// The range of the entire declaration.
+	Range textRange
+	// The leading and trailing comments.
+	Comments string
+}
+
+// reference is a reference to a message or enum type by name.
+type reference struct {
+	// The fully-qualified name without a leading dot.
+	FullName string
+	// The range of the type name.
+	Range textRange
+}
+
+// fileIndex contains the definitions and references within a single file.
+type fileIndex struct {
+	FilePath    string
+	Package     string
+	Definitions []*definition
+	References  []*reference
+
+	fullNameToDefinition map[string]*definition
+}
+
+func newFileIndex(filePath string, fileDescriptorProto *descriptor.FileDescriptorProto) *fileIndex {
+	builder := &fileIndexBuilder{
+		fileIndex: &fileIndex{
+			FilePath:             filePath,
+			Package:              fileDescriptorProto.GetPackage(),
+			fullNameToDefinition: make(map[string]*definition),
+		},
+		pathToLocation: make(map[string]*descriptor.SourceCodeInfo_Location),
+	}
+	for _, location := range fileDescriptorProto.GetSourceCodeInfo().GetLocation() {
+		key := getPathKey(location.GetPath())
+		// the first location is the one for the declaration
+		if _, ok := builder.pathToLocation[key]; !ok {
+			builder.pathToLocation[key] = location
+		}
+	}
+	builder.addFile(fileDescriptorProto)
+	return builder.fileIndex
+}
+
+// GetDefinition gets the definition in this file for the full name, or nil.
+func (f *fileIndex) GetDefinition(fullName string) *definition {
+	return f.fullNameToDefinition[fullName]
+}
+
+// GetFullNameAt returns the full name of the definition or reference at
+// the given position, or "" if there is none.
+func (f *fileIndex) GetFullNameAt(pos position) (string, textRange) {
+	for _, reference := range f.References {
+		if rangeContains(reference.Range, pos) {
+			return reference.FullName, reference.Range
+		}
+	}
+	for _, definition := range f.Definitions {
+		if rangeContains(definition.NameRange, pos) {
+			return definition.FullName, definition.NameRange
+		}
+	}
+	return "", textRange{}
+}
+
+type fileIndexBuilder struct {
+	fileIndex      *fileIndex
+	pathToLocation map[string]*descriptor.SourceCodeInfo_Location
+}
+
+func (b *fileIndexBuilder) addFile(fileDescriptorProto *descriptor.FileDescriptorProto) {
+	scope := fileDescriptorProto.GetPackage()
+	for i, descriptorProto := range fileDescriptorProto.GetMessageType() {
+		b.addMessage(scope, descriptorProto, []int32{fileMessageTypeTag, int32(i)})
+	}
+	for i, enumDescriptorProto := range fileDescriptorProto.GetEnumType() {
+		b.addEnum(scope, enumDescriptorProto, []int32{fileEnumTypeTag, int32(i)})
+	}
+	for i, serviceDescriptorProto := range fileDescriptorProto.GetService() {
+		b.addService(scope, serviceDescriptorProto, []int32{fileServiceTag, int32(i)})
+	}
+	for i, fieldDescriptorProto := range fileDescriptorProto.GetExtension() {
+		b.addFieldReferences(fieldDescriptorProto, []int32{fileExtensionTag, int32(i)})
+	}
+}
+
+func (b *fileIndexBuilder) addMessage(scope string, descriptorProto *descriptor.DescriptorProto, path []int32) {
+	fullName := joinName(scope, descriptorProto.GetName())
+	b.addDefinition(fullName, "message", symbolKindStruct, path)
+	for i, fieldDescriptorProto := range descriptorProto.GetField() {
+		fieldPath := appendPath(path, messageFieldTag, int32(i))
+		b.addDefinition(joinName(fullName, fieldDescriptorProto.GetName()), "field", symbolKindField, fieldPath)
+		b.addFieldReferences(fieldDescriptorProto, fieldPath)
+	}
+	for i, fieldDescriptorProto := range descriptorProto.GetExtension() {
+		b.addFieldReferences(fieldDescriptorProto, appendPath(path, messageExtensionTag, int32(i)))
+	}
+	for i, nestedDescriptorProto := range descriptorProto.GetNestedType() {
+		// map entries are synthesized and have no source info
+		if nestedDescriptorProto.GetOptions().GetMapEntry() {
+			for j, fieldDescriptorProto := range nestedDescriptorProto.GetField() {
+				b.addFieldReferences(fieldDescriptorProto, appendPath(path, messageNestedTypeTag, int32(i), messageFieldTag, int32(j)))
+			}
+			continue
+		}
+		b.addMessage(fullName, nestedDescriptorProto, appendPath(path, messageNestedTypeTag, int32(i)))
+	}
+	for i, enumDescriptorProto := range descriptorProto.GetEnumType() {
+		b.addEnum(fullName, enumDescriptorProto, appendPath(path, messageEnumTypeTag, int32(i)))
+	}
+}
+
+func (b *fileIndexBuilder) addEnum(scope string, enumDescriptorProto *descriptor.EnumDescriptorProto, path []int32) {
+	fullName := joinName(scope, enumDescriptorProto.GetName())
+	b.addDefinition(fullName, "enum", symbolKindEnum, path)
+	for i, enumValueDescriptorProto := range enumDescriptorProto.GetValue() {
+		b.addDefinition(joinName(fullName, enumValueDescriptorProto.GetName()), "enum value", symbolKindEnumMember, appendPath(path, enumValueTag, int32(i)))
+	}
+}
+
+func (b *fileIndexBuilder) addService(scope string, serviceDescriptorProto *descriptor.ServiceDescriptorProto, path []int32) {
+	fullName := joinName(scope, serviceDescriptorProto.GetName())
+	b.addDefinition(fullName, "service", symbolKindInterface, path)
+	for i, methodDescriptorProto := range serviceDescriptorProto.GetMethod() {
+		methodPath := appendPath(path, serviceMethodTag, int32(i))
+		b.addDefinition(joinName(fullName, methodDescriptorProto.GetName()), "rpc", symbolKindMethod, methodPath)
+		b.addReference(methodDescriptorProto.GetInputType(), appendPath(methodPath, methodInputTypeTag))
+		b.addReference(methodDescriptorProto.GetOutputType(), appendPath(methodPath, methodOutputTypeTag))
+	}
+}
+
+func (b *fileIndexBuilder) addFieldReferences(fieldDescriptorProto *descriptor.FieldDescriptorProto, path []int32) {
+	if typeName := fieldDescriptorProto.GetTypeName(); typeName != "" {
+		b.addReference(typeName, appendPath(path, fieldTypeNameTag))
+	}
+	if extendee := fieldDescriptorProto.GetExtendee(); extendee != "" {
+		b.addReference(extendee, appendPath(path, fieldExtendeeTag))
+	}
+}
+
+func (b *fileIndexBuilder) addDefinition(fullName string, keyword string, kind int, path []int32) {
+	location, ok := b.pathToLocation[getPathKey(path)]
+	if !ok {
+		return
+	}
+	nameRange := getRange(location)
+	if nameLocation, ok := b.pathToLocation[getPathKey(appendPath(path, nameTag))]; ok {
+		nameRange = getRange(nameLocation)
+	}
+	definition := &definition{
+		FullName:  fullName,
+		Keyword:   keyword,
+		Kind:      kind,
+		NameRange: nameRange,
+		Range:     getRange(location),
+		Comments:  getComments(location),
+	}
+	b.fileIndex.Definitions = append(b.fileIndex.Definitions, definition)
+	b.fileIndex.fullNameToDefinition[fullName] = definition
+}
+
+func (b *fileIndexBuilder) addReference(typeName string, path []int32) {
+	location, ok := b.pathToLocation[getPathKey(path)]
+	if !ok {
+		return
+	}
+	b.fileIndex.References = append(b.fileIndex.References, &reference{
+		FullName: strings.TrimPrefix(typeName, "."),
+		Range:    getRange(location),
+	})
+}
+
+func getRange(location *descriptor.SourceCodeInfo_Location) textRange {
+	span := location.GetSpan()
+	switch len(span) {
+	case 3:
+		return textRange{
+			Start: position{Line: int(span[0]), Character: int(span[1])},
+			End:   position{Line: int(span[0]), Character: int(span[2])},
+		}
+	case 4:
+		return textRange{
+			Start: position{Line: int(span[0]), Character: int(span[1])},
+			End:   position{Line: int(span[2]), Character: int(span[3])},
+		}
+	default:
+		return textRange{}
+	}
+}
+
+func getComments(location *descriptor.SourceCodeInfo_Location) string {
+	var comments []string
+	for _, comment := range []string{location.GetLeadingComments(), location.GetTrailingComments()} {
+		if comment = strings.TrimSpace(comment); comment != "" {
+			comments = append(comments, comment)
+		}
+	}
+	return strings.Join(comments, "\n\n")
+}
+
+func getPathKey(path []int32) string {
+	var builder strings.Builder
+	for i, element := range path {
+		if i > 0 {
+			builder.WriteString(sourceCodeInfoPathSep)
+		}
+		builder.WriteString(strconv.Itoa(int(element)))
+	}
+	return builder.String()
+}
+
+func appendPath(path []int32, elements ...int32) []int32 {
+	newPath := make([]int32, 0, len(path)+len(elements))
+	return append(append(newPath, path...), elements...)
+}
+
+func joinName(scope string, name string) string {
+	if scope == "" {
+		return name
+	}
+	return scope + "." + name
+}
+
+func rangeContains(r textRange, pos position) bool {
+	return !positionLess(pos, r.Start) && !positionLess(r.End, pos)
+}
+
+func positionLess(a position, b position) bool {
+	return a.Line < b.Line || (a.Line == b.Line && a.Character < b.Character)
+}
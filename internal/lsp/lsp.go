@@ -0,0 +1,80 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package lsp implements a Language Server Protocol server for Protobuf files.
+//
+// The server speaks JSON-RPC 2.0 with Content-Length framing, as used by
+// LSP over stdio. Files are compiled with protoc when they are opened or
+// saved, and compile failures are published as diagnostics. Go-to-definition,
+// find-references, and hover are answered from the source info of the
+// resulting FileDescriptorSets, and document symbols come from the
+// reflect.PackageSet of the compiled files.
+//
+// Only saved file contents are used, changes that are not yet saved are ignored.
+//
+// See https://microsoft.github.io/language-server-protocol/specification
+package lsp
+
+import (
+	"context"
+	"io"
+
+	"github.com/uber/prototool/internal/file"
+	"github.com/uber/prototool/internal/protoc"
+	"go.uber.org/zap"
+)
+
+// Server is a Language Server Protocol server.
+type Server interface {
+	// Serve reads JSON-RPC messages from reader and writes the responses
+	// and notifications to writer.
+	//
+	// Returns nil when the exit notification is received or reader is
+	// at EOF, and an error if a message could not be read or written.
+	// The context is used for compiles, and if it is cancelled, the
+	// context error is returned.
+	Serve(ctx context.Context, reader io.Reader, writer io.Writer) error
+}
+
+// ServerOption is an option for a new Server.
+type ServerOption func(*server)
+
+// ServerWithLogger returns a ServerOption that uses the given logger.
+//
+// The default is to use zap.NewNop().
+func ServerWithLogger(logger *zap.Logger) ServerOption {
+	return func(server *server) {
+		server.logger = logger
+	}
+}
+
+// NewServer returns a new Server.
+//
+// workDirPath should generally be the current directory, and is used to
+// resolve the file names of compile failures. The compiler must be
+// configured to return FileDescriptorSets with imports and source info.
+func NewServer(
+	workDirPath string,
+	protoSetProvider file.ProtoSetProvider,
+	compiler protoc.Compiler,
+	options ...ServerOption,
+) Server {
+	return newServer(workDirPath, protoSetProvider, compiler, options...)
+}
@@ -0,0 +1,555 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package lsp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/uber/prototool/internal/file"
+	"github.com/uber/prototool/internal/protoc"
+	"github.com/uber/prototool/internal/reflect"
+	reflectv1 "github.com/uber/prototool/internal/reflect/gen/uber/proto/reflect/v1"
+	"github.com/uber/prototool/internal/text"
+	"github.com/uber/prototool/internal/vars"
+	"go.uber.org/zap"
+)
+
+var nullResult = json.RawMessage("null")
+
+type server struct {
+	logger           *zap.Logger
+	workDirPath      string
+	protoSetProvider file.ProtoSetProvider
+	compiler         protoc.Compiler
+
+	ctx         context.Context
+	writer      io.Writer
+	initialized bool
+	shutdown    bool
+	// the indexes of all files from the last successful compile that included them
+	filePathToFileIndex map[string]*fileIndex
+	// the FileDescriptorSet from the last successful compile that included the file
+	filePathToFileDescriptorSet map[string]*descriptor.FileDescriptorSet
+	// the files that diagnostics were last published for
+	diagnosedFilePaths map[string]struct{}
+}
+
+func newServer(
+	workDirPath string,
+	protoSetProvider file.ProtoSetProvider,
+	compiler protoc.Compiler,
+	options ...ServerOption,
+) *server {
+	server := &server{
+		logger:                      zap.NewNop(),
+		workDirPath:                 workDirPath,
+		protoSetProvider:            protoSetProvider,
+		compiler:                    compiler,
+		filePathToFileIndex:         make(map[string]*fileIndex),
+		filePathToFileDescriptorSet: make(map[string]*descriptor.FileDescriptorSet),
+		diagnosedFilePaths:          make(map[string]struct{}),
+	}
+	for _, option := range options {
+		option(server)
+	}
+	return server
+}
+
+func (s *server) Serve(ctx context.Context, reader io.Reader, writer io.Writer) error {
+	s.ctx = ctx
+	s.writer = writer
+	bufReader := bufio.NewReader(reader)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		data, err := readMessage(bufReader)
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		request := &request{}
+		if err := json.Unmarshal(data, request); err != nil {
+			if err := s.respond(nil, nil, &responseError{Code: codeParseError, Message: err.Error()}); err != nil {
+				return err
+			}
+			continue
+		}
+		if request.Method == "exit" {
+			return nil
+		}
+		result, err := s.handle(request)
+		// notifications have no response
+		if request.ID == nil {
+			if err != nil {
+				s.logger.Error("notification failed", zap.String("method", request.Method), zap.Error(err))
+				if err := s.notify("window/showMessage", &showMessageParams{Type: messageTypeError, Message: err.Error()}); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if err := s.respond(request.ID, result, err); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *server) handle(request *request) (interface{}, error) {
+	s.logger.Debug("handle", zap.String("method", request.Method))
+	if s.shutdown {
+		return nil, &responseError{Code: codeInvalidRequest, Message: "server is shut down"}
+	}
+	if !s.initialized && request.Method != "initialize" {
+		return nil, &responseError{Code: codeServerNotInitialized, Message: "server is not initialized"}
+	}
+	switch request.Method {
+	case "initialize":
+		s.initialized = true
+		return &initializeResult{
+			Capabilities: serverCapabilities{
+				TextDocumentSync: textDocumentSyncOptions{
+					OpenClose: true,
+					Change:    textDocumentSyncKindNone,
+					Save:      true,
+				},
+				DefinitionProvider:     true,
+				ReferencesProvider:     true,
+				HoverProvider:          true,
+				DocumentSymbolProvider: true,
+			},
+			ServerInfo: serverInfo{
+				Name:    "prototool",
+				Version: vars.Version,
+			},
+		}, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/didOpen", "textDocument/didSave":
+		params := &textDocumentParams{}
+		if err := unmarshalParams(request, params); err != nil {
+			return nil, err
+		}
+		filePath, err := uriToFilePath(params.TextDocument.URI)
+		if err != nil {
+			return nil, err
+		}
+		return nil, s.compile(filePath)
+	case "textDocument/definition":
+		params := &textDocumentPositionParams{}
+		if err := unmarshalParams(request, params); err != nil {
+			return nil, err
+		}
+		return s.definition(params)
+	case "textDocument/references":
+		params := &referenceParams{}
+		if err := unmarshalParams(request, params); err != nil {
+			return nil, err
+		}
+		return s.references(params)
+	case "textDocument/hover":
+		params := &textDocumentPositionParams{}
+		if err := unmarshalParams(request, params); err != nil {
+			return nil, err
+		}
+		return s.hover(params)
+	case "textDocument/documentSymbol":
+		params := &textDocumentParams{}
+		if err := unmarshalParams(request, params); err != nil {
+			return nil, err
+		}
+		return s.documentSymbols(params)
+	default:
+		if request.ID == nil {
+			// notifications we do not need, such as initialized or didChange
+			return nil, nil
+		}
+		return nil, &responseError{Code: codeMethodNotFound, Message: fmt.Sprintf("method not found: %s", request.Method)}
+	}
+}
+
+// compile compiles the ProtoSet for the directory of the file, publishes
+// the diagnostics, and updates the indexes if the compile succeeded.
+func (s *server) compile(filePath string) error {
+	protoSet, err := s.protoSetProvider.GetForDir(s.workDirPath, filepath.Dir(filePath))
+	if err != nil {
+		return err
+	}
+	compileResult, err := s.compiler.Compile(s.ctx, protoSet)
+	if err != nil {
+		return err
+	}
+	if err := s.publishDiagnostics(filePath, protoSet, compileResult.Failures); err != nil {
+		return err
+	}
+	for _, fileDescriptorSet := range compileResult.FileDescriptorSets {
+		for _, fileDescriptorProto := range fileDescriptorSet.GetFile() {
+			indexFilePath := getFilePath(fileDescriptorSet, fileDescriptorProto.GetName())
+			if indexFilePath == "" {
+				continue
+			}
+			s.filePathToFileIndex[indexFilePath] = newFileIndex(indexFilePath, fileDescriptorProto)
+			s.filePathToFileDescriptorSet[indexFilePath] = fileDescriptorSet.FileDescriptorSet
+		}
+	}
+	return nil
+}
+
+// publishDiagnostics publishes the failures, and clears diagnostics for
+// files within the ProtoSet that no longer have failures.
+func (s *server) publishDiagnostics(filePath string, protoSet *file.ProtoSet, failures []*text.Failure) error {
+	filePathToDiagnostics := make(map[string][]diagnostic)
+	for _, failure := range failures {
+		failureFilePath := filePath
+		if failure.Filename != "" {
+			failureFilePath = failure.Filename
+			if !filepath.IsAbs(failureFilePath) {
+				failureFilePath = filepath.Join(s.workDirPath, failureFilePath)
+			}
+		}
+		pos := position{}
+		if failure.Line > 0 {
+			pos.Line = failure.Line - 1
+		}
+		if failure.Column > 0 {
+			pos.Character = failure.Column - 1
+		}
+		filePathToDiagnostics[failureFilePath] = append(filePathToDiagnostics[failureFilePath], diagnostic{
+			Range:    textRange{Start: pos, End: pos},
+			Severity: getDiagnosticSeverity(failure.Severity),
+			Code:     failure.LintID,
+			Source:   "prototool",
+			Message:  failure.Message,
+		})
+	}
+	for diagnosedFilePath := range s.diagnosedFilePaths {
+		if _, ok := filePathToDiagnostics[diagnosedFilePath]; !ok && strings.HasPrefix(diagnosedFilePath, protoSet.DirPath) {
+			filePathToDiagnostics[diagnosedFilePath] = []diagnostic{}
+		}
+	}
+	diagnosticsFilePaths := make([]string, 0, len(filePathToDiagnostics))
+	for diagnosticsFilePath := range filePathToDiagnostics {
+		diagnosticsFilePaths = append(diagnosticsFilePaths, diagnosticsFilePath)
+	}
+	sort.Strings(diagnosticsFilePaths)
+	for _, diagnosticsFilePath := range diagnosticsFilePaths {
+		diagnostics := filePathToDiagnostics[diagnosticsFilePath]
+		if len(diagnostics) == 0 {
+			delete(s.diagnosedFilePaths, diagnosticsFilePath)
+		} else {
+			s.diagnosedFilePaths[diagnosticsFilePath] = struct{}{}
+		}
+		if err := s.notify("textDocument/publishDiagnostics", &publishDiagnosticsParams{
+			URI:         filePathToURI(diagnosticsFilePath),
+			Diagnostics: diagnostics,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *server) definition(params *textDocumentPositionParams) (interface{}, error) {
+	fullName, _, err := s.getFullNameAt(params.TextDocument.URI, params.Position)
+	if err != nil || fullName == "" {
+		return nil, err
+	}
+	filePath, definition := s.getDefinition(fullName)
+	if definition == nil {
+		return nil, nil
+	}
+	return &location{URI: filePathToURI(filePath), Range: definition.NameRange}, nil
+}
+
+func (s *server) references(params *referenceParams) (interface{}, error) {
+	fullName, _, err := s.getFullNameAt(params.TextDocument.URI, params.Position)
+	if err != nil {
+		return nil, err
+	}
+	locations := make([]location, 0)
+	if fullName == "" {
+		return locations, nil
+	}
+	if params.Context.IncludeDeclaration {
+		if filePath, definition := s.getDefinition(fullName); definition != nil {
+			locations = append(locations, location{URI: filePathToURI(filePath), Range: definition.NameRange})
+		}
+	}
+	for _, filePath := range s.getIndexedFilePaths() {
+		for _, reference := range s.filePathToFileIndex[filePath].References {
+			if reference.FullName == fullName {
+				locations = append(locations, location{URI: filePathToURI(filePath), Range: reference.Range})
+			}
+		}
+	}
+	return locations, nil
+}
+
+func (s *server) hover(params *textDocumentPositionParams) (interface{}, error) {
+	fullName, nameRange, err := s.getFullNameAt(params.TextDocument.URI, params.Position)
+	if err != nil || fullName == "" {
+		return nil, err
+	}
+	_, definition := s.getDefinition(fullName)
+	if definition == nil {
+		return nil, nil
+	}
+	value := fmt.Sprintf("```proto\n%s %s\n```", definition.Keyword, definition.FullName)
+	if definition.Comments != "" {
+		value = value + "\n\n" + definition.Comments
+	}
+	return &hover{
+		Contents: markupContent{Kind: "markdown", Value: value},
+		Range:    nameRange,
+	}, nil
+}
+
+func (s *server) documentSymbols(params *textDocumentParams) (interface{}, error) {
+	filePath, err := uriToFilePath(params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	documentSymbols := make([]*documentSymbol, 0)
+	fileIndex, ok := s.filePathToFileIndex[filePath]
+	if !ok {
+		return documentSymbols, nil
+	}
+	packageSet, err := reflect.NewPackageSet(s.filePathToFileDescriptorSet[filePath])
+	if err != nil {
+		return nil, err
+	}
+	for _, pkg := range packageSet.GetPackages() {
+		if pkg.GetName() != fileIndex.Package {
+			continue
+		}
+		for _, message := range pkg.GetMessages() {
+			if documentSymbol := getMessageDocumentSymbol(fileIndex, pkg.GetName(), message); documentSymbol != nil {
+				documentSymbols = append(documentSymbols, documentSymbol)
+			}
+		}
+		for _, enum := range pkg.GetEnums() {
+			if documentSymbol := getEnumDocumentSymbol(fileIndex, pkg.GetName(), enum); documentSymbol != nil {
+				documentSymbols = append(documentSymbols, documentSymbol)
+			}
+		}
+		for _, service := range pkg.GetServices() {
+			if documentSymbol := getServiceDocumentSymbol(fileIndex, pkg.GetName(), service); documentSymbol != nil {
+				documentSymbols = append(documentSymbols, documentSymbol)
+			}
+		}
+	}
+	sortDocumentSymbols(documentSymbols)
+	return documentSymbols, nil
+}
+
+func (s *server) getFullNameAt(uri string, pos position) (string, textRange, error) {
+	filePath, err := uriToFilePath(uri)
+	if err != nil {
+		return "", textRange{}, err
+	}
+	fileIndex, ok := s.filePathToFileIndex[filePath]
+	if !ok {
+		return "", textRange{}, nil
+	}
+	fullName, nameRange := fileIndex.GetFullNameAt(pos)
+	return fullName, nameRange, nil
+}
+
+func (s *server) getDefinition(fullName string) (string, *definition) {
+	for _, filePath := range s.getIndexedFilePaths() {
+		if definition := s.filePathToFileIndex[filePath].GetDefinition(fullName); definition != nil {
+			return filePath, definition
+		}
+	}
+	return "", nil
+}
+
+func (s *server) getIndexedFilePaths() []string {
+	filePaths := make([]string, 0, len(s.filePathToFileIndex))
+	for filePath := range s.filePathToFileIndex {
+		filePaths = append(filePaths, filePath)
+	}
+	sort.Strings(filePaths)
+	return filePaths
+}
+
+func (s *server) respond(id *json.RawMessage, result interface{}, err error) error {
+	response := &response{
+		JSONRPC: "2.0",
+		ID:      id,
+	}
+	if err != nil {
+		responseErr, ok := err.(*responseError)
+		if !ok {
+			responseErr = &responseError{Code: codeInternalError, Message: err.Error()}
+		}
+		response.Error = responseErr
+	} else {
+		response.Result = nullResult
+		if result != nil {
+			data, err := json.Marshal(result)
+			if err != nil {
+				return err
+			}
+			response.Result = data
+		}
+	}
+	return writeMessage(s.writer, response)
+}
+
+func (s *server) notify(method string, params interface{}) error {
+	return writeMessage(s.writer, &notification{
+		JSONRPC: "2.0",
+		Method:  method,
+		Params:  params,
+	})
+}
+
+func unmarshalParams(request *request, params interface{}) error {
+	if err := json.Unmarshal(request.Params, params); err != nil {
+		return &responseError{Code: codeInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+// getFilePath returns the absolute path of the file with the given name
+// within the FileDescriptorSet, or "" if it cannot be found, for example
+// for the well-known types.
+func getFilePath(fileDescriptorSet *protoc.FileDescriptorSet, name string) string {
+	for _, protoFile := range fileDescriptorSet.ProtoFiles {
+		if strings.HasSuffix(filepath.ToSlash(protoFile.Path), "/"+name) {
+			return protoFile.Path
+		}
+	}
+	config := fileDescriptorSet.ProtoSet.Config
+	for _, includePath := range append([]string{config.DirPath}, config.Compile.IncludePaths...) {
+		filePath := filepath.Join(includePath, filepath.FromSlash(name))
+		if _, err := os.Stat(filePath); err == nil {
+			return filePath
+		}
+	}
+	return ""
+}
+
+func getMessageDocumentSymbol(fileIndex *fileIndex, scope string, message *reflectv1.Message) *documentSymbol {
+	fullName := joinName(scope, message.GetName())
+	documentSymbol := newDocumentSymbol(fileIndex, fullName, message.GetName(), "")
+	if documentSymbol == nil {
+		return nil
+	}
+	for _, messageField := range message.GetMessageFields() {
+		detail := messageField.GetTypeName()
+		if detail == "" {
+			detail = strings.ToLower(strings.TrimPrefix(messageField.GetType().String(), "TYPE_"))
+		}
+		if child := newDocumentSymbol(fileIndex, joinName(fullName, messageField.GetName()), messageField.GetName(), detail); child != nil {
+			documentSymbol.Children = append(documentSymbol.Children, child)
+		}
+	}
+	for _, nestedMessage := range message.GetNestedMessages() {
+		if child := getMessageDocumentSymbol(fileIndex, fullName, nestedMessage); child != nil {
+			documentSymbol.Children = append(documentSymbol.Children, child)
+		}
+	}
+	for _, nestedEnum := range message.GetNestedEnums() {
+		if child := getEnumDocumentSymbol(fileIndex, fullName, nestedEnum); child != nil {
+			documentSymbol.Children = append(documentSymbol.Children, child)
+		}
+	}
+	sortDocumentSymbols(documentSymbol.Children)
+	return documentSymbol
+}
+
+func getEnumDocumentSymbol(fileIndex *fileIndex, scope string, enum *reflectv1.Enum) *documentSymbol {
+	fullName := joinName(scope, enum.GetName())
+	documentSymbol := newDocumentSymbol(fileIndex, fullName, enum.GetName(), "")
+	if documentSymbol == nil {
+		return nil
+	}
+	for _, enumValue := range enum.GetEnumValues() {
+		if child := newDocumentSymbol(fileIndex, joinName(fullName, enumValue.GetName()), enumValue.GetName(), fmt.Sprintf("%d", enumValue.GetNumber())); child != nil {
+			documentSymbol.Children = append(documentSymbol.Children, child)
+		}
+	}
+	sortDocumentSymbols(documentSymbol.Children)
+	return documentSymbol
+}
+
+func getServiceDocumentSymbol(fileIndex *fileIndex, scope string, service *reflectv1.Service) *documentSymbol {
+	fullName := joinName(scope, service.GetName())
+	documentSymbol := newDocumentSymbol(fileIndex, fullName, service.GetName(), "")
+	if documentSymbol == nil {
+		return nil
+	}
+	for _, serviceMethod := range service.GetServiceMethods() {
+		detail := fmt.Sprintf("(%s) returns (%s)", serviceMethod.GetRequestTypeName(), serviceMethod.GetResponseTypeName())
+		if child := newDocumentSymbol(fileIndex, joinName(fullName, serviceMethod.GetName()), serviceMethod.GetName(), detail); child != nil {
+			documentSymbol.Children = append(documentSymbol.Children, child)
+		}
+	}
+	sortDocumentSymbols(documentSymbol.Children)
+	return documentSymbol
+}
+
+// newDocumentSymbol returns nil if the symbol is not defined in the file.
+func newDocumentSymbol(fileIndex *fileIndex, fullName string, name string, detail string) *documentSymbol {
+	definition := fileIndex.GetDefinition(fullName)
+	if definition == nil {
+		return nil
+	}
+	return &documentSymbol{
+		Name:           name,
+		Detail:         detail,
+		Kind:           definition.Kind,
+		Range:          definition.Range,
+		SelectionRange: definition.NameRange,
+	}
+}
+
+// sortDocumentSymbols sorts by position in the file, as the PackageSet is sorted by name.
+func sortDocumentSymbols(documentSymbols []*documentSymbol) {
+	sort.SliceStable(documentSymbols, func(i int, j int) bool {
+		return positionLess(documentSymbols[i].Range.Start, documentSymbols[j].Range.Start)
+	})
+}
+
+func getDiagnosticSeverity(severity text.Severity) int {
+	switch severity {
+	case text.SeverityWarning:
+		return diagnosticSeverityWarning
+	case text.SeverityInfo:
+		return diagnosticSeverityInformation
+	default:
+		return diagnosticSeverityError
+	}
+}
@@ -0,0 +1,331 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/prototool/internal/file"
+	"github.com/uber/prototool/internal/protoc"
+	"github.com/uber/prototool/internal/text"
+)
+
+const testFileData = `syntax = "proto3";
+
+package foo.v1;
+
+// Bar is a bar.
+message Bar {
+  string id = 1;
+}
+
+message Foo {
+  Bar bar = 1;
+  Kind kind = 2;
+}
+
+enum Kind {
+  KIND_INVALID = 0;
+}
+
+service FooAPI {
+  rpc GetBar(Foo) returns (Bar);
+}
+`
+
+func TestServer(t *testing.T) {
+	dirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dirPath)
+	}()
+	dirPath, err = filepath.EvalSymlinks(dirPath)
+	require.NoError(t, err)
+	filePath := filepath.Join(dirPath, "foo", "v1", "foo.proto")
+	require.NoError(t, os.MkdirAll(filepath.Dir(filePath), 0755))
+	require.NoError(t, ioutil.WriteFile(filePath, []byte(testFileData), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dirPath, "prototool.yaml"), []byte{}, 0644))
+	uri := filePathToURI(filePath)
+
+	compiler := &testCompiler{
+		results: []*protoc.CompileResult{
+			{
+				Failures: []*text.Failure{
+					{Filename: "foo/v1/foo.proto", Line: 12, Column: 3, Message: `"Kind" is not defined.`},
+				},
+			},
+			{},
+		},
+	}
+	input := bytes.NewBuffer(nil)
+	writeTestMessage(t, input, 1, "initialize", map[string]interface{}{"rootUri": filePathToURI(dirPath)})
+	writeTestMessage(t, input, 0, "initialized", map[string]interface{}{})
+	writeTestMessage(t, input, 0, "textDocument/didOpen", map[string]interface{}{"textDocument": map[string]interface{}{"uri": uri}})
+	writeTestMessage(t, input, 0, "textDocument/didSave", map[string]interface{}{"textDocument": map[string]interface{}{"uri": uri}})
+	writeTestMessage(t, input, 2, "textDocument/definition", newTestPositionParams(uri, 10, 3))
+	writeTestMessage(t, input, 3, "textDocument/references", map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": uri},
+		"position":     map[string]interface{}{"line": 5, "character": 9},
+		"context":      map[string]interface{}{"includeDeclaration": true},
+	})
+	writeTestMessage(t, input, 4, "textDocument/hover", newTestPositionParams(uri, 10, 3))
+	writeTestMessage(t, input, 5, "textDocument/hover", newTestPositionParams(uri, 0, 0))
+	writeTestMessage(t, input, 6, "textDocument/documentSymbol", map[string]interface{}{"textDocument": map[string]interface{}{"uri": uri}})
+	writeTestMessage(t, input, 7, "textDocument/unknown", map[string]interface{}{})
+	writeTestMessage(t, input, 8, "shutdown", nil)
+	writeTestMessage(t, input, 0, "exit", nil)
+	output := bytes.NewBuffer(nil)
+	require.NoError(t, NewServer(dirPath, file.NewProtoSetProvider(), compiler).Serve(context.Background(), input, output))
+
+	messages := readTestMessages(t, output)
+	require.Len(t, messages, 10)
+	assert.Contains(t, messages[0], `"definitionProvider":true`)
+	assert.JSONEq(t, fmt.Sprintf(`{"jsonrpc":"2.0","method":"textDocument/publishDiagnostics","params":{"uri":%q,"diagnostics":[
+		{"range":{"start":{"line":11,"character":2},"end":{"line":11,"character":2}},"severity":1,"source":"prototool","message":"\"Kind\" is not defined."}
+	]}}`, uri), messages[1])
+	assert.JSONEq(t, fmt.Sprintf(`{"jsonrpc":"2.0","method":"textDocument/publishDiagnostics","params":{"uri":%q,"diagnostics":[]}}`, uri), messages[2])
+	assert.JSONEq(t, fmt.Sprintf(`{"jsonrpc":"2.0","id":2,"result":%s}`, newTestLocation(uri, 5, 8, 5, 11)), messages[3])
+	assert.JSONEq(t, fmt.Sprintf(
+		`{"jsonrpc":"2.0","id":3,"result":[%s,%s,%s]}`,
+		newTestLocation(uri, 5, 8, 5, 11),
+		newTestLocation(uri, 10, 2, 10, 5),
+		newTestLocation(uri, 19, 27, 19, 30),
+	), messages[4])
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":4,"result":{
+		"contents":{"kind":"markdown","value":"`+"```proto\\nmessage foo.v1.Bar\\n```"+`\n\nBar is a bar."},
+		"range":{"start":{"line":10,"character":2},"end":{"line":10,"character":5}}
+	}}`, messages[5])
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":5,"result":null}`, messages[6])
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":6,"result":[
+		{"name":"Bar","kind":23,"range":{"start":{"line":5,"character":0},"end":{"line":7,"character":1}},"selectionRange":{"start":{"line":5,"character":8},"end":{"line":5,"character":11}},"children":[
+			{"name":"id","detail":"string","kind":8,"range":{"start":{"line":6,"character":2},"end":{"line":6,"character":16}},"selectionRange":{"start":{"line":6,"character":9},"end":{"line":6,"character":11}}}
+		]},
+		{"name":"Foo","kind":23,"range":{"start":{"line":9,"character":0},"end":{"line":12,"character":1}},"selectionRange":{"start":{"line":9,"character":8},"end":{"line":9,"character":11}},"children":[
+			{"name":"bar","detail":"foo.v1.Bar","kind":8,"range":{"start":{"line":10,"character":2},"end":{"line":10,"character":14}},"selectionRange":{"start":{"line":10,"character":6},"end":{"line":10,"character":9}}},
+			{"name":"kind","detail":"foo.v1.Kind","kind":8,"range":{"start":{"line":11,"character":2},"end":{"line":11,"character":16}},"selectionRange":{"start":{"line":11,"character":7},"end":{"line":11,"character":11}}}
+		]},
+		{"name":"Kind","kind":10,"range":{"start":{"line":14,"character":0},"end":{"line":16,"character":1}},"selectionRange":{"start":{"line":14,"character":5},"end":{"line":14,"character":9}},"children":[
+			{"name":"KIND_INVALID","detail":"0","kind":22,"range":{"start":{"line":15,"character":2},"end":{"line":15,"character":19}},"selectionRange":{"start":{"line":15,"character":2},"end":{"line":15,"character":14}}}
+		]},
+		{"name":"FooAPI","kind":11,"range":{"start":{"line":18,"character":0},"end":{"line":20,"character":1}},"selectionRange":{"start":{"line":18,"character":8},"end":{"line":18,"character":14}},"children":[
+			{"name":"GetBar","detail":"(foo.v1.Foo) returns (foo.v1.Bar)","kind":6,"range":{"start":{"line":19,"character":2},"end":{"line":19,"character":32}},"selectionRange":{"start":{"line":19,"character":6},"end":{"line":19,"character":12}}}
+		]}
+	]}`, messages[7])
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":7,"error":{"code":-32601,"message":"method not found: textDocument/unknown"}}`, messages[8])
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":8,"result":null}`, messages[9])
+	assert.Equal(t, 2, compiler.calls)
+}
+
+func TestServerNotInitialized(t *testing.T) {
+	input := bytes.NewBuffer(nil)
+	writeTestMessage(t, input, 1, "textDocument/hover", newTestPositionParams("file:///a.proto", 0, 0))
+	output := bytes.NewBuffer(nil)
+	require.NoError(t, NewServer("/", file.NewProtoSetProvider(), &testCompiler{}).Serve(context.Background(), input, output))
+	messages := readTestMessages(t, output)
+	require.Len(t, messages, 1)
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":1,"error":{"code":-32002,"message":"server is not initialized"}}`, messages[0])
+}
+
+type testCompiler struct {
+	results []*protoc.CompileResult
+	calls   int
+}
+
+func (c *testCompiler) Compile(ctx context.Context, protoSet *file.ProtoSet) (*protoc.CompileResult, error) {
+	if c.calls >= len(c.results) {
+		return nil, fmt.Errorf("unexpected compile")
+	}
+	result := c.results[c.calls]
+	c.calls++
+	if len(result.Failures) > 0 {
+		return result, nil
+	}
+	var protoFiles []*file.ProtoFile
+	for _, dirProtoFiles := range protoSet.DirPathToFiles {
+		protoFiles = append(protoFiles, dirProtoFiles...)
+	}
+	return &protoc.CompileResult{
+		FileDescriptorSets: protoc.FileDescriptorSets{
+			{
+				FileDescriptorSet: &descriptor.FileDescriptorSet{
+					File: []*descriptor.FileDescriptorProto{newTestFileDescriptorProto()},
+				},
+				ProtoSet:   protoSet,
+				DirPath:    filepath.Dir(protoFiles[0].Path),
+				ProtoFiles: protoFiles,
+			},
+		},
+	}, nil
+}
+
+func (c *testCompiler) ProtocCommands(*file.ProtoSet) ([]string, error) {
+	return nil, nil
+}
+
+// newTestFileDescriptorProto returns what protoc would produce for testFileData.
+func newTestFileDescriptorProto() *descriptor.FileDescriptorProto {
+	newLocation := func(path []int32, span []int32, leadingComments string) *descriptor.SourceCodeInfo_Location {
+		location := &descriptor.SourceCodeInfo_Location{Path: path, Span: span}
+		if leadingComments != "" {
+			location.LeadingComments = proto.String(leadingComments)
+		}
+		return location
+	}
+	return &descriptor.FileDescriptorProto{
+		Name:    proto.String("foo/v1/foo.proto"),
+		Package: proto.String("foo.v1"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptor.DescriptorProto{
+			{
+				Name: proto.String("Bar"),
+				Field: []*descriptor.FieldDescriptorProto{
+					{
+						Name:   proto.String("id"),
+						Number: proto.Int32(1),
+						Label:  descriptor.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+						Type:   descriptor.FieldDescriptorProto_TYPE_STRING.Enum(),
+					},
+				},
+			},
+			{
+				Name: proto.String("Foo"),
+				Field: []*descriptor.FieldDescriptorProto{
+					{
+						Name:     proto.String("bar"),
+						Number:   proto.Int32(1),
+						Label:    descriptor.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+						Type:     descriptor.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+						TypeName: proto.String(".foo.v1.Bar"),
+					},
+					{
+						Name:     proto.String("kind"),
+						Number:   proto.Int32(2),
+						Label:    descriptor.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+						Type:     descriptor.FieldDescriptorProto_TYPE_ENUM.Enum(),
+						TypeName: proto.String(".foo.v1.Kind"),
+					},
+				},
+			},
+		},
+		EnumType: []*descriptor.EnumDescriptorProto{
+			{
+				Name: proto.String("Kind"),
+				Value: []*descriptor.EnumValueDescriptorProto{
+					{Name: proto.String("KIND_INVALID"), Number: proto.Int32(0)},
+				},
+			},
+		},
+		Service: []*descriptor.ServiceDescriptorProto{
+			{
+				Name: proto.String("FooAPI"),
+				Method: []*descriptor.MethodDescriptorProto{
+					{
+						Name:       proto.String("GetBar"),
+						InputType:  proto.String(".foo.v1.Foo"),
+						OutputType: proto.String(".foo.v1.Bar"),
+					},
+				},
+			},
+		},
+		SourceCodeInfo: &descriptor.SourceCodeInfo{
+			Location: []*descriptor.SourceCodeInfo_Location{
+				newLocation([]int32{4, 0}, []int32{5, 0, 7, 1}, " Bar is a bar.\n"),
+				newLocation([]int32{4, 0, 1}, []int32{5, 8, 11}, ""),
+				newLocation([]int32{4, 0, 2, 0}, []int32{6, 2, 16}, ""),
+				newLocation([]int32{4, 0, 2, 0, 1}, []int32{6, 9, 11}, ""),
+				newLocation([]int32{4, 1}, []int32{9, 0, 12, 1}, ""),
+				newLocation([]int32{4, 1, 1}, []int32{9, 8, 11}, ""),
+				newLocation([]int32{4, 1, 2, 0}, []int32{10, 2, 14}, ""),
+				newLocation([]int32{4, 1, 2, 0, 6}, []int32{10, 2, 5}, ""),
+				newLocation([]int32{4, 1, 2, 0, 1}, []int32{10, 6, 9}, ""),
+				newLocation([]int32{4, 1, 2, 1}, []int32{11, 2, 16}, ""),
+				newLocation([]int32{4, 1, 2, 1, 6}, []int32{11, 2, 6}, ""),
+				newLocation([]int32{4, 1, 2, 1, 1}, []int32{11, 7, 11}, ""),
+				newLocation([]int32{5, 0}, []int32{14, 0, 16, 1}, ""),
+				newLocation([]int32{5, 0, 1}, []int32{14, 5, 9}, ""),
+				newLocation([]int32{5, 0, 2, 0}, []int32{15, 2, 19}, ""),
+				newLocation([]int32{5, 0, 2, 0, 1}, []int32{15, 2, 14}, ""),
+				newLocation([]int32{6, 0}, []int32{18, 0, 20, 1}, ""),
+				newLocation([]int32{6, 0, 1}, []int32{18, 8, 14}, ""),
+				newLocation([]int32{6, 0, 2, 0}, []int32{19, 2, 32}, ""),
+				newLocation([]int32{6, 0, 2, 0, 1}, []int32{19, 6, 12}, ""),
+				newLocation([]int32{6, 0, 2, 0, 2}, []int32{19, 13, 16}, ""),
+				newLocation([]int32{6, 0, 2, 0, 3}, []int32{19, 27, 30}, ""),
+			},
+		},
+	}
+}
+
+func newTestPositionParams(uri string, line int, character int) map[string]interface{} {
+	return map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": uri},
+		"position":     map[string]interface{}{"line": line, "character": character},
+	}
+}
+
+func newTestLocation(uri string, startLine int, startCharacter int, endLine int, endCharacter int) string {
+	return fmt.Sprintf(
+		`{"uri":%q,"range":{"start":{"line":%d,"character":%d},"end":{"line":%d,"character":%d}}}`,
+		uri, startLine, startCharacter, endLine, endCharacter,
+	)
+}
+
+// writeTestMessage writes a request, or a notification if id is 0.
+func writeTestMessage(t *testing.T, writer io.Writer, id int, method string, params interface{}) {
+	message := map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  method,
+	}
+	if id != 0 {
+		message["id"] = id
+	}
+	if params != nil {
+		message["params"] = params
+	}
+	require.NoError(t, writeMessage(writer, message))
+}
+
+func readTestMessages(t *testing.T, reader io.Reader) []string {
+	bufReader := bufio.NewReader(reader)
+	var messages []string
+	for {
+		data, err := readMessage(bufReader)
+		if err == io.EOF {
+			return messages
+		}
+		require.NoError(t, err)
+		require.True(t, json.Valid(data))
+		messages = append(messages, string(data))
+	}
+}
@@ -0,0 +1,635 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package openapi generates OpenAPI v3 documents for the services in
+// FileDescriptorSets.
+//
+// Messages are mapped to schemas following the protojson mapping, and the
+// Google Well-Known Types are mapped to their JSON equivalents. Methods use
+// their google.api.http annotation if present, otherwise they are mapped to
+// POST /package.Service/Method with the request message as the body.
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/uber/prototool/internal/wkt"
+	"google.golang.org/genproto/googleapis/api/annotations"
+)
+
+// Version is the OpenAPI version of generated documents.
+const Version = "3.0.3"
+
+// MergedFileName is the file name of the document generated with
+// GeneratorWithMerge.
+const MergedFileName = "openapi.json"
+
+// Generator generates OpenAPI documents.
+type Generator interface {
+	// Generate returns the JSON documents for the services in the given
+	// FileDescriptorSets, keyed by file name.
+	//
+	// There is one document per package with services, named after the
+	// package such as foo.v1.openapi.json, unless GeneratorWithMerge is used.
+	// If packageNames is not empty, only the services in the given packages
+	// are included. The FileDescriptorSets must include all imports.
+	Generate(fileDescriptorSets []*descriptor.FileDescriptorSet, packageNames ...string) (map[string][]byte, error)
+}
+
+// GeneratorOption is an option for a new Generator.
+type GeneratorOption func(*generator)
+
+// GeneratorWithMerge returns a GeneratorOption that generates a single
+// document named MergedFileName for the services in all packages.
+func GeneratorWithMerge() GeneratorOption {
+	return func(generator *generator) {
+		generator.merge = true
+	}
+}
+
+// GeneratorWithVersion returns a GeneratorOption that uses the given
+// version as info.version of the documents.
+//
+// The default is to use "version not set".
+func GeneratorWithVersion(version string) GeneratorOption {
+	return func(generator *generator) {
+		generator.version = version
+	}
+}
+
+// NewGenerator returns a new Generator.
+func NewGenerator(options ...GeneratorOption) Generator {
+	return newGenerator(options...)
+}
+
+type generator struct {
+	merge   bool
+	version string
+}
+
+func newGenerator(options ...GeneratorOption) *generator {
+	generator := &generator{
+		version: "version not set",
+	}
+	for _, option := range options {
+		option(generator)
+	}
+	return generator
+}
+
+func (g *generator) Generate(fileDescriptorSets []*descriptor.FileDescriptorSet, packageNames ...string) (map[string][]byte, error) {
+	registry, err := newRegistry(fileDescriptorSets)
+	if err != nil {
+		return nil, err
+	}
+	packageNameToServices := make(map[string][]*service)
+	for _, service := range registry.services {
+		if len(packageNames) > 0 && !containsString(packageNames, service.packageName) {
+			continue
+		}
+		packageNameToServices[service.packageName] = append(packageNameToServices[service.packageName], service)
+	}
+	sortedPackageNames := make([]string, 0, len(packageNameToServices))
+	for packageName := range packageNameToServices {
+		sortedPackageNames = append(sortedPackageNames, packageName)
+	}
+	sort.Strings(sortedPackageNames)
+	fileNameToData := make(map[string][]byte)
+	if g.merge {
+		var services []*service
+		for _, packageName := range sortedPackageNames {
+			services = append(services, packageNameToServices[packageName]...)
+		}
+		data, err := g.generate(registry, strings.Join(sortedPackageNames, ", "), services)
+		if err != nil {
+			return nil, err
+		}
+		fileNameToData[MergedFileName] = data
+		return fileNameToData, nil
+	}
+	for _, packageName := range sortedPackageNames {
+		data, err := g.generate(registry, packageName, packageNameToServices[packageName])
+		if err != nil {
+			return nil, err
+		}
+		fileNameToData[packageName+".openapi.json"] = data
+	}
+	return fileNameToData, nil
+}
+
+func (g *generator) generate(registry *registry, title string, services []*service) ([]byte, error) {
+	builder := newDocumentBuilder(registry)
+	doc := &document{
+		OpenAPI: Version,
+		Info: &info{
+			Title:   title,
+			Version: g.version,
+		},
+		Paths: make(map[string]pathItem),
+	}
+	for _, service := range services {
+		doc.Tags = append(doc.Tags, &tag{
+			Name:        service.fullName,
+			Description: registry.getComments(service.fileName, service.path),
+		})
+		for i, methodDescriptorProto := range service.GetMethod() {
+			if err := builder.addMethod(doc, service, methodDescriptorProto, appendPath(service.path, serviceMethodTag, int32(i))); err != nil {
+				return nil, err
+			}
+		}
+	}
+	doc.Components = &components{
+		Schemas: builder.schemas,
+	}
+	buffer := bytes.NewBuffer(nil)
+	encoder := json.NewEncoder(buffer)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(doc); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// documentBuilder adds operations to a document and collects the schemas
+// of all types they reference.
+type documentBuilder struct {
+	registry *registry
+	schemas  map[string]*schema
+}
+
+func newDocumentBuilder(registry *registry) *documentBuilder {
+	return &documentBuilder{
+		registry: registry,
+		schemas:  make(map[string]*schema),
+	}
+}
+
+func (b *documentBuilder) addMethod(doc *document, service *service, methodDescriptorProto *descriptor.MethodDescriptorProto, path []int32) error {
+	requestMessage, err := b.registry.getMessage(methodDescriptorProto.GetInputType())
+	if err != nil {
+		return err
+	}
+	responseMessage, err := b.registry.getMessage(methodDescriptorProto.GetOutputType())
+	if err != nil {
+		return err
+	}
+	httpRules, err := getHTTPRules(methodDescriptorProto)
+	if err != nil {
+		return err
+	}
+	operationID := service.fullName + "." + methodDescriptorProto.GetName()
+	if len(httpRules) == 0 {
+		// the same as the gRPC path
+		httpRules = []*annotations.HttpRule{
+			{
+				Pattern: &annotations.HttpRule_Post{Post: "/" + service.fullName + "/" + methodDescriptorProto.GetName()},
+				Body:    "*",
+			},
+		}
+	}
+	for i, httpRule := range httpRules {
+		method, pathTemplate := getMethodAndPathTemplate(httpRule)
+		if method == "" {
+			return fmt.Errorf("no pattern in google.api.http annotation of %s", operationID)
+		}
+		op := &operation{
+			OperationID: operationID,
+			Tags:        []string{service.fullName},
+			Description: b.registry.getComments(service.fileName, path),
+			Deprecated:  methodDescriptorProto.GetOptions().GetDeprecated(),
+			Responses:   make(map[string]*response),
+		}
+		if i > 0 {
+			op.OperationID = fmt.Sprintf("%s%d", operationID, i+1)
+		}
+		openAPIPath, pathFieldNames := parsePathTemplate(pathTemplate)
+		for _, pathFieldName := range pathFieldNames {
+			fieldSchema, err := b.getFieldPathSchema(requestMessage, pathFieldName)
+			if err != nil {
+				return fmt.Errorf("%s: %v", operationID, err)
+			}
+			op.Parameters = append(op.Parameters, &parameter{
+				Name:     pathFieldName,
+				In:       "path",
+				Required: true,
+				Schema:   fieldSchema,
+			})
+		}
+		switch body := httpRule.GetBody(); body {
+		case "":
+			// all fields not in the path are query parameters
+			queryParameters, err := b.getQueryParameters(requestMessage, pathFieldNames)
+			if err != nil {
+				return err
+			}
+			op.Parameters = append(op.Parameters, queryParameters...)
+		case "*":
+			op.RequestBody = newRequestBody(b.getMessageSchema(requestMessage))
+		default:
+			field := getField(requestMessage, body)
+			if field == nil {
+				return fmt.Errorf("%s: no field %s for body", operationID, body)
+			}
+			bodySchema, err := b.getFieldSchema(field)
+			if err != nil {
+				return err
+			}
+			op.RequestBody = newRequestBody(bodySchema)
+			// all fields not in the path or the body are query parameters
+			queryParameters, err := b.getQueryParameters(requestMessage, append(pathFieldNames, body))
+			if err != nil {
+				return err
+			}
+			op.Parameters = append(op.Parameters, queryParameters...)
+		}
+		responseSchema := b.getMessageSchema(responseMessage)
+		if responseBody := httpRule.GetResponseBody(); responseBody != "" {
+			field := getField(responseMessage, responseBody)
+			if field == nil {
+				return fmt.Errorf("%s: no field %s for response body", operationID, responseBody)
+			}
+			if responseSchema, err = b.getFieldSchema(field); err != nil {
+				return err
+			}
+		}
+		op.Responses["200"] = &response{
+			Description: "A successful response.",
+			Content: map[string]*mediaType{
+				"application/json": {Schema: responseSchema},
+			},
+		}
+		item, ok := doc.Paths[openAPIPath]
+		if !ok {
+			item = make(pathItem)
+			doc.Paths[openAPIPath] = item
+		}
+		if _, ok := item[method]; ok {
+			return fmt.Errorf("%s: duplicate operation %s %s", operationID, strings.ToUpper(method), openAPIPath)
+		}
+		item[method] = op
+	}
+	return nil
+}
+
+// getQueryParameters returns the query parameters for the fields of the
+// message that are not excluded.
+func (b *documentBuilder) getQueryParameters(message *message, excludeFieldNames []string) ([]*parameter, error) {
+	var parameters []*parameter
+	for _, field := range message.GetField() {
+		if containsString(excludeFieldNames, field.GetName()) {
+			continue
+		}
+		// only scalars can be query parameters
+		if field.GetType() == descriptor.FieldDescriptorProto_TYPE_MESSAGE || field.GetType() == descriptor.FieldDescriptorProto_TYPE_GROUP {
+			continue
+		}
+		fieldSchema, err := b.getFieldSchema(field)
+		if err != nil {
+			return nil, err
+		}
+		parameters = append(parameters, &parameter{
+			Name:   field.GetJsonName(),
+			In:     "query",
+			Schema: fieldSchema,
+		})
+	}
+	return parameters, nil
+}
+
+// getFieldPathSchema returns the schema of the field at the dot-separated
+// path within the message.
+func (b *documentBuilder) getFieldPathSchema(message *message, fieldPath string) (*schema, error) {
+	fieldNames := strings.Split(fieldPath, ".")
+	for i, fieldName := range fieldNames {
+		field := getField(message, fieldName)
+		if field == nil {
+			return nil, fmt.Errorf("no field %s for path parameter in %s", fieldPath, message.fullName)
+		}
+		if i == len(fieldNames)-1 {
+			return b.getFieldSchema(field)
+		}
+		if field.GetType() != descriptor.FieldDescriptorProto_TYPE_MESSAGE {
+			return nil, fmt.Errorf("field %s of path parameter %s is not a message", fieldName, fieldPath)
+		}
+		var err error
+		if message, err = b.registry.getMessage(field.GetTypeName()); err != nil {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("empty path parameter")
+}
+
+// getMessageSchema returns the schema of the message, which is a reference
+// to a component schema unless the message is a Well-Known Type.
+func (b *documentBuilder) getMessageSchema(message *message) *schema {
+	if wktSchema := getWKTSchema(message); wktSchema != nil {
+		return wktSchema
+	}
+	if _, ok := b.schemas[message.fullName]; !ok {
+		// add before populating so recursive messages terminate
+		messageSchema := &schema{
+			Type:        "object",
+			Description: b.registry.getComments(message.fileName, message.path),
+			Deprecated:  message.GetOptions().GetDeprecated(),
+			Properties:  make(map[string]*schema),
+		}
+		b.schemas[message.fullName] = messageSchema
+		b.populateMessageSchema(messageSchema, message)
+	}
+	return newRefSchema(message.fullName)
+}
+
+func (b *documentBuilder) populateMessageSchema(messageSchema *schema, message *message) {
+	for i, field := range message.GetField() {
+		fieldSchema, err := b.getFieldSchema(field)
+		if err != nil {
+			// types are resolved by protoc, so this only happens for
+			// FileDescriptorSets that do not include all imports
+			fieldSchema = &schema{}
+		}
+		if description := b.registry.getComments(message.fileName, appendPath(message.path, messageFieldTag, int32(i))); description != "" || field.GetOptions().GetDeprecated() {
+			// properties that reference other schemas cannot have siblings
+			if fieldSchema.Ref != "" {
+				fieldSchema = &schema{AllOf: []*schema{fieldSchema}}
+			} else {
+				fieldSchemaCopy := *fieldSchema
+				fieldSchema = &fieldSchemaCopy
+			}
+			fieldSchema.Description = description
+			fieldSchema.Deprecated = field.GetOptions().GetDeprecated()
+		}
+		messageSchema.Properties[field.GetJsonName()] = fieldSchema
+	}
+}
+
+func (b *documentBuilder) getFieldSchema(field *descriptor.FieldDescriptorProto) (*schema, error) {
+	if field.GetLabel() == descriptor.FieldDescriptorProto_LABEL_REPEATED {
+		if field.GetType() == descriptor.FieldDescriptorProto_TYPE_MESSAGE {
+			fieldMessage, err := b.registry.getMessage(field.GetTypeName())
+			if err != nil {
+				return nil, err
+			}
+			if fieldMessage.GetOptions().GetMapEntry() {
+				valueSchema, err := b.getSingularFieldSchema(fieldMessage.GetField()[1])
+				if err != nil {
+					return nil, err
+				}
+				return &schema{
+					Type:                 "object",
+					AdditionalProperties: valueSchema,
+				}, nil
+			}
+		}
+		itemsSchema, err := b.getSingularFieldSchema(field)
+		if err != nil {
+			return nil, err
+		}
+		return &schema{
+			Type:  "array",
+			Items: itemsSchema,
+		}, nil
+	}
+	return b.getSingularFieldSchema(field)
+}
+
+func (b *documentBuilder) getSingularFieldSchema(field *descriptor.FieldDescriptorProto) (*schema, error) {
+	switch field.GetType() {
+	case descriptor.FieldDescriptorProto_TYPE_MESSAGE, descriptor.FieldDescriptorProto_TYPE_GROUP:
+		fieldMessage, err := b.registry.getMessage(field.GetTypeName())
+		if err != nil {
+			return nil, err
+		}
+		return b.getMessageSchema(fieldMessage), nil
+	case descriptor.FieldDescriptorProto_TYPE_ENUM:
+		enum, err := b.registry.getEnum(field.GetTypeName())
+		if err != nil {
+			return nil, err
+		}
+		return b.getEnumSchema(enum), nil
+	default:
+		return getScalarSchema(field.GetType()), nil
+	}
+}
+
+func (b *documentBuilder) getEnumSchema(enum *enum) *schema {
+	// google.protobuf.NullValue is always null in JSON
+	if enum.fullName == "google.protobuf.NullValue" {
+		return &schema{Nullable: true}
+	}
+	if _, ok := b.schemas[enum.fullName]; !ok {
+		enumSchema := &schema{
+			Type:        "string",
+			Description: b.registry.getComments(enum.fileName, enum.path),
+			Deprecated:  enum.GetOptions().GetDeprecated(),
+		}
+		for _, enumValueDescriptorProto := range enum.GetValue() {
+			enumSchema.Enum = append(enumSchema.Enum, enumValueDescriptorProto.GetName())
+		}
+		b.schemas[enum.fullName] = enumSchema
+	}
+	return newRefSchema(enum.fullName)
+}
+
+// getScalarSchema returns the schema of the scalar type in the protojson mapping.
+func getScalarSchema(fieldType descriptor.FieldDescriptorProto_Type) *schema {
+	switch fieldType {
+	case descriptor.FieldDescriptorProto_TYPE_DOUBLE:
+		return &schema{Type: "number", Format: "double"}
+	case descriptor.FieldDescriptorProto_TYPE_FLOAT:
+		return &schema{Type: "number", Format: "float"}
+	case descriptor.FieldDescriptorProto_TYPE_INT32,
+		descriptor.FieldDescriptorProto_TYPE_SINT32,
+		descriptor.FieldDescriptorProto_TYPE_SFIXED32:
+		return &schema{Type: "integer", Format: "int32"}
+	case descriptor.FieldDescriptorProto_TYPE_UINT32,
+		descriptor.FieldDescriptorProto_TYPE_FIXED32:
+		return &schema{Type: "integer", Format: "int64"}
+	// 64-bit integers are strings in JSON
+	case descriptor.FieldDescriptorProto_TYPE_INT64,
+		descriptor.FieldDescriptorProto_TYPE_SINT64,
+		descriptor.FieldDescriptorProto_TYPE_SFIXED64:
+		return &schema{Type: "string", Format: "int64"}
+	case descriptor.FieldDescriptorProto_TYPE_UINT64,
+		descriptor.FieldDescriptorProto_TYPE_FIXED64:
+		return &schema{Type: "string", Format: "uint64"}
+	case descriptor.FieldDescriptorProto_TYPE_BOOL:
+		return &schema{Type: "boolean"}
+	case descriptor.FieldDescriptorProto_TYPE_BYTES:
+		return &schema{Type: "string", Format: "byte"}
+	default:
+		return &schema{Type: "string"}
+	}
+}
+
+// getWKTSchema returns the schema of the JSON equivalent of the message
+// if it is a Well-Known Type with a special JSON mapping, or nil otherwise.
+func getWKTSchema(message *message) *schema {
+	if _, ok := wkt.Filenames[message.fileName]; !ok {
+		return nil
+	}
+	switch message.fullName {
+	case "google.protobuf.Any":
+		return &schema{
+			Type: "object",
+			Properties: map[string]*schema{
+				"@type": {Type: "string"},
+			},
+			AdditionalProperties: &schema{},
+		}
+	case "google.protobuf.Duration":
+		return &schema{Type: "string", Format: "duration"}
+	case "google.protobuf.Empty", "google.protobuf.Struct":
+		return &schema{Type: "object"}
+	case "google.protobuf.FieldMask":
+		return &schema{Type: "string", Format: "field-mask"}
+	case "google.protobuf.ListValue":
+		return &schema{Type: "array", Items: &schema{}}
+	case "google.protobuf.Timestamp":
+		return &schema{Type: "string", Format: "date-time"}
+	case "google.protobuf.Value":
+		return &schema{}
+	case "google.protobuf.BoolValue":
+		return getScalarSchema(descriptor.FieldDescriptorProto_TYPE_BOOL)
+	case "google.protobuf.BytesValue":
+		return getScalarSchema(descriptor.FieldDescriptorProto_TYPE_BYTES)
+	case "google.protobuf.DoubleValue":
+		return getScalarSchema(descriptor.FieldDescriptorProto_TYPE_DOUBLE)
+	case "google.protobuf.FloatValue":
+		return getScalarSchema(descriptor.FieldDescriptorProto_TYPE_FLOAT)
+	case "google.protobuf.Int32Value":
+		return getScalarSchema(descriptor.FieldDescriptorProto_TYPE_INT32)
+	case "google.protobuf.Int64Value":
+		return getScalarSchema(descriptor.FieldDescriptorProto_TYPE_INT64)
+	case "google.protobuf.StringValue":
+		return getScalarSchema(descriptor.FieldDescriptorProto_TYPE_STRING)
+	case "google.protobuf.UInt32Value":
+		return getScalarSchema(descriptor.FieldDescriptorProto_TYPE_UINT32)
+	case "google.protobuf.UInt64Value":
+		return getScalarSchema(descriptor.FieldDescriptorProto_TYPE_UINT64)
+	default:
+		return nil
+	}
+}
+
+// getHTTPRules returns the google.api.http annotation of the method and its
+// additional bindings, or nil if there is no annotation.
+func getHTTPRules(methodDescriptorProto *descriptor.MethodDescriptorProto) ([]*annotations.HttpRule, error) {
+	options := methodDescriptorProto.GetOptions()
+	if options == nil || !proto.HasExtension(options, annotations.E_Http) {
+		return nil, nil
+	}
+	extension, err := proto.GetExtension(options, annotations.E_Http)
+	if err != nil {
+		return nil, err
+	}
+	httpRule, ok := extension.(*annotations.HttpRule)
+	if !ok {
+		return nil, fmt.Errorf("unexpected type %T for google.api.http", extension)
+	}
+	return append([]*annotations.HttpRule{httpRule}, httpRule.GetAdditionalBindings()...), nil
+}
+
+// getMethodAndPathTemplate returns the lowercase HTTP method and the path
+// template of the HttpRule.
+func getMethodAndPathTemplate(httpRule *annotations.HttpRule) (string, string) {
+	switch pattern := httpRule.GetPattern().(type) {
+	case *annotations.HttpRule_Get:
+		return "get", pattern.Get
+	case *annotations.HttpRule_Put:
+		return "put", pattern.Put
+	case *annotations.HttpRule_Post:
+		return "post", pattern.Post
+	case *annotations.HttpRule_Delete:
+		return "delete", pattern.Delete
+	case *annotations.HttpRule_Patch:
+		return "patch", pattern.Patch
+	case *annotations.HttpRule_Custom:
+		return strings.ToLower(pattern.Custom.GetKind()), pattern.Custom.GetPath()
+	default:
+		return "", ""
+	}
+}
+
+// parsePathTemplate converts the google.api.http path template to an OpenAPI
+// path, and returns the field paths of the variables in the template.
+//
+// For example, /v1/{name=shelves/*}/books:get becomes /v1/{name}/books:get.
+func parsePathTemplate(pathTemplate string) (string, []string) {
+	var fieldPaths []string
+	var buffer strings.Builder
+	for {
+		start := strings.IndexByte(pathTemplate, '{')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(pathTemplate[start:], '}')
+		if end < 0 {
+			break
+		}
+		fieldPath := pathTemplate[start+1 : start+end]
+		if i := strings.IndexByte(fieldPath, '='); i >= 0 {
+			fieldPath = fieldPath[:i]
+		}
+		fieldPaths = append(fieldPaths, fieldPath)
+		buffer.WriteString(pathTemplate[:start])
+		buffer.WriteString("{" + fieldPath + "}")
+		pathTemplate = pathTemplate[start+end+1:]
+	}
+	buffer.WriteString(pathTemplate)
+	return buffer.String(), fieldPaths
+}
+
+func getField(message *message, name string) *descriptor.FieldDescriptorProto {
+	for _, field := range message.GetField() {
+		if field.GetName() == name {
+			return field
+		}
+	}
+	return nil
+}
+
+func newRefSchema(fullName string) *schema {
+	return &schema{Ref: "#/components/schemas/" + fullName}
+}
+
+func newRequestBody(bodySchema *schema) *requestBody {
+	return &requestBody{
+		Required: true,
+		Content: map[string]*mediaType{
+			"application/json": {Schema: bodySchema},
+		},
+	}
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
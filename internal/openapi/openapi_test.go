@@ -0,0 +1,260 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package openapi
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	fileNameToData, err := NewGenerator(GeneratorWithVersion("v1.2.3")).Generate(testFileDescriptorSets(t))
+	require.NoError(t, err)
+	require.Len(t, fileNameToData, 2)
+	assert.Contains(t, fileNameToData, "bar.v1.openapi.json")
+
+	doc := testUnmarshal(t, fileNameToData["foo.v1.openapi.json"])
+	assert.Equal(t, "3.0.3", doc["openapi"])
+	assert.Equal(t, map[string]interface{}{"title": "foo.v1", "version": "v1.2.3"}, doc["info"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"name": "foo.v1.FooAPI", "description": "FooAPI is an API."}}, doc["tags"])
+
+	paths := doc["paths"].(map[string]interface{})
+	assert.Len(t, paths, 3)
+	get := paths["/v1/{name}"].(map[string]interface{})["get"].(map[string]interface{})
+	assert.Equal(t, "foo.v1.FooAPI.Get", get["operationId"])
+	assert.Equal(t, "Get gets.", get["description"])
+	assert.Equal(
+		t,
+		[]interface{}{
+			map[string]interface{}{"name": "name", "in": "path", "required": true, "schema": map[string]interface{}{"type": "string"}},
+			map[string]interface{}{"name": "count", "in": "query", "schema": map[string]interface{}{"type": "string", "format": "int64"}},
+			map[string]interface{}{"name": "hello", "in": "query", "schema": map[string]interface{}{"$ref": "#/components/schemas/foo.v1.Hello"}},
+		},
+		get["parameters"],
+	)
+	assert.NotContains(t, get, "requestBody")
+	assert.Equal(
+		t,
+		map[string]interface{}{"$ref": "#/components/schemas/bar.v1.Bar"},
+		get["responses"].(map[string]interface{})["200"].(map[string]interface{})["content"].(map[string]interface{})["application/json"].(map[string]interface{})["schema"],
+	)
+	// additional bindings get a numbered operationId
+	post := paths["/v1/foos"].(map[string]interface{})["post"].(map[string]interface{})
+	assert.Equal(t, "foo.v1.FooAPI.Get2", post["operationId"])
+	assert.Contains(t, post, "requestBody")
+	// fields not in the path or the body are query parameters
+	patch := paths["/v1/{name}"].(map[string]interface{})["patch"].(map[string]interface{})
+	assert.Equal(t, "foo.v1.FooAPI.Get3", patch["operationId"])
+	assert.Equal(
+		t,
+		[]interface{}{
+			map[string]interface{}{"name": "name", "in": "path", "required": true, "schema": map[string]interface{}{"type": "string"}},
+			map[string]interface{}{"name": "hello", "in": "query", "schema": map[string]interface{}{"$ref": "#/components/schemas/foo.v1.Hello"}},
+		},
+		patch["parameters"],
+	)
+	assert.Contains(t, patch, "requestBody")
+	// methods without an annotation use the gRPC path
+	watch := paths["/foo.v1.FooAPI/Watch"].(map[string]interface{})["post"].(map[string]interface{})
+	assert.Equal(t, true, watch["deprecated"])
+	assert.Equal(
+		t,
+		map[string]interface{}{
+			"required": true,
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{
+					"schema": map[string]interface{}{"type": "object"},
+				},
+			},
+		},
+		watch["requestBody"],
+	)
+
+	schemas := doc["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+	assert.Len(t, schemas, 3)
+	assert.Equal(
+		t,
+		map[string]interface{}{
+			"type":        "object",
+			"description": "Foo is a foo.",
+			"properties": map[string]interface{}{
+				"name":  map[string]interface{}{"type": "string"},
+				"count": map[string]interface{}{"type": "string", "format": "int64", "description": "count is a count.", "deprecated": true},
+				"hello": map[string]interface{}{"$ref": "#/components/schemas/foo.v1.Hello"},
+				"labels": map[string]interface{}{
+					"type":                 "object",
+					"additionalProperties": map[string]interface{}{"$ref": "#/components/schemas/bar.v1.Bar"},
+				},
+				"createTime": map[string]interface{}{"type": "string", "format": "date-time"},
+				"bars": map[string]interface{}{
+					"type":  "array",
+					"items": map[string]interface{}{"$ref": "#/components/schemas/bar.v1.Bar"},
+				},
+			},
+		},
+		schemas["foo.v1.Foo"],
+	)
+	assert.Equal(t, map[string]interface{}{"type": "string", "enum": []interface{}{"HELLO_INVALID", "HELLO_WORLD"}}, schemas["foo.v1.Hello"])
+	assert.Contains(t, schemas, "bar.v1.Bar")
+}
+
+func TestGenerateWithMerge(t *testing.T) {
+	fileNameToData, err := NewGenerator(GeneratorWithMerge()).Generate(testFileDescriptorSets(t))
+	require.NoError(t, err)
+	require.Len(t, fileNameToData, 1)
+	doc := testUnmarshal(t, fileNameToData[MergedFileName])
+	assert.Equal(t, map[string]interface{}{"title": "bar.v1, foo.v1", "version": "version not set"}, doc["info"])
+	paths := doc["paths"].(map[string]interface{})
+	assert.Contains(t, paths, "/bar.v1.BarAPI/List")
+	assert.Contains(t, paths, "/v1/{name}")
+
+	fileNameToData, err = NewGenerator(GeneratorWithMerge()).Generate(testFileDescriptorSets(t), "bar.v1")
+	require.NoError(t, err)
+	paths = testUnmarshal(t, fileNameToData[MergedFileName])["paths"].(map[string]interface{})
+	assert.Len(t, paths, 1)
+}
+
+func TestGenerateErrors(t *testing.T) {
+	fileDescriptorSets := testFileDescriptorSets(t)
+	// drop bar.v1, which foo.v1 references
+	fileDescriptorSets[0].File = fileDescriptorSets[0].File[1:]
+	_, err := NewGenerator().Generate(fileDescriptorSets, "foo.v1")
+	assert.EqualError(t, err, "unknown message .bar.v1.Bar")
+}
+
+func TestParsePathTemplate(t *testing.T) {
+	path, fieldPaths := parsePathTemplate("/v1/{name=shelves/*}/books/{book.id}:get")
+	assert.Equal(t, "/v1/{name}/books/{book.id}:get", path)
+	assert.Equal(t, []string{"name", "book.id"}, fieldPaths)
+	path, fieldPaths = parsePathTemplate("/v1/foos")
+	assert.Equal(t, "/v1/foos", path)
+	assert.Empty(t, fieldPaths)
+}
+
+func testFileDescriptorSets(t *testing.T) []*descriptor.FileDescriptorSet {
+	fileDescriptorSet := &descriptor.FileDescriptorSet{}
+	require.NoError(t, jsonpb.UnmarshalString(testFileDescriptorSetJSON, fileDescriptorSet))
+	return []*descriptor.FileDescriptorSet{fileDescriptorSet}
+}
+
+func testUnmarshal(t *testing.T, data []byte) map[string]interface{} {
+	var doc map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &doc))
+	return doc
+}
+
+const testFileDescriptorSetJSON = `
+{
+  "file": [
+    {
+      "name": "bar/v1/bar.proto",
+      "package": "bar.v1",
+      "messageType": [{"name": "Bar"}],
+      "service": [
+        {
+          "name": "BarAPI",
+          "method": [{"name": "List", "inputType": ".bar.v1.Bar", "outputType": ".bar.v1.Bar"}]
+        }
+      ]
+    },
+    {
+      "name": "google/protobuf/empty.proto",
+      "package": "google.protobuf",
+      "messageType": [{"name": "Empty"}]
+    },
+    {
+      "name": "google/protobuf/timestamp.proto",
+      "package": "google.protobuf",
+      "messageType": [{"name": "Timestamp"}]
+    },
+    {
+      "name": "foo/v1/foo.proto",
+      "package": "foo.v1",
+      "messageType": [
+        {
+          "name": "Foo",
+          "field": [
+            {"name": "name", "number": 1, "label": "LABEL_OPTIONAL", "type": "TYPE_STRING", "jsonName": "name"},
+            {"name": "count", "number": 2, "label": "LABEL_OPTIONAL", "type": "TYPE_INT64", "options": {"deprecated": true}, "jsonName": "count"},
+            {"name": "hello", "number": 3, "label": "LABEL_OPTIONAL", "type": "TYPE_ENUM", "typeName": ".foo.v1.Hello", "jsonName": "hello"},
+            {"name": "labels", "number": 4, "label": "LABEL_REPEATED", "type": "TYPE_MESSAGE", "typeName": ".foo.v1.Foo.LabelsEntry", "jsonName": "labels"},
+            {"name": "create_time", "number": 5, "label": "LABEL_OPTIONAL", "type": "TYPE_MESSAGE", "typeName": ".google.protobuf.Timestamp", "jsonName": "createTime"},
+            {"name": "bars", "number": 6, "label": "LABEL_REPEATED", "type": "TYPE_MESSAGE", "typeName": ".bar.v1.Bar", "jsonName": "bars"}
+          ],
+          "nestedType": [
+            {
+              "name": "LabelsEntry",
+              "field": [
+                {"name": "key", "number": 1, "label": "LABEL_OPTIONAL", "type": "TYPE_STRING", "jsonName": "key"},
+                {"name": "value", "number": 2, "label": "LABEL_OPTIONAL", "type": "TYPE_MESSAGE", "typeName": ".bar.v1.Bar", "jsonName": "value"}
+              ],
+              "options": {"mapEntry": true}
+            }
+          ]
+        }
+      ],
+      "enumType": [
+        {
+          "name": "Hello",
+          "value": [{"name": "HELLO_INVALID", "number": 0}, {"name": "HELLO_WORLD", "number": 1}]
+        }
+      ],
+      "service": [
+        {
+          "name": "FooAPI",
+          "method": [
+            {
+              "name": "Get",
+              "inputType": ".foo.v1.Foo",
+              "outputType": ".bar.v1.Bar",
+              "options": {
+                "[google.api.http]": {
+                  "get": "/v1/{name=foos/*}",
+                  "additionalBindings": [{"post": "/v1/foos", "body": "*"}, {"patch": "/v1/{name=foos/*}", "body": "count"}]
+                }
+              }
+            },
+            {
+              "name": "Watch",
+              "inputType": ".google.protobuf.Empty",
+              "outputType": ".foo.v1.Foo",
+              "options": {"deprecated": true}
+            }
+          ]
+        }
+      ],
+      "sourceCodeInfo": {
+        "location": [
+          {"path": [4, 0], "leadingComments": " Foo is a foo.\n"},
+          {"path": [4, 0, 2, 1], "trailingComments": " count is a count.\n"},
+          {"path": [6, 0], "leadingComments": " FooAPI is an API.\n"},
+          {"path": [6, 0, 2, 0], "leadingComments": " Get gets.\n"}
+        ]
+      }
+    }
+  ]
+}
+`
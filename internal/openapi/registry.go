@@ -0,0 +1,207 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package openapi
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/uber/prototool/internal/protoc"
+)
+
+// The field numbers used in SourceCodeInfo location paths.
+const (
+	fileMessageTypeTag   = 4
+	fileEnumTypeTag      = 5
+	fileServiceTag       = 6
+	messageFieldTag      = 2
+	messageNestedTypeTag = 3
+	messageEnumTypeTag   = 4
+	serviceMethodTag     = 2
+)
+
+type message struct {
+	*descriptor.DescriptorProto
+	fullName string
+	fileName string
+	path     []int32
+}
+
+type enum struct {
+	*descriptor.EnumDescriptorProto
+	fullName string
+	fileName string
+	path     []int32
+}
+
+type service struct {
+	*descriptor.ServiceDescriptorProto
+	fullName    string
+	packageName string
+	fileName    string
+	path        []int32
+}
+
+// registry indexes the types and services of FileDescriptorSets by their
+// fully-qualified names.
+type registry struct {
+	messages            map[string]*message
+	enums               map[string]*enum
+	services            []*service
+	fileNameToLocations map[string]locations
+}
+
+func newRegistry(fileDescriptorSets []*descriptor.FileDescriptorSet) (*registry, error) {
+	registry := &registry{
+		messages:            make(map[string]*message),
+		enums:               make(map[string]*enum),
+		fileNameToLocations: make(map[string]locations),
+	}
+	for _, fileDescriptorProto := range protoc.MergeFileDescriptorSets(fileDescriptorSets...).GetFile() {
+		fileName := fileDescriptorProto.GetName()
+		registry.fileNameToLocations[fileName] = newLocations(fileDescriptorProto)
+		prefix := fileDescriptorProto.GetPackage()
+		for i, descriptorProto := range fileDescriptorProto.GetMessageType() {
+			registry.addMessage(prefix, fileName, []int32{fileMessageTypeTag, int32(i)}, descriptorProto)
+		}
+		for i, enumDescriptorProto := range fileDescriptorProto.GetEnumType() {
+			registry.addEnum(prefix, fileName, []int32{fileEnumTypeTag, int32(i)}, enumDescriptorProto)
+		}
+		for i, serviceDescriptorProto := range fileDescriptorProto.GetService() {
+			registry.services = append(registry.services, &service{
+				ServiceDescriptorProto: serviceDescriptorProto,
+				fullName:               getFullName(prefix, serviceDescriptorProto.GetName()),
+				packageName:            fileDescriptorProto.GetPackage(),
+				fileName:               fileName,
+				path:                   []int32{fileServiceTag, int32(i)},
+			})
+		}
+	}
+	sort.Slice(registry.services, func(i int, j int) bool {
+		return registry.services[i].fullName < registry.services[j].fullName
+	})
+	return registry, nil
+}
+
+func (r *registry) addMessage(prefix string, fileName string, path []int32, descriptorProto *descriptor.DescriptorProto) {
+	fullName := getFullName(prefix, descriptorProto.GetName())
+	r.messages[fullName] = &message{
+		DescriptorProto: descriptorProto,
+		fullName:        fullName,
+		fileName:        fileName,
+		path:            path,
+	}
+	for i, nestedDescriptorProto := range descriptorProto.GetNestedType() {
+		r.addMessage(fullName, fileName, appendPath(path, messageNestedTypeTag, int32(i)), nestedDescriptorProto)
+	}
+	for i, enumDescriptorProto := range descriptorProto.GetEnumType() {
+		r.addEnum(fullName, fileName, appendPath(path, messageEnumTypeTag, int32(i)), enumDescriptorProto)
+	}
+}
+
+func (r *registry) addEnum(prefix string, fileName string, path []int32, enumDescriptorProto *descriptor.EnumDescriptorProto) {
+	fullName := getFullName(prefix, enumDescriptorProto.GetName())
+	r.enums[fullName] = &enum{
+		EnumDescriptorProto: enumDescriptorProto,
+		fullName:            fullName,
+		fileName:            fileName,
+		path:                path,
+	}
+}
+
+// getMessage returns the message for the type name, which is
+// fully-qualified with a leading '.' as in FieldDescriptorProtos.
+func (r *registry) getMessage(typeName string) (*message, error) {
+	message, ok := r.messages[strings.TrimPrefix(typeName, ".")]
+	if !ok {
+		return nil, fmt.Errorf("unknown message %s", typeName)
+	}
+	return message, nil
+}
+
+// getEnum returns the enum for the type name, which is fully-qualified
+// with a leading '.' as in FieldDescriptorProtos.
+func (r *registry) getEnum(typeName string) (*enum, error) {
+	enum, ok := r.enums[strings.TrimPrefix(typeName, ".")]
+	if !ok {
+		return nil, fmt.Errorf("unknown enum %s", typeName)
+	}
+	return enum, nil
+}
+
+// getComments returns the leading comments of the element at the path, or
+// its trailing comments if there are no leading comments.
+func (r *registry) getComments(fileName string, path []int32) string {
+	location := r.fileNameToLocations[fileName].get(path)
+	if location == nil {
+		return ""
+	}
+	if comments := cleanComments(location.GetLeadingComments()); comments != "" {
+		return comments
+	}
+	return cleanComments(location.GetTrailingComments())
+}
+
+func getFullName(prefix string, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+// locations are the source code info locations of a file, keyed by
+// the string of their path.
+type locations map[string]*descriptor.SourceCodeInfo_Location
+
+func newLocations(fileDescriptorProto *descriptor.FileDescriptorProto) locations {
+	locations := make(locations)
+	for _, location := range fileDescriptorProto.GetSourceCodeInfo().GetLocation() {
+		locations[fmt.Sprint(location.GetPath())] = location
+	}
+	return locations
+}
+
+// get returns the location for the path, or nil if there is none.
+func (l locations) get(path []int32) *descriptor.SourceCodeInfo_Location {
+	return l[fmt.Sprint(path)]
+}
+
+// appendPath returns a new path so that paths of siblings do not share
+// a backing array.
+func appendPath(path []int32, elems ...int32) []int32 {
+	newPath := make([]int32, 0, len(path)+len(elems))
+	return append(append(newPath, path...), elems...)
+}
+
+// cleanComments removes the space protoc leaves after the comment markers
+// of each line, and the trailing newline.
+func cleanComments(comments string) string {
+	if comments == "" {
+		return ""
+	}
+	lines := strings.Split(strings.TrimRight(comments, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(strings.TrimPrefix(line, " "), " \t")
+	}
+	return strings.Join(lines, "\n")
+}
@@ -22,22 +22,24 @@ package protoc
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"os/exec"
-	"os/signal"
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
-	"syscall"
+	"time"
 
 	"github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/uber/prototool/internal/event"
 	"github.com/uber/prototool/internal/file"
 	"github.com/uber/prototool/internal/settings"
 	"github.com/uber/prototool/internal/text"
@@ -79,10 +81,15 @@ var (
 
 type compiler struct {
 	logger                             *zap.Logger
+	emitter                            event.Emitter
 	cachePath                          string
 	protocBinPath                      string
 	protocWKTPath                      string
 	protocURL                          string
+	jobs                               int
+	timeout                            time.Duration
+	overlay                            map[string][]byte
+	hiddenFilePaths                    map[string]struct{}
 	doGen                              bool
 	doFileDescriptorSet                bool
 	fileDescriptorSetFullControl       bool
@@ -92,7 +99,9 @@ type compiler struct {
 
 func newCompiler(options ...CompilerOption) *compiler {
 	compiler := &compiler{
-		logger: zap.NewNop(),
+		logger:  zap.NewNop(),
+		emitter: event.NewNopEmitter(),
+		jobs:    runtime.NumCPU(),
 	}
 	for _, option := range options {
 		option(compiler)
@@ -100,8 +109,13 @@ func newCompiler(options ...CompilerOption) *compiler {
 	return compiler
 }
 
-func (c *compiler) Compile(protoSet *file.ProtoSet) (*CompileResult, error) {
-	cmdMetas, err := c.getCmdMetas(protoSet)
+func (c *compiler) Compile(ctx context.Context, protoSet *file.ProtoSet) (*CompileResult, error) {
+	stats := &CompileStats{}
+	// the overlaid files are written to a temporary directory
+	// that is removed when we return
+	overlay := newOverlay(c.overlay, c.hiddenFilePaths)
+	defer overlay.Clean()
+	cmdMetas, err := c.getCmdMetas(protoSet, stats, overlay)
 	if err != nil {
 		cleanCmdMetas(cmdMetas)
 		return nil, err
@@ -127,14 +141,29 @@ func (c *compiler) Compile(protoSet *file.ProtoSet) (*CompileResult, error) {
 	var errs []error
 	var lock sync.Mutex
 	var wg sync.WaitGroup
-	semaphoreC := make(chan struct{}, runtime.NumCPU())
+	semaphoreC := make(chan struct{}, c.jobs)
 	for _, cmdMeta := range cmdMetas {
 		cmdMeta := cmdMeta
+		select {
+		case semaphoreC <- struct{}{}:
+		case <-ctx.Done():
+		}
+		// do not start any more invocations once cancelled
+		if ctx.Err() != nil {
+			break
+		}
 		wg.Add(1)
-		semaphoreC <- struct{}{}
 		go func() {
 			defer wg.Done()
-			iFailures, iErr := c.runCmdMeta(cmdMeta)
+			iFailures, iErr := c.runCmdMeta(ctx, cmdMeta)
+			if iErr == nil && len(iFailures) == 0 && cmdMeta.pluginName != "" {
+				c.emitter.Emit(&event.Event{
+					Type:       event.TypePluginOutputWritten,
+					Directory:  getDisplayPath(cmdMeta.protoSet, cmdMeta.dirPath),
+					Plugin:     cmdMeta.pluginName,
+					OutputPath: getDisplayPath(cmdMeta.protoSet, cmdMeta.outputPath),
+				})
+			}
 			lock.Lock()
 			failures = append(failures, iFailures...)
 			if iErr != nil {
@@ -145,6 +174,9 @@ func (c *compiler) Compile(protoSet *file.ProtoSet) (*CompileResult, error) {
 		}()
 	}
 	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	// errors are not text.Failures, these are actual unhandled
 	// system errors from calling protoc, so we short circuit
 	if len(errs) > 0 {
@@ -159,13 +191,15 @@ func (c *compiler) Compile(protoSet *file.ProtoSet) (*CompileResult, error) {
 		}
 		return nil, errors.New(strings.Join(errStrings, "\n"))
 	}
-	// if we have failures, it does not matter if we have file descriptor sets
+	text.SortFailures(failures)
+	stats.Invocations = getInvocationStats(cmdMetas)
+	// if we have error failures, it does not matter if we have file descriptor sets
 	// as we should error out, so we do not do any parsing of file descriptor sets
 	// this decision could be revisited
-	if len(failures) > 0 {
-		text.SortFailures(failures)
+	if text.CountSeverities(failures...)[text.SeverityError] > 0 {
 		return &CompileResult{
 			Failures: failures,
+			Stats:    stats,
 		}, nil
 	}
 
@@ -182,7 +216,9 @@ func (c *compiler) Compile(protoSet *file.ProtoSet) (*CompileResult, error) {
 		}
 	}
 	return &CompileResult{
+		Failures:           failures,
 		FileDescriptorSets: fileDescriptorSets,
+		Stats:              stats,
 	}, nil
 }
 
@@ -191,7 +227,7 @@ func (c *compiler) ProtocCommands(protoSet *file.ProtoSet) ([]string, error) {
 	// anyways, so we need to clean them up with cleanCmdMetas
 	// this logic could be simplified to have a "dry run" option, but ProtocCommands
 	// is more for debugging anyways
-	cmdMetas, err := c.getCmdMetas(protoSet)
+	cmdMetas, err := c.getCmdMetas(protoSet, nil, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -214,8 +250,8 @@ func (c *compiler) makeGenDirs(protoSet *file.ProtoSet) error {
 			genDirs[baseOutputPath] = struct{}{}
 		} else {
 			for dirPath := range protoSet.DirPathToFiles {
-				// skip those files not under the directory
-				if !strings.HasPrefix(dirPath, protoSet.DirPath) {
+				// skip those files not under the directory or not targeted
+				if !protoSet.IsTargetDirPath(dirPath) {
 					continue
 				}
 				relOutputFilePath, err := getRelOutputFilePath(protoSet, dirPath, genPlugin.FileSuffix)
@@ -239,7 +275,7 @@ func (c *compiler) makeGenDirs(protoSet *file.ProtoSet) error {
 	return nil
 }
 
-func (c *compiler) runCmdMeta(cmdMeta *cmdMeta) ([]*text.Failure, error) {
+func (c *compiler) runCmdMeta(ctx context.Context, cmdMeta *cmdMeta) ([]*text.Failure, error) {
 	c.logger.Debug("running protoc", zap.String("command", cmdMeta.String()))
 	buffer := bytes.NewBuffer(nil)
 	cmdMeta.execCmd.Stderr = buffer
@@ -248,27 +284,73 @@ func (c *compiler) runCmdMeta(cmdMeta *cmdMeta) ([]*text.Failure, error) {
 	// is a stdout, it will be printed to os.Stdout.
 	cmdMeta.execCmd.Stdout = ioutil.Discard
 
-	// Prepare a signal buffer so that we can kill the protoc
-	// process when Prototool receives a SIGINT or SIGTERM.
-	sig := make(chan os.Signal, 1)
-	done := make(chan error, 1)
-	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	// The flag takes precedence over the config.
+	timeout := c.timeout
+	if timeout == 0 {
+		timeout = cmdMeta.protoSet.Config.Compile.Timeout
+	}
+	var timeoutC <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		timeoutC = timer.C
+	}
 
+	c.emitter.Emit(&event.Event{
+		Type:      event.TypeProtocStarted,
+		Directory: getDisplayPath(cmdMeta.protoSet, cmdMeta.dirPath),
+		Plugin:    cmdMeta.pluginName,
+		Args:      cmdMeta.execCmd.Args,
+	})
+	start := time.Now()
+	defer func() {
+		c.emitter.Emit(&event.Event{
+			Type:      event.TypeProtocFinished,
+			Directory: getDisplayPath(cmdMeta.protoSet, cmdMeta.dirPath),
+			Plugin:    cmdMeta.pluginName,
+			Duration:  time.Since(start).Seconds(),
+		})
+	}()
+	// plugins are started by protoc, so protoc is put in its own process
+	// group to kill the plugins along with it
+	setProcessGroup(cmdMeta.execCmd)
+	if err := cmdMeta.execCmd.Start(); err != nil {
+		return nil, err
+	}
+	done := make(chan error, 1)
 	go func() {
-		done <- cmdMeta.execCmd.Run()
+		done <- cmdMeta.execCmd.Wait()
 	}()
 
 	var runErr error
 	select {
-	case s := <-sig:
+	case <-ctx.Done():
 		// Kill the process, and terminate early.
 		c.logger.Debug(
 			"terminating protoc",
 			zap.String("command", cmdMeta.String()),
-			zap.String("signal", s.String()),
+			zap.Error(ctx.Err()),
+		)
+		if err := killCmdMeta(cmdMeta, done); err != nil {
+			return nil, err
+		}
+		return nil, ctx.Err()
+	case <-timeoutC:
+		c.logger.Debug(
+			"protoc timed out",
+			zap.String("command", cmdMeta.String()),
+			zap.Duration("timeout", timeout),
 		)
-		return nil, cmdMeta.execCmd.Process.Kill()
+		if err := killCmdMeta(cmdMeta, done); err != nil {
+			return nil, err
+		}
+		cmdMeta.wallTime = time.Since(start)
+		return []*text.Failure{newTimeoutFailure(cmdMeta, timeout)}, nil
 	case runErr = <-done:
+		cmdMeta.wallTime = time.Since(start)
+		if processState := cmdMeta.execCmd.ProcessState; processState != nil {
+			cmdMeta.cpuTime = processState.UserTime() + processState.SystemTime()
+		}
 		// Exit errors are ok, we can probably parse them into text.Failures
 		// if not an exec.ExitError, short circuit.
 		if _, ok := runErr.(*exec.ExitError); !ok && runErr != nil {
@@ -285,6 +367,7 @@ func (c *compiler) runCmdMeta(cmdMeta *cmdMeta) ([]*text.Failure, error) {
 	// and plugins in general do not produce output unless there is an error.
 	// See https://github.com/uber/prototool/issues/128 for a full discussion.
 	failures := c.parseProtocOutput(cmdMeta, output)
+	text.SetSeverities(cmdMeta.protoSet.Config.IDToSeverity, failures...)
 	// We had a run error but for whatever reason did not get any parsed
 	// output lines, we still want to fail in this case
 	// this generally should not happen, especially as plugins that fail
@@ -296,7 +379,8 @@ func (c *compiler) runCmdMeta(cmdMeta *cmdMeta) ([]*text.Failure, error) {
 	return failures, nil
 }
 
-func (c *compiler) getCmdMetas(protoSet *file.ProtoSet) (cmdMetas []*cmdMeta, retErr error) {
+// stats is optional, and if set, the cache lookup is recorded
+func (c *compiler) getCmdMetas(protoSet *file.ProtoSet, stats *CompileStats, overlay *overlay) (cmdMetas []*cmdMeta, retErr error) {
 	defer func() {
 		// if we error in this function, we clean ourselves up
 		if retErr != nil {
@@ -313,9 +397,16 @@ func (c *compiler) getCmdMetas(protoSet *file.ProtoSet) (cmdMetas []*cmdMeta, re
 	if _, err := downloader.Download(); err != nil {
 		return cmdMetas, err
 	}
+	if stats != nil && c.protocBinPath == "" {
+		if downloader.CacheHit() {
+			stats.CacheHits++
+		} else {
+			stats.CacheMisses++
+		}
+	}
 	for dirPath, protoFiles := range protoSet.DirPathToFiles {
-		// skip those files not under the directory
-		if !strings.HasPrefix(dirPath, protoSet.DirPath) {
+		// skip those files not under the directory or not targeted
+		if !protoSet.IsTargetDirPath(dirPath) {
 			continue
 		}
 		// you want your proto files to be in at least one of the -I directories
@@ -339,7 +430,24 @@ func (c *compiler) getCmdMetas(protoSet *file.ProtoSet) (cmdMetas []*cmdMeta, re
 		}
 		var args []string
 		for _, include := range includes {
-			args = append(args, "-I", include)
+			// the overlaid files take precedence over the files in the include
+			overlayInclude, err := overlay.getIncludeDirPath(include)
+			if err != nil {
+				return cmdMetas, err
+			}
+			if overlayInclude != "" {
+				args = append(args, "-I", overlayInclude)
+			}
+			if !overlay.isMirroredIncludePath(include) {
+				args = append(args, "-I", include)
+			}
+		}
+		protoFilePaths := make([]string, 0, len(protoFiles))
+		for _, protoFile := range protoFiles {
+			protoFilePaths = append(protoFilePaths, protoFile.Path)
+		}
+		if err := overlay.mirrorFiles(includes, protoFilePaths); err != nil {
+			return cmdMetas, err
 		}
 		protocPath, err := downloader.ProtocPath()
 		if err != nil {
@@ -376,13 +484,14 @@ func (c *compiler) getCmdMetas(protoSet *file.ProtoSet) (cmdMetas []*cmdMeta, re
 				}
 			}
 			for _, protoFile := range protoFiles {
-				iArgs = append(iArgs, protoFile.Path)
+				iArgs = append(iArgs, overlay.getFilePath(includes, protoFile.Path))
 			}
 			cmdMetas = append(cmdMetas, &cmdMeta{
 				execCmd:    exec.Command(protocPath, iArgs...),
 				protoSet:   protoSet,
 				dirPath:    dirPath,
 				protoFiles: protoFiles,
+				overlay:    overlay,
 				// used for cleaning up the cmdMeta after everything is done
 				descriptorSetTempFilePath: descriptorSetTempFilePath,
 			})
@@ -391,16 +500,25 @@ func (c *compiler) getCmdMetas(protoSet *file.ProtoSet) (cmdMetas []*cmdMeta, re
 		if err != nil {
 			return cmdMetas, err
 		}
-		for _, pluginFlagSet := range pluginFlagSets {
+		for i, pluginFlagSet := range pluginFlagSets {
 			iArgs := append(args, pluginFlagSet...)
 			for _, protoFile := range protoFiles {
-				iArgs = append(iArgs, protoFile.Path)
+				iArgs = append(iArgs, overlay.getFilePath(includes, protoFile.Path))
+			}
+			// plugin flag sets are in the same order as the plugins
+			genPlugin := protoSet.Config.Gen.Plugins[i]
+			outputPath, err := getPluginOutputPath(protoSet, dirPath, genPlugin)
+			if err != nil {
+				return cmdMetas, err
 			}
 			cmdMetas = append(cmdMetas, &cmdMeta{
 				execCmd:    exec.Command(protocPath, iArgs...),
 				protoSet:   protoSet,
 				dirPath:    dirPath,
 				protoFiles: protoFiles,
+				overlay:    overlay,
+				pluginName: genPlugin.Name,
+				outputPath: outputPath,
 			})
 		}
 	}
@@ -474,18 +592,27 @@ func (c *compiler) getPluginFlagSets(protoSet *file.ProtoSet, dirPath string) ([
 	return pluginFlagSets, nil
 }
 
+// getPluginOutputPath returns the directory that the plugin writes to, or
+// the file if the plugin has a FileSuffix.
+func getPluginOutputPath(protoSet *file.ProtoSet, dirPath string, genPlugin settings.GenPlugin) (string, error) {
+	if genPlugin.FileSuffix == "" {
+		return genPlugin.OutputPath.AbsPath, nil
+	}
+	relOutputFilePath, err := getRelOutputFilePath(protoSet, dirPath, genPlugin.FileSuffix)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(genPlugin.OutputPath.AbsPath, relOutputFilePath), nil
+}
+
 func getPluginFlagSet(protoSet *file.ProtoSet, dirPath string, genPlugin settings.GenPlugin) ([]string, error) {
 	protoFlags, err := getPluginFlagSetProtoFlags(protoSet, dirPath, genPlugin)
 	if err != nil {
 		return nil, err
 	}
-	outputPath := genPlugin.OutputPath.AbsPath
-	if genPlugin.FileSuffix != "" {
-		relOutputFilePath, err := getRelOutputFilePath(protoSet, dirPath, genPlugin.FileSuffix)
-		if err != nil {
-			return nil, err
-		}
-		outputPath = filepath.Join(outputPath, relOutputFilePath)
+	outputPath, err := getPluginOutputPath(protoSet, dirPath, genPlugin)
+	if err != nil {
+		return nil, err
 	}
 	flagSet := []string{fmt.Sprintf("--%s_out=%s", genPlugin.Name, outputPath)}
 	if len(protoFlags) > 0 {
@@ -674,6 +801,7 @@ func (c *compiler) parseProtocLine(cmdMeta *cmdMeta, protocLine string) *text.Fa
 				Filename: bestFilePath(cmdMeta, matches[1]),
 				Line:     line,
 				Column:   column,
+				LintID:   text.UnusedImportFailureID,
 				Message:  fmt.Sprintf(`Import "%s" was not used.`, matches[4]),
 			}
 		}
@@ -694,6 +822,7 @@ func (c *compiler) parseProtocLine(cmdMeta *cmdMeta, protocLine string) *text.Fa
 				Filename: bestFilePath(cmdMeta, matches[1]),
 				Line:     line,
 				Column:   column,
+				LintID:   text.UnusedImportFailureID,
 				Message:  fmt.Sprintf(`Import "%s" was not used.`, matches[4]),
 			}
 		}
@@ -703,6 +832,7 @@ func (c *compiler) parseProtocLine(cmdMeta *cmdMeta, protocLine string) *text.Fa
 			}
 			return &text.Failure{
 				Filename: bestFilePath(cmdMeta, matches[1]),
+				LintID:   text.UnusedImportFailureID,
 				Message:  fmt.Sprintf(`Import "%s" was not used.`, matches[2]),
 			}
 		}
@@ -849,6 +979,15 @@ func (c *compiler) handleUninterpretedProtocLine(protocLine string) *text.Failur
 //
 // this does getDisplayFilePath but returns match if there is an error
 func bestFilePath(cmdMeta *cmdMeta, match string) string {
+	// failures for overlaid files point to the real files
+	if filePath, ok := cmdMeta.overlay.getRealFilePath(match); ok {
+		for _, protoFile := range cmdMeta.protoFiles {
+			if protoFile.Path == filePath {
+				return protoFile.DisplayPath
+			}
+		}
+		return filePath
+	}
 	displayFilePath, err := getDisplayFilePath(cmdMeta, match)
 	if err != nil {
 		return match
@@ -928,6 +1067,72 @@ type cmdMeta struct {
 	dirPath                   string
 	protoFiles                []*file.ProtoFile
 	descriptorSetTempFilePath string
+	// nil if there is no overlay
+	overlay *overlay
+	// empty if no plugin is used
+	pluginName string
+	outputPath string
+	// set after the command is run
+	wallTime time.Duration
+	cpuTime  time.Duration
+}
+
+// killCmdMeta kills protoc and the plugins it started, and waits for the
+// result of execCmd.Wait on done.
+func killCmdMeta(cmdMeta *cmdMeta, done <-chan error) error {
+	if err := killProcessGroup(cmdMeta.execCmd); err != nil {
+		return err
+	}
+	<-done
+	return nil
+}
+
+// newTimeoutFailure returns a Failure for the first file of the timed
+// out invocation, so that it is printed along with the other Failures
+// for the files.
+func newTimeoutFailure(cmdMeta *cmdMeta, timeout time.Duration) *text.Failure {
+	dirPath := getDisplayPath(cmdMeta.protoSet, cmdMeta.dirPath)
+	message := fmt.Sprintf("protoc timed out after %v for directory %s.", timeout, dirPath)
+	if cmdMeta.pluginName != "" {
+		message = fmt.Sprintf("protoc timed out after %v for directory %s with plugin %s.", timeout, dirPath, cmdMeta.pluginName)
+	}
+	failure := &text.Failure{
+		Message: message,
+	}
+	if len(cmdMeta.protoFiles) > 0 {
+		failure.Filename = cmdMeta.protoFiles[0].DisplayPath
+	}
+	return failure
+}
+
+// getDisplayPath returns the path relative to the working directory
+// if the path is within the working directory.
+func getDisplayPath(protoSet *file.ProtoSet, path string) string {
+	relPath, err := filepath.Rel(protoSet.WorkDirPath, path)
+	if err != nil || strings.HasPrefix(relPath, "..") {
+		return path
+	}
+	return relPath
+}
+
+func getInvocationStats(cmdMetas []*cmdMeta) []*InvocationStats {
+	invocationStats := make([]*InvocationStats, 0, len(cmdMetas))
+	for _, cmdMeta := range cmdMetas {
+		invocationStats = append(invocationStats, &InvocationStats{
+			DirPath:    cmdMeta.dirPath,
+			PluginName: cmdMeta.pluginName,
+			NumFiles:   len(cmdMeta.protoFiles),
+			WallTime:   cmdMeta.wallTime,
+			CPUTime:    cmdMeta.cpuTime,
+		})
+	}
+	sort.Slice(invocationStats, func(i int, j int) bool {
+		if invocationStats[i].DirPath != invocationStats[j].DirPath {
+			return invocationStats[i].DirPath < invocationStats[j].DirPath
+		}
+		return invocationStats[i].PluginName < invocationStats[j].PluginName
+	})
+	return invocationStats
 }
 
 func (c *cmdMeta) String() string {
@@ -0,0 +1,244 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package protoc
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/prototool/internal/file"
+	"github.com/uber/prototool/internal/text"
+)
+
+func TestCompileTimeout(t *testing.T) {
+	protoSet, compilerOptions := newTestSlowProtoc(t, "protoc:\n  timeout: 100ms\n")
+	compileResult, err := NewCompiler(compilerOptions...).Compile(context.Background(), protoSet)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		[]*text.Failure{
+			{
+				Filename: "foo/foo.proto",
+				Message:  "protoc timed out after 100ms for directory foo.",
+			},
+		},
+		compileResult.Failures,
+	)
+	require.Len(t, compileResult.Stats.Invocations, 1)
+	assert.True(t, compileResult.Stats.Invocations[0].WallTime < 10*time.Second)
+
+	// the option takes precedence over the config
+	compileResult, err = NewCompiler(append(compilerOptions, CompilerWithTimeout(200*time.Millisecond))...).Compile(context.Background(), protoSet)
+	require.NoError(t, err)
+	require.Len(t, compileResult.Failures, 1)
+	assert.Equal(t, "protoc timed out after 200ms for directory foo.", compileResult.Failures[0].Message)
+}
+
+func TestCompileTimeoutKillsChildren(t *testing.T) {
+	// the child inherits stderr, so waiting for protoc would block until
+	// the child exits if it was not killed along with protoc
+	protoSet, compilerOptions := newTestProtoc(t, "", "sleep 10 & wait")
+	start := time.Now()
+	compileResult, err := NewCompiler(append(compilerOptions, CompilerWithTimeout(100*time.Millisecond))...).Compile(context.Background(), protoSet)
+	require.NoError(t, err)
+	require.Len(t, compileResult.Failures, 1)
+	assert.Equal(t, "protoc timed out after 100ms for directory foo.", compileResult.Failures[0].Message)
+	assert.True(t, time.Since(start) < 5*time.Second)
+}
+
+func TestCompileCancel(t *testing.T) {
+	protoSet, compilerOptions := newTestSlowProtoc(t, "")
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := NewCompiler(append(compilerOptions, CompilerWithJobs(1))...).Compile(ctx, protoSet)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.True(t, time.Since(start) < 10*time.Second)
+}
+
+func TestCompileOverlay(t *testing.T) {
+	// prints a failure for every file given to protoc that was overlaid
+	protoSet, compilerOptions := newTestProtoc(
+		t,
+		"",
+		`for arg; do case "${arg}" in *.proto) grep -q overlaid "${arg}" && echo "${arg}:3:1:Overlaid." >&2; esac; done; exit 0`,
+	)
+	compileResult, err := NewCompiler(compilerOptions...).Compile(context.Background(), protoSet)
+	require.NoError(t, err)
+	assert.Empty(t, compileResult.Failures)
+
+	filePath := filepath.Join(protoSet.DirPath, "foo.proto")
+	compileResult, err = NewCompiler(
+		append(
+			compilerOptions,
+			CompilerWithOverlay(map[string][]byte{filePath: []byte("syntax = \"proto3\";\n\n// overlaid\n")}),
+		)...,
+	).Compile(context.Background(), protoSet)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		[]*text.Failure{
+			{
+				Filename: "foo/foo.proto",
+				Line:     3,
+				Column:   1,
+				Message:  "Overlaid.",
+			},
+		},
+		compileResult.Failures,
+	)
+	data, err := ioutil.ReadFile(filePath)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "overlaid")
+}
+
+func TestCompileHiddenFilePaths(t *testing.T) {
+	// prints a failure for every file given to protoc that exists, and
+	// for every include path that contains foo/hidden.proto
+	protoSet, compilerOptions := newTestProtoc(
+		t,
+		"",
+		`prev=; for arg; do [ "${prev}" = -I ] && [ -f "${arg}/foo/hidden.proto" ] && echo "foo/hidden.proto:1:1:Visible." >&2; case "${arg}" in *.proto) [ -f "${arg}" ] && echo "${arg}:3:1:Compiled." >&2; esac; prev="${arg}"; done; exit 0`,
+	)
+	hiddenFilePath := filepath.Join(protoSet.DirPath, "hidden.proto")
+	require.NoError(t, ioutil.WriteFile(hiddenFilePath, []byte("syntax = \"proto3\";\n"), 0644))
+	compileResult, err := NewCompiler(compilerOptions...).Compile(context.Background(), protoSet)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		[]*text.Failure{
+			{
+				// protoc is given the real path
+				Filename: filepath.Join(protoSet.DirPath, "foo.proto"),
+				Line:     3,
+				Column:   1,
+				Message:  "Compiled.",
+			},
+			{
+				Filename: "foo/hidden.proto",
+				Line:     1,
+				Column:   1,
+				Message:  "Visible.",
+			},
+		},
+		compileResult.Failures,
+	)
+
+	compileResult, err = NewCompiler(
+		append(
+			compilerOptions,
+			CompilerWithHiddenFilePaths([]string{hiddenFilePath}),
+		)...,
+	).Compile(context.Background(), protoSet)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		[]*text.Failure{
+			{
+				Filename: "foo/foo.proto",
+				Line:     3,
+				Column:   1,
+				Message:  "Compiled.",
+			},
+		},
+		compileResult.Failures,
+	)
+}
+
+func TestCompileHiddenFilePathsImports(t *testing.T) {
+	// prints a failure for every include path that contains the given files
+	protoSet, compilerOptions := newTestProtoc(
+		t,
+		"",
+		`prev=; for arg; do if [ "${prev}" = -I ]; then for f in foo/dep.proto foo/other.proto .git/git.proto; do [ -f "${arg}/${f}" ] && echo "${f}:1:1:Visible." >&2; done; fi; prev="${arg}"; done; exit 0`,
+	)
+	workDirPath := filepath.Dir(protoSet.DirPath)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(protoSet.DirPath, "foo.proto"), []byte("syntax = \"proto3\";\n\npackage foo;\n\nimport \"foo/dep.proto\";\n"), 0644))
+	// symlinked imports are followed
+	depFilePath := filepath.Join(filepath.Dir(workDirPath), "dep.proto")
+	require.NoError(t, ioutil.WriteFile(depFilePath, []byte("syntax = \"proto3\";\n\npackage foo;\n"), 0644))
+	require.NoError(t, os.Symlink(depFilePath, filepath.Join(protoSet.DirPath, "dep.proto")))
+	// files that are not imported are not copied
+	require.NoError(t, ioutil.WriteFile(filepath.Join(protoSet.DirPath, "other.proto"), []byte("syntax = \"proto3\";\n"), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(workDirPath, ".git"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(workDirPath, ".git", "git.proto"), []byte("syntax = \"proto3\";\n"), 0644))
+	hiddenFilePath := filepath.Join(protoSet.DirPath, "hidden.proto")
+	require.NoError(t, ioutil.WriteFile(hiddenFilePath, []byte("syntax = \"proto3\";\n"), 0644))
+
+	compileResult, err := NewCompiler(
+		append(
+			compilerOptions,
+			CompilerWithHiddenFilePaths([]string{hiddenFilePath}),
+		)...,
+	).Compile(context.Background(), protoSet)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		[]*text.Failure{
+			{
+				Filename: "foo/dep.proto",
+				Line:     1,
+				Column:   1,
+				Message:  "Visible.",
+			},
+		},
+		compileResult.Failures,
+	)
+}
+
+// newTestSlowProtoc returns a ProtoSet for the directory foo, and the
+// CompilerOptions to compile it with a protoc that sleeps for 10 seconds.
+func newTestSlowProtoc(t *testing.T, configData string) (*file.ProtoSet, []CompilerOption) {
+	return newTestProtoc(t, configData, "exec sleep 10")
+}
+
+// newTestProtoc returns a ProtoSet for the directory foo, and the
+// CompilerOptions to compile it with a protoc that runs the given script.
+func newTestProtoc(t *testing.T, configData string, script string) (*file.ProtoSet, []CompilerOption) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a shell script as protoc")
+	}
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, os.RemoveAll(tmpDir)) })
+	protocBinPath := filepath.Join(tmpDir, "bin", "protoc")
+	protocWKTPath := filepath.Join(tmpDir, "include")
+	require.NoError(t, os.MkdirAll(filepath.Dir(protocBinPath), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(protocWKTPath, "google", "protobuf"), 0755))
+	require.NoError(t, ioutil.WriteFile(protocBinPath, []byte("#!/bin/sh\n"+script+"\n"), 0755))
+	workDirPath := filepath.Join(tmpDir, "work")
+	require.NoError(t, os.MkdirAll(filepath.Join(workDirPath, "foo"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(workDirPath, "foo", "foo.proto"), []byte("syntax = \"proto3\";\n\npackage foo;\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(workDirPath, "prototool.yaml"), []byte(configData), 0644))
+	protoSet, err := file.NewProtoSetProvider().GetForDir(workDirPath, filepath.Join(workDirPath, "foo"))
+	require.NoError(t, err)
+	return protoSet, []CompilerOption{
+		CompilerWithProtocBinPath(protocBinPath),
+		CompilerWithProtocWKTPath(protocWKTPath),
+	}
+}
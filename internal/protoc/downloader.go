@@ -61,6 +61,8 @@ type downloader struct {
 
 	// the looked-up and verified to exist base path
 	cachedBasePath string
+	// whether cachedBasePath was already downloaded when looked up
+	cacheHit bool
 
 	// If set, Prototool will invoke protoc and include
 	// the well-known-types, from the configured binPath
@@ -141,12 +143,19 @@ func (d *downloader) WellKnownTypesIncludePath() (string, error) {
 	return filepath.Join(basePath, "include"), nil
 }
 
+func (d *downloader) CacheHit() bool {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+	return d.cacheHit
+}
+
 func (d *downloader) Delete() error {
 	basePath, err := d.getBasePathNoVersionOSARCH()
 	if err != nil {
 		return err
 	}
 	d.cachedBasePath = ""
+	d.cacheHit = false
 	d.logger.Debug("deleting", zap.String("path", basePath))
 	return os.RemoveAll(basePath)
 }
@@ -183,6 +192,7 @@ func (d *downloader) cache() (_ string, retErr error) {
 		d.logger.Debug("protobuf downloaded", zap.String("path", basePath))
 	} else {
 		d.logger.Debug("protobuf already downloaded", zap.String("path", basePath))
+		d.cacheHit = true
 	}
 
 	d.cachedBasePath = basePath
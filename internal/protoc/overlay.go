@@ -0,0 +1,285 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package protoc
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/uber/prototool/internal/file"
+)
+
+// overlay writes the overlaid file contents to a temporary directory
+// that protoc can read from.
+//
+// For every include path that contains overlaid files, a directory that
+// mirrors the include path but only contains the overlaid files is
+// created, which is then included before the include path so that
+// protoc resolves the overlaid files first. As the files keep their
+// path relative to the include path, protoc reports them with the
+// same names as the real files.
+//
+// For every include path that contains hidden files, the directory is
+// included instead of the include path, so that protoc cannot resolve the
+// hidden files. The compiled files and the files they transitively import
+// are then also copied to the directory by mirrorFiles.
+//
+// All methods are safe to call on a nil overlay, which does nothing.
+type overlay struct {
+	filePathToData  map[string][]byte
+	hiddenFilePaths map[string]struct{}
+	// created lazily
+	dirPath string
+	// include path to the directory that mirrors it, or empty if
+	// the include path contains no overlaid or hidden files
+	includePathToDirPath map[string]string
+	// the include paths that are replaced by the directory that mirrors them
+	mirroredIncludePaths map[string]struct{}
+	// overlaid file path within dirPath to the real file path
+	overlayFilePathToFilePath map[string]string
+}
+
+// newOverlay returns nil if filePathToData and hiddenFilePaths are empty.
+func newOverlay(filePathToData map[string][]byte, hiddenFilePaths map[string]struct{}) *overlay {
+	if len(filePathToData) == 0 && len(hiddenFilePaths) == 0 {
+		return nil
+	}
+	return &overlay{
+		filePathToData:            filePathToData,
+		hiddenFilePaths:           hiddenFilePaths,
+		includePathToDirPath:      make(map[string]string),
+		mirroredIncludePaths:      make(map[string]struct{}),
+		overlayFilePathToFilePath: make(map[string]string),
+	}
+}
+
+// getIncludeDirPath returns the directory to include before the given
+// include path, or empty if the include path contains no overlaid or
+// hidden files.
+//
+// If isMirroredIncludePath then returns true for the include path, the
+// directory is included instead of the include path.
+//
+// This is not thread-safe.
+func (o *overlay) getIncludeDirPath(includePath string) (string, error) {
+	if o == nil {
+		return "", nil
+	}
+	if dirPath, ok := o.includePathToDirPath[includePath]; ok {
+		return dirPath, nil
+	}
+	dirPath := ""
+	getDirPath := func() (string, error) {
+		if dirPath == "" {
+			if o.dirPath == "" {
+				tempDirPath, err := ioutil.TempDir("", "prototool")
+				if err != nil {
+					return "", err
+				}
+				o.dirPath = tempDirPath
+			}
+			dirPath = filepath.Join(o.dirPath, strconv.Itoa(len(o.includePathToDirPath)))
+		}
+		return dirPath, nil
+	}
+	for filePath, data := range o.filePathToData {
+		relFilePath, ok := getRelPath(includePath, filePath)
+		if !ok {
+			continue
+		}
+		if _, err := getDirPath(); err != nil {
+			return "", err
+		}
+		if err := o.writeFile(dirPath, relFilePath, filePath, data); err != nil {
+			return "", err
+		}
+	}
+	if o.containsHiddenFile(includePath) {
+		if _, err := getDirPath(); err != nil {
+			return "", err
+		}
+		o.mirroredIncludePaths[includePath] = struct{}{}
+	}
+	o.includePathToDirPath[includePath] = dirPath
+	return dirPath, nil
+}
+
+// isMirroredIncludePath returns true if the directory returned by
+// getIncludeDirPath for the include path replaces the include path.
+func (o *overlay) isMirroredIncludePath(includePath string) bool {
+	if o == nil {
+		return false
+	}
+	_, ok := o.mirroredIncludePaths[includePath]
+	return ok
+}
+
+func (o *overlay) containsHiddenFile(includePath string) bool {
+	for filePath := range o.hiddenFilePaths {
+		if _, ok := getRelPath(includePath, filePath); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// mirrorFiles copies the given files, and the files they transitively
+// import, to the directories that mirror the include paths that contain
+// them, if those include paths are replaced.
+//
+// Imports are resolved against the include paths in order, skipping the
+// hidden files, as protoc would. Unresolved imports are left for protoc
+// to report. getIncludeDirPath must have been called for all include paths.
+//
+// This is not thread-safe.
+func (o *overlay) mirrorFiles(includePaths []string, filePaths []string) error {
+	if o == nil || len(o.mirroredIncludePaths) == 0 {
+		return nil
+	}
+	seen := make(map[string]struct{})
+	queue := append([]string{}, filePaths...)
+	for len(queue) > 0 {
+		filePath := queue[0]
+		queue = queue[1:]
+		if _, ok := seen[filePath]; ok {
+			continue
+		}
+		seen[filePath] = struct{}{}
+		data, ok := o.filePathToData[filePath]
+		if !ok {
+			var err error
+			// follows symlinks
+			if data, err = ioutil.ReadFile(filePath); err != nil {
+				return err
+			}
+		}
+		for _, includePath := range includePaths {
+			if !o.isMirroredIncludePath(includePath) {
+				continue
+			}
+			relFilePath, ok := getRelPath(includePath, filePath)
+			if !ok {
+				continue
+			}
+			if err := o.writeFile(o.includePathToDirPath[includePath], relFilePath, filePath, data); err != nil {
+				return err
+			}
+		}
+		_, imports := file.ParsePackageAndImports(data)
+		for _, imp := range imports {
+			if importFilePath, ok := o.resolveImport(includePaths, imp); ok {
+				queue = append(queue, importFilePath)
+			}
+		}
+	}
+	return nil
+}
+
+// resolveImport returns the path of the first file for the import within
+// the include paths that is overlaid or exists on disk and is not hidden.
+func (o *overlay) resolveImport(includePaths []string, imp string) (string, bool) {
+	for _, includePath := range includePaths {
+		filePath := filepath.Join(includePath, filepath.FromSlash(imp))
+		if _, ok := o.hiddenFilePaths[filePath]; ok {
+			continue
+		}
+		if _, ok := o.filePathToData[filePath]; ok {
+			return filePath, true
+		}
+		if fileInfo, err := os.Stat(filePath); err == nil && fileInfo.Mode().IsRegular() {
+			return filePath, true
+		}
+	}
+	return "", false
+}
+
+func (o *overlay) writeFile(dirPath string, relFilePath string, filePath string, data []byte) error {
+	overlayFilePath := filepath.Join(dirPath, relFilePath)
+	if _, ok := o.overlayFilePathToFilePath[overlayFilePath]; ok {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(overlayFilePath), 0755); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(overlayFilePath, data, 0644); err != nil {
+		return err
+	}
+	o.overlayFilePathToFilePath[overlayFilePath] = filePath
+	return nil
+}
+
+// getFilePath returns the path to give to protoc for the given file path.
+//
+// This is the path within the directory that mirrors the first of the
+// given include paths that contains the file if the file is overlaid or
+// the include path is mirrored, and the file path otherwise.
+// getIncludeDirPath must have been called for all include paths.
+func (o *overlay) getFilePath(includePaths []string, filePath string) string {
+	if o == nil {
+		return filePath
+	}
+	_, isOverlaid := o.filePathToData[filePath]
+	for _, includePath := range includePaths {
+		relFilePath, ok := getRelPath(includePath, filePath)
+		if !ok {
+			continue
+		}
+		if !isOverlaid && !o.isMirroredIncludePath(includePath) {
+			return filePath
+		}
+		if dirPath := o.includePathToDirPath[includePath]; dirPath != "" {
+			return filepath.Join(dirPath, relFilePath)
+		}
+		return filePath
+	}
+	return filePath
+}
+
+// getRealFilePath returns the real file path for the given path
+// if it is an overlaid file written by getIncludeDirPath.
+//
+// This is thread-safe once all calls to getIncludeDirPath are done.
+func (o *overlay) getRealFilePath(path string) (string, bool) {
+	if o == nil {
+		return "", false
+	}
+	filePath, ok := o.overlayFilePathToFilePath[path]
+	return filePath, ok
+}
+
+// Clean removes the temporary directory.
+func (o *overlay) Clean() {
+	if o == nil || o.dirPath == "" {
+		return
+	}
+	_ = os.RemoveAll(o.dirPath)
+}
+
+func getRelPath(dirPath string, filePath string) (string, bool) {
+	relPath, err := filepath.Rel(dirPath, filePath)
+	if err != nil || relPath == "." || relPath == ".." || strings.HasPrefix(relPath, ".."+string(os.PathSeparator)) {
+		return "", false
+	}
+	return relPath, true
+}
@@ -21,7 +21,11 @@
 package protoc
 
 import (
+	"context"
+	"time"
+
 	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/uber/prototool/internal/event"
 	"github.com/uber/prototool/internal/file"
 	"github.com/uber/prototool/internal/settings"
 	"github.com/uber/prototool/internal/text"
@@ -56,6 +60,11 @@ type Downloader interface {
 	// If not downloaded, this downloads and caches protobuf. This is thread-safe.
 	WellKnownTypesIncludePath() (string, error)
 
+	// CacheHit returns true if Download found protobuf already downloaded in
+	// the cache, and false if protobuf had to be downloaded, or if Download
+	// has not been called or a protoc binary path is set.
+	CacheHit() bool
+
 	// Delete any downloaded artifacts.
 	//
 	// This is not thread-safe and no calls to other functions can be reliably
@@ -148,15 +157,65 @@ func (f FileDescriptorSets) Unwrap() []*descriptor.FileDescriptorSet {
 	return d
 }
 
+// Merge merges f with MergeFileDescriptorSets.
+func (f FileDescriptorSets) Merge() *descriptor.FileDescriptorSet {
+	return MergeFileDescriptorSets(f.Unwrap()...)
+}
+
+// MergeFileDescriptorSets merges the FileDescriptorSets into one, keeping
+// the first of each file that is in multiple FileDescriptorSets due to imports.
+func MergeFileDescriptorSets(fileDescriptorSets ...*descriptor.FileDescriptorSet) *descriptor.FileDescriptorSet {
+	merged := &descriptor.FileDescriptorSet{}
+	seen := make(map[string]struct{})
+	for _, fileDescriptorSet := range fileDescriptorSets {
+		for _, fileDescriptorProto := range fileDescriptorSet.GetFile() {
+			if _, ok := seen[fileDescriptorProto.GetName()]; ok {
+				continue
+			}
+			seen[fileDescriptorProto.GetName()] = struct{}{}
+			merged.File = append(merged.File, fileDescriptorProto)
+		}
+	}
+	return merged
+}
+
 // CompileResult is the result of a compile
 type CompileResult struct {
 	// The failures from all calls.
 	Failures []*text.Failure
-	// Will not be set if there are any failures.
+	// Will not be set if there are any failures with text.SeverityError.
 	//
 	// Will only be set if the CompilerWithFileDescriptorSet
 	// option is used.
 	FileDescriptorSets FileDescriptorSets
+	// The statistics of all calls.
+	Stats *CompileStats
+}
+
+// CompileStats are statistics about the protoc invocations of a compile.
+type CompileStats struct {
+	// One per protoc invocation, sorted by directory and then plugin name.
+	Invocations []*InvocationStats
+	// The number of times protobuf was found in the cache.
+	CacheHits int
+	// The number of times protobuf had to be downloaded.
+	CacheMisses int
+}
+
+// InvocationStats are statistics about a single protoc invocation.
+type InvocationStats struct {
+	// The directory of the compiled files.
+	// Expected to be absolute path.
+	DirPath string
+	// The name of the plugin, or empty if no plugin was used, which
+	// is the case when only compiling or producing a FileDescriptorSet.
+	PluginName string
+	// The number of files compiled.
+	NumFiles int
+	// The elapsed time of the invocation.
+	WallTime time.Duration
+	// The user and system CPU time of protoc and any plugins.
+	CPUTime time.Duration
 }
 
 // Compiler compiles protobuf files.
@@ -167,7 +226,11 @@ type Compiler interface {
 	// and there will be no error. The caller can determine if this is
 	// an error case. If there is any other type of error, or some output
 	// from protoc cannot be interpreted, an error will be returned.
-	Compile(*file.ProtoSet) (*CompileResult, error)
+	//
+	// If the context is cancelled, all running protoc invocations are
+	// killed and the context error is returned. Invocations that exceed
+	// the timeout are killed and returned as failures.
+	Compile(context.Context, *file.ProtoSet) (*CompileResult, error)
 
 	// Return the protoc commands that would be run on Compile.
 	//
@@ -187,6 +250,16 @@ func CompilerWithLogger(logger *zap.Logger) CompilerOption {
 	}
 }
 
+// CompilerWithEmitter returns a CompilerOption that emits protoc events
+// to the given Emitter.
+//
+// The default is to use event.NewNopEmitter().
+func CompilerWithEmitter(emitter event.Emitter) CompilerOption {
+	return func(compiler *compiler) {
+		compiler.emitter = emitter
+	}
+}
+
 // CompilerWithCachePath returns a CompilerOption that uses the given cachePath.
 //
 // The default is ${XDG_CACHE_HOME}/prototool/$(uname -s)/$(uname -m).
@@ -221,6 +294,61 @@ func CompilerWithProtocURL(protocURL string) CompilerOption {
 	}
 }
 
+// CompilerWithJobs returns a CompilerOption that runs at most the given
+// number of protoc invocations in parallel.
+//
+// The default is to use runtime.NumCPU(), which is also used if jobs
+// is not positive.
+func CompilerWithJobs(jobs int) CompilerOption {
+	return func(compiler *compiler) {
+		if jobs > 0 {
+			compiler.jobs = jobs
+		}
+	}
+}
+
+// CompilerWithTimeout returns a CompilerOption that kills protoc
+// invocations that take longer than the given timeout.
+//
+// The default is to use the protoc timeout from the config,
+// or no timeout if that is not set.
+func CompilerWithTimeout(timeout time.Duration) CompilerOption {
+	return func(compiler *compiler) {
+		compiler.timeout = timeout
+	}
+}
+
+// CompilerWithOverlay returns a CompilerOption that uses the given overlay,
+// a map from absolute, cleaned file paths to file contents, instead of
+// reading the files from disk.
+//
+// The files do not need to exist on disk, however they must be within an
+// include path. Failures refer to the real file paths.
+// ProtocCommands ignores the overlay.
+func CompilerWithOverlay(overlay map[string][]byte) CompilerOption {
+	return func(compiler *compiler) {
+		compiler.overlay = overlay
+	}
+}
+
+// CompilerWithHiddenFilePaths returns a CompilerOption that hides the given
+// files, absolute and cleaned, from protoc, so that they cannot be imported
+// even if they exist on disk.
+//
+// Include paths that contain hidden files are replaced by a temporary
+// directory that only contains the compiled files and the files they
+// transitively import, other than the hidden files. Failures refer to the
+// real file paths.
+// ProtocCommands ignores the hidden files.
+func CompilerWithHiddenFilePaths(filePaths []string) CompilerOption {
+	return func(compiler *compiler) {
+		compiler.hiddenFilePaths = make(map[string]struct{}, len(filePaths))
+		for _, filePath := range filePaths {
+			compiler.hiddenFilePaths[filePath] = struct{}{}
+		}
+	}
+}
+
 // CompilerWithGen says to also generate the code.
 func CompilerWithGen() CompilerOption {
 	return func(compiler *compiler) {
@@ -0,0 +1,49 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package protoc
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeFileDescriptorSets(t *testing.T) {
+	newFileDescriptorSet := func(names ...string) *FileDescriptorSet {
+		fileDescriptorSet := &descriptor.FileDescriptorSet{}
+		for _, name := range names {
+			name := name
+			fileDescriptorSet.File = append(fileDescriptorSet.File, &descriptor.FileDescriptorProto{Name: &name})
+		}
+		return &FileDescriptorSet{FileDescriptorSet: fileDescriptorSet}
+	}
+	merged := FileDescriptorSets{
+		newFileDescriptorSet("bar/v1/bar.proto"),
+		newFileDescriptorSet("bar/v1/bar.proto", "foo/v1/foo.proto"),
+	}.Merge()
+	var names []string
+	for _, fileDescriptorProto := range merged.GetFile() {
+		names = append(names, fileDescriptorProto.GetName())
+	}
+	assert.Equal(t, []string{"bar/v1/bar.proto", "foo/v1/foo.proto"}, names)
+	assert.Empty(t, MergeFileDescriptorSets().GetFile())
+}
@@ -31,8 +31,8 @@
 //
 // A non-comprehensive list of excluded items:
 //
-// - Source code information.
-// - All options.
+// - Source code information other than comments.
+// - All options other than deprecated.
 // - Reserved ranges and names.
 // - Message field default values.
 // - Message field oneof indexes.
@@ -70,8 +70,8 @@ const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
 // The numbers match the FieldDescriptorProto.Label numbers.
 //
 // Note that a map field will come up as repeated, with type TYPE_MESSAGE,
-// and the type_name will be *Entry. We may parse this from FieldDescriptorProtos
-// in the future and add a LABEL_MAP.
+// and the type_name will be *Entry. The Message for the type_name will have
+// map_entry set.
 type MessageField_Label int32
 
 const (
@@ -332,10 +332,20 @@ type Enum struct {
 	// enum_values contains the enum values.
 	//
 	// These will be sorted by number.
-	EnumValues           []*EnumValue `protobuf:"bytes,2,rep,name=enum_values,json=enumValues,proto3" json:"enum_values,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
-	XXX_unrecognized     []byte       `json:"-"`
-	XXX_sizecache        int32        `json:"-"`
+	EnumValues []*EnumValue `protobuf:"bytes,2,rep,name=enum_values,json=enumValues,proto3" json:"enum_values,omitempty"`
+	// leading_comments contains the comments directly before the enum.
+	//
+	// This is only set if the FileDescriptorProtos contain source code info.
+	LeadingComments string `protobuf:"bytes,3,opt,name=leading_comments,json=leadingComments,proto3" json:"leading_comments,omitempty"`
+	// trailing_comments contains the comments directly after the enum.
+	//
+	// This is only set if the FileDescriptorProtos contain source code info.
+	TrailingComments string `protobuf:"bytes,4,opt,name=trailing_comments,json=trailingComments,proto3" json:"trailing_comments,omitempty"`
+	// deprecated is true if the deprecated option is set on the enum.
+	Deprecated           bool     `protobuf:"varint,5,opt,name=deprecated,proto3" json:"deprecated,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
 func (m *Enum) Reset()         { *m = Enum{} }
@@ -377,12 +387,43 @@ func (m *Enum) GetEnumValues() []*EnumValue {
 	return nil
 }
 
+func (m *Enum) GetLeadingComments() string {
+	if m != nil {
+		return m.LeadingComments
+	}
+	return ""
+}
+
+func (m *Enum) GetTrailingComments() string {
+	if m != nil {
+		return m.TrailingComments
+	}
+	return ""
+}
+
+func (m *Enum) GetDeprecated() bool {
+	if m != nil {
+		return m.Deprecated
+	}
+	return false
+}
+
 // EnumValue describes a Protobuf enum value.
 type EnumValue struct {
 	// name contains the value name.
 	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
 	// number contains the value number.
-	Number               int32    `protobuf:"varint,2,opt,name=number,proto3" json:"number,omitempty"`
+	Number int32 `protobuf:"varint,2,opt,name=number,proto3" json:"number,omitempty"`
+	// leading_comments contains the comments directly before the enum value.
+	//
+	// This is only set if the FileDescriptorProtos contain source code info.
+	LeadingComments string `protobuf:"bytes,3,opt,name=leading_comments,json=leadingComments,proto3" json:"leading_comments,omitempty"`
+	// trailing_comments contains the comments directly after the enum value.
+	//
+	// This is only set if the FileDescriptorProtos contain source code info.
+	TrailingComments string `protobuf:"bytes,4,opt,name=trailing_comments,json=trailingComments,proto3" json:"trailing_comments,omitempty"`
+	// deprecated is true if the deprecated option is set on the enum value.
+	Deprecated           bool     `protobuf:"varint,5,opt,name=deprecated,proto3" json:"deprecated,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -427,6 +468,27 @@ func (m *EnumValue) GetNumber() int32 {
 	return 0
 }
 
+func (m *EnumValue) GetLeadingComments() string {
+	if m != nil {
+		return m.LeadingComments
+	}
+	return ""
+}
+
+func (m *EnumValue) GetTrailingComments() string {
+	if m != nil {
+		return m.TrailingComments
+	}
+	return ""
+}
+
+func (m *EnumValue) GetDeprecated() bool {
+	if m != nil {
+		return m.Deprecated
+	}
+	return false
+}
+
 // Message describes a Protobuf message.
 type Message struct {
 	// name is the name of the message.
@@ -450,7 +512,23 @@ type Message struct {
 	// nested_enums contains the enums directed nested on this message.
 	//
 	// These will be sorted by name.
-	NestedEnums          []*Enum  `protobuf:"bytes,5,rep,name=nested_enums,json=nestedEnums,proto3" json:"nested_enums,omitempty"`
+	NestedEnums []*Enum `protobuf:"bytes,5,rep,name=nested_enums,json=nestedEnums,proto3" json:"nested_enums,omitempty"`
+	// leading_comments contains the comments directly before the message.
+	//
+	// This is only set if the FileDescriptorProtos contain source code info.
+	LeadingComments string `protobuf:"bytes,6,opt,name=leading_comments,json=leadingComments,proto3" json:"leading_comments,omitempty"`
+	// trailing_comments contains the comments directly after the message.
+	//
+	// This is only set if the FileDescriptorProtos contain source code info.
+	TrailingComments string `protobuf:"bytes,7,opt,name=trailing_comments,json=trailingComments,proto3" json:"trailing_comments,omitempty"`
+	// deprecated is true if the deprecated option is set on the message.
+	Deprecated bool `protobuf:"varint,8,opt,name=deprecated,proto3" json:"deprecated,omitempty"`
+	// map_entry is true if this message is the entry type that protoc
+	// generates for a map field.
+	//
+	// Map fields are repeated fields with this type, whose fields are
+	// named key and value.
+	MapEntry             bool     `protobuf:"varint,9,opt,name=map_entry,json=mapEntry,proto3" json:"map_entry,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -516,6 +594,34 @@ func (m *Message) GetNestedEnums() []*Enum {
 	return nil
 }
 
+func (m *Message) GetLeadingComments() string {
+	if m != nil {
+		return m.LeadingComments
+	}
+	return ""
+}
+
+func (m *Message) GetTrailingComments() string {
+	if m != nil {
+		return m.TrailingComments
+	}
+	return ""
+}
+
+func (m *Message) GetDeprecated() bool {
+	if m != nil {
+		return m.Deprecated
+	}
+	return false
+}
+
+func (m *Message) GetMapEntry() bool {
+	if m != nil {
+		return m.MapEntry
+	}
+	return false
+}
+
 // MessageField describes a Protobuf message field.
 type MessageField struct {
 	// name is the name of the message field.
@@ -532,7 +638,22 @@ type MessageField struct {
 	// This does not include the prefix '.' found in the traditional package
 	// fully-qualified name. If this is a nested message, the parent messages
 	// will be part of the name.
-	TypeName             string   `protobuf:"bytes,5,opt,name=type_name,json=typeName,proto3" json:"type_name,omitempty"`
+	TypeName string `protobuf:"bytes,5,opt,name=type_name,json=typeName,proto3" json:"type_name,omitempty"`
+	// leading_comments contains the comments directly before the field.
+	//
+	// This is only set if the FileDescriptorProtos contain source code info.
+	LeadingComments string `protobuf:"bytes,6,opt,name=leading_comments,json=leadingComments,proto3" json:"leading_comments,omitempty"`
+	// trailing_comments contains the comments directly after the field.
+	//
+	// This is only set if the FileDescriptorProtos contain source code info.
+	TrailingComments string `protobuf:"bytes,7,opt,name=trailing_comments,json=trailingComments,proto3" json:"trailing_comments,omitempty"`
+	// deprecated is true if the deprecated option is set on the field.
+	Deprecated bool `protobuf:"varint,8,opt,name=deprecated,proto3" json:"deprecated,omitempty"`
+	// json_name is the name of the field in the JSON mapping.
+	//
+	// This is the lowerCamelCase name of the field unless the json_name
+	// option is set.
+	JsonName             string   `protobuf:"bytes,9,opt,name=json_name,json=jsonName,proto3" json:"json_name,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -598,6 +719,34 @@ func (m *MessageField) GetTypeName() string {
 	return ""
 }
 
+func (m *MessageField) GetLeadingComments() string {
+	if m != nil {
+		return m.LeadingComments
+	}
+	return ""
+}
+
+func (m *MessageField) GetTrailingComments() string {
+	if m != nil {
+		return m.TrailingComments
+	}
+	return ""
+}
+
+func (m *MessageField) GetDeprecated() bool {
+	if m != nil {
+		return m.Deprecated
+	}
+	return false
+}
+
+func (m *MessageField) GetJsonName() string {
+	if m != nil {
+		return m.JsonName
+	}
+	return ""
+}
+
 // MessageOneof describes a Protobuf message oneof.
 type MessageOneof struct {
 	// name is the name of the message oneof.
@@ -606,7 +755,15 @@ type MessageOneof struct {
 	// this message oneof.
 	//
 	// This will be sorted.
-	FieldNumbers         []int32  `protobuf:"varint,2,rep,packed,name=field_numbers,json=fieldNumbers,proto3" json:"field_numbers,omitempty"`
+	FieldNumbers []int32 `protobuf:"varint,2,rep,packed,name=field_numbers,json=fieldNumbers,proto3" json:"field_numbers,omitempty"`
+	// leading_comments contains the comments directly before the oneof.
+	//
+	// This is only set if the FileDescriptorProtos contain source code info.
+	LeadingComments string `protobuf:"bytes,3,opt,name=leading_comments,json=leadingComments,proto3" json:"leading_comments,omitempty"`
+	// trailing_comments contains the comments directly after the oneof.
+	//
+	// This is only set if the FileDescriptorProtos contain source code info.
+	TrailingComments     string   `protobuf:"bytes,4,opt,name=trailing_comments,json=trailingComments,proto3" json:"trailing_comments,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -651,6 +808,20 @@ func (m *MessageOneof) GetFieldNumbers() []int32 {
 	return nil
 }
 
+func (m *MessageOneof) GetLeadingComments() string {
+	if m != nil {
+		return m.LeadingComments
+	}
+	return ""
+}
+
+func (m *MessageOneof) GetTrailingComments() string {
+	if m != nil {
+		return m.TrailingComments
+	}
+	return ""
+}
+
 // Service describes a Protobuf service.
 type Service struct {
 	// name is the name of the service.
@@ -658,10 +829,20 @@ type Service struct {
 	// service_methods are the service methods.
 	//
 	// These will be sorted by name.
-	ServiceMethods       []*ServiceMethod `protobuf:"bytes,2,rep,name=service_methods,json=serviceMethods,proto3" json:"service_methods,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
-	XXX_unrecognized     []byte           `json:"-"`
-	XXX_sizecache        int32            `json:"-"`
+	ServiceMethods []*ServiceMethod `protobuf:"bytes,2,rep,name=service_methods,json=serviceMethods,proto3" json:"service_methods,omitempty"`
+	// leading_comments contains the comments directly before the service.
+	//
+	// This is only set if the FileDescriptorProtos contain source code info.
+	LeadingComments string `protobuf:"bytes,3,opt,name=leading_comments,json=leadingComments,proto3" json:"leading_comments,omitempty"`
+	// trailing_comments contains the comments directly after the service.
+	//
+	// This is only set if the FileDescriptorProtos contain source code info.
+	TrailingComments string `protobuf:"bytes,4,opt,name=trailing_comments,json=trailingComments,proto3" json:"trailing_comments,omitempty"`
+	// deprecated is true if the deprecated option is set on the service.
+	Deprecated           bool     `protobuf:"varint,5,opt,name=deprecated,proto3" json:"deprecated,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
 func (m *Service) Reset()         { *m = Service{} }
@@ -703,6 +884,27 @@ func (m *Service) GetServiceMethods() []*ServiceMethod {
 	return nil
 }
 
+func (m *Service) GetLeadingComments() string {
+	if m != nil {
+		return m.LeadingComments
+	}
+	return ""
+}
+
+func (m *Service) GetTrailingComments() string {
+	if m != nil {
+		return m.TrailingComments
+	}
+	return ""
+}
+
+func (m *Service) GetDeprecated() bool {
+	if m != nil {
+		return m.Deprecated
+	}
+	return false
+}
+
 // ServiceMethod describes a Protobuf service method.
 type ServiceMethod struct {
 	// name is the name of the service method.
@@ -724,7 +926,17 @@ type ServiceMethod struct {
 	ClientStreaming bool `protobuf:"varint,4,opt,name=client_streaming,json=clientStreaming,proto3" json:"client_streaming,omitempty"`
 	// server_streaming representing whether this is a server-side streaming
 	// method
-	ServerStreaming      bool     `protobuf:"varint,5,opt,name=server_streaming,json=serverStreaming,proto3" json:"server_streaming,omitempty"`
+	ServerStreaming bool `protobuf:"varint,5,opt,name=server_streaming,json=serverStreaming,proto3" json:"server_streaming,omitempty"`
+	// leading_comments contains the comments directly before the method.
+	//
+	// This is only set if the FileDescriptorProtos contain source code info.
+	LeadingComments string `protobuf:"bytes,6,opt,name=leading_comments,json=leadingComments,proto3" json:"leading_comments,omitempty"`
+	// trailing_comments contains the comments directly after the method.
+	//
+	// This is only set if the FileDescriptorProtos contain source code info.
+	TrailingComments string `protobuf:"bytes,7,opt,name=trailing_comments,json=trailingComments,proto3" json:"trailing_comments,omitempty"`
+	// deprecated is true if the deprecated option is set on the method.
+	Deprecated           bool     `protobuf:"varint,8,opt,name=deprecated,proto3" json:"deprecated,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -790,6 +1002,27 @@ func (m *ServiceMethod) GetServerStreaming() bool {
 	return false
 }
 
+func (m *ServiceMethod) GetLeadingComments() string {
+	if m != nil {
+		return m.LeadingComments
+	}
+	return ""
+}
+
+func (m *ServiceMethod) GetTrailingComments() string {
+	if m != nil {
+		return m.TrailingComments
+	}
+	return ""
+}
+
+func (m *ServiceMethod) GetDeprecated() bool {
+	if m != nil {
+		return m.Deprecated
+	}
+	return false
+}
+
 func init() {
 	proto.RegisterEnum("uber.proto.reflect.v1.MessageField_Label", MessageField_Label_name, MessageField_Label_value)
 	proto.RegisterEnum("uber.proto.reflect.v1.MessageField_Type", MessageField_Type_name, MessageField_Type_value)
@@ -809,58 +1042,65 @@ func init() {
 }
 
 var fileDescriptor_4826d1b778a478a3 = []byte{
-	// 833 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x54, 0x4d, 0x6f, 0xeb, 0x44,
-	0x14, 0xc5, 0x4e, 0xdc, 0x36, 0x37, 0x5f, 0xd3, 0x91, 0x1e, 0x32, 0x7a, 0x12, 0x8a, 0x5c, 0x16,
-	0x29, 0x42, 0xa9, 0x92, 0x56, 0x45, 0x42, 0x08, 0x94, 0x28, 0x6e, 0x08, 0xca, 0x87, 0x99, 0x38,
-	0x11, 0x0f, 0x15, 0x59, 0x69, 0x3a, 0x7d, 0x54, 0xd8, 0x4e, 0xf0, 0x38, 0x91, 0xfa, 0x77, 0x58,
-	0xf2, 0x23, 0x58, 0x23, 0xb6, 0xfc, 0x14, 0x56, 0xec, 0xd0, 0xcc, 0xd8, 0x53, 0x07, 0x99, 0xbc,
-	0xd7, 0x95, 0x67, 0xce, 0x3d, 0xe7, 0xde, 0x33, 0xe3, 0x7b, 0x07, 0xce, 0xb6, 0x77, 0x34, 0xba,
-	0xd8, 0x44, 0xeb, 0x78, 0x7d, 0x11, 0xd1, 0x07, 0x9f, 0xae, 0xe2, 0x8b, 0x5d, 0x3b, 0x5d, 0xb6,
-	0x44, 0x00, 0xbf, 0xe2, 0x24, 0xb9, 0x6e, 0xa5, 0x91, 0x5d, 0xdb, 0xfa, 0x06, 0xc0, 0x59, 0xae,
-	0x7e, 0x5e, 0xbe, 0xa5, 0x33, 0x1a, 0xe3, 0x2f, 0xe0, 0x64, 0x23, 0x77, 0xcc, 0xd4, 0x1a, 0x85,
-	0x66, 0xb9, 0xf3, 0x71, 0x2b, 0x57, 0xd7, 0x4a, 0x44, 0x44, 0xf1, 0xad, 0xbf, 0x35, 0x38, 0x4e,
-	0x50, 0x8c, 0xa1, 0x18, 0x2e, 0x03, 0x6a, 0x6a, 0x0d, 0xad, 0x59, 0x22, 0x62, 0x8d, 0xcf, 0x01,
-	0xdd, 0xd3, 0x0d, 0x0d, 0xef, 0x69, 0xb8, 0x7a, 0xf2, 0x38, 0xc4, 0x4c, 0xbd, 0x51, 0x68, 0x96,
-	0x48, 0xfd, 0x19, 0x9f, 0x70, 0x18, 0xb7, 0xc1, 0xa0, 0xe1, 0x36, 0x60, 0x66, 0x41, 0x78, 0x78,
-	0xfd, 0x3f, 0x1e, 0xec, 0x70, 0x1b, 0x10, 0xc9, 0xe4, 0xce, 0x03, 0xca, 0x98, 0x70, 0x5e, 0x3c,
-	0xe8, 0x7c, 0x2c, 0x69, 0x44, 0xf1, 0xb9, 0x96, 0xd1, 0x68, 0xf7, 0xb8, 0xa2, 0xcc, 0x34, 0x0e,
-	0x6a, 0x67, 0x92, 0x46, 0x14, 0xdf, 0xfa, 0x11, 0x8a, 0xdc, 0x46, 0xee, 0x89, 0xbb, 0x50, 0xe6,
-	0xe6, 0xbc, 0xdd, 0xd2, 0xdf, 0x26, 0x87, 0x2d, 0x77, 0x1a, 0x07, 0x0e, 0xb3, 0xe0, 0x44, 0x02,
-	0x34, 0x5d, 0x32, 0xeb, 0x73, 0x28, 0xa9, 0x40, 0x6e, 0x8d, 0x0f, 0xe1, 0x28, 0xdc, 0x06, 0x77,
-	0x34, 0x32, 0xf5, 0x86, 0xd6, 0x34, 0x48, 0xb2, 0xb3, 0x7e, 0xd7, 0xe1, 0x38, 0x39, 0x69, 0xae,
-	0xee, 0x5b, 0xa8, 0x25, 0xe7, 0xf7, 0x1e, 0x1e, 0xa9, 0x7f, 0x9f, 0xda, 0x3b, 0x3b, 0x7c, 0x6b,
-	0x37, 0x9c, 0x4b, 0xaa, 0x41, 0x66, 0xc7, 0xb2, 0xb9, 0xd6, 0x21, 0x5d, 0x3f, 0xa4, 0xff, 0xed,
-	0x1d, 0xb9, 0xa6, 0x9c, 0xab, 0x72, 0x89, 0x1d, 0xc3, 0x03, 0xa8, 0x87, 0x94, 0xc5, 0xf4, 0xde,
-	0x7b, 0xe1, 0xef, 0xac, 0x49, 0xd9, 0x38, 0xfd, 0xa9, 0x5f, 0x41, 0x25, 0x49, 0x24, 0x5b, 0xc9,
-	0x78, 0x77, 0x2b, 0x95, 0xa5, 0x80, 0xaf, 0x99, 0xf5, 0x4f, 0x11, 0x2a, 0xd9, 0x43, 0xbf, 0xe4,
-	0xf6, 0xf1, 0xd7, 0x60, 0xf8, 0xcb, 0x3b, 0xea, 0x9b, 0x85, 0x86, 0xd6, 0xac, 0x75, 0xce, 0xdf,
-	0xe3, 0x52, 0x5b, 0x23, 0x2e, 0x20, 0x52, 0x87, 0xbf, 0x84, 0x62, 0xfc, 0xb4, 0xa1, 0x66, 0x51,
-	0xe8, 0x9b, 0xef, 0xa3, 0x77, 0x9f, 0x36, 0x94, 0x08, 0x15, 0x7e, 0x0d, 0x25, 0xfe, 0x15, 0x43,
-	0x66, 0x1a, 0xc2, 0xef, 0x09, 0x07, 0xf8, 0x74, 0x59, 0x0b, 0x30, 0x44, 0x29, 0x7c, 0x0a, 0xd5,
-	0x51, 0xb7, 0x67, 0x8f, 0xbc, 0xe1, 0x64, 0xd1, 0x1d, 0x0d, 0xfb, 0xe8, 0x03, 0x8c, 0xa1, 0x26,
-	0xa1, 0xa9, 0xe3, 0x0e, 0xa7, 0x93, 0xee, 0x08, 0x69, 0xcf, 0x18, 0xb1, 0xbf, 0x9b, 0x0f, 0x89,
-	0xdd, 0x47, 0x7a, 0x16, 0x73, 0xec, 0xae, 0x6b, 0xf7, 0x51, 0xc1, 0xfa, 0x43, 0x87, 0x22, 0xf7,
-	0x80, 0x11, 0x54, 0xdc, 0x37, 0x8e, 0x9d, 0x49, 0x5b, 0x87, 0xb2, 0x40, 0xfa, 0xd3, 0x79, 0x6f,
-	0x64, 0x23, 0x0d, 0xd7, 0x00, 0x04, 0x70, 0x33, 0x9a, 0x76, 0x5d, 0xa4, 0xab, 0xfd, 0x70, 0xe2,
-	0x5e, 0x5f, 0xa1, 0x82, 0x12, 0xcc, 0x25, 0x50, 0xcc, 0x12, 0x2e, 0x3b, 0xc8, 0x50, 0x35, 0x6e,
-	0x86, 0xdf, 0xdb, 0xfd, 0xeb, 0x2b, 0x74, 0xb4, 0x8f, 0x5c, 0x76, 0xd0, 0x31, 0xae, 0x42, 0x49,
-	0x20, 0xbd, 0xe9, 0x74, 0x84, 0x4e, 0x54, 0xce, 0x99, 0x4b, 0x86, 0x93, 0x01, 0x2a, 0xa9, 0x9c,
-	0x03, 0x32, 0x9d, 0x3b, 0x08, 0x54, 0x86, 0xb1, 0x3d, 0x9b, 0x75, 0x07, 0x36, 0x2a, 0x2b, 0x46,
-	0xef, 0x8d, 0x6b, 0xcf, 0x50, 0x65, 0xcf, 0xd6, 0x65, 0x07, 0x55, 0x55, 0x09, 0x7b, 0x32, 0x1f,
-	0xa3, 0x1a, 0xbf, 0x51, 0x59, 0x22, 0x35, 0x51, 0xff, 0x0f, 0x74, 0x7d, 0x85, 0xd0, 0xb3, 0x11,
-	0x99, 0xe5, 0x74, 0x0f, 0xb8, 0xbe, 0x42, 0xd8, 0x1a, 0xa8, 0xd6, 0x13, 0x53, 0x91, 0xdb, 0x7a,
-	0x67, 0x50, 0x15, 0x83, 0xeb, 0xc9, 0x96, 0x93, 0xf3, 0x6b, 0x90, 0x8a, 0x00, 0x27, 0x12, 0xb3,
-	0x7c, 0x38, 0x4e, 0x9e, 0xac, 0xdc, 0x1c, 0x63, 0xa8, 0x27, 0x0f, 0x99, 0x17, 0xd0, 0xf8, 0xa7,
-	0xb5, 0x7a, 0x05, 0x3e, 0x39, 0xfc, 0xfe, 0x8d, 0x05, 0x99, 0xd4, 0x58, 0x76, 0xcb, 0xac, 0xbf,
-	0x34, 0xa8, 0xee, 0x31, 0x72, 0x8b, 0x7e, 0x0a, 0xa7, 0x11, 0xfd, 0x65, 0x4b, 0x59, 0xec, 0x3d,
-	0x37, 0xa9, 0x2e, 0x08, 0xf5, 0x24, 0xe0, 0x26, 0xbd, 0x8a, 0x3f, 0x03, 0x1c, 0x51, 0xb6, 0x59,
-	0x87, 0x8c, 0x66, 0xc8, 0x05, 0x41, 0x46, 0x69, 0x44, 0xb1, 0xcf, 0x01, 0xad, 0xfc, 0x47, 0x1a,
-	0xc6, 0x1e, 0x8b, 0x23, 0xba, 0x0c, 0x1e, 0xc3, 0xb7, 0x62, 0x80, 0x4e, 0x48, 0x5d, 0xe2, 0xb3,
-	0x14, 0xe6, 0x54, 0x6e, 0x9e, 0x46, 0x19, 0xaa, 0x21, 0xa9, 0x12, 0x57, 0xd4, 0x9e, 0x0f, 0x1f,
-	0xad, 0xd6, 0x41, 0xfe, 0x85, 0xf4, 0x2a, 0x44, 0xae, 0x1d, 0x1e, 0x70, 0xb4, 0x1f, 0x4a, 0x49,
-	0x6c, 0xd7, 0xfe, 0x55, 0x2f, 0xcc, 0x1d, 0xf2, 0x9b, 0xfe, 0x6a, 0xce, 0x85, 0x22, 0xde, 0x4a,
-	0xc8, 0xad, 0x45, 0xfb, 0x4f, 0x89, 0xdf, 0x0a, 0xfc, 0x36, 0xc1, 0x6f, 0x17, 0xed, 0xbb, 0x23,
-	0x51, 0xe2, 0xf2, 0xdf, 0x00, 0x00, 0x00, 0xff, 0xff, 0x4a, 0x32, 0x37, 0xc1, 0xd4, 0x07, 0x00,
-	0x00,
+	// 954 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xc4, 0x55, 0xdd, 0x6e, 0xeb, 0x44,
+	0x10, 0xc6, 0xf9, 0xf7, 0xe4, 0x6f, 0xbb, 0xd2, 0x41, 0x41, 0x47, 0x3a, 0x8a, 0x52, 0x2e, 0x72,
+	0x00, 0xa5, 0x4a, 0x5a, 0xf5, 0x02, 0x21, 0x50, 0x42, 0xdc, 0x12, 0x94, 0x3f, 0x36, 0x4e, 0xc4,
+	0x41, 0x95, 0x2c, 0x37, 0x99, 0x96, 0x40, 0xec, 0x04, 0xdb, 0x89, 0xd4, 0x97, 0xe0, 0x15, 0x90,
+	0xb8, 0xe4, 0x86, 0xd7, 0x40, 0x70, 0x89, 0x78, 0x03, 0xde, 0x80, 0x17, 0x40, 0xbb, 0x6b, 0xbb,
+	0xce, 0x91, 0x49, 0xdb, 0x0b, 0xd4, 0x2b, 0xef, 0x7e, 0xf3, 0xcd, 0xec, 0x37, 0x3b, 0xb3, 0x63,
+	0x38, 0xde, 0x5e, 0xa3, 0x73, 0xb2, 0x71, 0xd6, 0xde, 0xfa, 0xc4, 0xc1, 0x9b, 0x15, 0xce, 0xbd,
+	0x93, 0x5d, 0x33, 0x58, 0x36, 0x84, 0x81, 0xbe, 0xe0, 0x24, 0xb9, 0x6e, 0x04, 0x96, 0x5d, 0xb3,
+	0xf6, 0x05, 0xc0, 0xd8, 0x9c, 0x7f, 0x6f, 0xde, 0xe2, 0x04, 0x3d, 0xfa, 0x31, 0xe4, 0x36, 0x72,
+	0xe7, 0x56, 0x94, 0x6a, 0xb2, 0x9e, 0x6f, 0xbd, 0x6a, 0xc4, 0xfa, 0x35, 0x7c, 0x27, 0x16, 0xf2,
+	0x6b, 0xff, 0x28, 0x90, 0xf5, 0x51, 0x4a, 0x21, 0x65, 0x9b, 0x16, 0x56, 0x94, 0xaa, 0x52, 0x57,
+	0x99, 0x58, 0xd3, 0xd7, 0x40, 0x16, 0xb8, 0x41, 0x7b, 0x81, 0xf6, 0xfc, 0xce, 0xe0, 0x90, 0x5b,
+	0x49, 0x54, 0x93, 0x75, 0x95, 0x95, 0xef, 0xf1, 0x21, 0x87, 0x69, 0x13, 0xd2, 0x68, 0x6f, 0x2d,
+	0xb7, 0x92, 0x14, 0x1a, 0x5e, 0xfe, 0x87, 0x06, 0xcd, 0xde, 0x5a, 0x4c, 0x32, 0xb9, 0x72, 0x0b,
+	0x5d, 0x57, 0x28, 0x4f, 0x1d, 0x54, 0x3e, 0x90, 0x34, 0x16, 0xf2, 0xb9, 0xaf, 0x8b, 0xce, 0x6e,
+	0x39, 0x47, 0xb7, 0x92, 0x3e, 0xe8, 0x3b, 0x91, 0x34, 0x16, 0xf2, 0x6b, 0x7f, 0x29, 0x90, 0xe2,
+	0x3a, 0x62, 0x53, 0x6e, 0x43, 0x9e, 0xab, 0x33, 0x76, 0xe6, 0x6a, 0xeb, 0x67, 0x9b, 0x6f, 0x55,
+	0x0f, 0x64, 0x33, 0xe3, 0x44, 0x06, 0x18, 0x2c, 0x5d, 0x7e, 0x6b, 0x2b, 0x34, 0x17, 0x4b, 0xfb,
+	0xd6, 0x98, 0xaf, 0x2d, 0x0b, 0x6d, 0x8f, 0xdf, 0x0a, 0x3f, 0xa2, 0xec, 0xe3, 0x9f, 0xfb, 0x30,
+	0xfd, 0x10, 0x8e, 0x3c, 0xc7, 0x5c, 0xae, 0xf6, 0xb8, 0x29, 0xc1, 0x25, 0x81, 0x21, 0x24, 0xbf,
+	0x02, 0x58, 0xe0, 0xc6, 0xc1, 0xb9, 0xe9, 0xe1, 0xa2, 0x92, 0xae, 0x2a, 0xf5, 0x1c, 0x8b, 0x20,
+	0xb5, 0x5f, 0x15, 0x50, 0x43, 0x45, 0xb1, 0xc9, 0xbd, 0x0b, 0x19, 0x7b, 0x6b, 0x5d, 0xa3, 0x53,
+	0x49, 0x54, 0x95, 0x7a, 0x9a, 0xf9, 0xbb, 0x67, 0x53, 0xfc, 0x67, 0x12, 0xb2, 0x7e, 0x6d, 0x63,
+	0xf5, 0x7e, 0x09, 0x25, 0xbf, 0xe2, 0xc6, 0xcd, 0x12, 0x57, 0x8b, 0xa0, 0x1e, 0xc7, 0x87, 0xfb,
+	0xe4, 0x82, 0x73, 0x59, 0xd1, 0x8a, 0xec, 0xdc, 0x68, 0xac, 0xb5, 0x8d, 0xeb, 0x9b, 0xa0, 0x53,
+	0x1f, 0x88, 0x35, 0xe2, 0xdc, 0x30, 0x96, 0xd8, 0xb9, 0xf4, 0x12, 0xca, 0x36, 0xba, 0x1e, 0x2e,
+	0x8c, 0x27, 0x36, 0x70, 0x49, 0xba, 0x0d, 0x82, 0x36, 0xfe, 0x14, 0x0a, 0x7e, 0x20, 0xf9, 0x78,
+	0xd2, 0x0f, 0x3f, 0x9e, 0xbc, 0x74, 0xd0, 0xc4, 0x13, 0x8a, 0x2b, 0x5c, 0xe6, 0x09, 0x85, 0xcb,
+	0x3e, 0xaa, 0x70, 0xb9, 0xb7, 0x0b, 0x47, 0x5f, 0x82, 0x6a, 0x99, 0x1b, 0x03, 0x6d, 0xcf, 0xb9,
+	0xab, 0xa8, 0xc2, 0x9c, 0xb3, 0xcc, 0x8d, 0xc6, 0xf7, 0xb5, 0x1f, 0x33, 0x50, 0x88, 0x56, 0xe2,
+	0x49, 0xad, 0xf8, 0x19, 0xa4, 0x57, 0xe6, 0x35, 0xae, 0x44, 0xff, 0x95, 0x5a, 0xaf, 0x1f, 0x51,
+	0xe9, 0x46, 0x9f, 0x3b, 0x30, 0xe9, 0x47, 0x3f, 0x81, 0x94, 0x77, 0xb7, 0x41, 0xd1, 0x93, 0xa5,
+	0x56, 0xfd, 0x31, 0xfe, 0xfa, 0xdd, 0x06, 0x99, 0xf0, 0xe2, 0x89, 0xf1, 0xaf, 0x98, 0x75, 0xa2,
+	0x61, 0x55, 0x96, 0xe3, 0xc0, 0xd0, 0x1f, 0x87, 0xcf, 0x75, 0xdb, 0xdf, 0xb9, 0x6b, 0x5b, 0x8a,
+	0x52, 0xa5, 0x28, 0x0e, 0x70, 0x51, 0xb5, 0x19, 0xa4, 0x45, 0xfe, 0xf4, 0x08, 0x8a, 0xfd, 0x76,
+	0x47, 0xeb, 0x1b, 0xbd, 0xe1, 0xac, 0xdd, 0xef, 0x75, 0xc9, 0x3b, 0x94, 0x42, 0x49, 0x42, 0xa3,
+	0xb1, 0xde, 0x1b, 0x0d, 0xdb, 0x7d, 0xa2, 0xdc, 0x63, 0x4c, 0xfb, 0x6a, 0xda, 0x63, 0x5a, 0x97,
+	0x24, 0xa2, 0xd8, 0x58, 0x6b, 0xeb, 0x5a, 0x97, 0x24, 0x6b, 0xbf, 0x25, 0x20, 0xc5, 0x2f, 0x86,
+	0x12, 0x28, 0xe8, 0x6f, 0xc6, 0x5a, 0x24, 0x6c, 0x19, 0xf2, 0x02, 0xe9, 0x8e, 0xa6, 0x9d, 0xbe,
+	0x46, 0x14, 0x5a, 0x02, 0x10, 0xc0, 0x45, 0x7f, 0xd4, 0xd6, 0x49, 0x22, 0xdc, 0xf7, 0x86, 0xfa,
+	0xf9, 0x19, 0x49, 0x86, 0x0e, 0x53, 0x09, 0xa4, 0xa2, 0x84, 0xd3, 0x16, 0x49, 0x87, 0x67, 0x5c,
+	0xf4, 0xbe, 0xd6, 0xba, 0xe7, 0x67, 0x24, 0xb3, 0x8f, 0x9c, 0xb6, 0x48, 0x96, 0x16, 0x41, 0x15,
+	0x48, 0x67, 0x34, 0xea, 0x93, 0x5c, 0x18, 0x73, 0xa2, 0xb3, 0xde, 0xf0, 0x92, 0xa8, 0x61, 0xcc,
+	0x4b, 0x36, 0x9a, 0x8e, 0x09, 0x84, 0x11, 0x06, 0xda, 0x64, 0xd2, 0xbe, 0xd4, 0x48, 0x3e, 0x64,
+	0x74, 0xde, 0xe8, 0xda, 0x84, 0x14, 0xf6, 0x64, 0x9d, 0xb6, 0x48, 0x31, 0x3c, 0x42, 0x1b, 0x4e,
+	0x07, 0xa4, 0xc4, 0x6f, 0x54, 0x1e, 0x11, 0x88, 0x28, 0xbf, 0x05, 0x9d, 0x9f, 0x11, 0x72, 0x2f,
+	0x44, 0x46, 0x39, 0xda, 0x03, 0xce, 0xcf, 0x08, 0xad, 0xfd, 0xa4, 0x84, 0x0f, 0x42, 0x0c, 0x90,
+	0xd8, 0x07, 0x71, 0x0c, 0x45, 0x31, 0xe3, 0x0c, 0xf9, 0x10, 0xe4, 0xa8, 0x4b, 0xb3, 0x82, 0x00,
+	0x87, 0x12, 0xfb, 0xbf, 0x06, 0x75, 0xed, 0x6f, 0x05, 0xb2, 0xfe, 0x8f, 0x32, 0x56, 0xdc, 0x00,
+	0xca, 0xfe, 0xef, 0xd3, 0xb0, 0xd0, 0xfb, 0x76, 0x1d, 0x4e, 0xe2, 0xf7, 0x0f, 0xff, 0x75, 0x07,
+	0x82, 0xcc, 0x4a, 0x6e, 0x74, 0xfb, 0x7c, 0x7f, 0xc8, 0x3f, 0x12, 0x50, 0xdc, 0x53, 0x16, 0x9b,
+	0xec, 0x07, 0x70, 0xe4, 0xe0, 0x0f, 0x5b, 0x74, 0x3d, 0xe3, 0x7e, 0x16, 0x24, 0xa4, 0x3c, 0xdf,
+	0xa0, 0x07, 0x23, 0xe1, 0x23, 0xa0, 0x0e, 0xba, 0x9b, 0xb5, 0xed, 0x62, 0x84, 0x2c, 0x73, 0x21,
+	0x81, 0x45, 0x8f, 0x0c, 0x90, 0xf9, 0x6a, 0x89, 0xb6, 0x67, 0xb8, 0x9e, 0x83, 0xa6, 0xb5, 0xb4,
+	0x6f, 0x45, 0x2e, 0x39, 0x56, 0x96, 0xf8, 0x24, 0x80, 0x39, 0x95, 0x5f, 0x1a, 0x3a, 0x11, 0xaa,
+	0x4c, 0xa8, 0x2c, 0xf1, 0x3d, 0xea, 0x73, 0x8c, 0xa5, 0xce, 0x0a, 0xde, 0x9b, 0xaf, 0xad, 0xf8,
+	0x06, 0xe8, 0x14, 0x98, 0x5c, 0x8f, 0xb9, 0x61, 0xac, 0x7c, 0xa3, 0xfa, 0xb6, 0x5d, 0xf3, 0xe7,
+	0x44, 0x72, 0x3a, 0x66, 0xbf, 0x24, 0x5e, 0x4c, 0xb9, 0xa3, 0xb0, 0x37, 0x7c, 0x72, 0x63, 0xd6,
+	0xfc, 0x5d, 0xe2, 0x57, 0x02, 0xbf, 0xf2, 0xf1, 0xab, 0x59, 0xf3, 0x3a, 0x23, 0x8e, 0x38, 0xfd,
+	0x77, 0x00, 0x25, 0xac, 0x7e, 0x10, 0x3a, 0x0b, 0x00, 0x00,
 }
@@ -24,6 +24,7 @@ import (
 	"bytes"
 	"fmt"
 	"sort"
+	"strings"
 
 	"github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/protoc-gen-go/descriptor"
@@ -31,9 +32,25 @@ import (
 	"github.com/uber/prototool/internal/strs"
 )
 
+// The field numbers used in SourceCodeInfo location paths.
+const (
+	fileMessageTypeTag   = 4
+	fileEnumTypeTag      = 5
+	fileServiceTag       = 6
+	messageFieldTag      = 2
+	messageNestedTypeTag = 3
+	messageEnumTypeTag   = 4
+	messageOneofDeclTag  = 8
+	enumValueTag         = 2
+	serviceMethodTag     = 2
+)
+
 // NewPackageSet returns a new valid PackageSet for the given
 // FileDescriptorSets.
 //
+// Comments are only populated if the FileDescriptorSets were produced
+// with --include_source_info.
+//
 // The FileDescriptorSets can have FileDescriptorProtos with the same name, but
 // they must be equal.
 func NewPackageSet(fileDescriptorSets ...*descriptor.FileDescriptorSet) (*reflectv1.PackageSet, error) {
@@ -160,7 +177,7 @@ func populateEnums(
 	fileNameToFileDescriptorProto map[string]*descriptor.FileDescriptorProto,
 ) error {
 	for _, fileDescriptorProto := range fileNameToFileDescriptorProto {
-		enums, err := getEnums(fileDescriptorProto.GetEnumType())
+		enums, err := getEnums(fileDescriptorProto.GetEnumType(), newLocations(fileDescriptorProto), []int32{fileEnumTypeTag})
 		if err != nil {
 			return err
 		}
@@ -178,7 +195,7 @@ func populateMessages(
 	fileNameToFileDescriptorProto map[string]*descriptor.FileDescriptorProto,
 ) error {
 	for _, fileDescriptorProto := range fileNameToFileDescriptorProto {
-		messages, err := getMessages(fileDescriptorProto.GetMessageType())
+		messages, err := getMessages(fileDescriptorProto.GetMessageType(), newLocations(fileDescriptorProto), []int32{fileMessageTypeTag})
 		if err != nil {
 			return err
 		}
@@ -196,7 +213,7 @@ func populateServices(
 	fileNameToFileDescriptorProto map[string]*descriptor.FileDescriptorProto,
 ) error {
 	for _, fileDescriptorProto := range fileNameToFileDescriptorProto {
-		services, err := getServices(fileDescriptorProto.GetService())
+		services, err := getServices(fileDescriptorProto.GetService(), newLocations(fileDescriptorProto), []int32{fileServiceTag})
 		if err != nil {
 			return err
 		}
@@ -241,13 +258,13 @@ func getFileNameToPackageName(packageNameToFileNameToFileDescriptorProto map[str
 	return fileNameToPackageName, nil
 }
 
-func getEnums(enumDescriptorProtos []*descriptor.EnumDescriptorProto) ([]*reflectv1.Enum, error) {
+func getEnums(enumDescriptorProtos []*descriptor.EnumDescriptorProto, locations locations, path []int32) ([]*reflectv1.Enum, error) {
 	if len(enumDescriptorProtos) == 0 {
 		return nil, nil
 	}
 	enums := make([]*reflectv1.Enum, 0, len(enumDescriptorProtos))
-	for _, enumDescriptorProto := range enumDescriptorProtos {
-		enum, err := newEnum(enumDescriptorProto)
+	for i, enumDescriptorProto := range enumDescriptorProtos {
+		enum, err := newEnum(enumDescriptorProto, locations, appendPath(path, int32(i)))
 		if err != nil {
 			return nil, err
 		}
@@ -257,27 +274,35 @@ func getEnums(enumDescriptorProtos []*descriptor.EnumDescriptorProto) ([]*reflec
 	return enums, nil
 }
 
-func newEnum(enumDescriptorProto *descriptor.EnumDescriptorProto) (*reflectv1.Enum, error) {
+func newEnum(enumDescriptorProto *descriptor.EnumDescriptorProto, locations locations, path []int32) (*reflectv1.Enum, error) {
+	location := locations.get(path)
 	enum := &reflectv1.Enum{
-		Name: enumDescriptorProto.GetName(),
+		Name:             enumDescriptorProto.GetName(),
+		LeadingComments:  cleanComments(location.GetLeadingComments()),
+		TrailingComments: cleanComments(location.GetTrailingComments()),
+		Deprecated:       enumDescriptorProto.GetOptions().GetDeprecated(),
 	}
-	for _, enumValueDescriptorProto := range enumDescriptorProto.GetValue() {
+	for i, enumValueDescriptorProto := range enumDescriptorProto.GetValue() {
+		valueLocation := locations.get(appendPath(path, enumValueTag, int32(i)))
 		enum.EnumValues = append(enum.EnumValues, &reflectv1.EnumValue{
-			Name:   enumValueDescriptorProto.GetName(),
-			Number: enumValueDescriptorProto.GetNumber(),
+			Name:             enumValueDescriptorProto.GetName(),
+			Number:           enumValueDescriptorProto.GetNumber(),
+			LeadingComments:  cleanComments(valueLocation.GetLeadingComments()),
+			TrailingComments: cleanComments(valueLocation.GetTrailingComments()),
+			Deprecated:       enumValueDescriptorProto.GetOptions().GetDeprecated(),
 		})
 	}
 	sort.Slice(enum.EnumValues, func(i int, j int) bool { return enum.EnumValues[i].Number < enum.EnumValues[j].Number })
 	return enum, nil
 }
 
-func getMessages(descriptorProtos []*descriptor.DescriptorProto) ([]*reflectv1.Message, error) {
+func getMessages(descriptorProtos []*descriptor.DescriptorProto, locations locations, path []int32) ([]*reflectv1.Message, error) {
 	if len(descriptorProtos) == 0 {
 		return nil, nil
 	}
 	messages := make([]*reflectv1.Message, 0, len(descriptorProtos))
-	for _, descriptorProto := range descriptorProtos {
-		message, err := newMessage(descriptorProto)
+	for i, descriptorProto := range descriptorProtos {
+		message, err := newMessage(descriptorProto, locations, appendPath(path, int32(i)))
 		if err != nil {
 			return nil, err
 		}
@@ -287,27 +312,35 @@ func getMessages(descriptorProtos []*descriptor.DescriptorProto) ([]*reflectv1.M
 	return messages, nil
 }
 
-func newMessage(descriptorProto *descriptor.DescriptorProto) (*reflectv1.Message, error) {
-	nestedMessages, err := getMessages(descriptorProto.GetNestedType())
+func newMessage(descriptorProto *descriptor.DescriptorProto, locations locations, path []int32) (*reflectv1.Message, error) {
+	nestedMessages, err := getMessages(descriptorProto.GetNestedType(), locations, appendPath(path, messageNestedTypeTag))
 	if err != nil {
 		return nil, err
 	}
-	nestedEnums, err := getEnums(descriptorProto.GetEnumType())
+	nestedEnums, err := getEnums(descriptorProto.GetEnumType(), locations, appendPath(path, messageEnumTypeTag))
 	if err != nil {
 		return nil, err
 	}
+	location := locations.get(path)
 	message := &reflectv1.Message{
-		Name:           descriptorProto.GetName(),
-		NestedMessages: nestedMessages,
-		NestedEnums:    nestedEnums,
+		Name:             descriptorProto.GetName(),
+		NestedMessages:   nestedMessages,
+		NestedEnums:      nestedEnums,
+		LeadingComments:  cleanComments(location.GetLeadingComments()),
+		TrailingComments: cleanComments(location.GetTrailingComments()),
+		Deprecated:       descriptorProto.GetOptions().GetDeprecated(),
+		MapEntry:         descriptorProto.GetOptions().GetMapEntry(),
 	}
 	nameToMessageOneof := make(map[string]*reflectv1.MessageOneof, len(descriptorProto.GetOneofDecl()))
-	for _, oneofDescriptorProto := range descriptorProto.GetOneofDecl() {
+	for i, oneofDescriptorProto := range descriptorProto.GetOneofDecl() {
+		oneofLocation := locations.get(appendPath(path, messageOneofDeclTag, int32(i)))
 		nameToMessageOneof[oneofDescriptorProto.GetName()] = &reflectv1.MessageOneof{
-			Name: oneofDescriptorProto.GetName(),
+			Name:             oneofDescriptorProto.GetName(),
+			LeadingComments:  cleanComments(oneofLocation.GetLeadingComments()),
+			TrailingComments: cleanComments(oneofLocation.GetTrailingComments()),
 		}
 	}
-	for _, fieldDescriptorProto := range descriptorProto.GetField() {
+	for i, fieldDescriptorProto := range descriptorProto.GetField() {
 		typeName := fieldDescriptorProto.GetTypeName()
 		if typeName != "" {
 			typeName, err = verifyFullyQualifiedNameAndStrip(typeName)
@@ -315,6 +348,7 @@ func newMessage(descriptorProto *descriptor.DescriptorProto) (*reflectv1.Message
 				return nil, err
 			}
 		}
+		fieldLocation := locations.get(appendPath(path, messageFieldTag, int32(i)))
 		message.MessageFields = append(message.MessageFields, &reflectv1.MessageField{
 			Name:   fieldDescriptorProto.GetName(),
 			Number: fieldDescriptorProto.GetNumber(),
@@ -322,9 +356,13 @@ func newMessage(descriptorProto *descriptor.DescriptorProto) (*reflectv1.Message
 			// that the numbers match up...which they do, but this isn't future proof
 			// however, the values for descriptor.proto have not changed since proto1
 			// which isn't even OSS, so we're probably fine for 10-20 years
-			Type:     reflectv1.MessageField_Type(fieldDescriptorProto.GetType()),
-			Label:    reflectv1.MessageField_Label(fieldDescriptorProto.GetLabel()),
-			TypeName: typeName,
+			Type:             reflectv1.MessageField_Type(fieldDescriptorProto.GetType()),
+			Label:            reflectv1.MessageField_Label(fieldDescriptorProto.GetLabel()),
+			TypeName:         typeName,
+			LeadingComments:  cleanComments(fieldLocation.GetLeadingComments()),
+			TrailingComments: cleanComments(fieldLocation.GetTrailingComments()),
+			Deprecated:       fieldDescriptorProto.GetOptions().GetDeprecated(),
+			JsonName:         getJSONName(fieldDescriptorProto),
 		})
 		if fieldDescriptorProto.OneofIndex != nil {
 			// TODO: super unsafe
@@ -341,13 +379,13 @@ func newMessage(descriptorProto *descriptor.DescriptorProto) (*reflectv1.Message
 	return message, nil
 }
 
-func getServices(serviceDescriptorProtos []*descriptor.ServiceDescriptorProto) ([]*reflectv1.Service, error) {
+func getServices(serviceDescriptorProtos []*descriptor.ServiceDescriptorProto, locations locations, path []int32) ([]*reflectv1.Service, error) {
 	if len(serviceDescriptorProtos) == 0 {
 		return nil, nil
 	}
 	services := make([]*reflectv1.Service, 0, len(serviceDescriptorProtos))
-	for _, serviceDescriptorProto := range serviceDescriptorProtos {
-		service, err := newService(serviceDescriptorProto)
+	for i, serviceDescriptorProto := range serviceDescriptorProtos {
+		service, err := newService(serviceDescriptorProto, locations, appendPath(path, int32(i)))
 		if err != nil {
 			return nil, err
 		}
@@ -357,12 +395,16 @@ func getServices(serviceDescriptorProtos []*descriptor.ServiceDescriptorProto) (
 	return services, nil
 }
 
-func newService(serviceDescriptorProto *descriptor.ServiceDescriptorProto) (*reflectv1.Service, error) {
+func newService(serviceDescriptorProto *descriptor.ServiceDescriptorProto, locations locations, path []int32) (*reflectv1.Service, error) {
+	location := locations.get(path)
 	service := &reflectv1.Service{
-		Name: serviceDescriptorProto.GetName(),
+		Name:             serviceDescriptorProto.GetName(),
+		LeadingComments:  cleanComments(location.GetLeadingComments()),
+		TrailingComments: cleanComments(location.GetTrailingComments()),
+		Deprecated:       serviceDescriptorProto.GetOptions().GetDeprecated(),
 	}
-	for _, methodDescriptorProto := range serviceDescriptorProto.GetMethod() {
-		serviceMethod, err := newServiceMethod(methodDescriptorProto)
+	for i, methodDescriptorProto := range serviceDescriptorProto.GetMethod() {
+		serviceMethod, err := newServiceMethod(methodDescriptorProto, locations, appendPath(path, serviceMethodTag, int32(i)))
 		if err != nil {
 			return nil, err
 		}
@@ -372,7 +414,7 @@ func newService(serviceDescriptorProto *descriptor.ServiceDescriptorProto) (*ref
 	return service, nil
 }
 
-func newServiceMethod(methodDescriptorProto *descriptor.MethodDescriptorProto) (*reflectv1.ServiceMethod, error) {
+func newServiceMethod(methodDescriptorProto *descriptor.MethodDescriptorProto, locations locations, path []int32) (*reflectv1.ServiceMethod, error) {
 	requestTypeName, err := verifyFullyQualifiedNameAndStrip(methodDescriptorProto.GetInputType())
 	if err != nil {
 		return nil, err
@@ -381,15 +423,41 @@ func newServiceMethod(methodDescriptorProto *descriptor.MethodDescriptorProto) (
 	if err != nil {
 		return nil, err
 	}
+	location := locations.get(path)
 	return &reflectv1.ServiceMethod{
 		Name:             methodDescriptorProto.GetName(),
 		RequestTypeName:  requestTypeName,
 		ResponseTypeName: responseTypeName,
 		ClientStreaming:  methodDescriptorProto.GetClientStreaming(),
 		ServerStreaming:  methodDescriptorProto.GetServerStreaming(),
+		LeadingComments:  cleanComments(location.GetLeadingComments()),
+		TrailingComments: cleanComments(location.GetTrailingComments()),
+		Deprecated:       methodDescriptorProto.GetOptions().GetDeprecated(),
 	}, nil
 }
 
+// getJSONName returns the json_name protoc sets on fields, or computes it the
+// same way for FileDescriptorProtos that do not have it.
+func getJSONName(fieldDescriptorProto *descriptor.FieldDescriptorProto) string {
+	if jsonName := fieldDescriptorProto.GetJsonName(); jsonName != "" {
+		return jsonName
+	}
+	var builder strings.Builder
+	upper := false
+	for _, c := range fieldDescriptorProto.GetName() {
+		if c == '_' {
+			upper = true
+			continue
+		}
+		if upper && 'a' <= c && c <= 'z' {
+			c -= 'a' - 'A'
+		}
+		upper = false
+		builder.WriteRune(c)
+	}
+	return builder.String()
+}
+
 func verifyFullyQualifiedNameAndStrip(s string) (string, error) {
 	if s == "" {
 		return "", fmt.Errorf("name empty")
@@ -399,3 +467,46 @@ func verifyFullyQualifiedNameAndStrip(s string) (string, error) {
 	}
 	return s[1:], nil
 }
+
+// locations are the source code info locations of a file, keyed by
+// the string of their path.
+type locations map[string]*descriptor.SourceCodeInfo_Location
+
+// newLocations returns the locations of the FileDescriptorProto, which
+// are empty unless it was produced with --include_source_info.
+func newLocations(fileDescriptorProto *descriptor.FileDescriptorProto) locations {
+	locations := make(locations)
+	for _, location := range fileDescriptorProto.GetSourceCodeInfo().GetLocation() {
+		locations[getPathKey(location.GetPath())] = location
+	}
+	return locations
+}
+
+// get returns the location for the path, or nil if there is none.
+func (l locations) get(path []int32) *descriptor.SourceCodeInfo_Location {
+	return l[getPathKey(path)]
+}
+
+func getPathKey(path []int32) string {
+	return fmt.Sprint(path)
+}
+
+// appendPath returns a new path so that paths of siblings do not share
+// a backing array.
+func appendPath(path []int32, elems ...int32) []int32 {
+	newPath := make([]int32, 0, len(path)+len(elems))
+	return append(append(newPath, path...), elems...)
+}
+
+// cleanComments removes the space protoc leaves after the comment markers
+// of each line, and the trailing newline.
+func cleanComments(comments string) string {
+	if comments == "" {
+		return ""
+	}
+	lines := strings.Split(strings.TrimRight(comments, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(strings.TrimPrefix(line, " "), " \t")
+	}
+	return strings.Join(lines, "\n")
+}
@@ -24,6 +24,7 @@ import (
 	"testing"
 
 	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
 	"github.com/stretchr/testify/require"
 	reflectv1 "github.com/uber/prototool/internal/reflect/gen/uber/proto/reflect/v1"
 	ptesting "github.com/uber/prototool/internal/testing"
@@ -45,12 +46,14 @@ func TestOne(t *testing.T) {
             {
               "name": "one",
               "number": 1,
+              "jsonName": "one",
               "label": "LABEL_OPTIONAL",
               "type": "TYPE_INT64"
             },
             {
               "name": "two",
               "number": 2,
+              "jsonName": "two",
               "label": "LABEL_OPTIONAL",
               "type": "TYPE_STRING"
             }
@@ -62,6 +65,7 @@ func TestOne(t *testing.T) {
             {
               "name": "one_foo",
               "number": 1,
+              "jsonName": "oneFoo",
               "label": "LABEL_OPTIONAL",
               "type": "TYPE_MESSAGE",
               "typeName": "uber.proto.bar.v1.OneFoo"
@@ -69,6 +73,7 @@ func TestOne(t *testing.T) {
             {
               "name": "one_bar",
               "number": 2,
+              "jsonName": "oneBar",
               "label": "LABEL_OPTIONAL",
               "type": "TYPE_MESSAGE",
               "typeName": "uber.proto.bar.v1.OneBar"
@@ -76,6 +81,7 @@ func TestOne(t *testing.T) {
             {
               "name": "two_foo",
               "number": 3,
+              "jsonName": "twoFoo",
               "label": "LABEL_OPTIONAL",
               "type": "TYPE_MESSAGE",
               "typeName": "uber.proto.bar.v1.TwoFoo"
@@ -83,6 +89,7 @@ func TestOne(t *testing.T) {
             {
               "name": "two_bar",
               "number": 4,
+              "jsonName": "twoBar",
               "label": "LABEL_OPTIONAL",
               "type": "TYPE_MESSAGE",
               "typeName": "uber.proto.bar.v1.TwoBar"
@@ -95,18 +102,21 @@ func TestOne(t *testing.T) {
             {
               "name": "one",
               "number": 1,
+              "jsonName": "one",
               "label": "LABEL_OPTIONAL",
               "type": "TYPE_INT64"
             },
             {
               "name": "two",
               "number": 2,
+              "jsonName": "two",
               "label": "LABEL_OPTIONAL",
               "type": "TYPE_STRING"
             },
             {
               "name": "one_bar",
               "number": 3,
+              "jsonName": "oneBar",
               "label": "LABEL_OPTIONAL",
               "type": "TYPE_MESSAGE",
               "typeName": "uber.proto.bar.v1.OneBar"
@@ -119,12 +129,14 @@ func TestOne(t *testing.T) {
             {
               "name": "one",
               "number": 1,
+              "jsonName": "one",
               "label": "LABEL_OPTIONAL",
               "type": "TYPE_INT64"
             },
             {
               "name": "two",
               "number": 2,
+              "jsonName": "two",
               "label": "LABEL_OPTIONAL",
               "type": "TYPE_STRING"
             }
@@ -136,18 +148,21 @@ func TestOne(t *testing.T) {
             {
               "name": "one",
               "number": 1,
+              "jsonName": "one",
               "label": "LABEL_OPTIONAL",
               "type": "TYPE_INT64"
             },
             {
               "name": "two",
               "number": 2,
+              "jsonName": "two",
               "label": "LABEL_OPTIONAL",
               "type": "TYPE_STRING"
             },
             {
               "name": "two_bar",
               "number": 3,
+              "jsonName": "twoBar",
               "label": "LABEL_OPTIONAL",
               "type": "TYPE_MESSAGE",
               "typeName": "uber.proto.bar.v1.TwoBar"
@@ -186,6 +201,7 @@ func TestOne(t *testing.T) {
             {
               "name": "one",
               "number": 1,
+              "jsonName": "one",
               "label": "LABEL_OPTIONAL",
               "type": "TYPE_INT64"
             }
@@ -197,6 +213,7 @@ func TestOne(t *testing.T) {
             {
               "name": "one",
               "number": 1,
+              "jsonName": "one",
               "label": "LABEL_OPTIONAL",
               "type": "TYPE_INT64"
             }
@@ -208,6 +225,7 @@ func TestOne(t *testing.T) {
             {
               "name": "one",
               "number": 1,
+              "jsonName": "one",
               "label": "LABEL_OPTIONAL",
               "type": "TYPE_INT64"
             }
@@ -219,6 +237,7 @@ func TestOne(t *testing.T) {
             {
               "name": "one",
               "number": 1,
+              "jsonName": "one",
               "label": "LABEL_OPTIONAL",
               "type": "TYPE_INT64"
             }
@@ -230,12 +249,14 @@ func TestOne(t *testing.T) {
             {
               "name": "one",
               "number": 1,
+              "jsonName": "one",
               "label": "LABEL_OPTIONAL",
               "type": "TYPE_INT64"
             },
             {
               "name": "two",
               "number": 2,
+              "jsonName": "two",
               "label": "LABEL_OPTIONAL",
               "type": "TYPE_STRING"
             }
@@ -247,6 +268,7 @@ func TestOne(t *testing.T) {
             {
               "name": "one_foo",
               "number": 1,
+              "jsonName": "oneFoo",
               "label": "LABEL_OPTIONAL",
               "type": "TYPE_MESSAGE",
               "typeName": "uber.proto.bar.v1.OneFoo"
@@ -254,6 +276,7 @@ func TestOne(t *testing.T) {
             {
               "name": "one_bar",
               "number": 2,
+              "jsonName": "oneBar",
               "label": "LABEL_OPTIONAL",
               "type": "TYPE_MESSAGE",
               "typeName": "uber.proto.bar.v1.OneBar"
@@ -261,6 +284,7 @@ func TestOne(t *testing.T) {
             {
               "name": "two_foo",
               "number": 3,
+              "jsonName": "twoFoo",
               "label": "LABEL_OPTIONAL",
               "type": "TYPE_MESSAGE",
               "typeName": "uber.proto.bar.v1.TwoFoo"
@@ -268,6 +292,7 @@ func TestOne(t *testing.T) {
             {
               "name": "two_bar",
               "number": 4,
+              "jsonName": "twoBar",
               "label": "LABEL_OPTIONAL",
               "type": "TYPE_MESSAGE",
               "typeName": "uber.proto.bar.v1.TwoBar"
@@ -280,6 +305,7 @@ func TestOne(t *testing.T) {
             {
               "name": "one_foo",
               "number": 1,
+              "jsonName": "oneFoo",
               "label": "LABEL_OPTIONAL",
               "type": "TYPE_MESSAGE",
               "typeName": "uber.proto.foo.v1.OneFoo"
@@ -287,6 +313,7 @@ func TestOne(t *testing.T) {
             {
               "name": "one_bar",
               "number": 2,
+              "jsonName": "oneBar",
               "label": "LABEL_OPTIONAL",
               "type": "TYPE_MESSAGE",
               "typeName": "uber.proto.foo.v1.OneBar"
@@ -294,6 +321,7 @@ func TestOne(t *testing.T) {
             {
               "name": "two_foo",
               "number": 3,
+              "jsonName": "twoFoo",
               "label": "LABEL_OPTIONAL",
               "type": "TYPE_MESSAGE",
               "typeName": "uber.proto.foo.v1.TwoFoo"
@@ -301,6 +329,7 @@ func TestOne(t *testing.T) {
             {
               "name": "two_bar",
               "number": 4,
+              "jsonName": "twoBar",
               "label": "LABEL_OPTIONAL",
               "type": "TYPE_MESSAGE",
               "typeName": "uber.proto.foo.v1.TwoBar"
@@ -313,18 +342,21 @@ func TestOne(t *testing.T) {
             {
               "name": "one",
               "number": 1,
+              "jsonName": "one",
               "label": "LABEL_OPTIONAL",
               "type": "TYPE_INT64"
             },
             {
               "name": "two",
               "number": 2,
+              "jsonName": "two",
               "label": "LABEL_OPTIONAL",
               "type": "TYPE_STRING"
             },
             {
               "name": "one_bar",
               "number": 3,
+              "jsonName": "oneBar",
               "label": "LABEL_OPTIONAL",
               "type": "TYPE_MESSAGE",
               "typeName": "uber.proto.foo.v1.OneBar"
@@ -337,24 +369,28 @@ func TestOne(t *testing.T) {
             {
               "name": "one",
               "number": 1,
+              "jsonName": "one",
               "label": "LABEL_OPTIONAL",
               "type": "TYPE_INT64"
             },
             {
               "name": "two",
               "number": 2,
+              "jsonName": "two",
               "label": "LABEL_OPTIONAL",
               "type": "TYPE_STRING"
             },
             {
               "name": "three",
               "number": 3,
+              "jsonName": "three",
               "label": "LABEL_REPEATED",
               "type": "TYPE_INT64"
             },
             {
               "name": "four",
               "number": 4,
+              "jsonName": "four",
               "label": "LABEL_REPEATED",
               "type": "TYPE_MESSAGE",
               "typeName": "uber.proto.foo.v1.Simple.FourEntry"
@@ -362,12 +398,14 @@ func TestOne(t *testing.T) {
             {
               "name": "five",
               "number": 5,
+              "jsonName": "five",
               "label": "LABEL_OPTIONAL",
               "type": "TYPE_INT64"
             },
             {
               "name": "one_bat",
               "number": 6,
+              "jsonName": "oneBat",
               "label": "LABEL_OPTIONAL",
               "type": "TYPE_MESSAGE",
               "typeName": "uber.proto.foo.v1.OneBat"
@@ -385,16 +423,19 @@ func TestOne(t *testing.T) {
           "nestedMessages": [
             {
               "name": "FourEntry",
+              "mapEntry": true,
               "messageFields": [
                 {
                   "name": "key",
                   "number": 1,
+                  "jsonName": "key",
                   "label": "LABEL_OPTIONAL",
                   "type": "TYPE_INT64"
                 },
                 {
                   "name": "value",
                   "number": 2,
+                  "jsonName": "value",
                   "label": "LABEL_OPTIONAL",
                   "type": "TYPE_STRING"
                 }
@@ -406,12 +447,14 @@ func TestOne(t *testing.T) {
                 {
                   "name": "one",
                   "number": 1,
+                  "jsonName": "one",
                   "label": "LABEL_OPTIONAL",
                   "type": "TYPE_INT64"
                 },
                 {
                   "name": "two",
                   "number": 2,
+                  "jsonName": "two",
                   "label": "LABEL_OPTIONAL",
                   "type": "TYPE_STRING"
                 }
@@ -443,12 +486,14 @@ func TestOne(t *testing.T) {
             {
               "name": "one",
               "number": 1,
+              "jsonName": "one",
               "label": "LABEL_OPTIONAL",
               "type": "TYPE_INT64"
             },
             {
               "name": "two",
               "number": 2,
+              "jsonName": "two",
               "label": "LABEL_OPTIONAL",
               "type": "TYPE_STRING"
             }
@@ -460,18 +505,21 @@ func TestOne(t *testing.T) {
             {
               "name": "one",
               "number": 1,
+              "jsonName": "one",
               "label": "LABEL_OPTIONAL",
               "type": "TYPE_INT64"
             },
             {
               "name": "two",
               "number": 2,
+              "jsonName": "two",
               "label": "LABEL_OPTIONAL",
               "type": "TYPE_STRING"
             },
             {
               "name": "two_bar",
               "number": 3,
+              "jsonName": "twoBar",
               "label": "LABEL_OPTIONAL",
               "type": "TYPE_MESSAGE",
               "typeName": "uber.proto.foo.v1.TwoBar"
@@ -503,6 +551,137 @@ func TestOne(t *testing.T) {
 	)
 }
 
+func TestComments(t *testing.T) {
+	fileDescriptorSet := &descriptor.FileDescriptorSet{}
+	require.NoError(t, jsonpb.UnmarshalString(`
+{
+  "file": [
+    {
+      "name": "a.proto",
+      "package": "foo.v1",
+      "messageType": [
+        {
+          "name": "Foo",
+          "field": [
+            {
+              "name": "one",
+              "number": 1,
+              "jsonName": "one",
+              "label": "LABEL_OPTIONAL",
+              "type": "TYPE_INT64",
+              "options": {"deprecated": true}
+            }
+          ],
+          "nestedType": [
+            {
+              "name": "Bar"
+            }
+          ],
+          "options": {"deprecated": true}
+        }
+      ],
+      "enumType": [
+        {
+          "name": "Hello",
+          "value": [
+            {
+              "name": "HELLO_INVALID",
+              "number": 0
+            }
+          ]
+        }
+      ],
+      "service": [
+        {
+          "name": "FooAPI",
+          "method": [
+            {
+              "name": "Get",
+              "inputType": ".foo.v1.Foo",
+              "outputType": ".foo.v1.Foo",
+              "serverStreaming": true
+            }
+          ]
+        }
+      ],
+      "sourceCodeInfo": {
+        "location": [
+          {"path": [4, 0], "leadingComments": " Foo is a foo.\n\n It has two lines.\n"},
+          {"path": [4, 0, 2, 0], "trailingComments": " one is one.\n"},
+          {"path": [4, 0, 3, 0], "leadingComments": "Bar is nested.\n"},
+          {"path": [5, 0, 2, 0], "leadingComments": " The invalid value.\n"},
+          {"path": [6, 0], "leadingComments": " FooAPI is an API.\n"},
+          {"path": [6, 0, 2, 0], "leadingComments": " Get gets.\n"}
+        ]
+      }
+    }
+  ]
+}
+`, fileDescriptorSet))
+	packageSet, err := NewPackageSet(fileDescriptorSet)
+	require.NoError(t, err)
+	require.Equal(t, requireUnmarshalPackageSet(t, `
+{
+  "packages": [
+    {
+      "name": "foo.v1",
+      "enums": [
+        {
+          "name": "Hello",
+          "enumValues": [
+            {
+              "name": "HELLO_INVALID",
+              "leadingComments": "The invalid value."
+            }
+          ]
+        }
+      ],
+      "messages": [
+        {
+          "name": "Foo",
+          "leadingComments": "Foo is a foo.\n\nIt has two lines.",
+          "deprecated": true,
+          "messageFields": [
+            {
+              "name": "one",
+              "number": 1,
+              "jsonName": "one",
+              "label": "LABEL_OPTIONAL",
+              "type": "TYPE_INT64",
+              "trailingComments": "one is one.",
+              "deprecated": true,
+              "jsonName": "one"
+            }
+          ],
+          "nestedMessages": [
+            {
+              "name": "Bar",
+              "leadingComments": "Bar is nested."
+            }
+          ]
+        }
+      ],
+      "services": [
+        {
+          "name": "FooAPI",
+          "leadingComments": "FooAPI is an API.",
+          "serviceMethods": [
+            {
+              "name": "Get",
+              "requestTypeName": "foo.v1.Foo",
+              "responseTypeName": "foo.v1.Foo",
+              "serverStreaming": true,
+              "leadingComments": "Get gets."
+            }
+          ]
+        }
+      ]
+    }
+  ]
+}
+`), packageSet)
+}
+
 func testNewPackageSet(t *testing.T, subDirPath string, packageSetJSON string) {
 	fileDescriptorSets := ptesting.RequireGetFileDescriptorSets(t, ".", "testdata/"+subDirPath)
 	packageSet, err := NewPackageSet(fileDescriptorSets.Unwrap()...)
@@ -0,0 +1,337 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package sample generates random messages of the types in
+// FileDescriptorSets, for use as test fixtures and fuzz corpora.
+package sample
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"math/rand"
+	"strings"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/uber/prototool/internal/protoc"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const (
+	// DefaultMaxDepth is the default depth of nested messages that are
+	// populated.
+	DefaultMaxDepth = 3
+
+	// maxElements is the maximum number of elements in repeated and map fields.
+	maxElements = 3
+	// minTimestampSeconds is 2000-01-01T00:00:00Z.
+	minTimestampSeconds = 946684800
+	// maxTimestampSeconds is 2030-01-01T00:00:00Z.
+	maxTimestampSeconds = 1893456000
+	// maxDurationSeconds is one day.
+	maxDurationSeconds = 86400
+)
+
+var words = []string{
+	"alpha", "bravo", "charlie", "delta", "echo", "foxtrot", "golf", "hotel",
+	"india", "juliett", "kilo", "lima", "mike", "november", "oscar", "papa",
+	"quebec", "romeo", "sierra", "tango", "uniform", "victor", "whiskey",
+	"xray", "yankee", "zulu",
+}
+
+// Generator generates random messages of a single type.
+type Generator interface {
+	// Generate returns a new random message.
+	//
+	// The messages returned by a Generator are the same for the same seed.
+	Generate() proto.Message
+}
+
+// GeneratorOption is an option for a new Generator.
+type GeneratorOption func(*generator)
+
+// GeneratorWithSeed returns a GeneratorOption that uses the given seed.
+//
+// The default is to use a seed of 0.
+func GeneratorWithSeed(seed int64) GeneratorOption {
+	return func(generator *generator) {
+		generator.seed = seed
+	}
+}
+
+// GeneratorWithMaxDepth returns a GeneratorOption that only populates
+// message fields up to the given depth of nesting, which limits the size
+// of recursive messages.
+//
+// Required fields are always populated. The default is DefaultMaxDepth.
+func GeneratorWithMaxDepth(maxDepth int) GeneratorOption {
+	return func(generator *generator) {
+		generator.maxDepth = maxDepth
+	}
+}
+
+// NewGenerator returns a new Generator for the message with the given
+// fully-qualified name, without the leading '.'.
+//
+// Enums only use their declared values, one field of each oneof is set,
+// and google.protobuf.Timestamp, google.protobuf.Duration and
+// google.protobuf.FieldMask have values that are valid in the JSON mapping.
+// FieldMask paths are names of fields of the message. google.protobuf.Any
+// fields are not set. The FileDescriptorSets must include all imports.
+func NewGenerator(fileDescriptorSets []*descriptor.FileDescriptorSet, typeName string, options ...GeneratorOption) (Generator, error) {
+	return newGenerator(fileDescriptorSets, typeName, options...)
+}
+
+type generator struct {
+	messageDescriptor protoreflect.MessageDescriptor
+	seed              int64
+	maxDepth          int
+	rand              *rand.Rand
+}
+
+func newGenerator(fileDescriptorSets []*descriptor.FileDescriptorSet, typeName string, options ...GeneratorOption) (*generator, error) {
+	generator := &generator{
+		maxDepth: DefaultMaxDepth,
+	}
+	for _, option := range options {
+		option(generator)
+	}
+	messageDescriptor, err := getMessageDescriptor(fileDescriptorSets, typeName)
+	if err != nil {
+		return nil, err
+	}
+	generator.messageDescriptor = messageDescriptor
+	generator.rand = rand.New(rand.NewSource(generator.seed))
+	return generator, nil
+}
+
+func (g *generator) Generate() proto.Message {
+	message := dynamicpb.NewMessage(g.messageDescriptor)
+	g.populateMessage(message, 0)
+	return message
+}
+
+func (g *generator) populateMessage(message protoreflect.Message, depth int) {
+	messageDescriptor := message.Descriptor()
+	switch messageDescriptor.FullName() {
+	case "google.protobuf.Timestamp":
+		message.Set(messageDescriptor.Fields().ByName("seconds"), protoreflect.ValueOfInt64(minTimestampSeconds+g.rand.Int63n(maxTimestampSeconds-minTimestampSeconds)))
+		message.Set(messageDescriptor.Fields().ByName("nanos"), protoreflect.ValueOfInt32(g.rand.Int31n(1000)*1000000))
+		return
+	case "google.protobuf.Duration":
+		message.Set(messageDescriptor.Fields().ByName("seconds"), protoreflect.ValueOfInt64(g.rand.Int63n(maxDurationSeconds)))
+		message.Set(messageDescriptor.Fields().ByName("nanos"), protoreflect.ValueOfInt32(g.rand.Int31n(1000)*1000000))
+		return
+	case "google.protobuf.Value":
+		// a Value must have a kind, so do not use struct_value or
+		// list_value which may be cut off by the max depth
+		kinds := []protoreflect.Name{"null_value", "number_value", "string_value", "bool_value"}
+		g.populateField(message, messageDescriptor.Fields().ByName(kinds[g.rand.Intn(len(kinds))]), depth)
+		return
+	case "google.protobuf.FieldMask":
+		// arbitrary strings are not valid paths in the JSON mapping
+		fields := g.messageDescriptor.Fields()
+		if fields.Len() == 0 {
+			return
+		}
+		paths := message.Mutable(messageDescriptor.Fields().ByName("paths")).List()
+		for i, n := 0, g.rand.Intn(maxElements+1); i < n; i++ {
+			if name := string(fields.Get(g.rand.Intn(fields.Len())).Name()); isFieldMaskPath(name) {
+				paths.Append(protoreflect.ValueOfString(name))
+			}
+		}
+		return
+	}
+	oneofs := messageDescriptor.Oneofs()
+	for i := 0; i < oneofs.Len(); i++ {
+		oneof := oneofs.Get(i)
+		// proto3 optional fields are in synthetic oneofs
+		if oneof.IsSynthetic() {
+			if g.rand.Intn(2) == 0 {
+				g.populateField(message, oneof.Fields().Get(0), depth)
+			}
+			continue
+		}
+		g.populateField(message, oneof.Fields().Get(g.rand.Intn(oneof.Fields().Len())), depth)
+	}
+	fields := messageDescriptor.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		if field := fields.Get(i); field.ContainingOneof() == nil {
+			g.populateField(message, field, depth)
+		}
+	}
+}
+
+func (g *generator) populateField(message protoreflect.Message, field protoreflect.FieldDescriptor, depth int) {
+	isMessage := field.Message() != nil
+	if isMessage && depth >= g.maxDepth {
+		// required fields are still populated, up to a limit for
+		// recursive required fields which can never be valid
+		if field.Cardinality() != protoreflect.Required || depth >= 2*g.maxDepth {
+			return
+		}
+	}
+	if isAny(field) {
+		// there is no type to put in it
+		return
+	}
+	switch {
+	case field.IsMap():
+		mapValue := message.Mutable(field).Map()
+		for i, n := 0, g.rand.Intn(maxElements+1); i < n; i++ {
+			key := g.newScalarValue(field.MapKey()).MapKey()
+			if field.MapValue().Message() != nil {
+				g.populateMessage(mapValue.Mutable(key).Message(), depth+1)
+			} else {
+				mapValue.Set(key, g.newScalarValue(field.MapValue()))
+			}
+		}
+	case field.IsList():
+		list := message.Mutable(field).List()
+		for i, n := 0, g.rand.Intn(maxElements+1); i < n; i++ {
+			if isMessage {
+				element := list.NewElement()
+				g.populateMessage(element.Message(), depth+1)
+				list.Append(element)
+			} else {
+				list.Append(g.newScalarValue(field))
+			}
+		}
+	case isMessage:
+		g.populateMessage(message.Mutable(field).Message(), depth+1)
+	default:
+		message.Set(field, g.newScalarValue(field))
+	}
+}
+
+func (g *generator) newScalarValue(field protoreflect.FieldDescriptor) protoreflect.Value {
+	switch field.Kind() {
+	case protoreflect.BoolKind:
+		return protoreflect.ValueOfBool(g.rand.Intn(2) == 0)
+	case protoreflect.EnumKind:
+		return protoreflect.ValueOfEnum(g.newEnumNumber(field.Enum()))
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return protoreflect.ValueOfInt32(g.rand.Int31n(1000))
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return protoreflect.ValueOfInt64(g.rand.Int63n(1000000))
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return protoreflect.ValueOfUint32(uint32(g.rand.Int31n(1000)))
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return protoreflect.ValueOfUint64(uint64(g.rand.Int63n(1000000)))
+	case protoreflect.FloatKind:
+		return protoreflect.ValueOfFloat32(float32(g.rand.Intn(100000)) / 100)
+	case protoreflect.DoubleKind:
+		return protoreflect.ValueOfFloat64(float64(g.rand.Intn(100000)) / 100)
+	case protoreflect.BytesKind:
+		data := make([]byte, 1+g.rand.Intn(16))
+		_, _ = g.rand.Read(data)
+		return protoreflect.ValueOfBytes(data)
+	default:
+		return protoreflect.ValueOfString(g.newString(string(field.Name())))
+	}
+}
+
+// newEnumNumber returns a declared value of the enum, preferring values
+// other than the zero value, which is usually the invalid value.
+func (g *generator) newEnumNumber(enum protoreflect.EnumDescriptor) protoreflect.EnumNumber {
+	values := enum.Values()
+	var numbers []protoreflect.EnumNumber
+	for i := 0; i < values.Len(); i++ {
+		if number := values.Get(i).Number(); number != 0 {
+			numbers = append(numbers, number)
+		}
+	}
+	if len(numbers) == 0 {
+		return values.Get(0).Number()
+	}
+	return numbers[g.rand.Intn(len(numbers))]
+}
+
+// newString returns a string that looks like what the field name suggests.
+func (g *generator) newString(fieldName string) string {
+	word := words[g.rand.Intn(len(words))]
+	switch {
+	case strings.Contains(fieldName, "email"):
+		return word + "@example.com"
+	case strings.Contains(fieldName, "url") || strings.Contains(fieldName, "uri"):
+		return "https://example.com/" + word
+	case strings.Contains(fieldName, "uuid"):
+		data := make([]byte, 16)
+		_, _ = g.rand.Read(data)
+		return fmt.Sprintf("%x-%x-%x-%x-%x", data[0:4], data[4:6], data[6:8], data[8:10], data[10:])
+	default:
+		return fmt.Sprintf("%s-%d", word, g.rand.Intn(1000))
+	}
+}
+
+// CorpusFileName returns the file name Go uses for a fuzz corpus file
+// with the given contents, as returned by NewCorpusFile.
+func CorpusFileName(data []byte) string {
+	return fmt.Sprintf("%x", sha256.Sum256(data))[:16]
+}
+
+// NewCorpusFile returns the contents of a Go native fuzzing corpus file
+// with the given bytes as its only value, for a fuzz target that takes
+// a single []byte argument.
+func NewCorpusFile(data []byte) []byte {
+	return []byte(fmt.Sprintf("go test fuzz v1\n[]byte(%q)\n", data))
+}
+
+func getMessageDescriptor(fileDescriptorSets []*descriptor.FileDescriptorSet, typeName string) (protoreflect.MessageDescriptor, error) {
+	files, err := protodesc.NewFiles(protoc.MergeFileDescriptorSets(fileDescriptorSets...))
+	if err != nil {
+		return nil, err
+	}
+	d, err := files.FindDescriptorByName(protoreflect.FullName(typeName))
+	if err != nil {
+		return nil, fmt.Errorf("unknown message %s", typeName)
+	}
+	messageDescriptor, ok := d.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("unknown message %s", typeName)
+	}
+	return messageDescriptor, nil
+}
+
+// isAny returns true if the field, or the values of the map field, are
+// google.protobuf.Any messages.
+func isAny(field protoreflect.FieldDescriptor) bool {
+	if field.IsMap() {
+		field = field.MapValue()
+	}
+	return field.Message() != nil && field.Message().FullName() == "google.protobuf.Any"
+}
+
+// isFieldMaskPath returns true if the field name can be a
+// google.protobuf.FieldMask path in the JSON mapping, which requires
+// the path to round-trip through lowerCamelCase.
+func isFieldMaskPath(name string) bool {
+	for i, c := range name {
+		switch {
+		case 'a' <= c && c <= 'z', '0' <= c && c <= '9':
+		case c == '_' && i+1 < len(name) && 'a' <= name[i+1] && name[i+1] <= 'z':
+		default:
+			return false
+		}
+	}
+	return name != ""
+}
@@ -0,0 +1,216 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package sample
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/prototool/internal/convert"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestGenerate(t *testing.T) {
+	first := testGenerate(t, 10, GeneratorWithSeed(1))
+	assert.Equal(t, first, testGenerate(t, 10, GeneratorWithSeed(1)))
+	assert.NotEqual(t, first, testGenerate(t, 10, GeneratorWithSeed(2)))
+}
+
+func TestGenerateValues(t *testing.T) {
+	generator := testNewGenerator(t, GeneratorWithSeed(3))
+	var sawMap, sawList, sawNested, sawMask bool
+	for i := 0; i < 50; i++ {
+		message := generator.Generate().ProtoReflect()
+		fields := message.Descriptor().Fields()
+		assert.Equal(t, protoreflect.EnumNumber(1), message.Get(fields.ByName("hello")).Enum())
+		// exactly one field of the oneof is set
+		assert.NotEqual(t, message.Has(fields.ByName("name")), message.Has(fields.ByName("bar")))
+		createTime := message.Get(fields.ByName("create_time")).Message()
+		seconds := createTime.Get(createTime.Descriptor().Fields().ByName("seconds")).Int()
+		assert.True(t, seconds >= minTimestampSeconds && seconds < maxTimestampSeconds)
+		timeout := message.Get(fields.ByName("timeout")).Message()
+		seconds = timeout.Get(timeout.Descriptor().Fields().ByName("seconds")).Int()
+		assert.True(t, seconds >= 0 && seconds < maxDurationSeconds)
+		assert.False(t, message.Has(fields.ByName("details")))
+		assert.True(t, message.Has(fields.ByName("metadata")))
+		assert.True(t, message.Has(fields.ByName("max_count")))
+		assert.True(t, message.Has(fields.ByName("nickname")))
+		sawMap = sawMap || message.Get(fields.ByName("labels")).Map().Len() > 0
+		sawList = sawList || message.Get(fields.ByName("children")).List().Len() > 0
+		sawNested = sawNested || message.Has(fields.ByName("bar"))
+		updateMask := message.Get(fields.ByName("update_mask")).Message()
+		paths := updateMask.Get(updateMask.Descriptor().Fields().ByName("paths")).List()
+		for j := 0; j < paths.Len(); j++ {
+			assert.NotNil(t, fields.ByName(protoreflect.Name(paths.Get(j).String())))
+		}
+		sawMask = sawMask || paths.Len() > 0
+		for _, format := range []convert.Format{convert.FormatBinary, convert.FormatJSON, convert.FormatText} {
+			_, err := convert.Marshal(message.Interface(), format, false)
+			require.NoError(t, err)
+		}
+		// the well-known types round-trip through the JSON mapping
+		data, err := convert.Marshal(message.Interface(), convert.FormatJSON, false)
+		require.NoError(t, err)
+		jsonMessage := dynamicpb.NewMessage(message.Descriptor())
+		require.NoError(t, protojson.Unmarshal(data, jsonMessage))
+		assert.True(t, proto.Equal(message.Interface(), jsonMessage), string(data))
+	}
+	assert.True(t, sawMap)
+	assert.True(t, sawList)
+	assert.True(t, sawNested)
+	assert.True(t, sawMask)
+}
+
+func TestGenerateMaxDepth(t *testing.T) {
+	generator := testNewGenerator(t, GeneratorWithMaxDepth(0))
+	for i := 0; i < 10; i++ {
+		message := generator.Generate().ProtoReflect()
+		fields := message.Descriptor().Fields()
+		assert.False(t, message.Has(fields.ByName("create_time")))
+		assert.False(t, message.Has(fields.ByName("children")))
+	}
+}
+
+func TestNewGeneratorErrors(t *testing.T) {
+	_, err := NewGenerator(testFileDescriptorSets(t), "foo.v1.Baz")
+	assert.EqualError(t, err, "unknown message foo.v1.Baz")
+	_, err = NewGenerator(testFileDescriptorSets(t), "foo.v1.Hello")
+	assert.EqualError(t, err, "unknown message foo.v1.Hello")
+}
+
+func TestCorpusFile(t *testing.T) {
+	corpusFile := NewCorpusFile([]byte("\x08\x01\xff"))
+	assert.Equal(t, "go test fuzz v1\n[]byte(\"\\b\\x01\\xff\")\n", string(corpusFile))
+	assert.Len(t, CorpusFileName(corpusFile), 16)
+	assert.NotEqual(t, CorpusFileName(corpusFile), CorpusFileName(NewCorpusFile([]byte("\x08\x02"))))
+}
+
+func testGenerate(t *testing.T, count int, options ...GeneratorOption) [][]byte {
+	generator := testNewGenerator(t, options...)
+	var result [][]byte
+	for i := 0; i < count; i++ {
+		data, err := proto.MarshalOptions{Deterministic: true}.Marshal(generator.Generate())
+		require.NoError(t, err)
+		result = append(result, data)
+	}
+	return result
+}
+
+func testNewGenerator(t *testing.T, options ...GeneratorOption) Generator {
+	generator, err := NewGenerator(testFileDescriptorSets(t), "foo.v1.Foo", options...)
+	require.NoError(t, err)
+	return generator
+}
+
+func testFileDescriptorSets(t *testing.T) []*descriptor.FileDescriptorSet {
+	fileDescriptorSet := &descriptor.FileDescriptorSet{}
+	require.NoError(t, jsonpb.UnmarshalString(testFileDescriptorSetJSON, fileDescriptorSet))
+	return []*descriptor.FileDescriptorSet{
+		{
+			File: []*descriptor.FileDescriptorProto{
+				protodesc.ToFileDescriptorProto(anypb.File_google_protobuf_any_proto),
+				protodesc.ToFileDescriptorProto(durationpb.File_google_protobuf_duration_proto),
+				protodesc.ToFileDescriptorProto(fieldmaskpb.File_google_protobuf_field_mask_proto),
+				protodesc.ToFileDescriptorProto(structpb.File_google_protobuf_struct_proto),
+				protodesc.ToFileDescriptorProto(timestamppb.File_google_protobuf_timestamp_proto),
+				protodesc.ToFileDescriptorProto(wrapperspb.File_google_protobuf_wrappers_proto),
+			},
+		},
+		fileDescriptorSet,
+	}
+}
+
+const testFileDescriptorSetJSON = `
+{
+  "file": [
+    {
+      "name": "foo/v1/foo.proto",
+      "package": "foo.v1",
+      "dependency": [
+        "google/protobuf/any.proto",
+        "google/protobuf/duration.proto",
+        "google/protobuf/field_mask.proto",
+        "google/protobuf/struct.proto",
+        "google/protobuf/timestamp.proto",
+        "google/protobuf/wrappers.proto"
+      ],
+      "syntax": "proto3",
+      "messageType": [
+        {
+          "name": "Foo",
+          "field": [
+            {"name": "id", "jsonName": "id", "number": 1, "label": "LABEL_OPTIONAL", "type": "TYPE_INT64"},
+            {"name": "name", "jsonName": "name", "number": 2, "label": "LABEL_OPTIONAL", "type": "TYPE_STRING", "oneofIndex": 0},
+            {"name": "bar", "jsonName": "bar", "number": 3, "label": "LABEL_OPTIONAL", "type": "TYPE_MESSAGE", "typeName": ".foo.v1.Foo.Bar", "oneofIndex": 0},
+            {"name": "hello", "jsonName": "hello", "number": 4, "label": "LABEL_OPTIONAL", "type": "TYPE_ENUM", "typeName": ".foo.v1.Hello"},
+            {"name": "labels", "jsonName": "labels", "number": 5, "label": "LABEL_REPEATED", "type": "TYPE_MESSAGE", "typeName": ".foo.v1.Foo.LabelsEntry"},
+            {"name": "children", "jsonName": "children", "number": 6, "label": "LABEL_REPEATED", "type": "TYPE_MESSAGE", "typeName": ".foo.v1.Foo"},
+            {"name": "create_time", "jsonName": "createTime", "number": 7, "label": "LABEL_OPTIONAL", "type": "TYPE_MESSAGE", "typeName": ".google.protobuf.Timestamp"},
+            {"name": "timeout", "jsonName": "timeout", "number": 8, "label": "LABEL_OPTIONAL", "type": "TYPE_MESSAGE", "typeName": ".google.protobuf.Duration"},
+            {"name": "details", "jsonName": "details", "number": 9, "label": "LABEL_OPTIONAL", "type": "TYPE_MESSAGE", "typeName": ".google.protobuf.Any"},
+            {"name": "data", "jsonName": "data", "number": 10, "label": "LABEL_REPEATED", "type": "TYPE_BYTES"},
+            {"name": "update_mask", "jsonName": "updateMask", "number": 11, "label": "LABEL_OPTIONAL", "type": "TYPE_MESSAGE", "typeName": ".google.protobuf.FieldMask"},
+            {"name": "metadata", "jsonName": "metadata", "number": 12, "label": "LABEL_OPTIONAL", "type": "TYPE_MESSAGE", "typeName": ".google.protobuf.Struct"},
+            {"name": "max_count", "jsonName": "maxCount", "number": 13, "label": "LABEL_OPTIONAL", "type": "TYPE_MESSAGE", "typeName": ".google.protobuf.Int64Value"},
+            {"name": "nickname", "jsonName": "nickname", "number": 14, "label": "LABEL_OPTIONAL", "type": "TYPE_MESSAGE", "typeName": ".google.protobuf.StringValue"}
+          ],
+          "nestedType": [
+            {
+              "name": "Bar",
+              "field": [
+                {"name": "ok", "jsonName": "ok", "number": 1, "label": "LABEL_OPTIONAL", "type": "TYPE_BOOL"}
+              ]
+            },
+            {
+              "name": "LabelsEntry",
+              "field": [
+                {"name": "key", "jsonName": "key", "number": 1, "label": "LABEL_OPTIONAL", "type": "TYPE_STRING"},
+                {"name": "value", "jsonName": "value", "number": 2, "label": "LABEL_OPTIONAL", "type": "TYPE_MESSAGE", "typeName": ".foo.v1.Foo.Bar"}
+              ],
+              "options": {"mapEntry": true}
+            }
+          ],
+          "oneofDecl": [{"name": "value"}]
+        }
+      ],
+      "enumType": [
+        {
+          "name": "Hello",
+          "value": [{"name": "HELLO_INVALID", "number": 0}, {"name": "HELLO_WORLD", "number": 1}]
+        }
+      ]
+    }
+  ]
+}
+`
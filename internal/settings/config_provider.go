@@ -27,11 +27,15 @@ import (
 	"io/ioutil"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 
+	"github.com/uber/prototool/internal/glob"
 	"github.com/uber/prototool/internal/strs"
+	"github.com/uber/prototool/internal/text"
 	"go.uber.org/zap"
 	yaml "gopkg.in/yaml.v2"
 )
@@ -89,15 +93,15 @@ func (c *configProvider) GetForData(dirPath string, externalConfigData string) (
 	return externalConfigToConfig(c.develMode, externalConfig, dirPath)
 }
 
-func (c *configProvider) GetExcludePrefixesForDir(dirPath string) ([]string, error) {
+func (c *configProvider) GetDiscoveryForDir(dirPath string) (*DiscoveryConfig, error) {
 	if !filepath.IsAbs(dirPath) {
 		return nil, fmt.Errorf("%s is not an absolute path", dirPath)
 	}
 	dirPath = filepath.Clean(dirPath)
-	return getExcludePrefixesForDir(dirPath)
+	return getDiscoveryForDir(dirPath)
 }
 
-func (c *configProvider) GetExcludePrefixesForData(dirPath string, externalConfigData string) ([]string, error) {
+func (c *configProvider) GetDiscoveryForData(dirPath string, externalConfigData string) (*DiscoveryConfig, error) {
 	if !filepath.IsAbs(dirPath) {
 		return nil, fmt.Errorf("%s is not an absolute path", dirPath)
 	}
@@ -106,7 +110,7 @@ func (c *configProvider) GetExcludePrefixesForData(dirPath string, externalConfi
 	if err := jsonUnmarshalStrict([]byte(externalConfigData), &externalConfig); err != nil {
 		return nil, err
 	}
-	return getExcludePrefixes(externalConfig.Excludes, dirPath)
+	return getDiscovery(externalConfig, dirPath)
 }
 
 // getFilePathForDir tries to find a file named by one of the ConfigFilenames starting in the
@@ -198,7 +202,7 @@ func getExternalConfig(filePath string) (ExternalConfig, error) {
 //
 // This will return a valid Config, or an error.
 func externalConfigToConfig(develMode bool, e ExternalConfig, dirPath string) (Config, error) {
-	excludePrefixes, err := getExcludePrefixes(e.Excludes, dirPath)
+	discovery, err := getDiscovery(e, dirPath)
 	if err != nil {
 		return Config{}, err
 	}
@@ -332,14 +336,35 @@ func externalConfigToConfig(develMode bool, e ExternalConfig, dirPath string) (C
 		}
 	}
 
+	idToSeverity, err := getIDToSeverity(e.Severities)
+	if err != nil {
+		return Config{}, err
+	}
+
+	var timeout time.Duration
+	if e.Protoc.Timeout != "" {
+		timeout, err = time.ParseDuration(e.Protoc.Timeout)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid protoc timeout %q: %v", e.Protoc.Timeout, err)
+		}
+		if timeout <= 0 {
+			return Config{}, fmt.Errorf("protoc timeout must be positive: %q", e.Protoc.Timeout)
+		}
+	}
+
 	config := Config{
 		DirPath:         dirPath,
-		ExcludePrefixes: excludePrefixes,
+		ExcludePrefixes: discovery.ExcludePrefixes,
+		ExcludeGlobs:    discovery.ExcludeGlobs,
+		IncludeGlobs:    discovery.IncludeGlobs,
+		HonorGitignore:  discovery.HonorGitignore,
+		IDToSeverity:    idToSeverity,
 		Compile: CompileConfig{
 			ProtobufVersion:       e.Protoc.Version,
 			IncludePaths:          includePaths,
 			IncludeWellKnownTypes: true, // Always include the well-known types.
 			AllowUnusedImports:    e.Protoc.AllowUnusedImports,
+			Timeout:               timeout,
 		},
 		Create: CreateConfig{
 			DirPathToBasePackage: createDirPathToBasePackage,
@@ -391,37 +416,133 @@ func externalConfigToConfig(develMode bool, e ExternalConfig, dirPath string) (C
 	return config, nil
 }
 
-func getExcludePrefixesForDir(dirPath string) ([]string, error) {
+func getIDToSeverity(severities map[string]string) (map[string]text.Severity, error) {
+	if len(severities) == 0 {
+		return nil, nil
+	}
+	idToSeverity := make(map[string]text.Severity, len(severities))
+	for id, severityString := range severities {
+		id = strings.ToUpper(id)
+		if id == text.CompileFailureID {
+			return nil, fmt.Errorf("the severity of %s failures cannot be changed", text.CompileFailureID)
+		}
+		if _, ok := idToSeverity[id]; ok {
+			return nil, fmt.Errorf("duplicate severity for %s", id)
+		}
+		severity, err := text.ParseSeverity(severityString)
+		if err != nil {
+			return nil, err
+		}
+		idToSeverity[id] = severity
+	}
+	return idToSeverity, nil
+}
+
+func getDiscoveryForDir(dirPath string) (*DiscoveryConfig, error) {
 	filePath, err := getSingleFilePathForDir(dirPath)
 	if err != nil {
 		return nil, err
 	}
 	if filePath == "" {
-		return []string{}, nil
+		return nil, nil
 	}
 	externalConfig, err := getExternalConfig(filePath)
 	if err != nil {
 		return nil, err
 	}
-	return getExcludePrefixes(externalConfig.Excludes, dirPath)
+	return getDiscovery(externalConfig, dirPath)
+}
+
+func getDiscovery(e ExternalConfig, dirPath string) (*DiscoveryConfig, error) {
+	excludePrefixes, excludeGlobs, err := getExcludes(e.Excludes, dirPath)
+	if err != nil {
+		return nil, err
+	}
+	includeGlobs, err := getIncludeGlobs(e.Includes)
+	if err != nil {
+		return nil, err
+	}
+	return &DiscoveryConfig{
+		DirPath:         dirPath,
+		ExcludePrefixes: excludePrefixes,
+		ExcludeGlobs:    excludeGlobs,
+		IncludeGlobs:    includeGlobs,
+		HonorGitignore:  e.HonorGitignore,
+	}, nil
 }
 
-func getExcludePrefixes(excludes []string, dirPath string) ([]string, error) {
+// getExcludes splits the excludes into absolute prefixes and glob patterns.
+//
+// An exclude is a glob pattern if it contains any of the glob metacharacters "*?[".
+func getExcludes(excludes []string, dirPath string) ([]string, []string, error) {
 	excludePrefixes := make([]string, 0, len(excludes))
+	var excludeGlobs []string
 	for _, excludePrefix := range strs.SortUniq(excludes) {
+		if glob.IsGlob(excludePrefix) {
+			excludeGlob, err := getGlob(excludePrefix)
+			if err != nil {
+				return nil, nil, err
+			}
+			excludeGlobs = append(excludeGlobs, excludeGlob)
+			continue
+		}
 		if !filepath.IsAbs(excludePrefix) {
 			excludePrefix = filepath.Join(dirPath, excludePrefix)
 		}
 		excludePrefix = filepath.Clean(excludePrefix)
 		if excludePrefix == dirPath {
-			return nil, fmt.Errorf("cannot exclude directory of config file: %s", dirPath)
+			return nil, nil, fmt.Errorf("cannot exclude directory of config file: %s", dirPath)
 		}
 		if !strings.HasPrefix(excludePrefix, dirPath) {
-			return nil, fmt.Errorf("cannot exclude directory outside of config file directory %s: %s", dirPath, excludePrefix)
+			return nil, nil, fmt.Errorf("cannot exclude directory outside of config file directory %s: %s", dirPath, excludePrefix)
 		}
 		excludePrefixes = append(excludePrefixes, excludePrefix)
 	}
-	return excludePrefixes, nil
+	return excludePrefixes, sortUniqOrNil(excludeGlobs), nil
+}
+
+// getIncludeGlobs validates and cleans the include glob patterns.
+//
+// Returns nil if there are no includes.
+func getIncludeGlobs(includes []string) ([]string, error) {
+	var includeGlobs []string
+	for _, include := range includes {
+		includeGlob, err := getGlob(include)
+		if err != nil {
+			return nil, err
+		}
+		includeGlobs = append(includeGlobs, includeGlob)
+	}
+	return sortUniqOrNil(includeGlobs), nil
+}
+
+// sortUniqOrNil is strs.SortUniq, but returns nil instead of an empty slice.
+func sortUniqOrNil(s []string) []string {
+	if len(s) == 0 {
+		return nil
+	}
+	return strs.SortUniq(s)
+}
+
+// getGlob validates and cleans the glob pattern, which must be
+// slash-separated and relative to the config file directory.
+func getGlob(pattern string) (string, error) {
+	if filepath.IsAbs(pattern) || strings.HasPrefix(pattern, "/") {
+		return "", fmt.Errorf("glob pattern must be relative to the config file directory: %s", pattern)
+	}
+	cleanPattern := path.Clean(filepath.ToSlash(pattern))
+	for _, element := range strings.Split(cleanPattern, "/") {
+		if element == ".." {
+			return "", fmt.Errorf("glob pattern cannot be outside of the config file directory: %s", pattern)
+		}
+		if _, err := path.Match(element, ""); err != nil {
+			return "", fmt.Errorf("invalid glob pattern %s: %v", pattern, err)
+		}
+	}
+	if cleanPattern == "." {
+		return "", fmt.Errorf("glob pattern cannot match the config file directory: %s", pattern)
+	}
+	return cleanPattern, nil
 }
 
 // jsonUnmarshalStrict makes sure there are no unknown fields when unmarshalling.
@@ -22,9 +22,12 @@ package settings
 
 import (
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/uber/prototool/internal/text"
 	"go.uber.org/zap"
 )
 
@@ -51,6 +54,14 @@ var (
 		"prototool.json",
 	}
 
+	// LintGroups are the valid values for the lint group.
+	LintGroups = []string{
+		"empty",
+		"google",
+		"uber1",
+		"uber2",
+	}
+
 	_genPluginTypeToString = map[GenPluginType]string{
 		GenPluginTypeNone: "",
 		GenPluginTypeGo:   "go",
@@ -101,6 +112,19 @@ func (g GenPluginType) IsGogo() bool {
 	return _genPluginTypeToIsGogo[g]
 }
 
+// GenPluginTypeStrings returns the sorted string values of all
+// GenPluginTypes other than GenPluginTypeNone.
+func GenPluginTypeStrings() []string {
+	genPluginTypeStrings := make([]string, 0, len(_stringToGenPluginType))
+	for s, genPluginType := range _stringToGenPluginType {
+		if genPluginType != GenPluginTypeNone {
+			genPluginTypeStrings = append(genPluginTypeStrings, s)
+		}
+	}
+	sort.Strings(genPluginTypeStrings)
+	return genPluginTypeStrings
+}
+
 // ParseGenPluginType parses the GenPluginType from the given string.
 //
 // Input is case-insensitive.
@@ -133,6 +157,25 @@ type Config struct {
 	// Expected to be absolute paths.
 	// Expected to be unique.
 	ExcludePrefixes []string
+	// The glob patterns of files and directories to exclude.
+	// Expected to be slash-separated and relative to DirPath.
+	// Expected to be unique.
+	// Nil if there are none.
+	ExcludeGlobs []string
+	// The glob patterns of files to include. If set, only files that match,
+	// or whose parent directories match, one of the patterns are included.
+	// Expected to be slash-separated and relative to DirPath.
+	// Expected to be unique.
+	// Nil if there are none.
+	IncludeGlobs []string
+	// HonorGitignore says to exclude files and directories ignored by
+	// .gitignore files found when searching for files.
+	HonorGitignore bool
+	// IDToSeverity is the map of failure ID to the Severity to report
+	// failures with that ID with, instead of text.SeverityError.
+	// IDs expected to be all upper-case.
+	// Nil if there are none.
+	IDToSeverity map[string]text.Severity
 	// The compile config.
 	Compile CompileConfig
 	// The create config.
@@ -147,6 +190,18 @@ type Config struct {
 	Gen GenConfig
 }
 
+// DiscoveryConfig is the part of a Config used to find files.
+type DiscoveryConfig struct {
+	// The directory path of the config file.
+	// Expected to be absolute path.
+	DirPath string
+	// See Config for the descriptions of the below fields.
+	ExcludePrefixes []string
+	ExcludeGlobs    []string
+	IncludeGlobs    []string
+	HonorGitignore  bool
+}
+
 // CompileConfig is the compile config.
 type CompileConfig struct {
 	// The Protobuf version to use from https://github.com/protocolbuffers/protobuf/releases.
@@ -161,6 +216,9 @@ type CompileConfig struct {
 	IncludeWellKnownTypes bool
 	// AllowUnusedImports says to not error when an import is not used.
 	AllowUnusedImports bool
+	// Timeout is the maximum time for a single protoc invocation.
+	// If zero, there is no timeout.
+	Timeout time.Duration
 }
 
 // CreateConfig is the create config.
@@ -292,11 +350,15 @@ type OutputPath struct {
 //
 // It is meant to be set by a YAML or JSON config file, or flags.
 type ExternalConfig struct {
-	Excludes []string `json:"excludes,omitempty" yaml:"excludes,omitempty"`
-	Protoc   struct {
+	Excludes       []string          `json:"excludes,omitempty" yaml:"excludes,omitempty"`
+	Includes       []string          `json:"includes,omitempty" yaml:"includes,omitempty"`
+	HonorGitignore bool              `json:"honor_gitignore,omitempty" yaml:"honor_gitignore,omitempty"`
+	Severities     map[string]string `json:"severities,omitempty" yaml:"severities,omitempty"`
+	Protoc         struct {
 		AllowUnusedImports bool     `json:"allow_unused_imports,omitempty" yaml:"allow_unused_imports,omitempty"`
 		Version            string   `json:"version,omitempty" yaml:"version,omitempty"`
 		Includes           []string `json:"includes,omitempty" yaml:"includes,omitempty"`
+		Timeout            string   `json:"timeout,omitempty" yaml:"timeout,omitempty"`
 	} `json:"protoc,omitempty" yaml:"protoc,omitempty"`
 	Create struct {
 		Packages []struct {
@@ -380,16 +442,16 @@ type ConfigProvider interface {
 	// The Config will be as if there was a configuration file at the given dirPath.
 	GetForData(dirPath string, externalConfigData string) (Config, error)
 
-	// GetExcludePrefixesForDir tries to find a file named by one of the ConfigFilenames in the given
-	// directory and returns the cleaned absolute exclude prefixes. Unlike other functions
+	// GetDiscoveryForDir tries to find a file named by one of the ConfigFilenames in the given
+	// directory and returns the DiscoveryConfig. Unlike other functions
 	// on ConfigProvider, this has no recursive functionality - if there is no
-	// config file, nothing is returned.
+	// config file, nil is returned.
 	// If multiple files named by one of the ConfigFilenames are found in the same
 	// directory, error is returned.
-	GetExcludePrefixesForDir(dirPath string) ([]string, error)
-	// GetExcludePrefixesForData gets the exclude prefixes for the given ExternalConfigData in JSON format.
+	GetDiscoveryForDir(dirPath string) (*DiscoveryConfig, error)
+	// GetDiscoveryForData gets the DiscoveryConfig for the given ExternalConfigData in JSON format.
 	// The logic will act is if there was a configuration file at the given dirPath.
-	GetExcludePrefixesForData(dirPath string, externalConfigData string) ([]string, error)
+	GetDiscoveryForData(dirPath string, externalConfigData string) (*DiscoveryConfig, error)
 }
 
 // ConfigProviderOption is an option for a new ConfigProvider.
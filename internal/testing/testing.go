@@ -21,6 +21,7 @@
 package testing
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"path/filepath"
@@ -54,7 +55,7 @@ func GetFileDescriptorSets(workDirPath string, dirPath string) (protoc.FileDescr
 	compileResult, err := protoc.NewCompiler(
 		protoc.CompilerWithFileDescriptorSet(),
 		protoc.CompilerWithCachePath(filepath.Join(workDirPath, "testcache")),
-	).Compile(protoSet)
+	).Compile(context.Background(), protoSet)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,154 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package text
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+)
+
+var (
+	githubActionsDataReplacer = strings.NewReplacer(
+		"%", "%25",
+		"\r", "%0D",
+		"\n", "%0A",
+	)
+	githubActionsPropertyReplacer = strings.NewReplacer(
+		"%", "%25",
+		"\r", "%0D",
+		"\n", "%0A",
+		":", "%3A",
+		",", "%2C",
+	)
+)
+
+type gitlabIssue struct {
+	Description string         `json:"description"`
+	CheckName   string         `json:"check_name"`
+	Fingerprint string         `json:"fingerprint"`
+	Severity    string         `json:"severity"`
+	Location    gitlabLocation `json:"location"`
+}
+
+type gitlabLocation struct {
+	Path  string      `json:"path"`
+	Lines gitlabLines `json:"lines"`
+}
+
+type gitlabLines struct {
+	Begin int `json:"begin"`
+}
+
+// printFailuresGitHubActions prints the Failures as error, warning, or notice
+// workflow commands depending on their Severity.
+//
+// See https://docs.github.com/en/actions/using-workflows/workflow-commands-for-github-actions
+func printFailuresGitHubActions(writer io.Writer, failures []*Failure) error {
+	buffer := bytes.NewBuffer(nil)
+	for _, failure := range failures {
+		buffer.WriteString("::")
+		buffer.WriteString(getGitHubActionsCommand(failure.Severity))
+		buffer.WriteRune(' ')
+		if failure.Filename != "" {
+			buffer.WriteString("file=")
+			buffer.WriteString(githubActionsPropertyReplacer.Replace(failure.Filename))
+			buffer.WriteString(",line=")
+			buffer.WriteString(strconv.Itoa(atLeastOne(failure.Line)))
+			buffer.WriteString(",col=")
+			buffer.WriteString(strconv.Itoa(atLeastOne(failure.Column)))
+			buffer.WriteRune(',')
+		}
+		buffer.WriteString("title=")
+		buffer.WriteString(githubActionsPropertyReplacer.Replace(failure.ID()))
+		buffer.WriteString("::")
+		buffer.WriteString(githubActionsDataReplacer.Replace(failure.Message))
+		buffer.WriteRune('\n')
+	}
+	_, err := writer.Write(buffer.Bytes())
+	return err
+}
+
+// printFailuresGitLab prints the Failures as a GitLab Code Quality report.
+//
+// See https://docs.gitlab.com/ee/ci/testing/code_quality.html#implement-a-custom-tool
+func printFailuresGitLab(writer io.Writer, failures []*Failure) error {
+	issues := make([]gitlabIssue, 0, len(failures))
+	for _, failure := range failures {
+		issues = append(issues, gitlabIssue{
+			Description: failure.Message,
+			CheckName:   failure.ID(),
+			Fingerprint: getFingerprint(failure),
+			Severity:    getGitLabSeverity(failure.Severity),
+			Location: gitlabLocation{
+				Path: getFailureFilename(failure),
+				Lines: gitlabLines{
+					Begin: atLeastOne(failure.Line),
+				},
+			},
+		})
+	}
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(issues)
+}
+
+// getFingerprint returns a stable hash of all fields of the Failure.
+func getFingerprint(failure *Failure) string {
+	hash := sha256.New()
+	for _, value := range []string{
+		failure.Filename,
+		strconv.Itoa(failure.Line),
+		strconv.Itoa(failure.Column),
+		failure.LintID,
+		failure.Message,
+	} {
+		// length-prefix each field so that fields cannot run together
+		_, _ = hash.Write([]byte(strconv.Itoa(len(value)) + ":" + value))
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func getGitHubActionsCommand(severity Severity) string {
+	switch severity {
+	case SeverityWarning:
+		return "warning"
+	case SeverityInfo:
+		return "notice"
+	default:
+		return "error"
+	}
+}
+
+func getGitLabSeverity(severity Severity) string {
+	switch severity {
+	case SeverityWarning:
+		return "minor"
+	case SeverityInfo:
+		return "info"
+	default:
+		return "major"
+	}
+}
@@ -0,0 +1,253 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package text
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+const (
+	// CompileFailureID is the ID used in output formats that require an ID for
+	// Failures without a LintID, which are compile failures.
+	CompileFailureID = "COMPILE"
+	// UnusedImportFailureID is the LintID of compile failures for unused imports.
+	UnusedImportFailureID = "UNUSED_IMPORT"
+)
+
+const (
+	// OutputFormatText says to print Failures as text with FailureFields.
+	OutputFormatText OutputFormat = iota
+	// OutputFormatJSON says to print Failures as one JSON object per line.
+	OutputFormatJSON
+	// OutputFormatSARIF says to print Failures as a SARIF 2.1.0 log.
+	OutputFormatSARIF
+	// OutputFormatJUnit says to print Failures as JUnit XML.
+	OutputFormatJUnit
+	// OutputFormatCheckstyle says to print Failures as Checkstyle XML.
+	OutputFormatCheckstyle
+	// OutputFormatGitHubActions says to print Failures as GitHub Actions
+	// workflow commands.
+	OutputFormatGitHubActions
+	// OutputFormatGitLab says to print Failures as a GitLab Code Quality
+	// report, which is a Code Climate JSON array.
+	OutputFormatGitLab
+)
+
+var (
+	_outputFormatToString = map[OutputFormat]string{
+		OutputFormatText:          "text",
+		OutputFormatJSON:          "json",
+		OutputFormatSARIF:         "sarif",
+		OutputFormatJUnit:         "junit",
+		OutputFormatCheckstyle:    "checkstyle",
+		OutputFormatGitHubActions: "github-actions",
+		OutputFormatGitLab:        "gitlab",
+	}
+	_stringToOutputFormat = map[string]OutputFormat{
+		"text":           OutputFormatText,
+		"json":           OutputFormatJSON,
+		"sarif":          OutputFormatSARIF,
+		"junit":          OutputFormatJUnit,
+		"checkstyle":     OutputFormatCheckstyle,
+		"github-actions": OutputFormatGitHubActions,
+		"gitlab":         OutputFormatGitLab,
+	}
+)
+
+// OutputFormat is a format to print Failures in.
+type OutputFormat int
+
+// String implements fmt.Stringer.
+func (o OutputFormat) String() string {
+	if s, ok := _outputFormatToString[o]; ok {
+		return s
+	}
+	return strconv.Itoa(int(o))
+}
+
+// ParseOutputFormat parses the OutputFormat from the given string.
+//
+// Input is case-insensitive.
+func ParseOutputFormat(s string) (OutputFormat, error) {
+	outputFormat, ok := _stringToOutputFormat[strings.ToLower(s)]
+	if !ok {
+		return 0, fmt.Errorf("could not parse %s to an OutputFormat", s)
+	}
+	return outputFormat, nil
+}
+
+// OutputFormatStrings returns the string values of all OutputFormats in order.
+func OutputFormatStrings() []string {
+	outputFormatStrings := make([]string, 0, len(_outputFormatToString))
+	for i := 0; i < len(_outputFormatToString); i++ {
+		outputFormatStrings = append(outputFormatStrings, OutputFormat(i).String())
+	}
+	return outputFormatStrings
+}
+
+// FailurePrinter prints Failures in an OutputFormat.
+type FailurePrinter interface {
+	// PrintFailures prints the Failures in the given order.
+	//
+	// Formats that produce a single document, such as SARIF, always print
+	// a document, even if there are no Failures.
+	PrintFailures(writer io.Writer, failures ...*Failure) error
+}
+
+// FailurePrinterOption is an option for a new FailurePrinter.
+type FailurePrinterOption func(*failurePrinter)
+
+// FailurePrinterWithFailureFields returns a FailurePrinterOption that prints
+// the given FailureFields for OutputFormatText.
+//
+// The default is to use DefaultFailureFields.
+func FailurePrinterWithFailureFields(failureFields ...FailureField) FailurePrinterOption {
+	return func(failurePrinter *failurePrinter) {
+		failurePrinter.failureFields = failureFields
+	}
+}
+
+// FailurePrinterWithTemplate returns a FailurePrinterOption that prints
+// each Failure with the given template for OutputFormatText, instead of
+// printing FailureFields. A newline is added after each Failure if the
+// template did not print one.
+//
+// Templates are parsed with ParseTemplate.
+func FailurePrinterWithTemplate(tmpl *template.Template) FailurePrinterOption {
+	return func(failurePrinter *failurePrinter) {
+		failurePrinter.template = tmpl
+	}
+}
+
+// FailurePrinterWithFilenames returns a FailurePrinterOption that reports
+// the given files as checked, so that formats that report per file, such as
+// OutputFormatJUnit and OutputFormatCheckstyle, include files without Failures.
+//
+// Files with Failures do not need to be given.
+func FailurePrinterWithFilenames(filenames ...string) FailurePrinterOption {
+	return func(failurePrinter *failurePrinter) {
+		failurePrinter.filenames = filenames
+	}
+}
+
+// NewFailurePrinter returns a new FailurePrinter for the given OutputFormat.
+func NewFailurePrinter(outputFormat OutputFormat, options ...FailurePrinterOption) (FailurePrinter, error) {
+	if _, ok := _outputFormatToString[outputFormat]; !ok {
+		return nil, fmt.Errorf("unknown OutputFormat: %v", outputFormat)
+	}
+	failurePrinter := &failurePrinter{
+		outputFormat:  outputFormat,
+		failureFields: DefaultFailureFields,
+	}
+	for _, option := range options {
+		option(failurePrinter)
+	}
+	return failurePrinter, nil
+}
+
+type failurePrinter struct {
+	outputFormat  OutputFormat
+	failureFields []FailureField
+	template      *template.Template
+	filenames     []string
+}
+
+func (f *failurePrinter) PrintFailures(writer io.Writer, failures ...*Failure) error {
+	switch f.outputFormat {
+	case OutputFormatText:
+		if f.template != nil {
+			return printFailuresTemplate(writer, f.template, failures)
+		}
+		return printFailuresText(writer, f.failureFields, failures)
+	case OutputFormatJSON:
+		return printFailuresJSON(writer, failures)
+	case OutputFormatSARIF:
+		return printFailuresSARIF(writer, failures)
+	case OutputFormatJUnit:
+		return printFailuresJUnit(writer, f.filenames, failures)
+	case OutputFormatCheckstyle:
+		return printFailuresCheckstyle(writer, f.filenames, failures)
+	case OutputFormatGitHubActions:
+		return printFailuresGitHubActions(writer, failures)
+	case OutputFormatGitLab:
+		return printFailuresGitLab(writer, failures)
+	default:
+		return fmt.Errorf("unknown OutputFormat: %v", f.outputFormat)
+	}
+}
+
+func printFailuresText(writer io.Writer, failureFields []FailureField, failures []*Failure) error {
+	buffer := bytes.NewBuffer(nil)
+	for _, failure := range failures {
+		if err := failure.Fprintln(buffer, failureFields...); err != nil {
+			return err
+		}
+	}
+	_, err := writer.Write(buffer.Bytes())
+	return err
+}
+
+func printFailuresJSON(writer io.Writer, failures []*Failure) error {
+	buffer := bytes.NewBuffer(nil)
+	for _, failure := range failures {
+		data, err := json.Marshal(failure)
+		if err != nil {
+			return err
+		}
+		buffer.Write(data)
+		buffer.WriteRune('\n')
+	}
+	_, err := writer.Write(buffer.Bytes())
+	return err
+}
+
+func getFailureFilename(failure *Failure) string {
+	if failure.Filename != "" {
+		return failure.Filename
+	}
+	return "<input>"
+}
+
+// getFilenameToFailures groups the Failures by filename, and returns the
+// sorted filenames including the given filenames without Failures.
+func getFilenameToFailures(filenames []string, failures []*Failure) ([]string, map[string][]*Failure) {
+	filenameToFailures := make(map[string][]*Failure)
+	for _, filename := range filenames {
+		filenameToFailures[filename] = nil
+	}
+	for _, failure := range failures {
+		filename := getFailureFilename(failure)
+		filenameToFailures[filename] = append(filenameToFailures[filename], failure)
+	}
+	sortedFilenames := make([]string, 0, len(filenameToFailures))
+	for filename := range filenameToFailures {
+		sortedFilenames = append(sortedFilenames, filename)
+	}
+	sort.Strings(sortedFilenames)
+	return sortedFilenames, filenameToFailures
+}
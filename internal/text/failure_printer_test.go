@@ -0,0 +1,304 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package text
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber/prototool/internal/vars"
+)
+
+func TestParseOutputFormat(t *testing.T) {
+	for _, outputFormatString := range OutputFormatStrings() {
+		outputFormat, err := ParseOutputFormat(outputFormatString)
+		require.NoError(t, err)
+		assert.Equal(t, outputFormatString, outputFormat.String())
+	}
+	outputFormat, err := ParseOutputFormat("SARIF")
+	require.NoError(t, err)
+	assert.Equal(t, OutputFormatSARIF, outputFormat)
+	_, err = ParseOutputFormat("xml")
+	assert.Error(t, err)
+}
+
+func TestFailurePrinterText(t *testing.T) {
+	testFailurePrinter(
+		t,
+		OutputFormatText,
+		[]FailurePrinterOption{FailurePrinterWithFailureFields(FailureFieldFilename, FailureFieldID)},
+		"foo.proto:BAR\n<input>:\n",
+		newTestFailure("foo.proto", 1, 2, "BAR", "hello"),
+		newTestFailure("", 0, 0, "", "world"),
+	)
+}
+
+func TestFailurePrinterJSON(t *testing.T) {
+	testFailurePrinter(
+		t,
+		OutputFormatJSON,
+		nil,
+		`{"filename":"foo.proto","line":1,"column":2,"lint_id":"BAR","message":"hello","severity":"error"}
+{"message":"world","severity":"error"}
+`,
+		newTestFailure("foo.proto", 1, 2, "BAR", "hello"),
+		newTestFailure("", 0, 0, "", "world"),
+	)
+}
+
+func TestFailurePrinterSARIF(t *testing.T) {
+	testFailurePrinter(
+		t,
+		OutputFormatSARIF,
+		nil,
+		fmt.Sprintf(`{
+  "$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+  "version": "2.1.0",
+  "runs": [
+    {
+      "tool": {
+        "driver": {
+          "name": "prototool",
+          "version": %q,
+          "informationUri": "https://github.com/uber/prototool",
+          "rules": [
+            {
+              "id": "BAR"
+            },
+            {
+              "id": "COMPILE",
+              "shortDescription": {
+                "text": "Protobuf compilation failure."
+              }
+            }
+          ]
+        }
+      },
+      "results": [
+        {
+          "ruleId": "BAR",
+          "ruleIndex": 0,
+          "level": "error",
+          "message": {
+            "text": "hello"
+          },
+          "locations": [
+            {
+              "physicalLocation": {
+                "artifactLocation": {
+                  "uri": "foo/a%%20b.proto"
+                },
+                "region": {
+                  "startLine": 3,
+                  "startColumn": 2
+                }
+              }
+            }
+          ]
+        },
+        {
+          "ruleId": "COMPILE",
+          "ruleIndex": 1,
+          "level": "error",
+          "message": {
+            "text": "world"
+          },
+          "locations": [
+            {
+              "physicalLocation": {
+                "artifactLocation": {
+                  "uri": "file:///tmp/foo.proto"
+                },
+                "region": {
+                  "startLine": 1,
+                  "startColumn": 1
+                }
+              }
+            }
+          ]
+        },
+        {
+          "ruleId": "COMPILE",
+          "ruleIndex": 1,
+          "level": "error",
+          "message": {
+            "text": "no file"
+          }
+        }
+      ]
+    }
+  ]
+}
+`, vars.Version),
+		newTestFailure("foo/a b.proto", 3, 2, "BAR", "hello"),
+		newTestFailure("/tmp/foo.proto", 0, 0, "", "world"),
+		newTestFailure("", 0, 0, "", "no file"),
+	)
+	// a run is always printed so that the absence of failures is recorded
+	buffer := bytes.NewBuffer(nil)
+	failurePrinter, err := NewFailurePrinter(OutputFormatSARIF)
+	require.NoError(t, err)
+	require.NoError(t, failurePrinter.PrintFailures(buffer))
+	assert.Contains(t, buffer.String(), `"results": []`)
+	assert.Contains(t, buffer.String(), `"rules": []`)
+}
+
+func testFailurePrinter(t *testing.T, outputFormat OutputFormat, options []FailurePrinterOption, expected string, failures ...*Failure) {
+	failurePrinter, err := NewFailurePrinter(outputFormat, options...)
+	require.NoError(t, err)
+	buffer := bytes.NewBuffer(nil)
+	require.NoError(t, failurePrinter.PrintFailures(buffer, failures...))
+	assert.Equal(t, expected, buffer.String())
+}
+
+func TestFailurePrinterJUnit(t *testing.T) {
+	testFailurePrinter(
+		t,
+		OutputFormatJUnit,
+		[]FailurePrinterOption{FailurePrinterWithFilenames("a.proto", "b.proto")},
+		`<?xml version="1.0" encoding="UTF-8"?>
+<testsuites name="prototool" tests="4" failures="3">
+  <testsuite name="a.proto" tests="1" failures="0">
+    <testcase name="a.proto" classname="a.proto"></testcase>
+  </testsuite>
+  <testsuite name="b.proto" tests="2" failures="2">
+    <testcase name="BAR" classname="b.proto">
+      <failure message="hello &lt;world&gt;" type="BAR">b.proto:1:2:BAR hello &lt;world&gt;</failure>
+    </testcase>
+    <testcase name="COMPILE" classname="b.proto">
+      <failure message="compile" type="COMPILE">b.proto:3:1:compile</failure>
+    </testcase>
+  </testsuite>
+  <testsuite name="c.proto" tests="1" failures="1">
+    <testcase name="COMPILE" classname="c.proto">
+      <failure message="other" type="COMPILE">c.proto:1:1:other</failure>
+    </testcase>
+  </testsuite>
+</testsuites>
+`,
+		newTestFailure("b.proto", 1, 2, "BAR", "hello <world>"),
+		newTestFailure("b.proto", 3, 0, "", "compile"),
+		newTestFailure("c.proto", 0, 0, "", "other"),
+	)
+}
+
+func TestFailurePrinterCheckstyle(t *testing.T) {
+	testFailurePrinter(
+		t,
+		OutputFormatCheckstyle,
+		[]FailurePrinterOption{FailurePrinterWithFilenames("a.proto", "b.proto")},
+		`<?xml version="1.0" encoding="UTF-8"?>
+<checkstyle version="4.3">
+  <file name="a.proto"></file>
+  <file name="b.proto">
+    <error line="1" column="2" severity="error" message="hello" source="BAR"></error>
+    <error line="3" severity="error" message="compile" source="COMPILE"></error>
+  </file>
+</checkstyle>
+`,
+		newTestFailure("b.proto", 1, 2, "BAR", "hello"),
+		newTestFailure("b.proto", 3, 0, "", "compile"),
+	)
+}
+
+func TestFailurePrinterGitHubActions(t *testing.T) {
+	testFailurePrinter(
+		t,
+		OutputFormatGitHubActions,
+		nil,
+		`::error file=b.proto,line=1,col=2,title=BAR::hello%0Aworld 100%25%0D
+::error file=b%2Cc.proto,line=3,col=1,title=COMPILE::compile: bad
+::error title=COMPILE::other
+`,
+		newTestFailure("b.proto", 1, 2, "BAR", "hello\nworld 100%%\r"),
+		newTestFailure("b,c.proto", 3, 0, "", "compile: bad"),
+		newTestFailure("", 0, 0, "", "other"),
+	)
+}
+
+func TestFailurePrinterGitLab(t *testing.T) {
+	buffer := bytes.NewBuffer(nil)
+	failurePrinter, err := NewFailurePrinter(OutputFormatGitLab)
+	require.NoError(t, err)
+	require.NoError(t, failurePrinter.PrintFailures(buffer))
+	assert.Equal(t, "[]\n", buffer.String())
+
+	buffer.Reset()
+	require.NoError(
+		t,
+		failurePrinter.PrintFailures(
+			buffer,
+			newTestFailure("b.proto", 1, 2, "BAR", "hello"),
+			newTestFailure("b.proto", 3, 0, "", "compile"),
+		),
+	)
+	var issues []gitlabIssue
+	require.NoError(t, json.Unmarshal(buffer.Bytes(), &issues))
+	require.Len(t, issues, 2)
+	assert.Equal(t, "hello", issues[0].Description)
+	assert.Equal(t, "BAR", issues[0].CheckName)
+	assert.Equal(t, "major", issues[0].Severity)
+	assert.Equal(t, gitlabLocation{Path: "b.proto", Lines: gitlabLines{Begin: 1}}, issues[0].Location)
+	assert.Equal(t, "COMPILE", issues[1].CheckName)
+	assert.Equal(t, 3, issues[1].Location.Lines.Begin)
+	assert.Len(t, issues[0].Fingerprint, 64)
+	assert.NotEqual(t, issues[0].Fingerprint, issues[1].Fingerprint)
+	assert.Equal(t, issues[0].Fingerprint, getFingerprint(newTestFailure("b.proto", 1, 2, "BAR", "hello")))
+	assert.NotEqual(t, issues[0].Fingerprint, getFingerprint(newTestFailure("b.proto", 1, 2, "BAR", "hello!")))
+}
+
+func TestFailurePrinterSeverities(t *testing.T) {
+	warning := newTestFailure("b.proto", 1, 2, "BAR", "hello")
+	warning.Severity = SeverityWarning
+	info := newTestFailure("b.proto", 3, 0, "BAZ", "world")
+	info.Severity = SeverityInfo
+	testFailurePrinter(
+		t,
+		OutputFormatGitHubActions,
+		nil,
+		`::warning file=b.proto,line=1,col=2,title=BAR::hello
+::notice file=b.proto,line=3,col=1,title=BAZ::world
+`,
+		warning,
+		info,
+	)
+	testFailurePrinter(
+		t,
+		OutputFormatCheckstyle,
+		nil,
+		`<?xml version="1.0" encoding="UTF-8"?>
+<checkstyle version="4.3">
+  <file name="b.proto">
+    <error line="1" column="2" severity="warning" message="hello" source="BAR"></error>
+    <error line="3" severity="info" message="world" source="BAZ"></error>
+  </file>
+</checkstyle>
+`,
+		warning,
+		info,
+	)
+	assert.Equal(t, "note", getSARIFLevel(SeverityInfo))
+	assert.Equal(t, "minor", getGitLabSeverity(SeverityWarning))
+}
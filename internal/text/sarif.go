@@ -0,0 +1,188 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package text
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+	"path/filepath"
+	"sort"
+
+	"github.com/uber/prototool/internal/vars"
+)
+
+const (
+	sarifVersion   = "2.1.0"
+	sarifSchemaURI = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string        `json:"id"`
+	ShortDescription *sarifMessage `json:"shortDescription,omitempty"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	RuleIndex int             `json:"ruleIndex"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+}
+
+// printFailuresSARIF prints the Failures as a SARIF log with a single run.
+//
+// See https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html
+func printFailuresSARIF(writer io.Writer, failures []*Failure) error {
+	ruleIDToIndex := make(map[string]int)
+	for _, failure := range failures {
+		ruleIDToIndex[failure.ID()] = 0
+	}
+	ruleIDs := make([]string, 0, len(ruleIDToIndex))
+	for ruleID := range ruleIDToIndex {
+		ruleIDs = append(ruleIDs, ruleID)
+	}
+	sort.Strings(ruleIDs)
+	rules := make([]sarifRule, 0, len(ruleIDs))
+	for i, ruleID := range ruleIDs {
+		ruleIDToIndex[ruleID] = i
+		rule := sarifRule{ID: ruleID}
+		if ruleID == CompileFailureID {
+			rule.ShortDescription = &sarifMessage{Text: "Protobuf compilation failure."}
+		}
+		rules = append(rules, rule)
+	}
+	results := make([]sarifResult, 0, len(failures))
+	for _, failure := range failures {
+		ruleID := failure.ID()
+		result := sarifResult{
+			RuleID:    ruleID,
+			RuleIndex: ruleIDToIndex[ruleID],
+			Level:     getSARIFLevel(failure.Severity),
+			Message:   sarifMessage{Text: failure.Message},
+		}
+		if failure.Filename != "" {
+			result.Locations = []sarifLocation{
+				{
+					PhysicalLocation: sarifPhysicalLocation{
+						ArtifactLocation: sarifArtifactLocation{URI: getSARIFURI(failure.Filename)},
+						Region: sarifRegion{
+							StartLine:   atLeastOne(failure.Line),
+							StartColumn: atLeastOne(failure.Column),
+						},
+					},
+				},
+			}
+		}
+		results = append(results, result)
+	}
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(&sarifLog{
+		Schema:  sarifSchemaURI,
+		Version: sarifVersion,
+		Runs: []sarifRun{
+			{
+				Tool: sarifTool{
+					Driver: sarifDriver{
+						Name:           "prototool",
+						Version:        vars.Version,
+						InformationURI: "https://github.com/uber/prototool",
+						Rules:          rules,
+					},
+				},
+				Results: results,
+			},
+		},
+	})
+}
+
+// getSARIFURI returns a relative URI reference for relative paths, and a
+// file URI for absolute paths.
+func getSARIFURI(filename string) string {
+	if filepath.IsAbs(filename) {
+		return (&url.URL{Scheme: "file", Path: filepath.ToSlash(filename)}).String()
+	}
+	return (&url.URL{Path: filepath.ToSlash(filename)}).String()
+}
+
+// atLeastOne returns value, or 1 if value is not set, as Failure.String does.
+func atLeastOne(value int) int {
+	if value < 1 {
+		return 1
+	}
+	return value
+}
+
+func getSARIFLevel(severity Severity) string {
+	switch severity {
+	case SeverityWarning:
+		return "warning"
+	case SeverityInfo:
+		return "note"
+	default:
+		return "error"
+	}
+}
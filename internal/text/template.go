@@ -0,0 +1,137 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package text
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"unicode/utf8"
+)
+
+// TemplatePrefix is the prefix of an error format that is a text/template
+// evaluated against each Failure, as opposed to colon-separated FailureFields.
+const TemplatePrefix = "template:"
+
+var _colorToCode = map[string]string{
+	"bold":    "1",
+	"red":     "31",
+	"green":   "32",
+	"yellow":  "33",
+	"blue":    "34",
+	"magenta": "35",
+	"cyan":    "36",
+	"gray":    "90",
+}
+
+// IsTemplate returns true if the error format starts with TemplatePrefix.
+func IsTemplate(errorFormat string) bool {
+	return strings.HasPrefix(errorFormat, TemplatePrefix)
+}
+
+// ParseTemplate parses the text/template after TemplatePrefix in the given
+// error format. The template is evaluated against a Failure, and has the
+// following functions available:
+//
+//	id       the LintID, or CompileFailureID if not set
+//	abs      the absolute path of a path
+//	rel      the path relative to the current directory, if possible
+//	color    the value wrapped in ANSI escape codes for the named color,
+//	         one of bold, red, green, yellow, blue, magenta, cyan, gray
+//	padLeft  the value padded with spaces on the left to the given width
+//	padRight the value padded with spaces on the right to the given width
+//
+// For example:
+//
+//	template:{{rel .Filename}}({{.Line}},{{.Column}}): {{color "red" (id .)}} {{.Message}}
+func ParseTemplate(errorFormat string) (*template.Template, error) {
+	if !IsTemplate(errorFormat) {
+		return nil, fmt.Errorf("error format must start with %q to be a template: %s", TemplatePrefix, errorFormat)
+	}
+	return template.New("error-format").
+		Option("missingkey=error").
+		Funcs(template.FuncMap{
+			"id":       (*Failure).ID,
+			"abs":      filepath.Abs,
+			"rel":      relPath,
+			"color":    color,
+			"padLeft":  padLeft,
+			"padRight": padRight,
+		}).
+		Parse(strings.TrimPrefix(errorFormat, TemplatePrefix))
+}
+
+func printFailuresTemplate(writer io.Writer, tmpl *template.Template, failures []*Failure) error {
+	buffer := bytes.NewBuffer(nil)
+	for _, failure := range failures {
+		if err := tmpl.Execute(buffer, failure); err != nil {
+			return err
+		}
+		if data := buffer.Bytes(); len(data) > 0 && data[len(data)-1] != '\n' {
+			buffer.WriteRune('\n')
+		}
+	}
+	_, err := writer.Write(buffer.Bytes())
+	return err
+}
+
+func relPath(path string) string {
+	if !filepath.IsAbs(path) {
+		return path
+	}
+	absWorkDirPath, err := filepath.Abs(".")
+	if err != nil {
+		return path
+	}
+	relPath, err := filepath.Rel(absWorkDirPath, path)
+	if err != nil {
+		return path
+	}
+	return relPath
+}
+
+func color(name string, value interface{}) (string, error) {
+	code, ok := _colorToCode[strings.ToLower(name)]
+	if !ok {
+		return "", fmt.Errorf("unknown color: %s", name)
+	}
+	return "\x1b[" + code + "m" + fmt.Sprint(value) + "\x1b[0m", nil
+}
+
+func padLeft(width int, value interface{}) string {
+	s := fmt.Sprint(value)
+	return strings.Repeat(" ", padding(width, s)) + s
+}
+
+func padRight(width int, value interface{}) string {
+	s := fmt.Sprint(value)
+	return s + strings.Repeat(" ", padding(width, s))
+}
+
+func padding(width int, s string) int {
+	if n := width - utf8.RuneCountInString(s); n > 0 {
+		return n
+	}
+	return 0
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package text
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTemplate(t *testing.T) {
+	_, err := ParseTemplate("filename:line")
+	assert.Error(t, err)
+	_, err = ParseTemplate("template:{{.Filename")
+	assert.Error(t, err)
+	assert.True(t, IsTemplate("template:{{.Message}}"))
+	assert.False(t, IsTemplate("filename:line:column:message"))
+}
+
+func TestFailurePrinterTemplate(t *testing.T) {
+	workDirPath, err := os.Getwd()
+	require.NoError(t, err)
+	testFailurePrinterTemplate(
+		t,
+		"template:{{.Filename}}({{.Line}},{{.Column}}): {{.Message}}",
+		"foo.proto(1,2): hello\n(0,0): world\n",
+		newTestFailure("foo.proto", 1, 2, "BAR", "hello"),
+		newTestFailure("", 0, 0, "", "world"),
+	)
+	testFailurePrinterTemplate(
+		t,
+		"template:{{rel .Filename}}:{{padLeft 3 .Line}}|{{padRight 8 (id .)}}|\n",
+		"foo.proto:  1|BAR     |\nbar.proto: 10|COMPILE |\n",
+		newTestFailure(filepath.Join(workDirPath, "foo.proto"), 1, 2, "BAR", "hello"),
+		newTestFailure("bar.proto", 10, 0, "", "world"),
+	)
+	testFailurePrinterTemplate(
+		t,
+		"template:{{abs .Filename}}",
+		filepath.Join(workDirPath, "foo.proto")+"\n",
+		newTestFailure("foo.proto", 1, 2, "BAR", "hello"),
+	)
+	testFailurePrinterTemplate(
+		t,
+		`template:{{color "red" .LintID}} {{color "Bold" .Line}}`,
+		"\x1b[31mBAR\x1b[0m \x1b[1m1\x1b[0m\n",
+		newTestFailure("foo.proto", 1, 2, "BAR", "hello"),
+	)
+
+	tmpl, err := ParseTemplate(`template:{{color "pink" .Message}}`)
+	require.NoError(t, err)
+	failurePrinter, err := NewFailurePrinter(OutputFormatText, FailurePrinterWithTemplate(tmpl))
+	require.NoError(t, err)
+	assert.Error(t, failurePrinter.PrintFailures(bytes.NewBuffer(nil), newTestFailure("foo.proto", 1, 2, "BAR", "hello")))
+}
+
+func testFailurePrinterTemplate(t *testing.T, errorFormat string, expected string, failures ...*Failure) {
+	tmpl, err := ParseTemplate(errorFormat)
+	require.NoError(t, err)
+	testFailurePrinter(t, OutputFormatText, []FailurePrinterOption{FailurePrinterWithTemplate(tmpl)}, expected, failures...)
+}
@@ -40,6 +40,19 @@ const (
 	FailureFieldID
 	// FailureFieldMessage references the Message field of a Failure.
 	FailureFieldMessage
+	// FailureFieldSeverity references the Severity field of a Failure.
+	FailureFieldSeverity
+)
+
+const (
+	// SeverityError says the Failure is an error.
+	//
+	// This is the zero value, so Failures are errors unless otherwise set.
+	SeverityError Severity = iota
+	// SeverityWarning says the Failure is a warning.
+	SeverityWarning
+	// SeverityInfo says the Failure is informational.
+	SeverityInfo
 )
 
 var (
@@ -57,6 +70,7 @@ var (
 		FailureFieldColumn:   "column",
 		FailureFieldID:       "id",
 		FailureFieldMessage:  "message",
+		FailureFieldSeverity: "severity",
 	}
 	_stringToFailureField = map[string]FailureField{
 		"filename": FailureFieldFilename,
@@ -64,6 +78,18 @@ var (
 		"column":   FailureFieldColumn,
 		"id":       FailureFieldID,
 		"message":  FailureFieldMessage,
+		"severity": FailureFieldSeverity,
+	}
+
+	_severityToString = map[Severity]string{
+		SeverityError:   "error",
+		SeverityWarning: "warning",
+		SeverityInfo:    "info",
+	}
+	_stringToSeverity = map[string]Severity{
+		"error":   SeverityError,
+		"warning": SeverityWarning,
+		"info":    SeverityInfo,
 	}
 )
 
@@ -89,6 +115,53 @@ func ParseFailureField(s string) (FailureField, error) {
 	return failureField, nil
 }
 
+// Severity is the severity of a Failure.
+type Severity int
+
+// String implements fmt.Stringer.
+func (s Severity) String() string {
+	if str, ok := _severityToString[s]; ok {
+		return str
+	}
+	return strconv.Itoa(int(s))
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *Severity) UnmarshalText(data []byte) error {
+	severity, err := ParseSeverity(string(data))
+	if err != nil {
+		return err
+	}
+	*s = severity
+	return nil
+}
+
+// SeverityStrings returns the string values of all Severities, from most
+// to least severe.
+func SeverityStrings() []string {
+	severityStrings := make([]string, 0, len(_severityToString))
+	for i := 0; i < len(_severityToString); i++ {
+		severityStrings = append(severityStrings, Severity(i).String())
+	}
+	return severityStrings
+}
+
+// ParseSeverity parses the Severity from the given string.
+//
+// Input is case-insensitive.
+func ParseSeverity(s string) (Severity, error) {
+	severity, ok := _stringToSeverity[strings.ToLower(s)]
+	if !ok {
+		return 0, fmt.Errorf("could not parse %s to a Severity", s)
+	}
+	return severity, nil
+}
+
 // ParseColonSeparatedFailureFields parses FailureFields from the given string. FailureFields are expected to be colon-separated in the given string. Input is case-insensitive. If the string is empty, DefaultFailureFields will be returned.
 func ParseColonSeparatedFailureFields(s string) ([]FailureField, error) {
 	if len(s) == 0 {
@@ -108,11 +181,12 @@ func ParseColonSeparatedFailureFields(s string) ([]FailureField, error) {
 
 // Failure is a failure with a position in text.
 type Failure struct {
-	Filename string `json:"filename,omitempty"`
-	Line     int    `json:"line,omitempty"`
-	Column   int    `json:"column,omitempty"`
-	LintID   string `json:"lint_id,omitempty"`
-	Message  string `json:"message,omitempty"`
+	Filename string   `json:"filename,omitempty"`
+	Line     int      `json:"line,omitempty"`
+	Column   int      `json:"column,omitempty"`
+	LintID   string   `json:"lint_id,omitempty"`
+	Message  string   `json:"message,omitempty"`
+	Severity Severity `json:"severity"`
 }
 
 // FailureWriter is a writer that Failure.Println can accept.
@@ -177,6 +251,11 @@ func (f *Failure) Fprintln(writer FailureWriter, fields ...FailureField) error {
 			} else {
 				printColon = false
 			}
+		case FailureFieldSeverity:
+			if _, err := writer.WriteString(f.Severity.String()); err != nil {
+				return err
+			}
+			written = true
 		default:
 			return fmt.Errorf("unknown FailureField: %v", field)
 		}
@@ -194,6 +273,14 @@ func (f *Failure) Fprintln(writer FailureWriter, fields ...FailureField) error {
 	return nil
 }
 
+// ID returns the LintID, or CompileFailureID if the LintID is not set.
+func (f *Failure) ID() string {
+	if f.LintID != "" {
+		return f.LintID
+	}
+	return CompileFailureID
+}
+
 // String implements fmt.Stringer.
 func (f *Failure) String() string {
 	filename := f.Filename
@@ -234,6 +321,28 @@ func NewFailuref(position scanner.Position, lintID string, format string, args .
 	}
 }
 
+// SetSeverities sets the Severity of each Failure whose ID is a key in the
+// given map. Failures without a LintID have the ID CompileFailureID.
+func SetSeverities(idToSeverity map[string]Severity, failures ...*Failure) {
+	if len(idToSeverity) == 0 {
+		return
+	}
+	for _, failure := range failures {
+		if severity, ok := idToSeverity[failure.ID()]; ok {
+			failure.Severity = severity
+		}
+	}
+}
+
+// CountSeverities returns the number of Failures with each Severity.
+func CountSeverities(failures ...*Failure) map[Severity]int {
+	severityToCount := make(map[Severity]int)
+	for _, failure := range failures {
+		severityToCount[failure.Severity]++
+	}
+	return severityToCount
+}
+
 // SortFailures sorts the Failures, by filename, line, column, id, message.
 func SortFailures(failures []*Failure) {
 	sort.Stable(sortFailures(failures))
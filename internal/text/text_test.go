@@ -22,6 +22,7 @@ package text
 
 import (
 	"bytes"
+	"encoding/json"
 	"testing"
 	"text/scanner"
 
@@ -45,6 +46,54 @@ func TestFailureFprintln(t *testing.T) {
 	)
 }
 
+func TestFailureFprintlnSeverity(t *testing.T) {
+	testFailureFprintln(t, "error:hello", newTestFailure("", 0, 2, "BAR", "hello"), FailureFieldSeverity, FailureFieldMessage)
+	failure := newTestFailure("", 0, 2, "BAR", "hello")
+	failure.Severity = SeverityWarning
+	testFailureFprintln(t, "warning:hello", failure, FailureFieldSeverity, FailureFieldMessage)
+}
+
+func TestParseSeverity(t *testing.T) {
+	for _, severityString := range SeverityStrings() {
+		severity, err := ParseSeverity(severityString)
+		assert.NoError(t, err)
+		assert.Equal(t, severityString, severity.String())
+	}
+	severity, err := ParseSeverity("WARNING")
+	assert.NoError(t, err)
+	assert.Equal(t, SeverityWarning, severity)
+	_, err = ParseSeverity("fatal")
+	assert.Error(t, err)
+}
+
+func TestFailureSeverityJSON(t *testing.T) {
+	data, err := json.Marshal(newTestFailure("foo", 1, 2, "", "hello"))
+	assert.NoError(t, err)
+	assert.Equal(t, `{"filename":"foo","line":1,"column":2,"message":"hello","severity":"error"}`, string(data))
+	failure := newTestFailure("foo", 1, 2, "BAR", "hello")
+	failure.Severity = SeverityInfo
+	data, err = json.Marshal(failure)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"filename":"foo","line":1,"column":2,"lint_id":"BAR","message":"hello","severity":"info"}`, string(data))
+	var unmarshaled Failure
+	assert.NoError(t, json.Unmarshal(data, &unmarshaled))
+	assert.Equal(t, failure, &unmarshaled)
+	assert.Error(t, json.Unmarshal([]byte(`{"severity":"fatal"}`), &unmarshaled))
+}
+
+func TestSetSeverities(t *testing.T) {
+	failures := []*Failure{
+		newTestFailure("foo", 1, 2, "BAR", "hello"),
+		newTestFailure("foo", 1, 2, "BAZ", "hello"),
+		newTestFailure("foo", 1, 2, "", "hello"),
+	}
+	SetSeverities(map[string]Severity{"BAR": SeverityWarning, "BAT": SeverityInfo}, failures...)
+	assert.Equal(t, SeverityWarning, failures[0].Severity)
+	assert.Equal(t, SeverityError, failures[1].Severity)
+	assert.Equal(t, SeverityError, failures[2].Severity)
+	assert.Equal(t, map[Severity]int{SeverityError: 2, SeverityWarning: 1}, CountSeverities(failures...))
+}
+
 func testFailureFprintln(t *testing.T, expected string, failure *Failure, failureFields ...FailureField) {
 	buffer := bytes.NewBuffer(nil)
 	assert.NoError(t, failure.Fprintln(buffer, failureFields...))
@@ -0,0 +1,157 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package text
+
+import (
+	"encoding/xml"
+	"io"
+)
+
+type junitTestSuites struct {
+	XMLName    xml.Name         `xml:"testsuites"`
+	Name       string           `xml:"name,attr"`
+	Tests      int              `xml:"tests,attr"`
+	Failures   int              `xml:"failures,attr"`
+	TestSuites []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type checkstyle struct {
+	XMLName xml.Name         `xml:"checkstyle"`
+	Version string           `xml:"version,attr"`
+	Files   []checkstyleFile `xml:"file"`
+}
+
+type checkstyleFile struct {
+	Name   string            `xml:"name,attr"`
+	Errors []checkstyleError `xml:"error"`
+}
+
+type checkstyleError struct {
+	Line     int    `xml:"line,attr"`
+	Column   int    `xml:"column,attr,omitempty"`
+	Severity string `xml:"severity,attr"`
+	Message  string `xml:"message,attr"`
+	Source   string `xml:"source,attr"`
+}
+
+// printFailuresJUnit prints the Failures as JUnit XML with a test suite per
+// file and a test case per Failure named by the ID. Files without Failures
+// have a single passing test case.
+func printFailuresJUnit(writer io.Writer, filenames []string, failures []*Failure) error {
+	sortedFilenames, filenameToFailures := getFilenameToFailures(filenames, failures)
+	testSuites := &junitTestSuites{
+		Name:       "prototool",
+		TestSuites: make([]junitTestSuite, 0, len(sortedFilenames)),
+	}
+	for _, filename := range sortedFilenames {
+		testSuite := junitTestSuite{
+			Name: filename,
+		}
+		fileFailures := filenameToFailures[filename]
+		if len(fileFailures) == 0 {
+			testSuite.TestCases = []junitTestCase{
+				{
+					Name:      filename,
+					Classname: filename,
+				},
+			}
+		}
+		for _, failure := range fileFailures {
+			id := failure.ID()
+			testSuite.TestCases = append(testSuite.TestCases, junitTestCase{
+				Name:      id,
+				Classname: filename,
+				Failure: &junitFailure{
+					Message: failure.Message,
+					Type:    id,
+					Text:    failure.String(),
+				},
+			})
+		}
+		testSuite.Tests = len(testSuite.TestCases)
+		testSuite.Failures = len(fileFailures)
+		testSuites.Tests += testSuite.Tests
+		testSuites.Failures += testSuite.Failures
+		testSuites.TestSuites = append(testSuites.TestSuites, testSuite)
+	}
+	return printXML(writer, testSuites)
+}
+
+// printFailuresCheckstyle prints the Failures as Checkstyle XML with an
+// error per Failure whose source is the ID. Files without Failures have
+// no errors.
+func printFailuresCheckstyle(writer io.Writer, filenames []string, failures []*Failure) error {
+	sortedFilenames, filenameToFailures := getFilenameToFailures(filenames, failures)
+	checkstyle := &checkstyle{
+		Version: "4.3",
+		Files:   make([]checkstyleFile, 0, len(sortedFilenames)),
+	}
+	for _, filename := range sortedFilenames {
+		checkstyleFile := checkstyleFile{
+			Name: filename,
+		}
+		for _, failure := range filenameToFailures[filename] {
+			checkstyleFile.Errors = append(checkstyleFile.Errors, checkstyleError{
+				Line:     atLeastOne(failure.Line),
+				Column:   failure.Column,
+				Severity: failure.Severity.String(),
+				Message:  failure.Message,
+				Source:   failure.ID(),
+			})
+		}
+		checkstyle.Files = append(checkstyle.Files, checkstyleFile)
+	}
+	return printXML(writer, checkstyle)
+}
+
+func printXML(writer io.Writer, value interface{}) error {
+	data, err := xml.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(writer, xml.Header); err != nil {
+		return err
+	}
+	if _, err := writer.Write(data); err != nil {
+		return err
+	}
+	_, err = io.WriteString(writer, "\n")
+	return err
+}
@@ -0,0 +1,143 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package watch
+
+import (
+	"context"
+	"os"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type poller struct {
+	logger   *zap.Logger
+	interval time.Duration
+	debounce time.Duration
+}
+
+func newPoller(options ...PollerOption) *poller {
+	poller := &poller{
+		logger:   zap.NewNop(),
+		interval: DefaultInterval,
+		debounce: DefaultDebounce,
+	}
+	for _, option := range options {
+		option(poller)
+	}
+	return poller
+}
+
+func (p *poller) Poll(ctx context.Context, getPaths func() ([]string, error), onChange func([]string) error) error {
+	paths, err := getPaths()
+	if err != nil {
+		return err
+	}
+	states := getStates(paths)
+	pending := make(map[string]struct{})
+	var lastChangeTime time.Time
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		paths, err := getPaths()
+		if err != nil {
+			return err
+		}
+		newStates := getStates(paths)
+		for path, state := range states {
+			if newState, ok := newStates[path]; ok && newState == state {
+				continue
+			}
+			// a path that is no longer returned by getPaths is only a
+			// change if it was deleted
+			if _, ok := newStates[path]; !ok {
+				if newState := getState(path); newState == state {
+					continue
+				}
+			}
+			p.logger.Debug("changed", zap.String("path", path))
+			pending[path] = struct{}{}
+			lastChangeTime = time.Now()
+		}
+		states = newStates
+		if len(pending) == 0 || time.Since(lastChangeTime) < p.debounce {
+			continue
+		}
+		changedPaths := make([]string, 0, len(pending))
+		for path := range pending {
+			changedPaths = append(changedPaths, path)
+		}
+		sort.Strings(changedPaths)
+		pending = make(map[string]struct{})
+		if err := onChange(changedPaths); err != nil {
+			return err
+		}
+		// the handler may have written files into the watched directories,
+		// for example when generating, so take the current state of
+		// directories, but keep the state of files so that files changed
+		// while the handler was running are still detected
+		paths, err = getPaths()
+		if err != nil {
+			return err
+		}
+		newStates = getStates(paths)
+		for path, newState := range newStates {
+			if state, ok := states[path]; ok && !state.isDir && !newState.isDir {
+				newStates[path] = state
+			}
+		}
+		states = newStates
+	}
+}
+
+type state struct {
+	exists  bool
+	isDir   bool
+	modTime time.Time
+	size    int64
+}
+
+func getStates(paths []string) map[string]state {
+	states := make(map[string]state, len(paths))
+	for _, path := range paths {
+		states[path] = getState(path)
+	}
+	return states
+}
+
+func getState(path string) state {
+	fileInfo, err := os.Stat(path)
+	if err != nil {
+		return state{}
+	}
+	return state{
+		exists:  true,
+		isDir:   fileInfo.IsDir(),
+		modTime: fileInfo.ModTime(),
+		size:    fileInfo.Size(),
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package watch
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPoll(t *testing.T) {
+	dirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dirPath)
+	}()
+	filePath := filepath.Join(dirPath, "a.proto")
+	missingFilePath := filepath.Join(dirPath, "b.proto")
+	require.NoError(t, ioutil.WriteFile(filePath, []byte("a"), 0644))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var once sync.Once
+	started := make(chan struct{})
+	changes := make(chan []string)
+	errC := make(chan error, 1)
+	go func() {
+		errC <- newTestPoller().Poll(
+			ctx,
+			func() ([]string, error) {
+				once.Do(func() { close(started) })
+				return []string{dirPath, filePath, missingFilePath}, nil
+			},
+			func(paths []string) error {
+				changes <- paths
+				return nil
+			},
+		)
+	}()
+	<-started
+
+	require.NoError(t, ioutil.WriteFile(filePath, []byte("aa"), 0644))
+	assert.Equal(t, []string{filePath}, receiveChange(t, changes))
+	require.NoError(t, ioutil.WriteFile(missingFilePath, []byte("b"), 0644))
+	assert.Equal(t, []string{dirPath, missingFilePath}, receiveChange(t, changes))
+	require.NoError(t, os.Remove(filePath))
+	assert.Equal(t, []string{dirPath, filePath}, receiveChange(t, changes))
+
+	cancel()
+	assert.NoError(t, <-errC)
+}
+
+func TestPollError(t *testing.T) {
+	dirPath, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer func() {
+		_ = os.RemoveAll(dirPath)
+	}()
+	filePath := filepath.Join(dirPath, "a.proto")
+
+	expectedErr := errors.New("handler error")
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		_ = ioutil.WriteFile(filePath, []byte("a"), 0644)
+	}()
+	err = newTestPoller().Poll(
+		context.Background(),
+		func() ([]string, error) {
+			return []string{filePath}, nil
+		},
+		func([]string) error {
+			return expectedErr
+		},
+	)
+	assert.Equal(t, expectedErr, err)
+}
+
+func newTestPoller() Poller {
+	return NewPoller(PollerWithInterval(10*time.Millisecond), PollerWithDebounce(20*time.Millisecond))
+}
+
+func receiveChange(t *testing.T, changes <-chan []string) []string {
+	select {
+	case paths := <-changes:
+		return paths
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for change")
+		return nil
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package watch contains functionality to watch files for changes.
+//
+// Watching is done by polling so that it works in environments without
+// filesystem notifications, such as some containers.
+package watch
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultInterval is the default interval between polls.
+	DefaultInterval = 500 * time.Millisecond
+	// DefaultDebounce is the default duration without changes to wait for
+	// before calling the change handler.
+	DefaultDebounce = 200 * time.Millisecond
+)
+
+// Poller polls paths for changes.
+type Poller interface {
+	// Poll polls the paths returned by getPaths until the context is done.
+	//
+	// getPaths is called before every poll, so the set of paths can change
+	// over time, for example after the handler is called. A path is changed
+	// if it was returned by the previous call to getPaths, and has since been
+	// modified, created, or deleted. Directories are changed when entries are
+	// added to or removed from them. Paths that do not exist can be returned
+	// to detect when they are created.
+	//
+	// Changes are debounced, and then onChange is called with the sorted
+	// changed paths. If either getPaths or onChange returns an error, polling
+	// stops and the error is returned.
+	//
+	// Returns nil when the context is done.
+	Poll(ctx context.Context, getPaths func() ([]string, error), onChange func([]string) error) error
+}
+
+// PollerOption is an option for a new Poller.
+type PollerOption func(*poller)
+
+// PollerWithLogger returns a PollerOption that uses the given logger.
+//
+// The default is to use zap.NewNop().
+func PollerWithLogger(logger *zap.Logger) PollerOption {
+	return func(poller *poller) {
+		poller.logger = logger
+	}
+}
+
+// PollerWithInterval returns a PollerOption that polls at the given interval.
+//
+// The default is to use DefaultInterval.
+func PollerWithInterval(interval time.Duration) PollerOption {
+	return func(poller *poller) {
+		poller.interval = interval
+	}
+}
+
+// PollerWithDebounce returns a PollerOption that waits until there have been
+// no changes for the given duration before calling the change handler.
+//
+// The default is to use DefaultDebounce.
+func PollerWithDebounce(debounce time.Duration) PollerOption {
+	return func(poller *poller) {
+		poller.debounce = debounce
+	}
+}
+
+// NewPoller returns a new Poller.
+func NewPoller(options ...PollerOption) Poller {
+	return newPoller(options...)
+}
@@ -0,0 +1,85 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package prototool_test
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/uber/prototool/pkg/prototool"
+)
+
+func ExampleFiles() {
+	files, err := prototool.Files(context.Background(), prototool.Config{Path: "testdata"})
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, file := range files {
+		fmt.Println(file)
+	}
+	// Output:
+	// testdata/bar/v1/bar.proto
+	// testdata/foo/v1/foo.proto
+}
+
+func ExampleCompile() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	compileResult, err := prototool.Compile(ctx, prototool.Config{Path: "testdata"})
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, failure := range compileResult.Failures {
+		fmt.Printf("%s:%d:%d:%s:%s\n", failure.Filename, failure.Line, failure.Column, failure.ID, failure.Message)
+	}
+}
+
+func ExampleGenerate() {
+	generateResult, err := prototool.Generate(context.Background(), prototool.Config{Path: "testdata"})
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(generateResult.Failures) > 0 {
+		log.Fatalf("%d failures", len(generateResult.Failures))
+	}
+	for _, file := range generateResult.Files {
+		fmt.Println(file)
+	}
+}
+
+func ExampleDescriptorSet() {
+	descriptorSetResult, err := prototool.DescriptorSet(
+		context.Background(),
+		prototool.Config{Path: "testdata"},
+		prototool.DescriptorSetOptions{IncludeImports: true},
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if descriptorSetResult.FileDescriptorSet == nil {
+		log.Fatalf("%d failures", len(descriptorSetResult.Failures))
+	}
+	for _, fileDescriptorProto := range descriptorSetResult.FileDescriptorSet.GetFile() {
+		fmt.Println(fileDescriptorProto.GetName())
+	}
+}
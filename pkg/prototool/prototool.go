@@ -0,0 +1,539 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package prototool is the public Go API for Prototool, for embedding
+// Prototool in other programs instead of running the prototool binary
+// and parsing its output.
+//
+// Each function takes a Config that specifies the file or directory to
+// operate on, and returns typed results. Compile failures, for example
+// a syntax error, are returned as Failures, and are not errors. Errors are
+// only returned for problems such as an invalid configuration file, or
+// protoc not being able to run.
+//
+// # Compatibility
+//
+// This package follows semantic versioning. Within a major version,
+// exported identifiers will not be removed or renamed, function
+// signatures will not change, and fields will only be added to structs,
+// so construct structs with field names. Failure messages and the contents
+// of FileDescriptorSets are produced by protoc, and may change between
+// protoc versions. Everything under internal/ has no compatibility promise.
+package prototool
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/uber/prototool/internal/file"
+	"github.com/uber/prototool/internal/protoc"
+	"github.com/uber/prototool/internal/text"
+	"go.uber.org/zap"
+)
+
+const (
+	// SeverityError says a Failure is an error.
+	SeverityError Severity = iota
+	// SeverityWarning says a Failure is a warning.
+	SeverityWarning
+	// SeverityInfo says a Failure is informational.
+	SeverityInfo
+)
+
+// Severity is the severity of a Failure.
+type Severity int
+
+// String implements fmt.Stringer.
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	case SeverityInfo:
+		return "info"
+	default:
+		return strconv.Itoa(int(s))
+	}
+}
+
+// Failure is a compile failure, for example a syntax error.
+type Failure struct {
+	// Relative to Config.WorkDirPath, or absolute if the file is outside
+	// of it. Empty if the Failure is not for a single file.
+	Filename string
+	// Zero if unknown.
+	Line int
+	// Zero if unknown.
+	Column int
+	// The ID used by the severities section of the configuration file,
+	// for example UNUSED_IMPORT, or COMPILE for other compile failures.
+	ID       string
+	Message  string
+	Severity Severity
+}
+
+// String implements fmt.Stringer.
+func (f *Failure) String() string {
+	return newTextFailure(f).String()
+}
+
+// Config configures a call.
+//
+// The zero value operates on the current directory with the protoc
+// version from the configuration file, downloaded to the default cache.
+type Config struct {
+	// The directory that Path is relative to, and that Failure filenames
+	// are relative to.
+	// The default is the current directory.
+	WorkDirPath string
+	// The .proto file or directory to operate on. If a file, the
+	// directory of the file is compiled, but only the Failures for the
+	// file are returned.
+	// The default is WorkDirPath.
+	Path string
+	// The configuration data to use instead of reading prototool.yaml or
+	// prototool.json files, as with the --config-data flag.
+	ConfigData string
+	// The path to use for the cache.
+	// The default is the same as for the prototool binary.
+	CachePath string
+	// The path to the protoc binary. Must be set with ProtocWKTPath.
+	ProtocBinPath string
+	// The path to include for the well-known types. Must be set with
+	// ProtocBinPath.
+	ProtocWKTPath string
+	// The URL of the protoc zip file to download instead of the release
+	// for the protoc version in the configuration file.
+	// Cannot be set with ProtocBinPath.
+	ProtocURL string
+	// The maximum number of protoc invocations to run in parallel.
+	// The default is the number of CPUs.
+	Jobs int
+	// The maximum time for a single protoc invocation. Invocations that
+	// take longer are killed and returned as Failures.
+	// The default is the protoc timeout in the configuration file,
+	// or no timeout if that is not set.
+	ProtocTimeout time.Duration
+	// The maximum time to walk directories looking for .proto files.
+	// The default is 3 seconds.
+	WalkTimeout time.Duration
+	// The contents to use instead of reading files from disk, for example
+	// the unsaved buffers of an editor. Relative paths are relative to
+	// WorkDirPath. The files do not need to exist, however their directory
+	// must. Failures refer to the given paths.
+	Overlay map[string][]byte
+	// The default is to not log.
+	Logger *zap.Logger
+}
+
+// CompileResult is the result of Compile.
+type CompileResult struct {
+	// Sorted by filename, line and column.
+	Failures []*Failure
+}
+
+// GenerateResult is the result of Generate.
+type GenerateResult struct {
+	// Sorted by filename, line and column.
+	Failures []*Failure
+	// The absolute paths of the files within the plugin output paths
+	// that were created or modified while generating, sorted.
+	//
+	// This is determined by comparing the files before and after
+	// generating, so files written to the output paths by other processes
+	// while generating are also included.
+	Files []string
+}
+
+// DescriptorSetOptions are the options for DescriptorSet.
+type DescriptorSetOptions struct {
+	// Include all imports, as with protoc --include_imports.
+	IncludeImports bool
+	// Include source code info, as with protoc --include_source_info.
+	IncludeSourceInfo bool
+}
+
+// DescriptorSetResult is the result of DescriptorSet.
+type DescriptorSetResult struct {
+	// Sorted by filename, line and column.
+	Failures []*Failure
+	// The merge of the FileDescriptorSets for each compiled directory,
+	// in the order returned by protoc, without duplicate files.
+	//
+	// Not set if there are any Failures with SeverityError.
+	FileDescriptorSet *descriptor.FileDescriptorSet
+}
+
+// Files returns the .proto files for the Config, sorted.
+//
+// The files are relative to Config.WorkDirPath, or absolute if outside of it.
+func Files(ctx context.Context, config Config) ([]string, error) {
+	c, err := newCall(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for dirPath, protoFiles := range c.protoSet.DirPathToFiles {
+		if !c.protoSet.IsTargetDirPath(dirPath) {
+			continue
+		}
+		for _, protoFile := range protoFiles {
+			if c.singleFilePath == "" || c.singleFilePath == protoFile.Path {
+				files = append(files, protoFile.DisplayPath)
+			}
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// Compile compiles the .proto files for the Config with protoc.
+//
+// If the context is cancelled, protoc is killed and the context error
+// is returned.
+func Compile(ctx context.Context, config Config) (*CompileResult, error) {
+	c, err := newCall(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	compileResult, err := c.compile()
+	if err != nil {
+		return nil, err
+	}
+	return &CompileResult{
+		Failures: c.getFailures(compileResult),
+	}, nil
+}
+
+// Generate compiles the .proto files for the Config with protoc, and
+// generates stubs with the plugins in the configuration file.
+//
+// If the context is cancelled, protoc is killed and the context error
+// is returned.
+func Generate(ctx context.Context, config Config) (*GenerateResult, error) {
+	c, err := newCall(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	before, err := getOutputFileStates(c.protoSet)
+	if err != nil {
+		return nil, err
+	}
+	compileResult, err := c.compile(protoc.CompilerWithGen())
+	if err != nil {
+		return nil, err
+	}
+	after, err := getOutputFileStates(c.protoSet)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]string, 0, len(after))
+	for filePath, state := range after {
+		if beforeState, ok := before[filePath]; !ok || !beforeState.equal(state) {
+			files = append(files, filePath)
+		}
+	}
+	sort.Strings(files)
+	return &GenerateResult{
+		Failures: c.getFailures(compileResult),
+		Files:    files,
+	}, nil
+}
+
+// DescriptorSet compiles the .proto files for the Config with protoc, and
+// returns the resulting FileDescriptorSet.
+//
+// If the context is cancelled, protoc is killed and the context error
+// is returned.
+func DescriptorSet(ctx context.Context, config Config, options DescriptorSetOptions) (*DescriptorSetResult, error) {
+	c, err := newCall(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	compileResult, err := c.compile(
+		protoc.CompilerWithFileDescriptorSetFullControl(options.IncludeImports, options.IncludeSourceInfo),
+	)
+	if err != nil {
+		return nil, err
+	}
+	failures := c.getFailures(compileResult)
+	if hasErrors(failures) {
+		return &DescriptorSetResult{
+			Failures: failures,
+		}, nil
+	}
+	return &DescriptorSetResult{
+		Failures:          failures,
+		FileDescriptorSet: compileResult.FileDescriptorSets.Merge(),
+	}, nil
+}
+
+type call struct {
+	ctx      context.Context
+	config   Config
+	protoSet *file.ProtoSet
+	// absolute, set if Config.Path is a file
+	singleFilePath string
+}
+
+func newCall(ctx context.Context, config Config) (*call, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if config.Logger == nil {
+		config.Logger = zap.NewNop()
+	}
+	workDirPath, err := file.AbsClean(config.WorkDirPath)
+	if err != nil {
+		return nil, err
+	}
+	if workDirPath == "" {
+		if workDirPath, err = os.Getwd(); err != nil {
+			return nil, err
+		}
+	}
+	config.WorkDirPath = workDirPath
+	config.Overlay = getAbsOverlay(workDirPath, config.Overlay)
+	path := config.Path
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(workDirPath, path)
+	}
+	path = filepath.Clean(path)
+	dirPath := path
+	singleFilePath := ""
+	if _, ok := config.Overlay[path]; ok {
+		dirPath = filepath.Dir(path)
+		singleFilePath = path
+	} else {
+		fileInfo, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case fileInfo.Mode().IsDir():
+		case fileInfo.Mode().IsRegular():
+			dirPath = filepath.Dir(path)
+			singleFilePath = path
+		default:
+			return nil, fmt.Errorf("%s is not a directory or a regular file", config.Path)
+		}
+	}
+	protoSetProviderOptions := []file.ProtoSetProviderOption{
+		file.ProtoSetProviderWithLogger(config.Logger),
+	}
+	if config.WalkTimeout > 0 {
+		protoSetProviderOptions = append(
+			protoSetProviderOptions,
+			file.ProtoSetProviderWithWalkTimeout(config.WalkTimeout),
+		)
+	}
+	if config.ConfigData != "" {
+		protoSetProviderOptions = append(
+			protoSetProviderOptions,
+			file.ProtoSetProviderWithConfigData(config.ConfigData),
+		)
+	}
+	if len(config.Overlay) > 0 {
+		protoSetProviderOptions = append(
+			protoSetProviderOptions,
+			file.ProtoSetProviderWithOverlay(config.Overlay),
+		)
+	}
+	protoSet, err := file.NewProtoSetProvider(protoSetProviderOptions...).GetForDir(workDirPath, dirPath)
+	if err != nil {
+		return nil, err
+	}
+	return &call{
+		ctx:            ctx,
+		config:         config,
+		protoSet:       protoSet,
+		singleFilePath: singleFilePath,
+	}, nil
+}
+
+func (c *call) compile(options ...protoc.CompilerOption) (*protoc.CompileResult, error) {
+	if (c.config.ProtocBinPath == "") != (c.config.ProtocWKTPath == "") {
+		return nil, fmt.Errorf("ProtocBinPath and ProtocWKTPath must be set together")
+	}
+	if c.config.ProtocBinPath != "" && c.config.ProtocURL != "" {
+		return nil, fmt.Errorf("ProtocURL cannot be set with ProtocBinPath")
+	}
+	compilerOptions := []protoc.CompilerOption{
+		protoc.CompilerWithLogger(c.config.Logger),
+		protoc.CompilerWithJobs(c.config.Jobs),
+	}
+	if c.config.CachePath != "" {
+		compilerOptions = append(
+			compilerOptions,
+			protoc.CompilerWithCachePath(c.config.CachePath),
+		)
+	}
+	if c.config.ProtocBinPath != "" {
+		compilerOptions = append(
+			compilerOptions,
+			protoc.CompilerWithProtocBinPath(c.config.ProtocBinPath),
+			protoc.CompilerWithProtocWKTPath(c.config.ProtocWKTPath),
+		)
+	}
+	if c.config.ProtocURL != "" {
+		compilerOptions = append(
+			compilerOptions,
+			protoc.CompilerWithProtocURL(c.config.ProtocURL),
+		)
+	}
+	if c.config.ProtocTimeout > 0 {
+		compilerOptions = append(
+			compilerOptions,
+			protoc.CompilerWithTimeout(c.config.ProtocTimeout),
+		)
+	}
+	if len(c.config.Overlay) > 0 {
+		compilerOptions = append(
+			compilerOptions,
+			protoc.CompilerWithOverlay(c.config.Overlay),
+		)
+	}
+	compilerOptions = append(compilerOptions, options...)
+	return protoc.NewCompiler(compilerOptions...).Compile(c.ctx, c.protoSet)
+}
+
+// getAbsOverlay returns the overlay with the paths made absolute
+// relative to the given absolute working directory path, and cleaned.
+func getAbsOverlay(workDirPath string, overlay map[string][]byte) map[string][]byte {
+	if len(overlay) == 0 {
+		return nil
+	}
+	absOverlay := make(map[string][]byte, len(overlay))
+	for path, data := range overlay {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(workDirPath, path)
+		}
+		absOverlay[filepath.Clean(path)] = data
+	}
+	return absOverlay
+}
+
+// getFailures returns the Failures for the single file if set,
+// or all Failures otherwise.
+func (c *call) getFailures(compileResult *protoc.CompileResult) []*Failure {
+	failures := make([]*Failure, 0, len(compileResult.Failures))
+	for _, failure := range compileResult.Failures {
+		if c.singleFilePath != "" {
+			filePath := failure.Filename
+			if !filepath.IsAbs(filePath) {
+				filePath = filepath.Join(c.config.WorkDirPath, filePath)
+			}
+			if filePath != c.singleFilePath {
+				continue
+			}
+		}
+		failures = append(failures, newFailure(failure))
+	}
+	return failures
+}
+
+func newFailure(failure *text.Failure) *Failure {
+	return &Failure{
+		Filename: failure.Filename,
+		Line:     failure.Line,
+		Column:   failure.Column,
+		ID:       failure.ID(),
+		Message:  failure.Message,
+		Severity: newSeverity(failure.Severity),
+	}
+}
+
+func newTextFailure(failure *Failure) *text.Failure {
+	textFailure := &text.Failure{
+		Filename: failure.Filename,
+		Line:     failure.Line,
+		Column:   failure.Column,
+		Message:  failure.Message,
+	}
+	if failure.ID != text.CompileFailureID {
+		textFailure.LintID = failure.ID
+	}
+	return textFailure
+}
+
+func newSeverity(severity text.Severity) Severity {
+	switch severity {
+	case text.SeverityWarning:
+		return SeverityWarning
+	case text.SeverityInfo:
+		return SeverityInfo
+	default:
+		return SeverityError
+	}
+}
+
+func hasErrors(failures []*Failure) bool {
+	for _, failure := range failures {
+		if failure.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// fileState is the state of a file that changes when the file is written.
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+func (f fileState) equal(other fileState) bool {
+	return f.modTime.Equal(other.modTime) && f.size == other.size
+}
+
+// getOutputFileStates returns the states of the regular files within the
+// plugin output paths.
+func getOutputFileStates(protoSet *file.ProtoSet) (map[string]fileState, error) {
+	filePathToState := make(map[string]fileState)
+	for _, genPlugin := range protoSet.Config.Gen.Plugins {
+		err := filepath.Walk(genPlugin.OutputPath.AbsPath, func(filePath string, fileInfo os.FileInfo, err error) error {
+			if err != nil {
+				// the output path may not have been created yet
+				if os.IsNotExist(err) {
+					return nil
+				}
+				return err
+			}
+			if fileInfo.Mode().IsRegular() {
+				filePathToState[filePath] = fileState{
+					modTime: fileInfo.ModTime(),
+					size:    fileInfo.Size(),
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return filePathToState, nil
+}
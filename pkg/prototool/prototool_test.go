@@ -0,0 +1,179 @@
+// Copyright (c) 2020 Uber Technologies, Inc.
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package prototool
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFiles(t *testing.T) {
+	files, err := Files(context.Background(), Config{WorkDirPath: "testdata", Path: "foo/v1/foo.proto"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"foo/v1/foo.proto"}, files)
+	_, err = Files(context.Background(), Config{Path: "testdata/baz"})
+	assert.Error(t, err)
+}
+
+func TestCompile(t *testing.T) {
+	config := newTestConfig(t, `case "$*" in
+  *foo.proto*)
+    echo 'foo/v1/foo.proto:8:3: "Baz" is not defined.' >&2
+    exit 1
+    ;;
+esac
+`)
+	compileResult, err := Compile(context.Background(), config)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		[]*Failure{
+			{
+				Filename: "foo/v1/foo.proto",
+				Line:     8,
+				Column:   3,
+				ID:       "COMPILE",
+				Message:  `"Baz" is not defined.`,
+			},
+		},
+		compileResult.Failures,
+	)
+	assert.Equal(t, `foo/v1/foo.proto:8:3:"Baz" is not defined.`, compileResult.Failures[0].String())
+	// only the failures for the file are returned
+	config.Path = "bar/v1/bar.proto"
+	compileResult, err = Compile(context.Background(), config)
+	require.NoError(t, err)
+	assert.Empty(t, compileResult.Failures)
+	config.ProtocWKTPath = ""
+	_, err = Compile(context.Background(), config)
+	assert.EqualError(t, err, "ProtocBinPath and ProtocWKTPath must be set together")
+}
+
+func TestCompileOverlay(t *testing.T) {
+	// prints a failure for every overlaid file given to protoc
+	config := newTestConfig(t, `for arg in "$@"; do
+  case "${arg}" in
+    *.proto)
+      grep -q overlaid "${arg}" && echo "foo/v1/$(basename "${arg}"):1:1:Overlaid." >&2
+      ;;
+  esac
+done
+exit 0
+`)
+	config.Path = "foo/v1/new.proto"
+	config.Overlay = map[string][]byte{
+		"foo/v1/new.proto": []byte("// overlaid\n"),
+	}
+	compileResult, err := Compile(context.Background(), config)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		[]*Failure{
+			{
+				Filename: "foo/v1/new.proto",
+				Line:     1,
+				Column:   1,
+				ID:       "COMPILE",
+				Message:  "Overlaid.",
+			},
+		},
+		compileResult.Failures,
+	)
+	files, err := Files(context.Background(), config)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"foo/v1/new.proto"}, files)
+}
+
+func TestGenerate(t *testing.T) {
+	// writes a file to the output path of each plugin
+	config := newTestConfig(t, `for arg in "$@"; do
+  case "${arg}" in
+    --*_out=*)
+      out="${arg#*_out=}"
+      out="${out##*:}"
+      mkdir -p "${out}"
+      touch "${out}/gen.txt"
+      ;;
+  esac
+done
+`)
+	require.NoError(t, ioutil.WriteFile(
+		filepath.Join(config.WorkDirPath, "prototool.yaml"),
+		[]byte("generate:\n  plugins:\n    - name: foo\n      output: gen/foo\n    - name: bar\n      output: gen/bar\n"),
+		0644,
+	))
+	require.NoError(t, os.MkdirAll(filepath.Join(config.WorkDirPath, "gen", "bar"), 0755))
+	oldFilePath := filepath.Join(config.WorkDirPath, "gen", "bar", "old.txt")
+	require.NoError(t, ioutil.WriteFile(oldFilePath, nil, 0644))
+	oldTime := time.Now().Add(-time.Hour)
+	require.NoError(t, os.Chtimes(oldFilePath, oldTime, oldTime))
+	// files written before generating are not included, even if they were
+	// written within the same second
+	recentFilePath := filepath.Join(config.WorkDirPath, "gen", "bar", "recent.txt")
+	require.NoError(t, ioutil.WriteFile(recentFilePath, nil, 0644))
+	generateResult, err := Generate(context.Background(), config)
+	require.NoError(t, err)
+	assert.Empty(t, generateResult.Failures)
+	assert.Equal(
+		t,
+		[]string{
+			filepath.Join(config.WorkDirPath, "gen", "bar", "gen.txt"),
+			filepath.Join(config.WorkDirPath, "gen", "foo", "gen.txt"),
+		},
+		generateResult.Files,
+	)
+}
+
+// newTestConfig returns a Config for a copy of testdata, with a protoc
+// that runs the given shell script.
+func newTestConfig(t *testing.T, protocScript string) Config {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a shell script as protoc")
+	}
+	tmpDir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, os.RemoveAll(tmpDir)) })
+	protocBinPath := filepath.Join(tmpDir, "bin", "protoc")
+	protocWKTPath := filepath.Join(tmpDir, "include")
+	require.NoError(t, os.MkdirAll(filepath.Dir(protocBinPath), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(protocWKTPath, "google", "protobuf"), 0755))
+	require.NoError(t, ioutil.WriteFile(protocBinPath, []byte("#!/bin/sh\n"+protocScript), 0755))
+	workDirPath := filepath.Join(tmpDir, "work")
+	for _, filePath := range []string{"prototool.yaml", "foo/v1/foo.proto", "bar/v1/bar.proto"} {
+		data, err := ioutil.ReadFile(filepath.Join("testdata", filePath))
+		require.NoError(t, err)
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(workDirPath, filePath)), 0755))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(workDirPath, filePath), data, 0644))
+	}
+	return Config{
+		WorkDirPath:   workDirPath,
+		ProtocBinPath: protocBinPath,
+		ProtocWKTPath: protocWKTPath,
+	}
+}